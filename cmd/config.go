@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -8,6 +9,10 @@ import (
 	"github.com/naoina/toml"
 
 	"github.com/FreifunkBremen/yanic/database"
+	"github.com/FreifunkBremen/yanic/federation"
+	"github.com/FreifunkBremen/yanic/lib/duration"
+	"github.com/FreifunkBremen/yanic/notify"
+	"github.com/FreifunkBremen/yanic/output/filter"
 	"github.com/FreifunkBremen/yanic/respond"
 	"github.com/FreifunkBremen/yanic/runtime"
 	"github.com/FreifunkBremen/yanic/webserver"
@@ -15,10 +20,12 @@ import (
 
 // Config represents the whole configuration
 type Config struct {
-	Respondd  respond.Config
-	Webserver webserver.Config
-	Nodes     runtime.NodesConfig
-	Database  database.Config
+	Respondd   respond.Config
+	Webserver  webserver.Config
+	Nodes      runtime.NodesConfig
+	Database   database.Config
+	Notify     notify.Config
+	Federation federation.Config
 }
 
 var (
@@ -50,5 +57,65 @@ func ReadConfigFile(path string) (config *Config, err error) {
 		return nil, err
 	}
 
+	if err = config.validate(); err != nil {
+		return nil, err
+	}
+
 	return
 }
+
+// validate checks the values, which could not be checked by their type
+func (config *Config) validate() error {
+	for _, d := range []struct {
+		key   string
+		value duration.Duration
+	}{
+		{"respondd.synchronize", config.Respondd.Synchronize},
+		{"respondd.collect_interval", config.Respondd.CollectInterval},
+		{"respondd.request_splay", config.Respondd.RequestSplay},
+		{"respondd.global_stats_interval", config.Respondd.GlobalStatsInterval},
+		{"nodes.save_interval", config.Nodes.SaveInterval},
+		{"nodes.offline_after", config.Nodes.OfflineAfter},
+		{"nodes.prune_after", config.Nodes.PruneAfter},
+		{"database.delete_after", config.Database.DeleteAfter},
+		{"database.delete_interval", config.Database.DeleteInterval},
+		{"webserver.events.window", config.Webserver.Events.Window},
+	} {
+		if d.value.Duration < 0 {
+			return fmt.Errorf("%s must not be negative", d.key)
+		}
+	}
+
+	if config.Respondd.Enable {
+		if config.Respondd.CollectInterval.Duration == 0 {
+			return errors.New("respondd.collect_interval is required")
+		}
+		if config.Respondd.CaptureSize < 0 || config.Respondd.CaptureSize > respond.CaptureSizeMax {
+			return fmt.Errorf("respondd.capture_size has to be between 0 and %d", respond.CaptureSizeMax)
+		}
+		if config.Respondd.RequestSplay.Duration >= config.Respondd.CollectInterval.Duration/2 {
+			return errors.New("respondd.request_splay has to be shorter than half of respondd.collect_interval")
+		}
+		if len(config.Respondd.Interfaces) == 0 {
+			return errors.New("respondd needs at least one [[respondd.interfaces]]")
+		}
+		for i, iface := range config.Respondd.Interfaces {
+			if iface.InterfaceName == "" {
+				return fmt.Errorf("ifname of respondd.interfaces #%d is required", i+1)
+			}
+		}
+	}
+	if config.Nodes.SaveInterval.Duration == 0 {
+		return errors.New("nodes.save_interval is required")
+	}
+	if config.Webserver.MaxNodes < 0 {
+		return errors.New("webserver.metrics_max_nodes must not be negative")
+	}
+	if config.Webserver.Enable && config.Webserver.Bind == "" {
+		return errors.New("webserver.bind is required")
+	}
+	if _, errs := filter.New(config.Webserver.APIFilter); len(errs) > 0 {
+		return fmt.Errorf("webserver.api_filter configuration errors: %v", errs)
+	}
+	return nil
+}
@@ -25,7 +25,7 @@ func TestReadConfig(t *testing.T) {
 	assert.Contains(config.Respondd.Sites["ffhb"].Domains, "city")
 
 	// Test output plugins
-	assert.Len(config.Nodes.Output, 5)
+	assert.Len(config.Nodes.Output, 6)
 	outputs := config.Nodes.Output["meshviewer"].([]interface{})
 	assert.Len(outputs, 1)
 	meshviewer := outputs[0]
@@ -48,3 +48,60 @@ func TestReadConfig(t *testing.T) {
 	assert.Error(err, "not found able")
 	assert.Contains(err.Error(), "no such file or directory")
 }
+
+func TestValidateConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	config, err := ReadConfigFile("../config_example.toml")
+	assert.NoError(err)
+	assert.NoError(config.validate())
+
+	config.Nodes.PruneAfter.Duration = -time.Hour
+	assert.EqualError(config.validate(), "nodes.prune_after must not be negative")
+	config.Nodes.PruneAfter.Duration = time.Hour
+
+	config.Respondd.CollectInterval.Duration = 0
+	assert.EqualError(config.validate(), "respondd.collect_interval is required")
+	config.Respondd.Enable = false
+	assert.NoError(config.validate())
+	config.Respondd.Enable = true
+	config.Respondd.CollectInterval.Duration = time.Minute
+
+	config.Respondd.RequestSplay.Duration = 30 * time.Second
+	assert.EqualError(config.validate(), "respondd.request_splay has to be shorter than half of respondd.collect_interval")
+	config.Respondd.RequestSplay.Duration = 5 * time.Second
+	assert.NoError(config.validate())
+
+	config.Respondd.CaptureSize = 100000
+	assert.EqualError(config.validate(), "respondd.capture_size has to be between 0 and 10000")
+	config.Respondd.CaptureSize = 1000
+	assert.NoError(config.validate())
+
+	config.Respondd.Interfaces[0].InterfaceName = ""
+	assert.EqualError(config.validate(), "ifname of respondd.interfaces #1 is required")
+	config.Respondd.Interfaces = nil
+	assert.Error(config.validate())
+	config.Respondd.Enable = false
+
+	config.Nodes.SaveInterval.Duration = 0
+	assert.EqualError(config.validate(), "nodes.save_interval is required")
+	config.Nodes.SaveInterval.Duration = time.Minute
+
+	config.Webserver.APIFilter = map[string]interface{}{"anonymize": map[string]interface{}{"owner": "blub"}}
+	assert.Error(config.validate())
+	config.Webserver.APIFilter = map[string]interface{}{"anonymize": map[string]interface{}{"owner": "remove"}}
+	assert.NoError(config.validate())
+
+	config.Webserver.Enable = true
+	config.Webserver.Bind = ""
+	assert.EqualError(config.validate(), "webserver.bind is required")
+
+	// unknown keys and bad durations are reported by the parser
+	_, err = ReadConfigFile("testdata/config_unknown_key.toml")
+	assert.Error(err)
+	assert.Contains(err.Error(), "colect_interval")
+
+	_, err = ReadConfigFile("testdata/config_invalid_duration.toml")
+	assert.Error(err)
+	assert.Contains(err.Error(), "missing unit")
+}
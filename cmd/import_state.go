@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/bdlm/log"
+	"github.com/spf13/cobra"
+
+	"github.com/FreifunkBremen/yanic/output/meshviewer"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+var importStateForce bool
+
+// importStateCmd represents the import-state command
+var importStateCmd = &cobra.Command{
+	Use:     "import-state <nodes.json> <state file>",
+	Short:   "Converts a nodes.json of meshviewer (version 1 or 2, e.g. of an older collector) into a state file",
+	Example: "yanic import-state /var/www/html/meshviewer/data/nodes.json /var/lib/yanic/state.json",
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := os.Stat(args[1]); err == nil && !importStateForce {
+			log.Panicf("state file %s exists already, use --force to replace it", args[1])
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.Panicf("could not open nodes: %s", err)
+		}
+		list, err := meshviewer.ReadNodes(file)
+		file.Close()
+		if err != nil {
+			log.Panicf("could not read nodes: %s", err)
+		}
+
+		nodes := runtime.NewNodes(&runtime.NodesConfig{})
+		for _, node := range list {
+			nodes.AddNode(node)
+		}
+		runtime.SaveJSON(nodes, args[1])
+		log.Infof("imported %d nodes into %s", len(nodes.List), args[1])
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(importStateCmd)
+	importStateCmd.Flags().BoolVar(&importStateForce, "force", false, "Replace an existing state file")
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"net"
 	"strings"
 	"time"
 
@@ -28,7 +27,10 @@ var queryCmd = &cobra.Command{
 	Args:    cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		ifaces := strings.Split(args[0], ",")
-		dstAddress := net.ParseIP(args[1])
+		dstAddress, err := respond.ParseAddress(args[1])
+		if err != nil {
+			log.Panic(err)
+		}
 
 		log.WithFields(map[string]interface{}{
 			"address": dstAddress,
@@ -51,23 +53,18 @@ var queryCmd = &cobra.Command{
 
 		collector := respond.NewCollector(nil, nodes, &config)
 		defer collector.Close()
-		collector.SendPacket(dstAddress)
-
-		time.Sleep(time.Second * time.Duration(wait))
 
-		for id, data := range nodes.List {
-			jq, err := json.Marshal(data)
-			if err != nil {
-				fmt.Printf("%s: %+v", id, data)
-			} else {
-				jqNeighbours, err := json.Marshal(data.Neighbours)
-				if err != nil {
-					fmt.Printf("%s: %s neighbours: %+v", id, string(jq), data.Neighbours)
-				} else {
-					fmt.Printf("%s: %s neighbours: %s", id, string(jq), string(jqNeighbours))
-				}
-			}
+		res, err := collector.Query(dstAddress, time.Second*time.Duration(wait))
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		jq, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			fmt.Printf("%+v\n", res)
+			return
 		}
+		fmt.Println(string(jq))
 	},
 }
 
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bdlm/log"
+	"github.com/spf13/cobra"
+
+	"github.com/FreifunkBremen/yanic/respond"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:     "replay <capture>",
+	Short:   "Parses the datagrams of a capture (downloaded from /debug/capture) and shows the resulting nodes",
+	Example: "yanic replay --config /etc/yanic.toml capture.bin",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		config := loadConfig()
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.Panicf("could not open capture: %s", err)
+		}
+		records, err := respond.ReadCapture(file)
+		file.Close()
+		if err != nil {
+			log.Panicf("could not read capture: %s", err)
+		}
+		log.Infof("replaying %d datagrams", len(records))
+
+		// parse like the collector (with its custom fields and processors), but without sockets
+		respondConfig := config.Respondd
+		respondConfig.Interfaces = nil
+		respondConfig.StaticNodeAddresses = nil
+		respondConfig.StaticNodesFile = ""
+		respondConfig.Resolver = ""
+		respondConfig.CaptureSize = 0
+
+		nodes := runtime.NewNodes(&runtime.NodesConfig{})
+		collector := respond.NewCollector(nil, nodes, &respondConfig)
+		collector.Replay(records)
+		collector.Close()
+
+		for id, node := range nodes.List {
+			jq, err := json.Marshal(node)
+			if err != nil {
+				fmt.Printf("%s: %+v\n", id, node)
+				continue
+			}
+			fmt.Printf("%s: %s\n", id, string(jq))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringVarP(&configPath, "config", "c", "config.toml", "Path to configuration file")
+}
@@ -4,14 +4,17 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/bdlm/log"
 	"github.com/bdlm/std/logger"
 	"github.com/spf13/cobra"
+
+	"github.com/FreifunkBremen/yanic/lib/logging"
 )
 
 var (
 	timestamps bool
 	loglevel   uint32
+	logFormat  string
+	logModules map[string]string
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -38,11 +41,13 @@ func init() {
 	// will be global for your application.
 	RootCmd.PersistentFlags().BoolVar(&timestamps, "timestamps", false, "Enables timestamps for log output")
 	RootCmd.PersistentFlags().Uint32Var(&loglevel, "loglevel", 40, "Show log message starting at level")
+	RootCmd.PersistentFlags().StringVar(&logFormat, "logformat", logging.FormatText, "Format of the log output: text or json")
+	RootCmd.PersistentFlags().StringToStringVar(&logModules, "loglevel-module", nil, "Level of the log messages of a module, e.g. respond=debug,database/influxdb=30")
 }
 
 func initConfig() {
-	log.SetLevel(logger.Level(loglevel))
-	log.SetFormatter(&log.TextFormatter{
-		DisableTimestamp: timestamps,
-	})
+	if err := logging.Setup(logFormat, logger.Level(loglevel), logModules, timestamps); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,66 +12,202 @@ import (
 	"github.com/spf13/cobra"
 
 	allDatabase "github.com/FreifunkBremen/yanic/database/all"
+	"github.com/FreifunkBremen/yanic/federation"
+	"github.com/FreifunkBremen/yanic/lib/systemd"
+	"github.com/FreifunkBremen/yanic/notify"
 	allOutput "github.com/FreifunkBremen/yanic/output/all"
 	"github.com/FreifunkBremen/yanic/respond"
 	"github.com/FreifunkBremen/yanic/runtime"
 	"github.com/FreifunkBremen/yanic/webserver"
 )
 
+// profilingBind is the address of the profiling endpoints, disabled if empty
+var profilingBind string
+
 // serveCmd represents the serve command
 var serveCmd = &cobra.Command{
 	Use:     "serve",
 	Short:   "Runs the yanic server",
 	Example: "yanic serve --config /etc/yanic.toml",
-	Run: func(cmd *cobra.Command, args []string) {
+	// errors on startup are printed by Execute, without the usage
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		config := loadConfig()
 
 		err := allDatabase.Start(config.Database)
 		if err != nil {
-			log.Panicf("could not connect to database: %s", err)
+			return fmt.Errorf("could not connect to database: %s", err)
 		}
 		defer allDatabase.Close()
 
 		nodes = runtime.NewNodes(&config.Nodes)
 		nodes.Start()
+		defer nodes.Close()
+
+		if config.Notify.Enable {
+			notifier, err := notify.New(config.Notify)
+			if err != nil {
+				return fmt.Errorf("error on init notify: %s", err)
+			}
+			notifier.Watch(nodes)
+			defer notifier.Close()
+		}
+
+		if config.Respondd.Provider.Enable {
+			provider, err := respond.NewProvider(config.Respondd.Provider, nodes)
+			if err != nil {
+				return fmt.Errorf("error on init respondd provider: %s", err)
+			}
+			defer provider.Close()
+		}
+
+		if config.Federation.Enable {
+			fed, err := federation.Start(config.Federation, nodes)
+			if err != nil {
+				return fmt.Errorf("error on init federation: %s", err)
+			}
+			defer fed.Close()
+		}
 
 		err = allOutput.Start(nodes, config.Nodes)
 		if err != nil {
-			log.Panicf("error on init outputs: %s", err)
+			return fmt.Errorf("error on init outputs: %s", err)
 		}
 		defer allOutput.Close()
 
+		if config.Respondd.Enable {
+			collector, err = respond.NewCollectorFromConfig(allDatabase.Conn, nodes, config.Respondd)
+			if err != nil {
+				return fmt.Errorf("error on init collector: %s", err)
+			}
+			defer collector.Close()
+		}
+
 		if config.Webserver.Enable {
 			log.Infof("starting webserver on %s", config.Webserver.Bind)
-			srv := webserver.New(config.Webserver.Bind, config.Webserver.Webroot)
+			srv := webserver.New(config.Webserver, nodes, collector)
 			go webserver.Start(srv)
-			defer srv.Close()
+			defer webserver.Shutdown(srv)
 		}
 
-		if config.Respondd.Enable {
+		if profilingBind != "" {
+			log.Infof("starting profiling on %s", profilingBind)
+			srv := webserver.NewProfiling(profilingBind, nodes, collector)
+			go func() {
+				if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+					log.Errorf("profiling stopped: %s", err)
+				}
+			}()
+			defer webserver.Shutdown(srv)
+		}
+
+		var start <-chan time.Time
+		if collector != nil {
 			// Delaying startup to start at a multiple of `duration` since the zero time.
+			var delay time.Duration
 			if duration := config.Respondd.Synchronize.Duration; duration > 0 {
 				now := time.Now()
-				delay := duration - now.Sub(now.Truncate(duration))
+				delay = duration - now.Sub(now.Truncate(duration))
 				log.Infof("delaying %0.1f seconds", delay.Seconds())
-				time.Sleep(delay)
 			}
+			start = time.After(delay)
+		}
 
-			collector = respond.NewCollector(allDatabase.Conn, nodes, &config.Respondd)
-			collector.Start(config.Respondd.CollectInterval.Duration)
-			defer collector.Close()
+		// the sockets are bound and the outputs initialized
+		notifySystemd(systemd.StateReady)
+		var watchdog <-chan time.Time
+		if interval := systemd.WatchdogInterval(); interval > 0 {
+			ticker := time.NewTicker(interval / 2)
+			defer ticker.Stop()
+			watchdog = ticker.C
 		}
 
-		// Wait for INT/TERM
+		// Wait for INT/TERM, reload on HUP, collect on USR1
 		sigs := make(chan os.Signal, 1)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-		sig := <-sigs
-		log.Infof("received %s", sig)
-
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		if collectSignal != nil {
+			signal.Notify(sigs, collectSignal)
+		}
+	wait:
+		for {
+			select {
+			case <-start:
+				collector.Start(config.Respondd.CollectInterval.Duration)
+				start = nil
+			case <-watchdog:
+				notifySystemd(systemd.StateWatchdog)
+			case sig := <-sigs:
+				log.Infof("received %s", sig)
+				switch sig {
+				case syscall.SIGHUP:
+					notifySystemd(systemd.StateReloading)
+					reloadConfig()
+					notifySystemd(systemd.StateReady)
+				case collectSignal:
+					if collector == nil {
+						log.Warn("unable to collect, respondd is disabled")
+					} else if err := collector.Collect(); err != nil {
+						log.Warnf("unable to collect: %s", err)
+					}
+				default:
+					break wait
+				}
+			}
+		}
+		notifySystemd(systemd.StateStopping)
+		return nil
 	},
 }
 
+// notifySystemd sends the state to systemd, if yanic runs as a service of Type=notify
+func notifySystemd(state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		log.Warnf("unable to notify systemd: %s", err)
+	}
+}
+
+// reloadConfig applies the reloadable parts of the config file
+func reloadConfig() {
+	config, err := ReadConfigFile(configPath)
+	if err != nil {
+		log.Errorf("unable to reload config file: %s", err)
+		return
+	}
+	if collector != nil {
+		collector.SetExcludeNodes(config.Respondd.ExcludeNodes)
+		log.Infof("reloaded %d excluded nodes", len(config.Respondd.ExcludeNodes))
+		collector.SetIncludeNodes(config.Respondd.IncludeNodes)
+		log.Infof("reloaded %d included nodes", len(config.Respondd.IncludeNodes))
+
+		collector.SetSitesDomains(config.Respondd.SitesDomains())
+		log.Infof("reloaded %d sites", len(config.Respondd.Sites))
+
+		if err := collector.SetInterval(config.Respondd.CollectInterval.Duration); err != nil {
+			log.Errorf("unable to reload collect interval: %s", err)
+		}
+		if err := collector.SetRequestIntervals(config.Respondd.RequestIntervalsByCategory()); err != nil {
+			log.Errorf("unable to reload request intervals: %s", err)
+		}
+
+		staticNodes, err := config.Respondd.StaticNodes()
+		if err != nil {
+			log.Errorf("unable to reload static nodes: %s", err)
+		} else {
+			collector.SetStaticNodes(staticNodes)
+			log.Infof("reloaded %d static nodes", len(staticNodes))
+		}
+	}
+
+	if err := allOutput.Reload(config.Nodes); err != nil {
+		log.Errorf("unable to reload outputs: %s", err)
+	} else {
+		log.Info("reloaded outputs")
+	}
+}
+
 func init() {
 	RootCmd.AddCommand(serveCmd)
 	serveCmd.Flags().StringVarP(&configPath, "config", "c", "config.toml", "Path to configuration file")
+	serveCmd.Flags().StringVar(&profilingBind, "profiling", "", "Serve pprof and the internal state on this address (e.g. 127.0.0.1:6060)")
 }
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// collectSignal starts a round of the collector on demand
+var collectSignal os.Signal = syscall.SIGUSR1
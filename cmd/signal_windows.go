@@ -0,0 +1,6 @@
+package cmd
+
+import "os"
+
+// collectSignal is not available on windows
+var collectSignal os.Signal
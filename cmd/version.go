@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	goruntime "runtime"
 
 	"github.com/spf13/cobra"
 )
@@ -12,15 +13,17 @@ var VERSION string
 // versionCMD to print version
 var versionCMD = &cobra.Command{
 	Use:   "version",
-	Short: "print version of yanic",
+	Short: "print version and build info of yanic",
 	Run: func(cmd *cobra.Command, args []string) {
-
-		fmt.Printf("yanic version: %s\n", VERSION)
+		version := VERSION
+		if version == "" {
+			version = "unknown"
+		}
+		fmt.Printf("yanic version: %s\n", version)
+		fmt.Printf("go version: %s %s/%s\n", goruntime.Version(), goruntime.GOOS, goruntime.GOARCH)
 	},
 }
 
 func init() {
-	if VERSION != "" {
-		RootCmd.AddCommand(versionCMD)
-	}
+	RootCmd.AddCommand(versionCMD)
 }
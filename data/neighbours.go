@@ -1,18 +1,75 @@
 package data
 
+import "encoding/json"
+
 // Neighbours struct
 type Neighbours struct {
 	Batadv map[string]BatadvNeighbours `json:"batadv"`
 	Babel  map[string]BabelNeighbours  `json:"babel"`
 	LLDP   map[string]LLDPNeighbours   `json:"lldp"`
-	//WifiNeighbours map[string]WifiNeighbours   `json:"wifi"`
-	NodeID string `json:"node_id"`
+	Wifi   map[string]WifiNeighbours   `json:"wifi,omitempty"`
+	NodeID string                      `json:"node_id"`
+	// sections of unknown link types
+	Unknown map[string]json.RawMessage `json:"-"`
+}
+
+// known sections of the neighbours, all other sections are kept in Unknown
+var knownNeighbours = map[string]bool{
+	"batadv":  true,
+	"babel":   true,
+	"lldp":    true,
+	"wifi":    true,
+	"node_id": true,
+}
+
+// UnmarshalJSON parses the known sections and keeps the unknown sections (e.g. of other link types)
+func (n *Neighbours) UnmarshalJSON(b []byte) error {
+	type neighbours Neighbours // without methods, to prevent recursion
+	if err := json.Unmarshal(b, (*neighbours)(n)); err != nil {
+		return err
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(b, &sections); err != nil {
+		return err
+	}
+	n.Unknown = nil
+	for name, section := range sections {
+		if knownNeighbours[name] {
+			continue
+		}
+		if n.Unknown == nil {
+			n.Unknown = make(map[string]json.RawMessage)
+		}
+		n.Unknown[name] = section
+	}
+	return nil
+}
+
+// MarshalJSON writes the known and the preserved unknown sections
+func (n *Neighbours) MarshalJSON() ([]byte, error) {
+	type neighbours Neighbours // without methods, to prevent recursion
+	b, err := json.Marshal((*neighbours)(n))
+	if err != nil || len(n.Unknown) == 0 {
+		return b, err
+	}
+
+	sections := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &sections); err != nil {
+		return nil, err
+	}
+	for name, section := range n.Unknown {
+		if _, ok := sections[name]; !ok {
+			sections[name] = section
+		}
+	}
+	return json.Marshal(sections)
 }
 
 // WifiLink struct
 type WifiLink struct {
 	Inactive int `json:"inactive"`
-	Noise    int `json:"nois"`
+	Noise    int `json:"noise"`
 	Signal   int `json:"signal"`
 }
 
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNeighboursUnknown(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := &Neighbours{}
+	err := json.Unmarshal([]byte(`{
+		"node_id": "f81a67a601ea",
+		"batadv": {"f8:1a:67:a6:01:ea": {"neighbours": {"f8:1a:67:a6:01:eb": {"tq": 200}}}},
+		"wifi": {"f8:1a:67:a6:01:ec": {"neighbours": {"f8:1a:67:a6:01:ed": {"signal": -60, "noise": -95, "inactive": 10}}}},
+		"mesh_vpn": {"peers": {}}
+	}`), obj)
+	assert.NoError(err)
+	assert.Equal("f81a67a601ea", obj.NodeID)
+	assert.Len(obj.Batadv, 1)
+	assert.Equal(WifiLink{Signal: -60, Noise: -95, Inactive: 10}, obj.Wifi["f8:1a:67:a6:01:ec"].Neighbours["f8:1a:67:a6:01:ed"])
+	assert.Len(obj.Unknown, 1)
+	assert.Contains(obj.Unknown, "mesh_vpn")
+
+	// unknown sections are preserved
+	b, err := json.Marshal(obj)
+	assert.NoError(err)
+	var sections map[string]interface{}
+	assert.NoError(json.Unmarshal(b, &sections))
+	assert.Contains(sections, "mesh_vpn")
+	assert.Contains(sections, "wifi")
+	assert.Contains(sections, "batadv")
+	assert.Equal("f81a67a601ea", sections["node_id"])
+
+	// without unknown sections
+	obj = &Neighbours{}
+	assert.NoError(json.Unmarshal([]byte(`{"node_id": "f81a67a601ea"}`), obj))
+	assert.Nil(obj.Unknown)
+	b, err = json.Marshal(obj)
+	assert.NoError(err)
+	assert.NotContains(string(b), "wifi")
+
+	assert.Error(json.Unmarshal([]byte(`{"node_id": 42}`), obj))
+}
@@ -1,5 +1,7 @@
 package data
 
+import "encoding/json"
+
 // Nodeinfo struct
 type Nodeinfo struct {
 	NodeID   string    `json:"node_id"`
@@ -12,6 +14,66 @@ type Nodeinfo struct {
 	Hardware Hardware  `json:"hardware"`
 	VPN      bool      `json:"vpn"`
 	Wireless *Wireless `json:"wireless,omitempty"`
+	// fields unknown to yanic, e.g. added by packages of a community
+	Custom map[string]json.RawMessage `json:"-"`
+}
+
+// known fields of the nodeinfo, all other fields are kept in Custom
+var knownNodeinfo = map[string]bool{
+	"node_id":  true,
+	"network":  true,
+	"owner":    true,
+	"system":   true,
+	"hostname": true,
+	"location": true,
+	"software": true,
+	"hardware": true,
+	"vpn":      true,
+	"wireless": true,
+}
+
+// UnmarshalJSON parses the known fields and keeps the unknown fields
+func (n *Nodeinfo) UnmarshalJSON(b []byte) error {
+	type nodeinfo Nodeinfo // without methods, to prevent recursion
+	if err := json.Unmarshal(b, (*nodeinfo)(n)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	n.Custom = nil
+	for name, field := range fields {
+		if knownNodeinfo[name] {
+			continue
+		}
+		if n.Custom == nil {
+			n.Custom = make(map[string]json.RawMessage)
+		}
+		n.Custom[name] = field
+	}
+	return nil
+}
+
+// MarshalJSON writes the known and the preserved unknown fields
+func (n *Nodeinfo) MarshalJSON() ([]byte, error) {
+	type nodeinfo Nodeinfo // without methods, to prevent recursion
+	b, err := json.Marshal((*nodeinfo)(n))
+	if err != nil || len(n.Custom) == 0 {
+		return b, err
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for name, field := range n.Custom {
+		if _, ok := fields[name]; !ok {
+			fields[name] = field
+		}
+	}
+	return json.Marshal(fields)
 }
 
 // NetworkInterface struct
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,3 +25,40 @@ func TestNodeinfoBatAddresses(t *testing.T) {
 	assert.NotNil(addr)
 	assert.Equal([]string{"aa:aa:aa:aa:aa", "aa:aa:aa:aa:ab"}, addr)
 }
+
+func TestNodeinfoCustom(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := &Nodeinfo{}
+	err := json.Unmarshal([]byte(`{
+		"node_id": "f81a67a601ea",
+		"hostname": "node1",
+		"software": {"firmware": {"release": "v2023.1"}},
+		"community": {"contact_room": "#ffhb"},
+		"pages": ["status"]
+	}`), obj)
+	assert.NoError(err)
+	assert.Equal("node1", obj.Hostname)
+	assert.Equal("v2023.1", obj.Software.Firmware.Release)
+	assert.Len(obj.Custom, 2)
+	assert.JSONEq(`{"contact_room": "#ffhb"}`, string(obj.Custom["community"]))
+
+	// custom fields are preserved
+	b, err := json.Marshal(obj)
+	assert.NoError(err)
+	var fields map[string]interface{}
+	assert.NoError(json.Unmarshal(b, &fields))
+	assert.Contains(fields, "community")
+	assert.Contains(fields, "pages")
+	assert.Equal("node1", fields["hostname"])
+
+	// without custom fields
+	obj = &Nodeinfo{}
+	assert.NoError(json.Unmarshal([]byte(`{"node_id": "f81a67a601ea"}`), obj))
+	assert.Nil(obj.Custom)
+	b, err = json.Marshal(obj)
+	assert.NoError(err)
+	assert.NotContains(string(b), "community")
+
+	assert.Error(json.Unmarshal([]byte(`{"node_id": 42}`), obj))
+}
@@ -56,6 +56,8 @@ type Traffic struct {
 	Bytes   float64 `json:"bytes,omitempty"`
 	Packets float64 `json:"packets,omitempty"`
 	Dropped float64 `json:"dropped,omitempty"`
+
+	Rate *TrafficRate `json:"rate,omitempty"` // calculated by yanic in regard to the previous statistics, nil if unknown
 }
 
 // Clients struct
@@ -67,6 +69,19 @@ type Clients struct {
 	Owe24  uint32 `json:"owe24"`
 	Owe5   uint32 `json:"owe5"`
 	Total  uint32 `json:"total"`
+
+	// Signal is only reported by some firmwares
+	Signal *ClientSignal `json:"signal,omitempty"`
+
+	// Unique is the best-effort count of the clients without the ones counted twice,
+	// set by yanic (see Deduplicate), nil if not derived
+	Unique *uint32 `json:"unique,omitempty"`
+}
+
+// ClientSignal is a summary of the signal of the wifi clients in dBm
+type ClientSignal struct {
+	Avg float64 `json:"avg"`
+	Min float64 `json:"min"` // worst signal of all clients
 }
 
 // DHCP struct
@@ -0,0 +1,21 @@
+package data
+
+// Deduplicate derives the count of the clients without the devices counted twice (see Unique).
+// Some firmwares count a dual-band device on both radios and add these counts to the total,
+// while the wifi clients (by the translation table of batman-adv) contain every device once:
+// the excess of the clients of both bands over the wifi clients is removed from the total.
+func (c *Clients) Deduplicate() {
+	unique := c.Total
+	if bands := c.Wifi24 + c.Wifi5; c.Wifi > 0 && bands > c.Wifi && c.Total >= bands {
+		unique -= bands - c.Wifi
+	}
+	c.Unique = &unique
+}
+
+// UniqueOrTotal returns the deduplicated count of the clients, the total if it is not derived
+func (c *Clients) UniqueOrTotal() uint32 {
+	if c.Unique != nil {
+		return *c.Unique
+	}
+	return c.Total
+}
@@ -0,0 +1,35 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientsDeduplicate(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range []struct {
+		name    string
+		clients Clients
+		unique  uint32
+	}{
+		{"no wifi", Clients{Total: 3}, 3},
+		{"counted once", Clients{Wifi: 5, Wifi24: 3, Wifi5: 2, Total: 7}, 7},
+		// two dual-band devices on both bands and two wired clients
+		{"counted twice", Clients{Wifi: 5, Wifi24: 4, Wifi5: 3, Total: 9}, 7},
+		// the total does not contain the counts of the bands
+		{"total by the translation table", Clients{Wifi: 5, Wifi24: 4, Wifi5: 3, Total: 6}, 6},
+		// older firmwares without the wifi clients
+		{"bands only", Clients{Wifi24: 4, Wifi5: 3, Total: 9}, 9},
+	} {
+		clients := test.clients
+		assert.Equal(clients.Total, clients.UniqueOrTotal(), test.name)
+		clients.Deduplicate()
+		if assert.NotNil(clients.Unique, test.name) {
+			assert.Equal(test.unique, *clients.Unique, test.name)
+		}
+		assert.Equal(test.unique, clients.UniqueOrTotal(), test.name)
+		assert.Equal(test.clients.Total, clients.Total, test.name)
+	}
+}
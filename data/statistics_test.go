@@ -35,3 +35,17 @@ func testfile(name string, obj interface{}) {
 		panic(err)
 	}
 }
+
+func TestStatisticsClientSignal(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := &Statistics{}
+	assert.NoError(json.Unmarshal([]byte(`{"clients":{"total":2}}`), obj))
+	assert.Nil(obj.Clients.Signal)
+
+	obj = &Statistics{}
+	assert.NoError(json.Unmarshal([]byte(`{"clients":{"total":2,"signal":{"avg":-61.5,"min":-83}}}`), obj))
+	assert.NotNil(obj.Clients.Signal)
+	assert.Equal(-61.5, obj.Clients.Signal.Avg)
+	assert.Equal(float64(-83), obj.Clients.Signal.Min)
+}
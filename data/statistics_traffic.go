@@ -0,0 +1,44 @@
+package data
+
+// TrafficRate is the rate of a traffic counter per second
+type TrafficRate struct {
+	Bytes   float64 `json:"bytes"`
+	Packets float64 `json:"packets"`
+}
+
+// SetTrafficRates calculates the rates of the traffic counters in regard to the previous statistics.
+// The interval is the difference of the uptimes, or the given fallback (in seconds) if a node does not report its uptime.
+// After a reboot (the uptime decreased) or a reset of a counter (it decreased) the rate of the interval is unknown.
+func (current *Statistics) SetTrafficRates(previous *Statistics, fallback float64) {
+	elapsed := fallback
+	if current.Uptime > 0 && previous.Uptime > 0 {
+		if current.Uptime < previous.Uptime {
+			// rebooted
+			return
+		}
+		elapsed = current.Uptime - previous.Uptime
+	}
+	if elapsed <= 0 {
+		return
+	}
+	setTrafficRate(current.Traffic.Rx, previous.Traffic.Rx, elapsed)
+	setTrafficRate(current.Traffic.Tx, previous.Traffic.Tx, elapsed)
+	setTrafficRate(current.Traffic.Forward, previous.Traffic.Forward, elapsed)
+	setTrafficRate(current.Traffic.MgmtRx, previous.Traffic.MgmtRx, elapsed)
+	setTrafficRate(current.Traffic.MgmtTx, previous.Traffic.MgmtTx, elapsed)
+}
+
+// setTrafficRate updates the rate of a counter in regard to its previous value
+func setTrafficRate(current, previous *Traffic, elapsed float64) {
+	if current == nil || previous == nil {
+		return
+	}
+	if current.Bytes < previous.Bytes || current.Packets < previous.Packets {
+		// reset
+		return
+	}
+	current.Rate = &TrafficRate{
+		Bytes:   (current.Bytes - previous.Bytes) / elapsed,
+		Packets: (current.Packets - previous.Packets) / elapsed,
+	}
+}
@@ -0,0 +1,45 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrafficRates(t *testing.T) {
+	assert := assert.New(t)
+
+	previous := &Statistics{Uptime: 100}
+	previous.Traffic.Rx = &Traffic{Bytes: 1000, Packets: 10}
+	previous.Traffic.Tx = &Traffic{Bytes: 5000, Packets: 50}
+
+	// by the uptime
+	current := &Statistics{Uptime: 160}
+	current.Traffic.Rx = &Traffic{Bytes: 7000, Packets: 70}
+	current.Traffic.Tx = &Traffic{Bytes: 100, Packets: 1}
+	current.Traffic.Forward = &Traffic{Bytes: 100}
+	current.SetTrafficRates(previous, 30)
+	assert.Equal(&TrafficRate{Bytes: 100, Packets: 1}, current.Traffic.Rx.Rate)
+	// reset counter
+	assert.Nil(current.Traffic.Tx.Rate)
+	// without previous value
+	assert.Nil(current.Traffic.Forward.Rate)
+
+	// by the fallback without uptime
+	current = &Statistics{}
+	current.Traffic.Rx = &Traffic{Bytes: 7000, Packets: 70}
+	current.SetTrafficRates(previous, 30)
+	assert.Equal(&TrafficRate{Bytes: 200, Packets: 2}, current.Traffic.Rx.Rate)
+
+	// rebooted
+	current = &Statistics{Uptime: 10}
+	current.Traffic.Rx = &Traffic{Bytes: 7000, Packets: 70}
+	current.SetTrafficRates(previous, 30)
+	assert.Nil(current.Traffic.Rx.Rate)
+
+	// no interval
+	current = &Statistics{Uptime: 100}
+	current.Traffic.Rx = &Traffic{Bytes: 7000, Packets: 70}
+	current.SetTrafficRates(previous, 30)
+	assert.Nil(current.Traffic.Rx.Rate)
+}
@@ -0,0 +1,35 @@
+package data
+
+import "math"
+
+// Usage returns the fraction of the used memory (1.0 equals 100%), false if the node does not report its memory.
+// Firmwares with a kernel reporting the available memory are calculated by it, older ones by the free, buffered and cached memory
+// like the node statuspage (look discussion:
+// https://github.com/FreifunkBremen/yanic/issues/35 and
+// https://github.com/freifunk-gluon/gluon/pull/1517)
+func (memory Memory) Usage() (float64, bool) {
+	if memory.Total <= 0 {
+		return 0, false
+	}
+	var usage float64
+	if memory.Available > 0 {
+		usage = 1 - float64(memory.Available)/float64(memory.Total)
+	} else {
+		usage = 1 - float64(memory.Free+memory.Buffers+memory.Cached)/float64(memory.Total)
+	}
+	return clampUsage(usage), true
+}
+
+// Normalize unifies the values, which are reported differently by the firmwares:
+// the usage of the rootfs is a fraction (1.0 equals 100%), some firmwares report a percentage
+func (s *Statistics) Normalize() {
+	if s.RootFsUsage > 1 && s.RootFsUsage <= 100 {
+		s.RootFsUsage /= 100
+	}
+	s.RootFsUsage = clampUsage(s.RootFsUsage)
+}
+
+// clampUsage limits a fraction to 0.0 - 1.0
+func clampUsage(usage float64) float64 {
+	return math.Max(0, math.Min(1, usage))
+}
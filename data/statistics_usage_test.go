@@ -0,0 +1,47 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	assert := assert.New(t)
+
+	_, ok := Memory{}.Usage()
+	assert.False(ok)
+
+	// by the available memory
+	usage, ok := Memory{Total: 1000, Available: 250, Free: 100}.Usage()
+	assert.True(ok)
+	assert.InDelta(0.75, usage, 0.0001)
+
+	// by free, buffered and cached memory of older firmwares
+	usage, ok = Memory{Total: 1000, Free: 100, Buffers: 50, Cached: 350}.Usage()
+	assert.True(ok)
+	assert.InDelta(0.5, usage, 0.0001)
+
+	// invalid values
+	usage, ok = Memory{Total: 1000, Available: 2000}.Usage()
+	assert.True(ok)
+	assert.EqualValues(0, usage)
+}
+
+func TestNormalize(t *testing.T) {
+	assert := assert.New(t)
+
+	for reported, normalized := range map[float64]float64{
+		0:    0,
+		0.25: 0.25,
+		1:    1,
+		42:   0.42,
+		100:  1,
+		150:  1,
+		-1:   0,
+	} {
+		stats := &Statistics{RootFsUsage: reported}
+		stats.Normalize()
+		assert.InDelta(normalized, stats.RootFsUsage, 0.0001, "%v", reported)
+	}
+}
@@ -15,8 +15,16 @@ type Connection struct {
 	list []database.Connection
 }
 
+// Connect connects every enabled database of all registered adapters,
+// on an error the already connected databases are closed again
 func Connect(allConnection map[string]interface{}) (database.Connection, error) {
 	var list []database.Connection
+	fail := func(err error) (database.Connection, error) {
+		for _, connected := range list {
+			connected.Close()
+		}
+		return nil, err
+	}
 	for dbType, conn := range database.Adapters {
 		configForType := allConnection[dbType]
 		if configForType == nil {
@@ -25,20 +33,20 @@ func Connect(allConnection map[string]interface{}) (database.Connection, error)
 		}
 		dbConfigs, ok := configForType.([]interface{})
 		if !ok {
-			return nil, fmt.Errorf("the database type '%s' has the wrong format", dbType)
+			return fail(fmt.Errorf("the database type '%s' has the wrong format", dbType))
 		}
 
 		for _, dbConfig := range dbConfigs {
 			config, ok := dbConfig.(map[string]interface{})
 			if !ok {
-				return nil, fmt.Errorf("the database type '%s' has the wrong format", dbType)
+				return fail(fmt.Errorf("the database type '%s' has the wrong format", dbType))
 			}
 			if c, ok := config["enable"].(bool); ok && !c {
 				continue
 			}
 			connected, err := conn(config)
 			if err != nil {
-				return nil, err
+				return fail(err)
 			}
 			if connected == nil {
 				continue
@@ -49,6 +57,38 @@ func Connect(allConnection map[string]interface{}) (database.Connection, error)
 	return &Connection{list: list}, nil
 }
 
+// mergeTags adds the global tags to the configuration of every connection.
+// Tags set on a connection itself take precedence over the global ones.
+func mergeTags(allConnection map[string]interface{}, tags map[string]interface{}) error {
+	for dbType, configForType := range allConnection {
+		dbConfigs, ok := configForType.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, dbConfig := range dbConfigs {
+			config, ok := dbConfig.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			merged := make(map[string]interface{})
+			for tag, value := range tags {
+				merged[tag] = value
+			}
+			if c := config["tags"]; c != nil {
+				connTags, ok := c.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("the tags of database type '%s' have the wrong format", dbType)
+				}
+				for tag, value := range connTags {
+					merged[tag] = value
+				}
+			}
+			config["tags"] = merged
+		}
+	}
+	return nil
+}
+
 func (conn *Connection) InsertNode(node *runtime.Node) {
 	for _, item := range conn.list {
 		item.InsertNode(node)
@@ -73,6 +113,48 @@ func (conn *Connection) PruneNodes(deleteAfter time.Duration) {
 	}
 }
 
+// Counters returns the counters of all connections, summed up by their name
+func (conn *Connection) Counters() []runtime.Counter {
+	var result []runtime.Counter
+	index := make(map[string]int)
+	for _, item := range conn.list {
+		counted, ok := item.(database.Counted)
+		if !ok {
+			continue
+		}
+		for _, counter := range counted.Counters() {
+			if i, ok := index[counter.Name]; ok {
+				result[i].Value += counter.Value
+				continue
+			}
+			index[counter.Name] = len(result)
+			result = append(result, counter)
+		}
+	}
+	return result
+}
+
+// Health returns the first error of the connections
+func (conn *Connection) Health() error {
+	for _, item := range conn.list {
+		if checker, ok := item.(database.HealthChecker); ok {
+			if err := checker.Health(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// InsertInternal passes the internal counters to all connections, which store them
+func (conn *Connection) InsertInternal(counters []runtime.Counter, time time.Time) {
+	for _, item := range conn.list {
+		if inserter, ok := item.(database.InternalInserter); ok {
+			inserter.InsertInternal(counters, time)
+		}
+	}
+}
+
 func (conn *Connection) Close() {
 	for _, item := range conn.list {
 		item.Close()
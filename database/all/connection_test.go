@@ -0,0 +1,66 @@
+package all
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/database"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+type closeConnection struct {
+	database.Connection
+	closed bool
+}
+
+func (conn *closeConnection) Close() {
+	conn.closed = true
+}
+
+func TestConnectCloseOnError(t *testing.T) {
+	assert := assert.New(t)
+
+	var connected []*closeConnection
+	database.RegisterAdapter("closing", func(config map[string]interface{}) (database.Connection, error) {
+		if config["fail"] == true {
+			return nil, errors.New("unable to connect")
+		}
+		conn := &closeConnection{}
+		connected = append(connected, conn)
+		return conn, nil
+	})
+	defer delete(database.Adapters, "closing")
+
+	_, err := Connect(map[string]interface{}{
+		"closing": []interface{}{
+			map[string]interface{}{},
+			map[string]interface{}{"fail": true},
+		},
+	})
+	assert.Error(err)
+	if assert.Len(connected, 1) {
+		assert.True(connected[0].closed)
+	}
+}
+
+type countedConnection struct {
+	database.Connection
+	counters []runtime.Counter
+}
+
+func (conn *countedConnection) Counters() []runtime.Counter {
+	return conn.counters
+}
+
+func TestCounters(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{list: []database.Connection{
+		&countedConnection{counters: []runtime.Counter{{Name: "a", Value: 1}, {Name: "b", Value: 2}}},
+		&closeConnection{},
+		&countedConnection{counters: []runtime.Counter{{Name: "a", Value: 3}}},
+	}}
+	assert.Equal([]runtime.Counter{{Name: "a", Value: 4}, {Name: "b", Value: 2}}, conn.Counters())
+}
@@ -12,13 +12,21 @@ var wg = sync.WaitGroup{}
 var quit chan struct{}
 
 func Start(config database.Config) (err error) {
+	if len(config.Tags) > 0 {
+		if err = mergeTags(config.Connection, config.Tags); err != nil {
+			return
+		}
+	}
 	Conn, err = Connect(config.Connection)
 	if err != nil {
 		return
 	}
 	quit = make(chan struct{})
-	wg.Add(1)
-	go deleteWorker(config.DeleteInterval.Duration, config.DeleteAfter.Duration)
+	// pruning is disabled without interval
+	if config.DeleteInterval.Duration > 0 {
+		wg.Add(1)
+		go deleteWorker(config.DeleteInterval.Duration, config.DeleteAfter.Duration)
+	}
 	return
 }
 
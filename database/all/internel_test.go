@@ -65,6 +65,13 @@ func TestStart(t *testing.T) {
 	// test close
 	Close()
 
+	// without delete interval
+	err = Start(database.Config{
+		Connection: map[string]interface{}{},
+	})
+	assert.NoError(err)
+	Close()
+
 	// wrong format
 	err = Start(database.Config{
 		Connection: map[string]interface{}{
@@ -73,3 +80,47 @@ func TestStart(t *testing.T) {
 	})
 	assert.Error(err)
 }
+
+func TestMergeTags(t *testing.T) {
+	assert := assert.New(t)
+
+	connections := map[string]interface{}{
+		"a": []interface{}{
+			map[string]interface{}{
+				"path": "a1",
+			},
+			map[string]interface{}{
+				"tags": map[string]interface{}{
+					"resolution": "1m",
+					"system":     "testing",
+				},
+			},
+		},
+		"b": true,
+	}
+	err := mergeTags(connections, map[string]interface{}{
+		"resolution": "raw",
+	})
+	assert.NoError(err)
+
+	configs := connections["a"].([]interface{})
+	assert.Equal(map[string]interface{}{
+		"resolution": "raw",
+	}, configs[0].(map[string]interface{})["tags"])
+	assert.Equal(map[string]interface{}{
+		"resolution": "1m",
+		"system":     "testing",
+	}, configs[1].(map[string]interface{})["tags"])
+
+	// wrong format of tags on connection
+	err = mergeTags(map[string]interface{}{
+		"a": []interface{}{
+			map[string]interface{}{
+				"tags": true,
+			},
+		},
+	}, map[string]interface{}{
+		"resolution": "raw",
+	})
+	assert.Error(err)
+}
@@ -4,5 +4,9 @@ import (
 	_ "github.com/FreifunkBremen/yanic/database/graphite"
 	_ "github.com/FreifunkBremen/yanic/database/influxdb"
 	_ "github.com/FreifunkBremen/yanic/database/logging"
+	_ "github.com/FreifunkBremen/yanic/database/mqtt"
+	_ "github.com/FreifunkBremen/yanic/database/nats"
+	_ "github.com/FreifunkBremen/yanic/database/postgres"
+	_ "github.com/FreifunkBremen/yanic/database/remotewrite"
 	_ "github.com/FreifunkBremen/yanic/database/respondd"
 )
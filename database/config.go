@@ -3,7 +3,8 @@ package database
 import "github.com/FreifunkBremen/yanic/lib/duration"
 
 type Config struct {
-	DeleteInterval duration.Duration `toml:"delete_interval"` // Delete stats of nodes every n minutes
-	DeleteAfter    duration.Duration `toml:"delete_after"`    // Delete stats of nodes till now-deletetill n minutes
+	DeleteInterval duration.Duration      `toml:"delete_interval"` // Delete stats of nodes every n minutes
+	DeleteAfter    duration.Duration      `toml:"delete_after"`    // Delete stats of nodes till now-deletetill n minutes
+	Tags           map[string]interface{} `toml:"tags"`            // Tags for every point of all connections
 	Connection     map[string]interface{}
 }
@@ -24,6 +24,24 @@ type Connection interface {
 	Close()
 }
 
+// Counted is implemented by connections with internal counters, e.g. of failed writes
+type Counted interface {
+	// Counters returns a snapshot of the counters
+	Counters() []runtime.Counter
+}
+
+// HealthChecker is implemented by connections, which know whether their last write succeeded
+type HealthChecker interface {
+	// Health returns the error of the last write, nil if it succeeded
+	Health() error
+}
+
+// InternalInserter is implemented by connections, which store the internal counters of yanic itself
+type InternalInserter interface {
+	// InsertInternal stores a snapshot of the internal counters
+	InsertInternal([]runtime.Counter, time.Time)
+}
+
 // Connect function with config to get DB connection interface
 type Connect func(config map[string]interface{}) (Connection, error)
 
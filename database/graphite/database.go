@@ -1,6 +1,7 @@
 package graphite
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/bdlm/log"
@@ -10,16 +11,22 @@ import (
 )
 
 const (
-	MeasurementNode               = "node"        // Measurement for per-node statistics
-	MeasurementGlobal             = "global"      // Measurement for summarized global statistics
-	CounterMeasurementFirmware    = "firmware"    // Measurement for firmware statistics
-	CounterMeasurementModel       = "model"       // Measurement for model statistics
-	CounterMeasurementAutoupdater = "autoupdater" // Measurement for autoupdater
+	MeasurementNode                       = "node"                 // Measurement for per-node statistics
+	MeasurementGlobal                     = "global"               // Measurement for summarized global statistics
+	MeasurementGateway                    = "gateway"              // Measurement for the usage of the gateways
+	CounterMeasurementFirmware            = "firmware"             // Measurement for firmware statistics
+	CounterMeasurementModel               = "model"                // Measurement for model statistics
+	CounterMeasurementAutoupdater         = "autoupdater"          // Measurement for autoupdater
+	CounterMeasurementAutoupdaterDisabled = "autoupdater_disabled" // Measurement for branches of disabled autoupdater
+	CounterMeasurementChannel24           = "channel24"            // Measurement for wifi channels on 2.4 GHz
+	CounterMeasurementChannel5            = "channel5"             // Measurement for wifi channels on 5 GHz
+	CounterMeasurementLatency             = "latency"              // Measurement for the response latency of the nodes
 )
 
 type Connection struct {
 	database.Connection
 	client graphigo.Client
+	send   func([]graphigo.Metric) error
 	points chan []graphigo.Metric
 	wg     sync.WaitGroup
 }
@@ -34,6 +41,13 @@ func (c Config) Prefix() string {
 	return c["prefix"].(string)
 }
 
+func (c Config) Protocol() string {
+	if protocol, ok := c["protocol"]; ok {
+		return protocol.(string)
+	}
+	return ProtocolPlaintext
+}
+
 func Connect(configuration map[string]interface{}) (database.Connection, error) {
 	var config Config
 
@@ -47,6 +61,15 @@ func Connect(configuration map[string]interface{}) (database.Connection, error)
 		points: make(chan []graphigo.Metric, 1000),
 	}
 
+	switch config.Protocol() {
+	case ProtocolPlaintext:
+		con.send = con.client.SendAll
+	case ProtocolPickle:
+		con.send = con.sendPickle
+	default:
+		return nil, fmt.Errorf("unknown protocol: %s", config.Protocol())
+	}
+
 	if err := con.client.Connect(); err != nil {
 		return nil, err
 	}
@@ -57,8 +80,10 @@ func Connect(configuration map[string]interface{}) (database.Connection, error)
 	return con, nil
 }
 
+// Close sends the pending points and closes the connection
 func (c *Connection) Close() {
 	close(c.points)
+	c.wg.Wait()
 	if c.client.Connection != nil {
 		c.client.Close()
 	}
@@ -66,9 +91,8 @@ func (c *Connection) Close() {
 
 func (c *Connection) addWorker() {
 	defer c.wg.Done()
-	defer c.Close()
 	for point := range c.points {
-		err := c.client.SendAll(point)
+		err := c.send(point)
 		if err != nil {
 			log.WithField("database", "graphite").Fatal(err)
 			return
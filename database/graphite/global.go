@@ -9,34 +9,56 @@ import (
 
 func (c *Connection) InsertGlobals(stats *runtime.GlobalStats, time time.Time, site string, domain string) {
 	measurementGlobal := MeasurementGlobal
+	measurementGateway := MeasurementGateway
 	counterMeasurementModel := CounterMeasurementModel
 	counterMeasurementFirmware := CounterMeasurementFirmware
 	counterMeasurementAutoupdater := CounterMeasurementAutoupdater
+	counterMeasurementAutoupdaterDisabled := CounterMeasurementAutoupdaterDisabled
+	counterMeasurementChannel24 := CounterMeasurementChannel24
+	counterMeasurementChannel5 := CounterMeasurementChannel5
+	counterMeasurementLatency := CounterMeasurementLatency
 
 	if site != runtime.GLOBAL_SITE {
 		measurementGlobal += "_" + site
+		measurementGateway += "_" + site
 		counterMeasurementModel += "_" + site
 		counterMeasurementFirmware += "_" + site
 		counterMeasurementAutoupdater += "_" + site
+		counterMeasurementAutoupdaterDisabled += "_" + site
+		counterMeasurementChannel24 += "_" + site
+		counterMeasurementChannel5 += "_" + site
+		counterMeasurementLatency += "_" + site
 	}
 
 	if domain != runtime.GLOBAL_DOMAIN {
 		measurementGlobal += "_" + domain
+		measurementGateway += "_" + domain
 		counterMeasurementModel += "_" + domain
 		counterMeasurementFirmware += "_" + domain
 		counterMeasurementAutoupdater += "_" + domain
+		counterMeasurementAutoupdaterDisabled += "_" + domain
+		counterMeasurementChannel24 += "_" + domain
+		counterMeasurementChannel5 += "_" + domain
+		counterMeasurementLatency += "_" + domain
 	}
 
 	c.addPoint(GlobalStatsFields(measurementGlobal, stats))
+	c.addGateways(measurementGateway, stats.PerGateway, time)
 	c.addCounterMap(counterMeasurementModel, stats.Models, time)
 	c.addCounterMap(counterMeasurementFirmware, stats.Firmwares, time)
 	c.addCounterMap(counterMeasurementAutoupdater, stats.Autoupdater, time)
+	c.addCounterMap(counterMeasurementAutoupdaterDisabled, stats.AutoupdaterDisabled, time)
+	c.addCounterMap(counterMeasurementChannel24, stats.Channels24, time)
+	c.addCounterMap(counterMeasurementChannel5, stats.Channels5, time)
+	c.addCounterMap(counterMeasurementLatency, stats.Latency, time)
 }
 
 func GlobalStatsFields(name string, stats *runtime.GlobalStats) []graphigo.Metric {
 	return []graphigo.Metric{
 		{Name: name + ".nodes", Value: stats.Nodes},
 		{Name: name + ".gateways", Value: stats.Gateways},
+		{Name: name + ".reboots", Value: stats.Reboots},
+		{Name: name + ".uplink_only", Value: stats.NodesUplinkOnly},
 		{Name: name + ".clients.total", Value: stats.Clients},
 		{Name: name + ".clients.wifi", Value: stats.ClientsWifi},
 		{Name: name + ".clients.wifi24", Value: stats.ClientsWifi24},
@@ -44,9 +66,25 @@ func GlobalStatsFields(name string, stats *runtime.GlobalStats) []graphigo.Metri
 		{Name: name + ".clients.owe", Value: stats.ClientsOwe},
 		{Name: name + ".clients.owe24", Value: stats.ClientsOwe24},
 		{Name: name + ".clients.owe5", Value: stats.ClientsOwe5},
+		{Name: name + ".clients.unique", Value: stats.ClientsUnique},
+		{Name: name + ".sections.nodeinfo", Value: stats.SectionRatio(stats.NodesNodeinfo)},
+		{Name: name + ".sections.statistics", Value: stats.SectionRatio(stats.NodesStatistics)},
+		{Name: name + ".sections.neighbours", Value: stats.SectionRatio(stats.NodesNeighbours)},
 	}
 }
 
+func (c *Connection) addGateways(name string, gateways map[string]*runtime.GatewayStats, t time.Time) {
+	var fields []graphigo.Metric
+	for gateway, stats := range gateways {
+		prefix := name + `.` + replaceInvalidChars(gateway)
+		fields = append(fields,
+			graphigo.Metric{Name: prefix + `.nodes`, Value: stats.Nodes, Timestamp: t},
+			graphigo.Metric{Name: prefix + `.clients`, Value: stats.Clients, Timestamp: t},
+		)
+	}
+	c.addPoint(fields)
+}
+
 func (c *Connection) addCounterMap(name string, m runtime.CounterMap, t time.Time) {
 	var fields []graphigo.Metric
 	for key, count := range m {
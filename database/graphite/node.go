@@ -3,6 +3,7 @@ package graphite
 import (
 	"time"
 
+	"github.com/FreifunkBremen/yanic/data"
 	"github.com/FreifunkBremen/yanic/runtime"
 	"github.com/fgrosse/graphigo"
 )
@@ -71,6 +72,11 @@ func (c *Connection) InsertNode(node *runtime.Node) {
 
 		// total is the sum of all protocols
 		addField("neighbours.total", batadv+babel+lldp)
+
+		// sections of unknown link types are not analysed, just counted
+		if unknown := len(neighbours.Unknown); unknown > 0 {
+			addField("neighbours.unknown", unknown)
+		}
 	}
 
 	if t := stats.Traffic.Rx; t != nil {
@@ -94,6 +100,19 @@ func (c *Connection) InsertNode(node *runtime.Node) {
 		addField("traffic.mgmt_tx.bytes", int64(t.Bytes))
 		addField("traffic.mgmt_tx.packets", t.Packets)
 	}
+	// rates per second of the counters, if known
+	for name, t := range map[string]*data.Traffic{
+		"rx":      stats.Traffic.Rx,
+		"tx":      stats.Traffic.Tx,
+		"forward": stats.Traffic.Forward,
+		"mgmt_rx": stats.Traffic.MgmtRx,
+		"mgmt_tx": stats.Traffic.MgmtTx,
+	} {
+		if t != nil && t.Rate != nil {
+			addField("traffic."+name+".rate.bytes", t.Rate.Bytes)
+			addField("traffic."+name+".rate.packets", t.Rate.Packets)
+		}
+	}
 
 	for _, airtime := range stats.Wireless {
 		suffix := airtime.FrequencyName()
@@ -116,11 +135,25 @@ func (c *Connection) InsertNode(node *runtime.Node) {
 	addField("clients.owe24", stats.Clients.Owe24)
 	addField("clients.owe5", stats.Clients.Owe5)
 	addField("clients.total", stats.Clients.Total)
+	if signal := stats.Clients.Signal; signal != nil {
+		addField("clients.signal.avg", signal.Avg)
+		addField("clients.signal.min", signal.Min)
+	}
+	if unique := stats.Clients.Unique; unique != nil {
+		addField("clients.unique", *unique)
+	}
 	addField("memory.buffers", stats.Memory.Buffers)
 	addField("memory.cached", stats.Memory.Cached)
 	addField("memory.free", stats.Memory.Free)
 	addField("memory.total", stats.Memory.Total)
 	addField("memory.available", stats.Memory.Available)
+	if usage, ok := stats.Memory.Usage(); ok {
+		addField("memory.usage", usage)
+	}
+	addField("rootfs_usage", stats.RootFsUsage)
+	if node.Latency > 0 {
+		addField("latency", node.Latency)
+	}
 
 	c.addPoint(fields)
 }
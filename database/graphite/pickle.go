@@ -0,0 +1,72 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/fgrosse/graphigo"
+)
+
+const (
+	ProtocolPlaintext = "plaintext" // line based protocol of carbon (default port 2003)
+	ProtocolPickle    = "pickle"    // pickle protocol of carbon (default port 2004)
+)
+
+// opcodes of the pickle protocol 2
+const (
+	pickleProto      = 0x80
+	pickleEmptyList  = ']'
+	pickleMark       = '('
+	pickleBinUnicode = 'X'
+	pickleBinFloat   = 'G'
+	pickleTuple2     = 0x86
+	pickleAppends    = 'e'
+	pickleStop       = '.'
+)
+
+// sendPickle sends the metrics as a single message of the pickle protocol:
+// a list of (name, (timestamp, value)) tuples, prefixed by its length
+func (c *Connection) sendPickle(metrics []graphigo.Metric) error {
+	if c.client.Connection == nil {
+		return fmt.Errorf("graphite is not connected")
+	}
+
+	var payload bytes.Buffer
+	payload.Write([]byte{pickleProto, 2, pickleEmptyList, pickleMark})
+	for _, metric := range metrics {
+		value, err := strconv.ParseFloat(fmt.Sprint(metric.Value), 64)
+		if err != nil {
+			continue
+		}
+		name := metric.Name
+		if c.client.Prefix != "" {
+			name = c.client.Prefix + "." + name
+		}
+		timestamp := metric.Timestamp
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+
+		payload.WriteByte(pickleBinUnicode)
+		binary.Write(&payload, binary.LittleEndian, uint32(len(name)))
+		payload.WriteString(name)
+		writePickleFloat(&payload, float64(timestamp.Unix()))
+		writePickleFloat(&payload, value)
+		payload.Write([]byte{pickleTuple2, pickleTuple2})
+	}
+	payload.Write([]byte{pickleAppends, pickleStop})
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(payload.Len()))
+	_, err := c.client.Connection.Write(append(header, payload.Bytes()...))
+	return err
+}
+
+func writePickleFloat(buf *bytes.Buffer, value float64) {
+	buf.WriteByte(pickleBinFloat)
+	binary.Write(buf, binary.BigEndian, math.Float64bits(value))
+}
@@ -0,0 +1,71 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/fgrosse/graphigo"
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferConnection struct {
+	bytes.Buffer
+}
+
+func (c *bufferConnection) Close() error {
+	return nil
+}
+
+// pickleFloat returns the BINFLOAT opcode with the big-endian value
+func pickleFloat(value float64) []byte {
+	result := []byte{'G', 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint64(result[1:], math.Float64bits(value))
+	return result
+}
+
+func TestSendPickle(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &bufferConnection{}
+	c := &Connection{client: graphigo.Client{Prefix: "ffhb", Connection: conn}}
+	timestamp := time.Unix(1500000000, 0)
+
+	assert.NoError(c.sendPickle([]graphigo.Metric{
+		{Name: "node.a.clients", Value: 23, Timestamp: timestamp},
+		{Name: "node.a.hostname", Value: "not a number", Timestamp: timestamp},
+		{Name: "node.a.load", Value: 0.5, Timestamp: timestamp},
+	}))
+
+	var payload []byte
+	// PROTO 2, EMPTY_LIST, MARK
+	payload = append(payload, 0x80, 0x02, ']', '(')
+	for _, metric := range []struct {
+		name  string
+		value float64
+	}{
+		{"ffhb.node.a.clients", 23},
+		{"ffhb.node.a.load", 0.5},
+	} {
+		// BINUNICODE with little-endian length
+		payload = append(payload, 'X', byte(len(metric.name)), 0, 0, 0)
+		payload = append(payload, metric.name...)
+		payload = append(payload, pickleFloat(1500000000)...)
+		payload = append(payload, pickleFloat(metric.value)...)
+		// TUPLE2 of (timestamp, value) and TUPLE2 of (name, (timestamp, value))
+		payload = append(payload, 0x86, 0x86)
+	}
+	// APPENDS, STOP
+	payload = append(payload, 'e', '.')
+
+	// big-endian length header
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)))
+	assert.Equal(append(header, payload...), conn.Bytes())
+
+	// not connected
+	c.client.Connection = nil
+	assert.Error(c.sendPickle(nil))
+}
@@ -0,0 +1,72 @@
+package influxdb
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb1-client/models"
+	"github.com/influxdata/influxdb1-client/v2"
+)
+
+// batch collects the points, which are written together.
+// With deduplication a point is merged into an earlier point of the batch with the same
+// measurement, tags and timestamp, like InfluxDB merges the fields of such points.
+type batch struct {
+	points       []*client.Point
+	index        map[string]int // position of a point by its key, only with deduplication
+	deduplicated int            // count of merged points
+}
+
+func newBatch(dedup bool) *batch {
+	b := &batch{}
+	if dedup {
+		b.index = make(map[string]int)
+	}
+	return b
+}
+
+// add a point to the batch
+func (b *batch) add(point *client.Point) {
+	if b.index == nil {
+		b.points = append(b.points, point)
+		return
+	}
+
+	key := pointKey(point)
+	if i, ok := b.index[key]; ok {
+		// the fields of the last occurrence take precedence
+		if merged, err := mergePoints(b.points[i], point); err == nil {
+			b.points[i] = merged
+		} else {
+			b.points[i] = point
+		}
+		b.deduplicated++
+		return
+	}
+	b.index[key] = len(b.points)
+	b.points = append(b.points, point)
+}
+
+// mergePoints returns a point with the fields of both points, the fields of the later point take precedence
+func mergePoints(earlier, later *client.Point) (*client.Point, error) {
+	fields, err := earlier.Fields()
+	if err != nil {
+		return nil, err
+	}
+	laterFields, err := later.Fields()
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range laterFields {
+		fields[name] = value
+	}
+	return client.NewPoint(later.Name(), later.Tags(), fields, later.Time())
+}
+
+// pointKey returns the identity of a point in InfluxDB:
+// measurement, tags and timestamp in precision of the batch
+func pointKey(point *client.Point) string {
+	key := models.MakeKey([]byte(point.Name()), models.NewTags(point.Tags()))
+	t := point.Time().Truncate(time.Minute).UnixNano()
+	return string(key) + " " + strconv.FormatInt(t, 10)
+}
@@ -0,0 +1,54 @@
+package influxdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb1-client/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now().Truncate(time.Minute)
+	newPoint := func(nodeID string, clients int, t time.Time) *client.Point {
+		point, err := client.NewPoint(MeasurementNode, map[string]string{"nodeid": nodeID}, map[string]interface{}{"clients.total": clients}, t)
+		assert.NoError(err)
+		return point
+	}
+
+	// without deduplication
+	b := newBatch(false)
+	b.add(newPoint("a", 1, now))
+	b.add(newPoint("a", 2, now))
+	assert.Len(b.points, 2)
+	assert.Equal(0, b.deduplicated)
+
+	// with deduplication
+	b = newBatch(true)
+	b.add(newPoint("a", 1, now))
+	b.add(newPoint("b", 1, now))
+	// same minute (precision of the batch)
+	b.add(newPoint("a", 2, now.Add(time.Second)))
+	// next minute
+	b.add(newPoint("a", 3, now.Add(time.Minute)))
+	assert.Len(b.points, 3)
+	assert.Equal(1, b.deduplicated)
+
+	// last occurrence is kept on the position of the first
+	fields, _ := b.points[0].Fields()
+	assert.EqualValues(2, fields["clients.total"])
+	assert.Equal("b", b.points[1].Tags()["nodeid"])
+
+	// the fields of the points are merged
+	b = newBatch(true)
+	b.add(newPoint("a", 1, now))
+	point, err := client.NewPoint(MeasurementNode, map[string]string{"nodeid": "a"}, map[string]interface{}{"load": 0.5}, now)
+	assert.NoError(err)
+	b.add(point)
+	assert.Len(b.points, 1)
+	fields, _ = b.points[0].Fields()
+	assert.EqualValues(1, fields["clients.total"])
+	assert.EqualValues(0.5, fields["load"])
+}
@@ -0,0 +1,117 @@
+package influxdb
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/influxdata/influxdb1-client/models"
+	"github.com/influxdata/influxdb1-client/v2"
+)
+
+const (
+	bufferSizeDefault = 100000          // points kept for a retry by default
+	retryIntervalMin  = batchTimeout    // first retry after a failed write
+	retryIntervalMax  = 5 * time.Minute // longest interval between the retries
+)
+
+// buffer keeps the points of failed writes for a retry, the oldest points are dropped if it is full
+type buffer struct {
+	points  []*client.Point
+	size    int
+	dropped int // count of points dropped since the last call of add
+}
+
+func newBuffer(size int) *buffer {
+	return &buffer{size: size}
+}
+
+// add appends the points and drops the oldest points above the size of the buffer
+func (b *buffer) add(points []*client.Point) {
+	b.points = append(b.points, points...)
+	if over := len(b.points) - b.size; over > 0 {
+		b.points = b.points[over:]
+		b.dropped += over
+	}
+}
+
+// next returns the oldest points of the buffer, at most the given count
+func (b *buffer) next(count int) []*client.Point {
+	if count > len(b.points) {
+		count = len(b.points)
+	}
+	return b.points[:count]
+}
+
+// done removes the given count of the oldest points
+func (b *buffer) done(count int) {
+	b.points = b.points[count:]
+	if len(b.points) == 0 {
+		b.points = nil
+	}
+}
+
+// retryInterval returns the doubled interval of the last retry, but within retryIntervalMin and retryIntervalMax
+func retryInterval(last time.Duration) time.Duration {
+	next := last * 2
+	if next < retryIntervalMin {
+		return retryIntervalMin
+	}
+	if next > retryIntervalMax {
+		return retryIntervalMax
+	}
+	return next
+}
+
+// save writes the points of the buffer in line protocol to the file, it is removed on an empty buffer
+func (b *buffer) save(path string) error {
+	if len(b.points) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var data bytes.Buffer
+	for _, point := range b.points {
+		data.WriteString(point.String())
+		data.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load adds the points of a file written by save, a missing file is no error
+func (b *buffer) load(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var points []*client.Point
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		parsed, err := models.ParsePoints(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+		for _, point := range parsed {
+			points = append(points, client.NewPointFrom(point))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	b.add(points)
+	return nil
+}
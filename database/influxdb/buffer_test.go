@@ -0,0 +1,120 @@
+package influxdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb1-client/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func bufferTestPoints(t *testing.T, count int) []*client.Point {
+	var points []*client.Point
+	for i := 0; i < count; i++ {
+		point, err := client.NewPoint(MeasurementNode, map[string]string{"nodeid": "a"}, map[string]interface{}{"clients.total": i}, time.Unix(1500000000, 0))
+		assert.NoError(t, err)
+		points = append(points, point)
+	}
+	return points
+}
+
+func TestBuffer(t *testing.T) {
+	assert := assert.New(t)
+
+	points := bufferTestPoints(t, 5)
+	b := newBuffer(3)
+	b.add(points[:2])
+	assert.Equal(0, b.dropped)
+	b.add(points[2:])
+	assert.Equal(2, b.dropped)
+	assert.Equal(points[2:], b.points)
+
+	assert.Equal(points[2:4], b.next(2))
+	b.done(2)
+	assert.Equal(points[4:], b.next(2))
+	b.done(1)
+	assert.Nil(b.points)
+
+	assert.Equal(retryIntervalMin, retryInterval(0))
+	assert.Equal(2*retryIntervalMin, retryInterval(retryIntervalMin))
+	assert.Equal(retryIntervalMax, retryInterval(retryIntervalMax))
+}
+
+func TestBufferFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "yanic-influxdb")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "buffer")
+
+	// missing file
+	b := newBuffer(10)
+	assert.NoError(b.load(path))
+	assert.Len(b.points, 0)
+
+	b.add(bufferTestPoints(t, 2))
+	assert.NoError(b.save(path))
+
+	loaded := newBuffer(10)
+	assert.NoError(loaded.load(path))
+	assert.Len(loaded.points, 2)
+	assert.Equal(b.points[1].String(), loaded.points[1].String())
+
+	// an empty buffer removes the file
+	assert.NoError(newBuffer(10).save(path))
+	_, err = os.Stat(path)
+	assert.True(os.IsNotExist(err))
+
+	assert.NoError(ioutil.WriteFile(path, []byte("invalid line protocol\n"), 0644))
+	assert.Error(newBuffer(10).load(path))
+}
+
+func TestWriteRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	available := false
+	var written []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		written = append(written, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	influxClient, err := client.NewHTTPClient(client.HTTPConfig{Addr: srv.URL})
+	assert.NoError(err)
+	conn := &Connection{
+		config: Config{"database": "ffhb"},
+		client: influxClient,
+		buffer: newBuffer(3),
+	}
+
+	points := bufferTestPoints(t, 4)
+	assert.True(conn.writeBatch(points[:2]))
+	assert.True(conn.retry())
+	// appended while buffered, the oldest is dropped
+	assert.True(conn.writeBatch(points[2:]))
+	assert.EqualValues(1, conn.dropped)
+	assert.Len(written, 0)
+	assert.Equal(WriteCounters{Transient: 2}, conn.WriteErrors())
+	assert.Error(conn.Health())
+
+	available = true
+	assert.False(conn.retry())
+	assert.NoError(conn.Health())
+	assert.Len(written, 3)
+	assert.Contains(written[0], "clients.total=1i")
+	assert.False(conn.writeBatch(points[:1]))
+	assert.Len(written, 4)
+}
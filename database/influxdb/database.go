@@ -1,7 +1,10 @@
 package influxdb
 
 import (
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bdlm/log"
@@ -9,25 +12,43 @@ import (
 	"github.com/influxdata/influxdb1-client/v2"
 
 	"github.com/FreifunkBremen/yanic/database"
+	"github.com/FreifunkBremen/yanic/lib/duration"
 )
 
 const (
-	MeasurementLink               = "link"        // Measurement for per-link statistics
-	MeasurementNode               = "node"        // Measurement for per-node statistics
-	MeasurementDHCP               = "dhcp"        // Measurement for DHCP server statistics
-	MeasurementGlobal             = "global"      // Measurement for summarized global statistics
-	CounterMeasurementFirmware    = "firmware"    // Measurement for firmware statistics
-	CounterMeasurementModel       = "model"       // Measurement for model statistics
-	CounterMeasurementAutoupdater = "autoupdater" // Measurement for autoupdater
-	batchMaxSize                  = 1000
-	batchTimeout                  = 5 * time.Second
+	MeasurementLink                       = "link"                 // Measurement for per-link statistics
+	MeasurementNode                       = "node"                 // Measurement for per-node statistics
+	MeasurementDHCP                       = "dhcp"                 // Measurement for DHCP server statistics
+	MeasurementGlobal                     = "global"               // Measurement for summarized global statistics
+	MeasurementGateway                    = "gateway"              // Measurement for the usage of the gateways
+	MeasurementNodeLatest                 = "node_latest"          // Measurement for the latest state per node (overwritten)
+	MeasurementInternal                   = "yanic"                // Measurement for the internal counters of yanic itself
+	CounterMeasurementFirmware            = "firmware"             // Measurement for firmware statistics
+	CounterMeasurementModel               = "model"                // Measurement for model statistics
+	CounterMeasurementAutoupdater         = "autoupdater"          // Measurement for autoupdater
+	CounterMeasurementAutoupdaterDisabled = "autoupdater_disabled" // Measurement for branches of disabled autoupdater
+	CounterMeasurementChannel24           = "channel24"            // Measurement for wifi channels on 2.4 GHz
+	CounterMeasurementChannel5            = "channel5"             // Measurement for wifi channels on 5 GHz
+	CounterMeasurementLatency             = "latency"              // Measurement for the response latency of the nodes
+	batchMaxSize                          = 1000
+	batchPrecision                        = "m"
+	batchTimeout                          = 5 * time.Second
 )
 
 type Connection struct {
+	// accessed atomically, keep 64-bit aligned at the beginning of the struct
+	writeErrors  WriteCounters
+	deduplicated uint64 // points merged into an earlier point of their batch
+	dropped      uint64 // points dropped from the full buffer of failed writes
+	writes       uint64 // writes of batches, successful or not
+	writeTime    uint64 // duration of all writes in milliseconds
+
 	database.Connection
 	config Config
 	client client.Client
 	points chan *client.Point
+	buffer *buffer      // points of failed writes for a retry
+	health atomic.Value // writeResult of the last write
 	wg     sync.WaitGroup
 }
 
@@ -37,7 +58,34 @@ func (c Config) Address() string {
 	return c["address"].(string)
 }
 func (c Config) Database() string {
-	return c["database"].(string)
+	if d, ok := c["database"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Version() int {
+	if d, ok := c["version"]; ok {
+		return int(d.(int64))
+	}
+	return 1
+}
+func (c Config) Token() string {
+	if d, ok := c["token"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Organization() string {
+	if d, ok := c["organization"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Bucket() string {
+	if d, ok := c["bucket"]; ok {
+		return d.(string)
+	}
+	return ""
 }
 func (c Config) Username() string {
 	return c["username"].(string)
@@ -51,6 +99,98 @@ func (c Config) InsecureSkipVerify() bool {
 	}
 	return false
 }
+func (c Config) CreateDatabase() bool {
+	if d, ok := c["create_database"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) LatestState() bool {
+	if d, ok := c["latest_state"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) Internal() bool {
+	if d, ok := c["internal"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) InterfaceTag() bool {
+	if d, ok := c["interface_tag"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) BatchDedup() bool {
+	if d, ok := c["batch_dedup"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) BatchSize() (int, error) {
+	d, ok := c["batch_size"]
+	if !ok {
+		return batchMaxSize, nil
+	}
+	size, ok := d.(int64)
+	if !ok || size < 1 {
+		return 0, errors.New("batch_size has to be a positive number")
+	}
+	return int(size), nil
+}
+func (c Config) BatchInterval() (time.Duration, error) {
+	d, ok := c["batch_interval"]
+	if !ok {
+		return batchTimeout, nil
+	}
+	value, ok := d.(string)
+	if !ok {
+		return 0, errors.New("batch_interval has the wrong format")
+	}
+	var interval duration.Duration
+	if err := interval.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("batch_interval is invalid: %s", err)
+	}
+	if interval.Duration <= 0 {
+		return 0, errors.New("batch_interval has to be positive")
+	}
+	return interval.Duration, nil
+}
+func (c Config) RetentionPolicy() string {
+	if d, ok := c["retention_policy"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) RetentionDuration() (time.Duration, error) {
+	d, ok := c["retention_duration"]
+	if !ok {
+		return 0, nil
+	}
+	value, ok := d.(string)
+	if !ok {
+		return 0, errors.New("retention_duration has the wrong format")
+	}
+	var retention duration.Duration
+	if err := retention.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("retention_duration is invalid: %s", err)
+	}
+	return retention.Duration, nil
+}
+func (c Config) BufferSize() int {
+	if d, ok := c["buffer_size"]; ok {
+		return int(d.(int64))
+	}
+	return bufferSizeDefault
+}
+func (c Config) BufferFile() string {
+	if d, ok := c["buffer_file"]; ok {
+		return d.(string)
+	}
+	return ""
+}
 func (c Config) Tags() map[string]interface{} {
 	if c["tags"] != nil {
 		return c["tags"].(map[string]interface{})
@@ -65,6 +205,55 @@ func Connect(configuration map[string]interface{}) (database.Connection, error)
 	var config Config
 	config = configuration
 
+	// the points of the latest state have a fixed timestamp at the epoch,
+	// influxdb rejects them as beyond every finite retention policy
+	if config.LatestState() && config.RetentionPolicy() != "" {
+		return nil, errors.New("latest_state could not be used with a retention_policy, the latest state needs the infinite default retention policy of the database")
+	}
+	if _, err := config.BatchSize(); err != nil {
+		return nil, err
+	}
+	if _, err := config.BatchInterval(); err != nil {
+		return nil, err
+	}
+
+	var c client.Client
+	var err error
+	switch config.Version() {
+	case 1:
+		c, err = connectV1(config)
+	case 2:
+		c, err = connectV2(config)
+	default:
+		err = fmt.Errorf("unsupported version %d of the API, use 1 or 2", config.Version())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db := &Connection{
+		config: config,
+		client: c,
+		points: make(chan *client.Point, batchMaxSize),
+		buffer: newBuffer(config.BufferSize()),
+	}
+	if path := config.BufferFile(); path != "" {
+		if err = db.buffer.load(path); err != nil {
+			return nil, fmt.Errorf("unable to load buffer_file: %s", err)
+		}
+		if len(db.buffer.points) > 0 {
+			log.WithField("count", len(db.buffer.points)).Info("loaded buffered points")
+		}
+	}
+
+	db.wg.Add(1)
+	go db.addWorker()
+
+	return db, nil
+}
+
+// connectV1 returns a client of the InfluxDB 1.x API and prepares the database and retention policy
+func connectV1(config Config) (client.Client, error) {
 	// Make client
 	c, err := client.NewHTTPClient(client.HTTPConfig{
 		Addr:               config.Address(),
@@ -82,16 +271,31 @@ func Connect(configuration map[string]interface{}) (database.Connection, error)
 		return nil, err
 	}
 
-	db := &Connection{
-		config: config,
-		client: c,
-		points: make(chan *client.Point, batchMaxSize),
+	if err = checkDatabase(c, config); err != nil {
+		return nil, err
 	}
+	if err = checkRetentionPolicy(c, config); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
 
-	db.wg.Add(1)
-	go db.addWorker()
-
-	return db, nil
+// connectV2 returns a client of the InfluxDB 2.x API, the bucket has to exist
+func connectV2(config Config) (client.Client, error) {
+	if config.CreateDatabase() || config.RetentionPolicy() != "" {
+		return nil, errors.New("create_database and retention_policy are not supported by the InfluxDB 2.x API, create the bucket with its retention")
+	}
+	c, err := newV2Client(config)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err = c.Ping(time.Second); err != nil {
+		return nil, err
+	}
+	if err = c.checkBucket(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 func (conn *Connection) addPoint(name string, tags models.Tags, fields models.Fields, t ...time.Time) {
@@ -121,53 +325,150 @@ func (conn *Connection) Close() {
 	conn.client.Close()
 }
 
-// stores data points in batches into the influxdb
-func (conn *Connection) addWorker() {
-	bpConfig := client.BatchPointsConfig{
-		Database:  conn.config.Database(),
-		Precision: "m",
+// write sends the points to the influxdb
+func (conn *Connection) write(points []*client.Point) error {
+	bp, err := client.NewBatchPoints(client.BatchPointsConfig{
+		Database:        conn.config.Database(),
+		RetentionPolicy: conn.config.RetentionPolicy(),
+		Precision:       batchPrecision,
+	})
+	if err != nil {
+		return err
 	}
+	bp.AddPoints(points)
+	start := time.Now()
+	err = conn.client.Write(bp)
+	atomic.AddUint64(&conn.writes, 1)
+	atomic.AddUint64(&conn.writeTime, uint64(time.Since(start)/time.Millisecond))
+	conn.health.Store(writeResult{err})
+	return err
+}
 
-	var bp client.BatchPoints
-	var err error
+// writeBatch writes the points of a batch, on a transient error they are buffered for a retry.
+// While points are buffered, the points are appended to keep their order.
+// It returns whether points are buffered.
+func (conn *Connection) writeBatch(points []*client.Point) bool {
+	if len(conn.buffer.points) > 0 {
+		conn.bufferPoints(points)
+		return true
+	}
+	err := conn.write(points)
+	if err == nil {
+		return false
+	}
+	if conn.countWriteError(err) {
+		log.WithField("database", conn.config.Database()).Errorf("unable to save points, check the configuration: %s", err)
+		return false
+	}
+	log.WithField("count", len(points)).Warnf("unable to save points, retrying later: %s", err)
+	conn.bufferPoints(points)
+	return len(conn.buffer.points) > 0
+}
+
+// retry writes the buffered points and returns whether points remain buffered
+func (conn *Connection) retry() bool {
+	for len(conn.buffer.points) > 0 {
+		size, _ := conn.config.BatchSize()
+		points := conn.buffer.next(size)
+		if err := conn.write(points); err != nil {
+			if !conn.countWriteError(err) {
+				log.WithField("count", len(conn.buffer.points)).Warnf("unable to save buffered points, retrying later: %s", err)
+				return true
+			}
+			log.WithField("database", conn.config.Database()).Errorf("unable to save buffered points, check the configuration: %s", err)
+		}
+		conn.buffer.done(len(points))
+	}
+	log.Info("saved buffered points")
+	return false
+}
+
+// bufferPoints adds the points to the buffer and counts the dropped points of a full buffer
+func (conn *Connection) bufferPoints(points []*client.Point) {
+	conn.buffer.add(points)
+	if conn.buffer.dropped > 0 {
+		log.WithField("count", conn.buffer.dropped).Warn("buffer of failed writes is full, dropped the oldest points")
+		atomic.AddUint64(&conn.dropped, uint64(conn.buffer.dropped))
+		conn.buffer.dropped = 0
+	}
+}
+
+// stores data points in batches into the influxdb,
+// a batch is written after the batch interval since its first point or by the batch size
+func (conn *Connection) addWorker() {
+	// validated by Connect
+	batchSize, _ := conn.config.BatchSize()
+	batchInterval, _ := conn.config.BatchInterval()
+
+	var b *batch
 	var writeNow, closed bool
-	timer := time.NewTimer(batchTimeout)
+	timer := time.NewTimer(batchInterval)
+
+	// retry of the buffered points with an exponential backoff
+	var retry <-chan time.Time
+	var interval time.Duration
+	if len(conn.buffer.points) > 0 {
+		retry = time.After(0)
+	}
 
 	for !closed {
 		// wait for new points
 		select {
 		case point, ok := <-conn.points:
 			if ok {
-				if bp == nil {
+				if b == nil {
 					// create new batch
-					timer.Reset(batchTimeout)
-					if bp, err = client.NewBatchPoints(bpConfig); err != nil {
-						log.Fatal(err)
-					}
+					timer.Reset(batchInterval)
+					b = newBatch(conn.config.BatchDedup())
 				}
-				bp.AddPoint(point)
+				b.add(point)
 			} else {
 				closed = true
 			}
 		case <-timer.C:
-			if bp == nil {
-				timer.Reset(batchTimeout)
+			if b == nil {
+				timer.Reset(batchInterval)
 			} else {
 				writeNow = true
 			}
+		case <-retry:
+			if conn.retry() {
+				interval = retryInterval(interval)
+				retry = time.After(interval)
+			} else {
+				interval = 0
+				retry = nil
+			}
 		}
 
 		// write batch now?
-		if bp != nil && (writeNow || closed || len(bp.Points()) >= batchMaxSize) {
-			log.WithField("count", len(bp.Points())).Info("saving points")
+		if b != nil && (writeNow || closed || len(b.points) >= batchSize) {
+			log.WithFields(map[string]interface{}{
+				"count":        len(b.points),
+				"deduplicated": b.deduplicated,
+			}).Info("saving points")
+			atomic.AddUint64(&conn.deduplicated, uint64(b.deduplicated))
 
-			if err = conn.client.Write(bp); err != nil {
-				log.Error(err)
+			if conn.writeBatch(b.points) && retry == nil {
+				interval = retryInterval(0)
+				retry = time.After(interval)
 			}
 			writeNow = false
-			bp = nil
+			b = nil
 		}
 	}
 	timer.Stop()
+
+	// last try of the buffered points, the remaining are kept in the file
+	if len(conn.buffer.points) > 0 {
+		conn.retry()
+	}
+	if path := conn.config.BufferFile(); path != "" {
+		if err := conn.buffer.save(path); err != nil {
+			log.WithField("count", len(conn.buffer.points)).Errorf("unable to save the buffered points into the buffer_file: %s", err)
+		}
+	} else if len(conn.buffer.points) > 0 {
+		log.WithField("count", len(conn.buffer.points)).Error("lost buffered points on shutdown")
+	}
 	conn.wg.Done()
 }
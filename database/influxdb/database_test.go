@@ -1,8 +1,11 @@
 package influxdb
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,20 +36,272 @@ func TestConnect(t *testing.T) {
 	assert.Nil(conn)
 	assert.Error(err)
 
-	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNoContent)
-	}))
+	srv, queries := testServer("ffhb")
 	defer srv.Close()
 
 	conn, err = Connect(map[string]interface{}{
 		"address":  srv.URL,
-		"database": "",
+		"database": "ffhb",
+		"username": "",
+		"password": "",
+	})
+
+	assert.NotNil(conn)
+	assert.NoError(err)
+	assert.Equal([]string{"SHOW DATABASES"}, *queries)
+
+	// not existing database
+	*queries = nil
+	conn, err = Connect(map[string]interface{}{
+		"address":  srv.URL,
+		"database": "missing",
 		"username": "",
 		"password": "",
 	})
+	assert.Nil(conn)
+	assert.Error(err)
+	assert.Contains(err.Error(), "create_database")
+
+	// create not existing database
+	*queries = nil
+	conn, err = Connect(map[string]interface{}{
+		"address":         srv.URL,
+		"database":        "missing",
+		"username":        "",
+		"password":        "",
+		"create_database": true,
+	})
+	assert.NotNil(conn)
+	assert.NoError(err)
+	assert.Equal([]string{"SHOW DATABASES", `CREATE DATABASE "missing"`}, *queries)
+}
+
+func TestConnectWithoutAdmin(t *testing.T) {
+	assert := assert.New(t)
+
+	var writes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/ping":
+			w.WriteHeader(http.StatusNoContent)
+		case "/query":
+			w.Write([]byte(`{"results":[{"statement_id":0,"error":"error authorizing query: writer not authorized to execute statement 'SHOW DATABASES', requires admin privilege"}]}`))
+		case "/write":
+			writes = append(writes, r.FormValue("db"))
+			if r.FormValue("db") != "ffhb" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":"database not found: \"` + r.FormValue("db") + `\""}`))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	config := func(database string) map[string]interface{} {
+		return map[string]interface{}{
+			"address":  srv.URL,
+			"database": database,
+			"username": "writer",
+			"password": "",
+		}
+	}
+
+	// the database is checked by a write
+	conn, err := Connect(config("ffhb"))
+	assert.NotNil(conn)
+	assert.NoError(err)
+	assert.Equal([]string{"ffhb"}, writes)
+	conn.Close()
+
+	conn, err = Connect(config("missing"))
+	assert.Nil(conn)
+	assert.Error(err)
+	assert.Contains(err.Error(), "create_database")
+}
+
+func TestConnectRetentionPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, queries := testServer("ffhb")
+	defer srv.Close()
+
+	config := func(policy string, retention interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"address":          srv.URL,
+			"database":         "ffhb",
+			"username":         "",
+			"password":         "",
+			"retention_policy": policy,
+		}
+		if retention != nil {
+			c["retention_duration"] = retention
+		}
+		return c
+	}
+
+	// existing policy
+	conn, err := Connect(config("autogen", nil))
+	assert.NotNil(conn)
+	assert.NoError(err)
+	assert.Equal([]string{"SHOW DATABASES", `SHOW RETENTION POLICIES ON "ffhb"`}, *queries)
 
+	// update existing policy
+	*queries = nil
+	conn, err = Connect(config("autogen", "7d"))
 	assert.NotNil(conn)
 	assert.NoError(err)
+	assert.Equal(`ALTER RETENTION POLICY "autogen" ON "ffhb" DURATION 604800s`, (*queries)[2])
+
+	// create policy
+	*queries = nil
+	conn, err = Connect(config("yanic", "2h"))
+	assert.NotNil(conn)
+	assert.NoError(err)
+	assert.Equal(`CREATE RETENTION POLICY "yanic" ON "ffhb" DURATION 7200s REPLICATION 1`, (*queries)[2])
+
+	// missing policy
+	conn, err = Connect(config("yanic", nil))
+	assert.Nil(conn)
+	assert.Error(err)
+	assert.Contains(err.Error(), "retention_duration")
+
+	// invalid duration
+	_, err = Connect(config("yanic", "2x"))
+	assert.Error(err)
+	_, err = Connect(config("yanic", 2))
+	assert.Error(err)
+
+	// the latest state needs the default retention policy
+	*queries = nil
+	c := config("yanic", "2h")
+	c["latest_state"] = true
+	conn, err = Connect(c)
+	assert.Nil(conn)
+	assert.Error(err)
+	assert.Contains(err.Error(), "latest_state")
+	assert.Empty(*queries)
+}
+
+func TestConnectBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, _ := testServer("ffhb")
+	defer srv.Close()
+
+	config := func(key string, value interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"address":  srv.URL,
+			"database": "ffhb",
+			"username": "",
+			"password": "",
+			key:        value,
+		}
+	}
+
+	for _, c := range []map[string]interface{}{
+		config("batch_size", int64(0)),
+		config("batch_size", "1000"),
+		config("batch_interval", "1x"),
+		config("batch_interval", "0s"),
+		config("batch_interval", 60),
+	} {
+		conn, err := Connect(c)
+		assert.Nil(conn, c)
+		assert.Error(err, c)
+	}
+
+	// written by the batch size, long before the batch interval
+	c := config("batch_interval", "1h")
+	c["batch_size"] = int64(2)
+	db, err := Connect(c)
+	assert.NoError(err)
+	conn := db.(*Connection)
+	for _, point := range bufferTestPoints(t, 3) {
+		conn.points <- point
+	}
+	assert.Eventually(func() bool {
+		return atomic.LoadUint64(&conn.writes) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// the rest on close
+	conn.Close()
+	assert.EqualValues(2, atomic.LoadUint64(&conn.writes))
+}
+
+func TestPruneNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, queries := testServer("ffhb")
+	defer srv.Close()
+
+	influxClient, err := client.NewHTTPClient(client.HTTPConfig{Addr: srv.URL})
+	assert.NoError(err)
+	conn := &Connection{
+		config: Config{"database": "ffhb"},
+		client: influxClient,
+	}
+	conn.PruneNodes(time.Hour)
+	assert.Equal([]string{
+		"delete from node where time < now() - 3600s",
+		"delete from link where time < now() - 3600s",
+		"delete from dhcp where time < now() - 3600s",
+	}, *queries)
+}
+
+// testServer simulates an influxdb with the given databases and records the queries
+func testServer(databases ...string) (*httptest.Server, *[]string) {
+	var queries []string
+	values := ""
+	for i, name := range databases {
+		if i > 0 {
+			values += ","
+		}
+		values += `["` + name + `"]`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/write" && !strings.Contains(values, `"`+r.FormValue("db")+`"`) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"database not found: \"` + r.FormValue("db") + `\""}`))
+			return
+		}
+		if r.URL.Path != "/query" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		queries = append(queries, r.FormValue("q"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("q") == "SHOW DATABASES" {
+			w.Write([]byte(`{"results":[{"statement_id":0,"series":[{"name":"databases","columns":["name"],"values":[` + values + `]}]}]}`))
+			return
+		}
+		if strings.HasPrefix(r.FormValue("q"), "SHOW RETENTION POLICIES") {
+			w.Write([]byte(`{"results":[{"statement_id":0,"series":[{"columns":["name","duration"],"values":[["autogen","0s"]]}]}]}`))
+			return
+		}
+		w.Write([]byte(`{"results":[{"statement_id":0}]}`))
+	}))
+	return srv, &queries
+}
+
+func TestWriteErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{}
+	assert.True(conn.countWriteError(errors.New(`{"error":"database not found: \"ffhb\""}`)))
+	assert.False(conn.countWriteError(errors.New("timeout")))
+	assert.False(conn.countWriteError(errors.New("connection refused")))
+	assert.Equal(WriteCounters{Permanent: 1, Transient: 2}, conn.WriteErrors())
+
+	conn.deduplicated = 4
+	counters := conn.Counters()
+	assert.Equal("influxdb_write_errors_permanent", counters[0].Name)
+	assert.EqualValues(1, counters[0].Value)
+	assert.EqualValues(2, counters[1].Value)
+	assert.Equal("influxdb_points_deduplicated", counters[2].Name)
+	assert.EqualValues(4, counters[2].Value)
 }
 
 func TestAddPoint(t *testing.T) {
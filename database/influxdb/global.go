@@ -12,31 +12,51 @@ func (conn *Connection) InsertGlobals(stats *runtime.GlobalStats, time time.Time
 	tags := models.Tags{}
 
 	measurementGlobal := MeasurementGlobal
+	measurementGateway := MeasurementGateway
 	counterMeasurementModel := CounterMeasurementModel
 	counterMeasurementFirmware := CounterMeasurementFirmware
 	counterMeasurementAutoupdater := CounterMeasurementAutoupdater
+	counterMeasurementAutoupdaterDisabled := CounterMeasurementAutoupdaterDisabled
+	counterMeasurementChannel24 := CounterMeasurementChannel24
+	counterMeasurementChannel5 := CounterMeasurementChannel5
+	counterMeasurementLatency := CounterMeasurementLatency
 
 	if site != runtime.GLOBAL_SITE {
 		tags.Set([]byte("site"), []byte(site))
 
 		measurementGlobal += "_site"
+		measurementGateway += "_site"
 		counterMeasurementModel += "_site"
 		counterMeasurementFirmware += "_site"
 		counterMeasurementAutoupdater += "_site"
+		counterMeasurementAutoupdaterDisabled += "_site"
+		counterMeasurementChannel24 += "_site"
+		counterMeasurementChannel5 += "_site"
+		counterMeasurementLatency += "_site"
 	}
 	if domain != runtime.GLOBAL_DOMAIN {
 		tags.Set([]byte("domain"), []byte(domain))
 
 		measurementGlobal += "_domain"
+		measurementGateway += "_domain"
 		counterMeasurementModel += "_domain"
 		counterMeasurementFirmware += "_domain"
 		counterMeasurementAutoupdater += "_domain"
+		counterMeasurementAutoupdaterDisabled += "_domain"
+		counterMeasurementChannel24 += "_domain"
+		counterMeasurementChannel5 += "_domain"
+		counterMeasurementLatency += "_domain"
 	}
 
 	conn.addPoint(measurementGlobal, tags, GlobalStatsFields(stats), time)
+	conn.addGateways(measurementGateway, stats.PerGateway, time, site, domain)
 	conn.addCounterMap(counterMeasurementModel, stats.Models, time, site, domain)
 	conn.addCounterMap(counterMeasurementFirmware, stats.Firmwares, time, site, domain)
 	conn.addCounterMap(counterMeasurementAutoupdater, stats.Autoupdater, time, site, domain)
+	conn.addCounterMap(counterMeasurementAutoupdaterDisabled, stats.AutoupdaterDisabled, time, site, domain)
+	conn.addCounterMap(counterMeasurementChannel24, stats.Channels24, time, site, domain)
+	conn.addCounterMap(counterMeasurementChannel5, stats.Channels5, time, site, domain)
+	conn.addCounterMap(counterMeasurementLatency, stats.Latency, time, site, domain)
 }
 
 // GlobalStatsFields returns fields for InfluxDB
@@ -44,6 +64,8 @@ func GlobalStatsFields(stats *runtime.GlobalStats) map[string]interface{} {
 	return map[string]interface{}{
 		"nodes":          stats.Nodes,
 		"gateways":       stats.Gateways,
+		"reboots":        stats.Reboots,
+		"uplink_only":    stats.NodesUplinkOnly,
 		"clients.total":  stats.Clients,
 		"clients.wifi":   stats.ClientsWifi,
 		"clients.wifi24": stats.ClientsWifi24,
@@ -51,6 +73,32 @@ func GlobalStatsFields(stats *runtime.GlobalStats) map[string]interface{} {
 		"clients.owe":    stats.ClientsOwe,
 		"clients.owe24":  stats.ClientsOwe24,
 		"clients.owe5":   stats.ClientsOwe5,
+		"clients.unique": stats.ClientsUnique,
+
+		"sections.nodeinfo":   stats.SectionRatio(stats.NodesNodeinfo),
+		"sections.statistics": stats.SectionRatio(stats.NodesStatistics),
+		"sections.neighbours": stats.SectionRatio(stats.NodesNeighbours),
+	}
+}
+
+// Saves the usage of the gateways in the database.
+// The node ID (or address) of the gateway is used as 'gateway' tag, its hostname as 'hostname' tag.
+func (conn *Connection) addGateways(name string, gateways map[string]*runtime.GatewayStats, t time.Time, site string, domain string) {
+	for gateway, stats := range gateways {
+		tags := models.Tags{
+			models.Tag{Key: []byte("gateway"), Value: []byte(gateway)},
+			models.Tag{Key: []byte("site"), Value: []byte(site)},
+			models.Tag{Key: []byte("domain"), Value: []byte(domain)},
+		}
+		if stats.Hostname != "" {
+			tags.SetString("hostname", stats.Hostname)
+		}
+		conn.addPoint(
+			name,
+			tags,
+			models.Fields{"nodes": stats.Nodes, "clients": stats.Clients},
+			t,
+		)
 	}
 }
 
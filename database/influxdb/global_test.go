@@ -25,6 +25,9 @@ func TestGlobalStats(t *testing.T) {
 	// check SITE_GLOBAL fields
 	fields := GlobalStatsFields(stats[runtime.GLOBAL_SITE][runtime.GLOBAL_DOMAIN])
 	assert.EqualValues(3, fields["nodes"])
+	assert.EqualValues(1, fields["sections.nodeinfo"])
+	assert.InDelta(2.0/3.0, fields["sections.statistics"], 0.001)
+	assert.EqualValues(0, fields["sections.neighbours"])
 
 	fields = GlobalStatsFields(stats[TEST_SITE][runtime.GLOBAL_DOMAIN])
 	assert.EqualValues(2, fields["nodes"])
@@ -181,3 +184,29 @@ func createTestNodes() *runtime.Nodes {
 
 	return nodes
 }
+
+func TestGlobalStatsGateways(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{
+		points: make(chan *client.Point, 10),
+	}
+	stats := &runtime.GlobalStats{PerGateway: map[string]*runtime.GatewayStats{
+		"00000000000a": {Hostname: "gw01", Nodes: 2, Clients: 6},
+	}}
+	conn.InsertGlobals(stats, time.Now(), TEST_SITE, runtime.GLOBAL_DOMAIN)
+	close(conn.points)
+
+	var gateways []*client.Point
+	for p := range conn.points {
+		if p.Name() == "gateway_site" {
+			gateways = append(gateways, p)
+		}
+	}
+	assert.Len(gateways, 1)
+	assert.Equal(map[string]string{"gateway": "00000000000a", "hostname": "gw01", "site": TEST_SITE, "domain": runtime.GLOBAL_DOMAIN}, gateways[0].Tags())
+	fields, err := gateways[0].Fields()
+	assert.NoError(err)
+	assert.EqualValues(2, fields["nodes"])
+	assert.EqualValues(6, fields["clients"])
+}
@@ -0,0 +1,21 @@
+package influxdb
+
+import (
+	"time"
+
+	"github.com/influxdata/influxdb1-client/models"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// InsertInternal stores the internal counters of yanic as fields of a single point, if enabled by internal
+func (conn *Connection) InsertInternal(counters []runtime.Counter, time time.Time) {
+	if !conn.config.Internal() || len(counters) == 0 {
+		return
+	}
+	fields := make(models.Fields, len(counters))
+	for _, counter := range counters {
+		fields[counter.Name] = int64(counter.Value)
+	}
+	conn.addPoint(MeasurementInternal, models.Tags{}, fields, time)
+}
@@ -0,0 +1,35 @@
+package influxdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb1-client/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestInsertInternal(t *testing.T) {
+	assert := assert.New(t)
+
+	counters := []runtime.Counter{
+		{Name: "datagrams_received", Value: 42},
+		{Name: "queue_length", Value: 3, Gauge: true},
+	}
+
+	// disabled
+	conn := &Connection{config: Config{}, points: make(chan *client.Point, 1)}
+	conn.InsertInternal(counters, time.Now())
+	assert.Len(conn.points, 0)
+
+	conn.config = Config{"internal": true}
+	conn.InsertInternal(counters, time.Unix(1500000000, 0))
+	assert.Len(conn.points, 1)
+	point := <-conn.points
+	assert.Equal(MeasurementInternal, point.Name())
+	fields, err := point.Fields()
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"datagrams_received": int64(42), "queue_length": int64(3)}, fields)
+	assert.Equal(int64(1500000000), point.Time().Unix())
+}
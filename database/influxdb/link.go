@@ -10,10 +10,16 @@ import (
 // InsertLink adds a link data point
 func (conn *Connection) InsertLink(link *runtime.Link, t time.Time) {
 	tags := models.Tags{}
+	if link.Protocol != "" {
+		tags.SetString("protocol", link.Protocol)
+	}
 	tags.SetString("source.id", link.SourceID)
 	tags.SetString("source.addr", link.SourceAddress)
 	tags.SetString("target.id", link.TargetID)
 	tags.SetString("target.addr", link.TargetAddress)
+	if link.Type != "" {
+		tags.SetString("type", link.Type)
+	}
 	if link.SourceHostname != "" {
 		tags.SetString("source.hostname", link.SourceHostname)
 	}
@@ -21,5 +27,10 @@ func (conn *Connection) InsertLink(link *runtime.Link, t time.Time) {
 		tags.SetString("target.hostname", link.TargetHostname)
 	}
 
-	conn.addPoint(MeasurementLink, tags, models.Fields{"tq": link.TQ * 100}, t)
+	fields := models.Fields{"tq": link.TQ * 100}
+	if link.Signal != 0 {
+		fields["signal"] = link.Signal
+	}
+
+	conn.addPoint(MeasurementLink, tags, fields, t)
 }
@@ -5,19 +5,34 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/bdlm/log"
 	models "github.com/influxdata/influxdb1-client/models"
 	client "github.com/influxdata/influxdb1-client/v2"
 
+	"github.com/FreifunkBremen/yanic/data"
 	"github.com/FreifunkBremen/yanic/runtime"
 )
 
 // PruneNodes prunes historical per-node data
 func (conn *Connection) PruneNodes(deleteAfter time.Duration) {
-	for _, measurement := range []string{MeasurementNode, MeasurementLink} {
+	if c, ok := conn.client.(*v2Client); ok {
+		for _, measurement := range []string{MeasurementNode, MeasurementLink, MeasurementDHCP} {
+			if err := c.delete(measurement, time.Now().Add(-deleteAfter)); err != nil {
+				log.WithField("measurement", measurement).Errorf("unable to prune data: %s", err)
+			}
+		}
+		return
+	}
+	for _, measurement := range []string{MeasurementNode, MeasurementLink, MeasurementDHCP} {
 		query := fmt.Sprintf("delete from %s where time < now() - %ds", measurement, deleteAfter/time.Second)
-		conn.client.Query(client.NewQuery(query, conn.config.Database(), "m"))
+		response, err := conn.client.Query(client.NewQuery(query, conn.config.Database(), "m"))
+		if err == nil {
+			err = response.Error()
+		}
+		if err != nil {
+			log.WithField("measurement", measurement).Errorf("unable to prune data: %s", err)
+		}
 	}
-
 }
 
 // InsertNode stores statistics and neighbours in the database
@@ -31,6 +46,9 @@ func (conn *Connection) InsertNode(node *runtime.Node) {
 
 	tags := models.Tags{}
 	tags.SetString("nodeid", stats.NodeID)
+	if node.Interface != "" && conn.config.InterfaceTag() {
+		tags.SetString("interface", node.Interface)
+	}
 
 	fields := models.Fields{
 		"load":             stats.LoadAverage,
@@ -51,6 +69,22 @@ func (conn *Connection) InsertNode(node *runtime.Node) {
 		"memory.available": stats.Memory.Available,
 	}
 
+	if usage, ok := stats.Memory.Usage(); ok {
+		fields["memory.usage"] = usage
+	}
+	fields["rootfs_usage"] = stats.RootFsUsage
+	if node.Latency > 0 {
+		fields["latency"] = node.Latency
+	}
+
+	if signal := stats.Clients.Signal; signal != nil {
+		fields["clients.signal.avg"] = signal.Avg
+		fields["clients.signal.min"] = signal.Min
+	}
+	if unique := stats.Clients.Unique; unique != nil {
+		fields["clients.unique"] = *unique
+	}
+
 	vpnInterfaces := make(map[string]bool)
 
 	if nodeinfo := node.Nodeinfo; nodeinfo != nil {
@@ -124,6 +158,11 @@ func (conn *Connection) InsertNode(node *runtime.Node) {
 
 		// total is the sum of all protocols
 		fields["neighbours.total"] = batadv + babel + lldp
+
+		// sections of unknown link types are not analysed, just counted
+		if unknown := len(neighbours.Unknown); unknown > 0 {
+			fields["neighbours.unknown"] = unknown
+		}
 	}
 	if procstat := stats.ProcStats; procstat != nil {
 		fields["stat.cpu.user"] = procstat.CPU.User
@@ -160,6 +199,19 @@ func (conn *Connection) InsertNode(node *runtime.Node) {
 		fields["traffic.mgmt_tx.bytes"] = int64(t.Bytes)
 		fields["traffic.mgmt_tx.packets"] = t.Packets
 	}
+	// rates per second of the counters, if known
+	for name, t := range map[string]*data.Traffic{
+		"rx":      stats.Traffic.Rx,
+		"tx":      stats.Traffic.Tx,
+		"forward": stats.Traffic.Forward,
+		"mgmt_rx": stats.Traffic.MgmtRx,
+		"mgmt_tx": stats.Traffic.MgmtTx,
+	} {
+		if t != nil && t.Rate != nil {
+			fields["traffic."+name+".rate.bytes"] = t.Rate.Bytes
+			fields["traffic."+name+".rate.packets"] = t.Rate.Packets
+		}
+	}
 
 	for _, airtime := range stats.Wireless {
 		suffix := airtime.FrequencyName()
@@ -173,6 +225,10 @@ func (conn *Connection) InsertNode(node *runtime.Node) {
 
 	conn.addPoint(MeasurementNode, tags, fields, time)
 
+	if conn.config.LatestState() {
+		conn.addLatestState(tags, fields, time)
+	}
+
 	// Add DHCP statistics
 	if dhcp := stats.DHCP; dhcp != nil {
 		fields := models.Fields{
@@ -200,3 +256,27 @@ func (conn *Connection) InsertNode(node *runtime.Node) {
 
 	return
 }
+
+// latestStateTime is the fixed timestamp of all points of the latest state,
+// a new point of a node overwrites the previous one
+var latestStateTime = time.Unix(0, 0)
+
+// addLatestState stores the point of a node as its latest state.
+// Only the nodeid is kept as tag, so that every node has exactly one series.
+func (conn *Connection) addLatestState(tags models.Tags, fields models.Fields, lastseen time.Time) {
+	latestTags := models.Tags{}
+	latestFields := models.Fields{
+		"lastseen": lastseen.Unix(),
+	}
+	for name, value := range fields {
+		latestFields[name] = value
+	}
+	for _, tag := range tags {
+		if string(tag.Key) == "nodeid" {
+			latestTags.SetString("nodeid", string(tag.Value))
+		} else {
+			latestFields[string(tag.Key)] = string(tag.Value)
+		}
+	}
+	conn.addPoint(MeasurementNodeLatest, latestTags, latestFields, latestStateTime)
+}
@@ -1,12 +1,14 @@
 package influxdb
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/influxdata/influxdb1-client/v2"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/lib/jsontime"
 	"github.com/FreifunkBremen/yanic/runtime"
 )
 
@@ -17,6 +19,11 @@ func TestToInflux(t *testing.T) {
 		Statistics: &data.Statistics{
 			NodeID:      "deadbeef",
 			LoadAverage: 0.5,
+			RootFsUsage: 0.25,
+			Memory:      data.Memory{Total: 1000, Available: 400},
+			Clients: data.Clients{
+				Signal: &data.ClientSignal{Avg: -60, Min: -80},
+			},
 			ProcStats: &data.ProcStats{
 				CPU: data.ProcStatsCPU{
 					User: 1,
@@ -34,7 +41,7 @@ func TestToInflux(t *testing.T) {
 				MgmtRx  *data.Traffic `json:"mgmt_rx"`
 			}{
 				Tx:      &data.Traffic{Dropped: 1321},
-				Rx:      &data.Traffic{Bytes: 1213},
+				Rx:      &data.Traffic{Bytes: 1213, Rate: &data.TrafficRate{Bytes: 12.5, Packets: 0.25}},
 				Forward: &data.Traffic{Bytes: 1322},
 				MgmtTx:  &data.Traffic{Packets: 2327},
 				MgmtRx:  &data.Traffic{Bytes: 2331},
@@ -98,9 +105,21 @@ func TestToInflux(t *testing.T) {
 					},
 				},
 			},
+			Wifi: map[string]data.WifiNeighbours{
+				"a-interface-mac": {
+					Neighbours: map[string]data.WifiLink{
+						"BAFF1E5": {
+							Signal: -65,
+						},
+					},
+				},
+			},
 			LLDP: map[string]data.LLDPNeighbours{
 				"b-interface-mac": {},
 			},
+			Unknown: map[string]json.RawMessage{
+				"mesh_vpn": json.RawMessage("{}"),
+			},
 		},
 	}
 
@@ -158,21 +177,29 @@ func TestToInflux(t *testing.T) {
 	assert.EqualValues("ffhb", tags["site"])
 	assert.EqualValues("city", tags["domain"])
 	assert.EqualValues(0.5, fields["load"])
+	assert.InDelta(0.6, fields["memory.usage"], 0.0001)
+	assert.EqualValues(0.25, fields["rootfs_usage"])
 	assert.EqualValues(0, fields["neighbours.lldp"])
 	assert.EqualValues(1, fields["neighbours.babel"])
 	assert.EqualValues(1, fields["neighbours.batadv"])
 	assert.EqualValues(2, fields["neighbours.vpn"])
 	assert.EqualValues(2, fields["neighbours.total"])
+	assert.EqualValues(1, fields["neighbours.unknown"])
 
 	assert.EqualValues(uint32(3), fields["wireless.txpower24"])
 	assert.EqualValues(uint32(5500), fields["airtime11a.frequency"])
 	assert.EqualValues("", tags["frequency5500"])
 
 	assert.EqualValues(int64(1213), fields["traffic.rx.bytes"])
+	assert.EqualValues(12.5, fields["traffic.rx.rate.bytes"])
+	assert.EqualValues(0.25, fields["traffic.rx.rate.packets"])
+	assert.Nil(fields["traffic.tx.rate.bytes"])
 	assert.EqualValues(float64(1321), fields["traffic.tx.dropped"])
 	assert.EqualValues(int64(1322), fields["traffic.forward.bytes"])
 	assert.EqualValues(int64(2331), fields["traffic.mgmt_rx.bytes"])
 	assert.EqualValues(float64(2327), fields["traffic.mgmt_tx.packets"])
+	assert.EqualValues(-60, fields["clients.signal.avg"])
+	assert.EqualValues(-80, fields["clients.signal.min"])
 
 	// second point contains the link
 	nPoint := points[1]
@@ -180,17 +207,22 @@ func TestToInflux(t *testing.T) {
 	fields, _ = nPoint.Fields()
 	assert.EqualValues("link", nPoint.Name())
 	assert.EqualValues(map[string]string{
+		"protocol":    "batadv",
 		"source.id":   "deadbeef",
 		"source.addr": "a-interface-mac",
 		"target.id":   "foobar",
 		"target.addr": "BAFF1E5",
+		"type":        "vpn",
 	}, tags)
 	assert.EqualValues(80, fields["tq"])
+	assert.EqualValues(-65, fields["signal"])
 
 	// third point contains the neighbour
 	nPoint = points[2]
 	tags = nPoint.Tags()
+	fields, _ = nPoint.Fields()
 	assert.EqualValues("disabled", tags["autoupdater"])
+	assert.NotContains(fields, "clients.signal.avg")
 }
 
 // Processes data and returns the InfluxDB points
@@ -233,3 +265,59 @@ func testPoints(nodes ...*runtime.Node) (points []*client.Point) {
 
 	return
 }
+
+func TestLatestState(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{
+		config: Config{"latest_state": true},
+		points: make(chan *client.Point, 2),
+	}
+	node := &runtime.Node{
+		Statistics: &data.Statistics{
+			NodeID:      "deadbeef",
+			LoadAverage: 0.5,
+		},
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "deadbeef",
+			Hostname: "examplehost",
+		},
+	}
+	node.Lastseen = jsontime.Now()
+	conn.InsertNode(node)
+	close(conn.points)
+
+	point := <-conn.points
+	assert.Equal(MeasurementNode, point.Name())
+
+	point = <-conn.points
+	assert.Equal(MeasurementNodeLatest, point.Name())
+	assert.Equal(map[string]string{"nodeid": "deadbeef"}, point.Tags())
+	assert.Equal(int64(0), point.UnixNano())
+	fields, _ := point.Fields()
+	assert.EqualValues(0.5, fields["load"])
+	assert.Equal("examplehost", fields["hostname"])
+	assert.EqualValues(node.Lastseen.Unix(), fields["lastseen"])
+}
+
+func TestInterfaceTag(t *testing.T) {
+	assert := assert.New(t)
+
+	node := &runtime.Node{
+		Interface: "bat-city",
+		Statistics: &data.Statistics{
+			NodeID: "deadbeef",
+		},
+	}
+
+	conn := &Connection{
+		config: Config{},
+		points: make(chan *client.Point, 1),
+	}
+	conn.InsertNode(node)
+	assert.NotContains((<-conn.points).Tags(), "interface")
+
+	conn.config["interface_tag"] = true
+	conn.InsertNode(node)
+	assert.Equal("bat-city", (<-conn.points).Tags()["interface"])
+}
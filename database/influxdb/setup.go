@@ -0,0 +1,195 @@
+package influxdb
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlm/log"
+	"github.com/influxdata/influxdb1-client/v2"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// WriteCounters are the counters of failed writes of batches
+type WriteCounters struct {
+	Permanent uint64 // misconfiguration, e.g. missing database or permissions - retrying will not help
+	Transient uint64 // e.g. timeouts or an unavailable server
+}
+
+// errors of influxdb, on which a retry of the write will not help
+var permanentErrors = []string{
+	"database not found",
+	"authorization failed",
+	"user not found",
+	"unable to parse",
+	"partial write",
+	"unauthorized",                 // 2.x: invalid token
+	"not found",                    // 2.x: missing organization or bucket
+	"400 Bad Request",              // 2.x: unable to parse
+	"413 Request Entity Too Large", // 2.x: batch above the limit of the server
+}
+
+// isPermanentError returns true if the error is caused by a misconfiguration
+func isPermanentError(err error) bool {
+	msg := err.Error()
+	for _, s := range permanentErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// countWriteError counts a failed write and returns whether it is a permanent error
+func (conn *Connection) countWriteError(err error) bool {
+	if isPermanentError(err) {
+		atomic.AddUint64(&conn.writeErrors.Permanent, 1)
+		return true
+	}
+	atomic.AddUint64(&conn.writeErrors.Transient, 1)
+	return false
+}
+
+// WriteErrors returns a snapshot of the counters of failed writes
+func (conn *Connection) WriteErrors() WriteCounters {
+	return WriteCounters{
+		Permanent: atomic.LoadUint64(&conn.writeErrors.Permanent),
+		Transient: atomic.LoadUint64(&conn.writeErrors.Transient),
+	}
+}
+
+// Counters returns the counters of failed writes as metrics
+func (conn *Connection) Counters() []runtime.Counter {
+	errors := conn.WriteErrors()
+	return []runtime.Counter{
+		{Name: "influxdb_write_errors_permanent", Help: "Failed writes of batches to InfluxDB caused by a misconfiguration", Value: errors.Permanent},
+		{Name: "influxdb_write_errors_transient", Help: "Failed writes of batches to InfluxDB, e.g. by timeouts", Value: errors.Transient},
+		{Name: "influxdb_points_deduplicated", Help: "Points merged into an earlier point of their batch (batch_dedup)", Value: atomic.LoadUint64(&conn.deduplicated)},
+		{Name: "influxdb_points_dropped", Help: "Points of failed writes dropped from the full buffer (buffer_size)", Value: atomic.LoadUint64(&conn.dropped)},
+		{Name: "influxdb_writes", Help: "Writes of batches to InfluxDB", Value: atomic.LoadUint64(&conn.writes)},
+		{Name: "influxdb_write_milliseconds", Help: "Duration of all writes of batches to InfluxDB in milliseconds", Value: atomic.LoadUint64(&conn.writeTime)},
+	}
+}
+
+// writeResult is the result of a write, as an atomic.Value could not store a nil error
+type writeResult struct {
+	err error
+}
+
+// Health returns the error of the last write of a batch
+func (conn *Connection) Health() error {
+	result, ok := conn.health.Load().(writeResult)
+	if !ok || result.err == nil {
+		return nil
+	}
+	return fmt.Errorf("last write to influxdb failed: %s", result.err)
+}
+
+// query runs a query and returns the first error of the response
+func query(c client.Client, command string) (*client.Response, error) {
+	response, err := c.Query(client.NewQuery(command, "", ""))
+	if err != nil {
+		return nil, err
+	}
+	if err = response.Error(); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// isAuthorizationError returns true if the user is not allowed to run a query, e.g. SHOW DATABASES without admin privilege
+func isAuthorizationError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "not authorized") || strings.Contains(msg, "authorization failed") || strings.Contains(msg, "requires admin privilege")
+}
+
+// checkDatabase ensures that the configured database exists, it is created if enabled by config.
+// Users without admin privilege could not list (all) databases, so a database which is not listed
+// is checked by a write without points, which only fails if the database does not exist.
+func checkDatabase(c client.Client, config Config) error {
+	name := config.Database()
+
+	response, err := query(c, "SHOW DATABASES")
+	if err != nil {
+		if !isAuthorizationError(err) {
+			return fmt.Errorf("unable to list databases: %s", err)
+		}
+		log.WithField("database", name).Warnf("unable to list databases, check the database by a write: %s", err)
+	} else {
+		for _, result := range response.Results {
+			for _, serie := range result.Series {
+				for _, value := range serie.Values {
+					if len(value) > 0 && value[0] == name {
+						return nil
+					}
+				}
+			}
+		}
+	}
+
+	if config.CreateDatabase() {
+		if _, err = query(c, fmt.Sprintf("CREATE DATABASE %q", name)); err != nil {
+			return fmt.Errorf("unable to create database %q: %s", name, err)
+		}
+		return nil
+	}
+
+	bp, err := client.NewBatchPoints(client.BatchPointsConfig{Database: name})
+	if err != nil {
+		return err
+	}
+	if err = c.Write(bp); err != nil {
+		if strings.Contains(err.Error(), "database not found") {
+			return fmt.Errorf("database %q does not exist, create it (CREATE DATABASE %q) or set create_database = true", name, name)
+		}
+		return fmt.Errorf("unable to write to database %q: %s", name, err)
+	}
+	return nil
+}
+
+// checkRetentionPolicy ensures that the configured retention policy exists,
+// it is created or updated if a retention duration is configured
+func checkRetentionPolicy(c client.Client, config Config) error {
+	name := config.RetentionPolicy()
+	if name == "" {
+		return nil
+	}
+	retention, err := config.RetentionDuration()
+	if err != nil {
+		return err
+	}
+	db := config.Database()
+
+	response, err := query(c, fmt.Sprintf("SHOW RETENTION POLICIES ON %q", db))
+	if err != nil {
+		return fmt.Errorf("unable to list retention policies: %s", err)
+	}
+	exists := false
+	for _, result := range response.Results {
+		for _, serie := range result.Series {
+			for _, value := range serie.Values {
+				if len(value) > 0 && value[0] == name {
+					exists = true
+				}
+			}
+		}
+	}
+
+	if retention == 0 {
+		if !exists {
+			return fmt.Errorf("retention policy %q does not exist on database %q, create it or set retention_duration", name, db)
+		}
+		return nil
+	}
+
+	command := fmt.Sprintf("CREATE RETENTION POLICY %q ON %q DURATION %ds REPLICATION 1", name, db, retention/time.Second)
+	if exists {
+		command = fmt.Sprintf("ALTER RETENTION POLICY %q ON %q DURATION %ds", name, db, retention/time.Second)
+	}
+	if _, err = query(c, command); err != nil {
+		return fmt.Errorf("unable to set retention policy %q: %s", name, err)
+	}
+	return nil
+}
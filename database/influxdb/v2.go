@@ -0,0 +1,198 @@
+package influxdb
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb1-client/v2"
+)
+
+// v2Timeout is the timeout of the requests to the InfluxDB 2.x API
+const v2Timeout = 30 * time.Second
+
+var errV2Query = errors.New("InfluxQL queries are not supported by the InfluxDB 2.x API")
+
+// v2Client writes to the InfluxDB 2.x API (token, organization and bucket) in place of the 1.x client
+type v2Client struct {
+	address      string
+	token        string
+	organization string
+	bucket       string
+	http         *http.Client
+}
+
+func newV2Client(config Config) (*v2Client, error) {
+	address := strings.TrimSuffix(config.Address(), "/")
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported protocol scheme: %s", u.Scheme)
+	}
+	if config.Organization() == "" || config.Bucket() == "" {
+		return nil, errors.New("organization and bucket are required by the InfluxDB 2.x API")
+	}
+	return &v2Client{
+		address:      address,
+		token:        config.Token(),
+		organization: config.Organization(),
+		bucket:       config.Bucket(),
+		http: &http.Client{
+			Timeout: v2Timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify()},
+			},
+		},
+	}, nil
+}
+
+// do sends a request to the API and returns the body of a successful response
+func (c *v2Client) do(method, path string, query url.Values, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, c.address+path+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Token "+c.token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 != 2 {
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiError) == nil && apiError.Message != "" {
+			return nil, fmt.Errorf("%s: %s", res.Status, apiError.Message)
+		}
+		return nil, fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// Ping checks the availability of the server
+func (c *v2Client) Ping(timeout time.Duration) (time.Duration, string, error) {
+	start := time.Now()
+	req, err := http.NewRequest(http.MethodGet, c.address+"/ping", nil)
+	if err != nil {
+		return 0, "", err
+	}
+	client := *c.http
+	client.Timeout = timeout
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		return 0, "", fmt.Errorf("ping: %s", res.Status)
+	}
+	return time.Since(start), res.Header.Get("X-Influxdb-Version"), nil
+}
+
+// Write sends the points into the bucket, the database and retention policy of the batch are ignored
+func (c *v2Client) Write(bp client.BatchPoints) error {
+	// the API supports no precision of minutes, so these points are truncated and sent in seconds
+	precision := bp.Precision()
+	truncate := time.Duration(0)
+	if precision == "m" {
+		precision = "s"
+		truncate = time.Minute
+	}
+
+	var body bytes.Buffer
+	for _, point := range bp.Points() {
+		if truncate > 0 {
+			fields, err := point.Fields()
+			if err != nil {
+				return err
+			}
+			if point, err = client.NewPoint(point.Name(), point.Tags(), fields, point.Time().Truncate(truncate)); err != nil {
+				return err
+			}
+		}
+		body.WriteString(point.PrecisionString(precision))
+		body.WriteByte('\n')
+	}
+
+	_, err := c.do(http.MethodPost, "/api/v2/write", url.Values{
+		"org":       {c.organization},
+		"bucket":    {c.bucket},
+		"precision": {precision},
+	}, "text/plain; charset=utf-8", body.Bytes())
+	return err
+}
+
+// checkBucket ensures that the configured bucket exists in the organization
+func (c *v2Client) checkBucket() error {
+	data, err := c.do(http.MethodGet, "/api/v2/buckets", url.Values{
+		"org":  {c.organization},
+		"name": {c.bucket},
+	}, "", nil)
+	if err != nil {
+		return fmt.Errorf("unable to list buckets: %s", err)
+	}
+	var response struct {
+		Buckets []struct {
+			Name string `json:"name"`
+		} `json:"buckets"`
+	}
+	if err = json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("unable to list buckets: %s", err)
+	}
+	for _, bucket := range response.Buckets {
+		if bucket.Name == c.bucket {
+			return nil
+		}
+	}
+	return fmt.Errorf("bucket %q does not exist in organization %q, create it (influx bucket create)", c.bucket, c.organization)
+}
+
+// delete removes the points of the measurement older than the given time
+func (c *v2Client) delete(measurement string, before time.Time) error {
+	body, err := json.Marshal(map[string]string{
+		"start":     time.Unix(0, 0).UTC().Format(time.RFC3339),
+		"stop":      before.UTC().Format(time.RFC3339),
+		"predicate": fmt.Sprintf("_measurement=%q", measurement),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.do(http.MethodPost, "/api/v2/delete", url.Values{
+		"org":    {c.organization},
+		"bucket": {c.bucket},
+	}, "application/json", body)
+	return err
+}
+
+func (c *v2Client) Query(q client.Query) (*client.Response, error) {
+	return nil, errV2Query
+}
+
+func (c *v2Client) QueryAsChunk(q client.Query) (*client.ChunkedResponse, error) {
+	return nil, errV2Query
+}
+
+func (c *v2Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
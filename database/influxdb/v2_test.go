@@ -0,0 +1,117 @@
+package influxdb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb1-client/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// testServerV2 simulates an InfluxDB 2.x with a bucket "ffhb" of the organization "ffhb" and records the requests
+func testServerV2() (*httptest.Server, *[]string) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"unauthorized","message":"unauthorized access"}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.String()+" "+string(body))
+		if r.FormValue("org") != "ffhb" || (r.FormValue("bucket") != "" && r.FormValue("bucket") != "ffhb") {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"not found","message":"bucket not found"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v2/buckets":
+			buckets := []map[string]string{}
+			if r.FormValue("name") == "ffhb" {
+				buckets = append(buckets, map[string]string{"name": "ffhb"})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"buckets": buckets})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	return srv, &requests
+}
+
+func TestConnectV2(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, requests := testServerV2()
+	defer srv.Close()
+
+	config := map[string]interface{}{
+		"address":      srv.URL,
+		"version":      int64(2),
+		"token":        "secret",
+		"organization": "ffhb",
+		"bucket":       "ffhb",
+	}
+	conn, err := Connect(config)
+	assert.NoError(err)
+	assert.NotNil(conn)
+	assert.Equal([]string{"GET /api/v2/buckets?name=ffhb&org=ffhb "}, *requests)
+	conn.Close()
+
+	for key, value := range map[string]interface{}{
+		"bucket":          "missing",
+		"organization":    "",
+		"token":           "invalid",
+		"version":         int64(3),
+		"create_database": true,
+	} {
+		invalid := map[string]interface{}{}
+		for k, v := range config {
+			invalid[k] = v
+		}
+		invalid[key] = value
+		conn, err = Connect(invalid)
+		assert.Nil(conn, key)
+		assert.Error(err, key)
+	}
+}
+
+func TestWriteV2(t *testing.T) {
+	assert := assert.New(t)
+
+	srv, requests := testServerV2()
+	defer srv.Close()
+
+	c, err := newV2Client(Config{"address": srv.URL, "token": "secret", "organization": "ffhb", "bucket": "ffhb"})
+	assert.NoError(err)
+	conn := &Connection{config: Config{}, client: c}
+
+	point, err := client.NewPoint(MeasurementNode, map[string]string{"nodeid": "a"}, map[string]interface{}{"clients.total": 23}, time.Unix(1500000042, 0))
+	assert.NoError(err)
+	assert.NoError(conn.write([]*client.Point{point}))
+	// truncated to the minute of the batch precision
+	assert.Equal([]string{"POST /api/v2/write?bucket=ffhb&org=ffhb&precision=s node,nodeid=a clients.total=23i 1500000000\n"}, *requests)
+
+	*requests = nil
+	conn.PruneNodes(time.Hour)
+	assert.Len(*requests, 3)
+	assert.Contains((*requests)[0], "POST /api/v2/delete?bucket=ffhb&org=ffhb ")
+	assert.Contains((*requests)[0], `"predicate":"_measurement=\"node\""`)
+
+	// invalid token is a permanent error
+	c.token = "invalid"
+	err = conn.write([]*client.Point{point})
+	assert.Error(err)
+	assert.Contains(err.Error(), "unauthorized access")
+	assert.True(conn.countWriteError(err))
+
+	_, err = c.Query(client.NewQuery("SHOW DATABASES", "", ""))
+	assert.Error(err)
+}
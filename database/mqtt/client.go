@@ -0,0 +1,158 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+)
+
+// packet types of MQTT 3.1.1 in the upper bits of the fixed header
+const (
+	packetConnect    = 1 << 4
+	packetConnack    = 2 << 4
+	packetPublish    = 3 << 4
+	packetPingreq    = 12 << 4
+	packetDisconnect = 14 << 4
+)
+
+// return codes of a CONNACK
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// client is a minimal MQTT 3.1.1 client, which publishes messages with QoS 0
+type client struct {
+	conn  net.Conn
+	write sync.Mutex
+	done  chan struct{} // closed if the connection is lost
+}
+
+// packet returns a packet with the fixed header of the given type and flags
+func packet(header byte, body []byte) []byte {
+	result := []byte{header}
+	// remaining length, 7 bits per byte with a continuation bit
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		result = append(result, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(result, body...)
+}
+
+// appendString appends a string with its length prefix
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+// dial connects to the broker and sends the CONNECT
+func dial(address string, useTLS bool, tlsConfig *tls.Config, clientID, username, password string, keepalive time.Duration) (*client, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// protocol name, level 4 (3.1.1), flags and keepalive in seconds
+	body := appendString(nil, "MQTT")
+	flags := byte(0x02) // clean session
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+	body = append(body, 4, flags)
+	body = append(body, byte(uint16(keepalive/time.Second)>>8), byte(uint16(keepalive/time.Second)))
+	body = appendString(body, clientID)
+	if username != "" {
+		body = appendString(body, username)
+		if password != "" {
+			body = appendString(body, password)
+		}
+	}
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err = conn.Write(packet(packetConnect, body)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	connack := make([]byte, 4)
+	if _, err = io.ReadFull(conn, connack); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("no CONNACK of the broker: %s", err)
+	}
+	if connack[0] != packetConnack || connack[1] != 2 {
+		conn.Close()
+		return nil, errors.New("invalid CONNACK of the broker")
+	}
+	if code := connack[3]; code != 0 {
+		conn.Close()
+		if msg, ok := connackErrors[code]; ok {
+			return nil, fmt.Errorf("connection refused: %s", msg)
+		}
+		return nil, fmt.Errorf("connection refused: code %d", code)
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &client{conn: conn, done: make(chan struct{})}
+	// the broker only sends PINGRESP for QoS 0, they are not checked
+	go func() {
+		io.Copy(ioutil.Discard, conn)
+		close(c.done)
+	}()
+	return c, nil
+}
+
+// send writes a packet within the timeout
+func (c *client) send(p []byte, timeout time.Duration) error {
+	c.write.Lock()
+	defer c.write.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := c.conn.Write(p)
+	return err
+}
+
+// publish sends a message with QoS 0
+func (c *client) publish(topic string, payload []byte, retain bool, timeout time.Duration) error {
+	header := byte(packetPublish)
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(make([]byte, 0, 2+len(topic)+len(payload)), topic)
+	return c.send(packet(header, append(body, payload...)), timeout)
+}
+
+// ping sends a PINGREQ to keep the connection
+func (c *client) ping(timeout time.Duration) error {
+	return c.send([]byte{packetPingreq, 0}, timeout)
+}
+
+// close sends a DISCONNECT and closes the connection
+func (c *client) close() {
+	c.send([]byte{packetDisconnect, 0}, time.Second)
+	c.conn.Close()
+	<-c.done
+}
@@ -0,0 +1,247 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/database"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	keepalive      = time.Minute
+	writeTimeout   = 10 * time.Second
+	reconnectDelay = 10 * time.Second // shortest delay between two connection attempts
+	queueSize      = 1000
+)
+
+// message is published to a topic of the broker
+type message struct {
+	topic   string
+	payload []byte
+}
+
+type Connection struct {
+	// accessed atomically, keep 64-bit aligned at the beginning of the struct
+	dropped uint64 // messages lost while the broker was unreachable
+
+	database.Connection
+	config   Config
+	client   *client
+	messages chan message
+	wg       sync.WaitGroup
+}
+
+type Config map[string]interface{}
+
+func (c Config) Address() string {
+	if d, ok := c["address"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) TLS() bool {
+	if d, ok := c["tls"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) InsecureSkipVerify() bool {
+	if d, ok := c["insecure_skip_verify"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) ClientID() string {
+	if d, ok := c["client_id"]; ok {
+		return d.(string)
+	}
+	return "yanic"
+}
+func (c Config) Username() string {
+	if d, ok := c["username"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Password() string {
+	if d, ok := c["password"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) TopicPrefix() string {
+	if d, ok := c["topic_prefix"]; ok {
+		return strings.TrimSuffix(d.(string), "/")
+	}
+	return "yanic"
+}
+func (c Config) Retain() bool {
+	if d, ok := c["retain"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+
+func init() {
+	database.RegisterAdapter("mqtt", Connect)
+}
+
+func Connect(configuration map[string]interface{}) (database.Connection, error) {
+	var config Config
+	config = configuration
+
+	if config.Address() == "" {
+		return nil, errors.New("no address given")
+	}
+
+	conn := &Connection{
+		config:   config,
+		messages: make(chan message, queueSize),
+	}
+	var err error
+	if conn.client, err = conn.dial(); err != nil {
+		return nil, err
+	}
+
+	conn.wg.Add(1)
+	go conn.publishWorker()
+
+	return conn, nil
+}
+
+func (conn *Connection) dial() (*client, error) {
+	return dial(conn.config.Address(), conn.config.TLS(), &tls.Config{InsecureSkipVerify: conn.config.InsecureSkipVerify()},
+		conn.config.ClientID(), conn.config.Username(), conn.config.Password(), keepalive)
+}
+
+// topic returns the topic below the prefix
+func (conn *Connection) topic(parts ...string) string {
+	return conn.config.TopicPrefix() + "/" + strings.Join(parts, "/")
+}
+
+// add queues a message with the JSON of the value, sections without data are skipped
+func (conn *Connection) add(topic string, value interface{}) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		log.WithField("topic", topic).Errorf("unable to encode message: %s", err)
+		return
+	}
+	conn.messages <- message{topic: topic, payload: payload}
+}
+
+// InsertNode publishes the sections of a node under <topic_prefix>/nodes/<nodeid>/<section>
+func (conn *Connection) InsertNode(node *runtime.Node) {
+	var nodeID string
+	if node.Nodeinfo != nil {
+		nodeID = node.Nodeinfo.NodeID
+	} else if node.Statistics != nil {
+		nodeID = node.Statistics.NodeID
+	}
+	if nodeID == "" {
+		return
+	}
+	if node.Nodeinfo != nil {
+		conn.add(conn.topic("nodes", nodeID, "nodeinfo"), node.Nodeinfo)
+	}
+	if node.Statistics != nil {
+		conn.add(conn.topic("nodes", nodeID, "statistics"), node.Statistics)
+	}
+	if node.Neighbours != nil {
+		conn.add(conn.topic("nodes", nodeID, "neighbours"), node.Neighbours)
+	}
+}
+
+// InsertLink is not published, the links are part of the neighbours of the nodes
+func (conn *Connection) InsertLink(link *runtime.Link, t time.Time) {
+}
+
+// InsertGlobals publishes the global statistics under <topic_prefix>/globals/<site>/<domain>
+func (conn *Connection) InsertGlobals(stats *runtime.GlobalStats, t time.Time, site string, domain string) {
+	conn.add(conn.topic("globals", site, domain), stats)
+}
+
+// PruneNodes is not supported by a broker
+func (conn *Connection) PruneNodes(deleteAfter time.Duration) {
+}
+
+// Close publishes the pending messages and disconnects
+func (conn *Connection) Close() {
+	close(conn.messages)
+	conn.wg.Wait()
+}
+
+// Counters returns the counter of lost messages as metric
+func (conn *Connection) Counters() []runtime.Counter {
+	return []runtime.Counter{
+		{Name: "mqtt_messages_dropped", Help: "Messages not published to MQTT, while the broker was unreachable", Value: atomic.LoadUint64(&conn.dropped)},
+	}
+}
+
+// publishes the messages, keeps the connection by pings and reconnects after a lost connection
+func (conn *Connection) publishWorker() {
+	defer conn.wg.Done()
+
+	ping := time.NewTicker(keepalive / 2)
+	defer ping.Stop()
+	var lastDial time.Time
+
+	for {
+		select {
+		case msg, ok := <-conn.messages:
+			if !ok {
+				if conn.client != nil {
+					conn.client.close()
+				}
+				return
+			}
+			if conn.client == nil && time.Since(lastDial) >= reconnectDelay {
+				lastDial = time.Now()
+				client, err := conn.dial()
+				if err != nil {
+					log.WithField("database", "mqtt").Errorf("unable to reconnect: %s", err)
+				} else {
+					log.WithField("database", "mqtt").Info("reconnected")
+					conn.client = client
+				}
+			}
+			if conn.client == nil {
+				atomic.AddUint64(&conn.dropped, 1)
+				continue
+			}
+			if err := conn.client.publish(msg.topic, msg.payload, conn.config.Retain(), writeTimeout); err != nil {
+				atomic.AddUint64(&conn.dropped, 1)
+				conn.lost(err)
+			}
+		case <-ping.C:
+			if conn.client != nil {
+				if err := conn.client.ping(writeTimeout); err != nil {
+					conn.lost(err)
+				}
+			}
+		}
+
+		if conn.client != nil {
+			select {
+			case <-conn.client.done:
+				conn.lost(errors.New("closed by the broker"))
+			default:
+			}
+		}
+	}
+}
+
+// lost closes a lost connection, it is reconnected by the next message
+func (conn *Connection) lost(err error) {
+	log.WithField("database", "mqtt").Errorf("lost connection: %s", err)
+	conn.client.conn.Close()
+	<-conn.client.done
+	conn.client = nil
+}
@@ -0,0 +1,112 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+type testPacket struct {
+	header byte
+	body   []byte
+}
+
+// testBroker accepts a single connection, answers the CONNECT with the return code and passes the received packets
+func testBroker(t *testing.T, code byte) (string, chan testPacket) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	packets := make(chan testPacket, 100)
+	go func() {
+		defer close(packets)
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			header, err := r.ReadByte()
+			if err != nil {
+				return
+			}
+			// the remaining length is encoded like a varint
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return
+			}
+			body := make([]byte, length)
+			if _, err = io.ReadFull(r, body); err != nil {
+				return
+			}
+			if header == packetConnect {
+				conn.Write([]byte{packetConnack, 2, 0, code})
+			}
+			packets <- testPacket{header: header, body: body}
+		}
+	}()
+	return ln.Addr().String(), packets
+}
+
+func TestConnect(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := Connect(map[string]interface{}{})
+	assert.Nil(conn)
+	assert.Error(err)
+
+	address, packets := testBroker(t, 4)
+	conn, err = Connect(map[string]interface{}{"address": address, "username": "yanic", "password": "wrong"})
+	assert.Nil(conn)
+	assert.EqualError(err, "connection refused: bad user name or password")
+
+	connect := <-packets
+	assert.Equal(byte(packetConnect), connect.header)
+	// protocol name and level, flags of username, password and clean session, keepalive
+	assert.Equal([]byte{0, 4, 'M', 'Q', 'T', 'T', 4, 0xc2, 0, 60}, connect.body[:10])
+}
+
+func TestPublish(t *testing.T) {
+	assert := assert.New(t)
+
+	address, packets := testBroker(t, 0)
+	c, err := Connect(map[string]interface{}{"address": address, "topic_prefix": "ffhb/", "retain": true})
+	assert.NoError(err)
+	assert.Equal(byte(packetConnect), (<-packets).header)
+
+	conn := c.(*Connection)
+	conn.InsertNode(&runtime.Node{
+		Statistics: &data.Statistics{NodeID: "a", Clients: data.Clients{Total: 23}},
+	})
+	conn.InsertNode(&runtime.Node{})
+	conn.InsertGlobals(&runtime.GlobalStats{Nodes: 2}, time.Now(), runtime.GLOBAL_SITE, runtime.GLOBAL_DOMAIN)
+	conn.Close()
+
+	topic := func(p testPacket) (string, string) {
+		length := int(p.body[0])<<8 | int(p.body[1])
+		return string(p.body[2 : 2+length]), string(p.body[2+length:])
+	}
+
+	p := <-packets
+	assert.Equal(byte(packetPublish|0x01), p.header)
+	name, payload := topic(p)
+	assert.Equal("ffhb/nodes/a/statistics", name)
+	assert.Contains(payload, `"total":23`)
+
+	name, payload = topic(<-packets)
+	assert.Equal("ffhb/globals/"+runtime.GLOBAL_SITE+"/"+runtime.GLOBAL_DOMAIN, name)
+	assert.Contains(payload, `"nodes":2`)
+
+	assert.Equal(byte(packetDisconnect), (<-packets).header)
+	assert.EqualValues(0, conn.Counters()[0].Value)
+}
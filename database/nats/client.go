@@ -0,0 +1,133 @@
+package nats
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// client is a minimal client of the NATS protocol, which only publishes
+type client struct {
+	conn  net.Conn
+	write sync.Mutex
+	done  chan struct{} // closed if the connection is lost
+}
+
+// connectOptions are sent by the CONNECT of the protocol
+type connectOptions struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	Protocol  int    `json:"protocol"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// dial connects to the server, sends the CONNECT and waits for the PONG of a PING, which confirms the connection
+func dial(address string, tlsConfig *tls.Config, options connectOptions) (*client, error) {
+	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("no INFO of the server: %s", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("invalid INFO of the server: %q", strings.TrimSpace(line))
+	}
+	if tlsConfig != nil {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+		r = bufio.NewReader(conn)
+	}
+
+	connect, err := json.Marshal(options)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err = fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	for {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("no PONG of the server: %s", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, errors.New(strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+		}
+		// e.g. +OK or a further INFO
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &client{conn: conn, done: make(chan struct{})}
+	go c.read(r)
+	return c, nil
+}
+
+// read answers the PINGs of the server until the connection is closed
+func (c *client) read(r *bufio.Reader) {
+	defer close(c.done)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if strings.TrimSpace(line) == "PING" {
+			if c.send([]byte("PONG\r\n"), 10*time.Second) != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes the data within the timeout
+func (c *client) send(p []byte, timeout time.Duration) error {
+	c.write.Lock()
+	defer c.write.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := c.conn.Write(p)
+	return err
+}
+
+// publish sends the payload to the subject
+func (c *client) publish(subject string, payload []byte, timeout time.Duration) error {
+	msg := make([]byte, 0, len(subject)+len(payload)+32)
+	msg = append(msg, fmt.Sprintf("PUB %s %d\r\n", subject, len(payload))...)
+	msg = append(msg, payload...)
+	msg = append(msg, '\r', '\n')
+	return c.send(msg, timeout)
+}
+
+// close closes the connection
+func (c *client) close() {
+	c.conn.Close()
+	<-c.done
+}
@@ -0,0 +1,252 @@
+package nats
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/database"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	PayloadNode     = "node"     // the updated node with its state (e.g. online, lastseen)
+	PayloadResponse = "response" // the parsed sections of the response
+
+	writeTimeout   = 10 * time.Second
+	reconnectDelay = 10 * time.Second // shortest delay between two connection attempts
+	queueSize      = 1000
+)
+
+// message is published to a subject
+type message struct {
+	subject string
+	payload []byte
+}
+
+type Connection struct {
+	// accessed atomically, keep 64-bit aligned at the beginning of the struct
+	dropped uint64 // messages lost while the server was unreachable
+
+	database.Connection
+	config   Config
+	client   *client
+	messages chan message
+	wg       sync.WaitGroup
+}
+
+type Config map[string]interface{}
+
+func (c Config) Address() string {
+	if d, ok := c["address"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) TLS() bool {
+	if d, ok := c["tls"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) InsecureSkipVerify() bool {
+	if d, ok := c["insecure_skip_verify"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) Username() string {
+	if d, ok := c["username"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Password() string {
+	if d, ok := c["password"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Token() string {
+	if d, ok := c["token"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) SubjectPrefix() string {
+	if d, ok := c["subject_prefix"]; ok {
+		return strings.TrimSuffix(d.(string), ".")
+	}
+	return "yanic"
+}
+func (c Config) Payload() string {
+	if d, ok := c["payload"]; ok {
+		return d.(string)
+	}
+	return PayloadNode
+}
+
+func init() {
+	database.RegisterAdapter("nats", Connect)
+}
+
+func Connect(configuration map[string]interface{}) (database.Connection, error) {
+	var config Config
+	config = configuration
+
+	if config.Address() == "" {
+		return nil, errors.New("no address given")
+	}
+	if payload := config.Payload(); payload != PayloadNode && payload != PayloadResponse {
+		return nil, fmt.Errorf("unknown payload: %s", payload)
+	}
+
+	conn := &Connection{
+		config:   config,
+		messages: make(chan message, queueSize),
+	}
+	var err error
+	if conn.client, err = conn.dial(); err != nil {
+		return nil, err
+	}
+
+	conn.wg.Add(1)
+	go conn.publishWorker()
+
+	return conn, nil
+}
+
+func (conn *Connection) dial() (*client, error) {
+	var tlsConfig *tls.Config
+	if conn.config.TLS() {
+		host := conn.config.Address()
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		tlsConfig = &tls.Config{ServerName: host, InsecureSkipVerify: conn.config.InsecureSkipVerify()}
+	}
+	return dial(conn.config.Address(), tlsConfig, connectOptions{
+		Name:      "yanic",
+		Lang:      "go",
+		Version:   "1",
+		Protocol:  1,
+		User:      conn.config.Username(),
+		Pass:      conn.config.Password(),
+		AuthToken: conn.config.Token(),
+	})
+}
+
+// subject returns the subject below the prefix
+func (conn *Connection) subject(tokens ...string) string {
+	return conn.config.SubjectPrefix() + "." + strings.Join(tokens, ".")
+}
+
+// add queues a message with the JSON of the value
+func (conn *Connection) add(subject string, value interface{}) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		log.WithField("subject", subject).Errorf("unable to encode message: %s", err)
+		return
+	}
+	conn.messages <- message{subject: subject, payload: payload}
+}
+
+// InsertNode publishes the node (or its response) under <subject_prefix>.nodes.<nodeid>
+func (conn *Connection) InsertNode(node *runtime.Node) {
+	var nodeID string
+	if node.Nodeinfo != nil {
+		nodeID = node.Nodeinfo.NodeID
+	} else if node.Statistics != nil {
+		nodeID = node.Statistics.NodeID
+	}
+	if nodeID == "" {
+		return
+	}
+	if conn.config.Payload() == PayloadResponse {
+		conn.add(conn.subject("nodes", nodeID), &data.ResponseData{
+			Nodeinfo:   node.Nodeinfo,
+			Statistics: node.Statistics,
+			Neighbours: node.Neighbours,
+		})
+		return
+	}
+	conn.add(conn.subject("nodes", nodeID), node)
+}
+
+// InsertLink is not published, the links are part of the neighbours of the nodes
+func (conn *Connection) InsertLink(link *runtime.Link, t time.Time) {
+}
+
+// InsertGlobals publishes the global statistics under <subject_prefix>.globals.<site>.<domain>
+func (conn *Connection) InsertGlobals(stats *runtime.GlobalStats, t time.Time, site string, domain string) {
+	conn.add(conn.subject("globals", site, domain), stats)
+}
+
+// PruneNodes is not supported by a stream
+func (conn *Connection) PruneNodes(deleteAfter time.Duration) {
+}
+
+// Close publishes the pending messages and disconnects
+func (conn *Connection) Close() {
+	close(conn.messages)
+	conn.wg.Wait()
+}
+
+// Counters returns the counter of lost messages as metric
+func (conn *Connection) Counters() []runtime.Counter {
+	return []runtime.Counter{
+		{Name: "nats_messages_dropped", Help: "Messages not published to NATS, while the server was unreachable", Value: atomic.LoadUint64(&conn.dropped)},
+	}
+}
+
+// publishes the messages and reconnects after a lost connection
+func (conn *Connection) publishWorker() {
+	defer conn.wg.Done()
+	var lastDial time.Time
+
+	for msg := range conn.messages {
+		if conn.client != nil {
+			select {
+			case <-conn.client.done:
+				conn.lost(errors.New("closed by the server"))
+			default:
+			}
+		}
+		if conn.client == nil && time.Since(lastDial) >= reconnectDelay {
+			lastDial = time.Now()
+			client, err := conn.dial()
+			if err != nil {
+				log.WithField("database", "nats").Errorf("unable to reconnect: %s", err)
+			} else {
+				log.WithField("database", "nats").Info("reconnected")
+				conn.client = client
+			}
+		}
+		if conn.client == nil {
+			atomic.AddUint64(&conn.dropped, 1)
+			continue
+		}
+		if err := conn.client.publish(msg.subject, msg.payload, writeTimeout); err != nil {
+			atomic.AddUint64(&conn.dropped, 1)
+			conn.lost(err)
+		}
+	}
+	if conn.client != nil {
+		conn.client.close()
+	}
+}
+
+// lost closes a lost connection, it is reconnected by the next message
+func (conn *Connection) lost(err error) {
+	log.WithField("database", "nats").Errorf("lost connection: %s", err)
+	conn.client.close()
+	conn.client = nil
+}
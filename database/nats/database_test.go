@@ -0,0 +1,108 @@
+package nats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// testServer accepts a single connection and passes the received lines and payloads,
+// a CONNECT with the token "invalid" is refused
+func testServer(t *testing.T) (string, chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "CONNECT") && strings.Contains(line, `"auth_token":"invalid"`):
+				fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			case line == "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+				// the client has to answer the pings of the server
+				fmt.Fprint(conn, "PING\r\n")
+			case strings.HasPrefix(line, "PUB "):
+				var subject string
+				var length int
+				fmt.Sscanf(line, "PUB %s %d", &subject, &length)
+				payload := make([]byte, length+2)
+				if _, err = io.ReadFull(r, payload); err != nil {
+					return
+				}
+				line += " " + string(payload[:length])
+			}
+			lines <- line
+		}
+	}()
+	return ln.Addr().String(), lines
+}
+
+func TestConnect(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := Connect(map[string]interface{}{})
+	assert.Nil(conn)
+	assert.Error(err)
+
+	conn, err = Connect(map[string]interface{}{"address": "127.0.0.1:4222", "payload": "blub"})
+	assert.Nil(conn)
+	assert.Error(err)
+
+	address, _ := testServer(t)
+	conn, err = Connect(map[string]interface{}{"address": address, "token": "invalid"})
+	assert.Nil(conn)
+	assert.EqualError(err, "Authorization Violation")
+}
+
+func TestPublish(t *testing.T) {
+	assert := assert.New(t)
+
+	address, lines := testServer(t)
+	c, err := Connect(map[string]interface{}{"address": address, "subject_prefix": "ffhb.", "payload": PayloadResponse})
+	assert.NoError(err)
+	assert.Contains(<-lines, `"name":"yanic"`)
+	assert.Equal("PING", <-lines)
+	assert.Equal("PONG", <-lines)
+
+	conn := c.(*Connection)
+	conn.InsertNode(&runtime.Node{
+		Online:     true,
+		Statistics: &data.Statistics{NodeID: "a", Clients: data.Clients{Total: 23}},
+	})
+	conn.InsertGlobals(&runtime.GlobalStats{Nodes: 2}, time.Now(), "ffhb", "city")
+
+	line := <-lines
+	assert.True(strings.HasPrefix(line, "PUB ffhb.nodes.a "), line)
+	assert.Contains(line, `"statistics":{"node_id":"a"`)
+	assert.NotContains(line, "online")
+	assert.Contains(<-lines, `PUB ffhb.globals.ffhb.city`)
+
+	conn.Close()
+	assert.EqualValues(0, conn.Counters()[0].Value)
+}
@@ -0,0 +1,328 @@
+package postgres
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/database"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	batchMaxSize   = 1000             // rows per write
+	batchTimeout   = 5 * time.Second  // longest delay of a row
+	connectTimeout = 10 * time.Second // timeout of the connection and authentication
+	queryTimeout   = time.Minute
+)
+
+// row is a row of a table, the values are in SQL syntax
+type row struct {
+	table  string
+	key    string // rows of the same table and key are replaced in a batch (upserts), empty to keep all
+	values string
+}
+
+// tables with their columns, nodes are updated by their nodeid
+var tables = map[string]string{
+	"nodes":           "nodeid, hostname, site, domain, model, firmware, autoupdater, latitude, longitude, nodeinfo, lastseen",
+	"node_statistics": "time, nodeid, clients, clients_wifi24, clients_wifi5, clients_owe24, clients_owe5, load, uptime, memory_usage, rootfs_usage, traffic_rx, traffic_tx, traffic_rx_rate, traffic_tx_rate, latency",
+	"links":           "time, source_id, source_addr, target_id, target_addr, protocol, tq",
+	"globals":         "time, site, domain, nodes, gateways, clients, clients_wifi24, clients_wifi5, clients_owe24, clients_owe5, reboots",
+}
+
+// upserts are the conflicts of the tables, which update the existing row
+var upserts = map[string]string{
+	"nodes": "nodeid",
+}
+
+type Connection struct {
+	// accessed atomically, keep 64-bit aligned at the beginning of the struct
+	writeErrors uint64
+
+	database.Connection
+	config Config
+	client *client // nil while disconnected
+	rows   chan row
+	prune  chan time.Duration
+	wg     sync.WaitGroup
+}
+
+type Config map[string]interface{}
+
+func (c Config) Address() string {
+	if d, ok := c["address"]; ok {
+		return d.(string)
+	}
+	return "localhost:5432"
+}
+func (c Config) Database() string {
+	if d, ok := c["database"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Username() string {
+	if d, ok := c["username"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Password() string {
+	if d, ok := c["password"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) TLS() bool {
+	if d, ok := c["tls"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) InsecureSkipVerify() bool {
+	if d, ok := c["insecure_skip_verify"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) Timescale() bool {
+	if d, ok := c["timescale"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+
+func init() {
+	database.RegisterAdapter("postgres", Connect)
+}
+
+func Connect(configuration map[string]interface{}) (database.Connection, error) {
+	var config Config
+	config = configuration
+
+	if config.Database() == "" || config.Username() == "" {
+		return nil, errors.New("database and username are required")
+	}
+
+	conn := &Connection{
+		config: config,
+		rows:   make(chan row, batchMaxSize),
+		prune:  make(chan time.Duration, 1),
+	}
+	c, err := conn.dial()
+	if err != nil {
+		return nil, err
+	}
+	if err = migrate(c, config.Timescale()); err != nil {
+		c.close()
+		return nil, err
+	}
+	conn.client = c
+
+	conn.wg.Add(1)
+	go conn.addWorker()
+
+	return conn, nil
+}
+
+func (conn *Connection) dial() (*client, error) {
+	options := connectOptions{
+		address:  conn.config.Address(),
+		user:     conn.config.Username(),
+		password: conn.config.Password(),
+		database: conn.config.Database(),
+		timeout:  connectTimeout,
+	}
+	if conn.config.TLS() {
+		host, _, err := net.SplitHostPort(options.address)
+		if err != nil {
+			return nil, err
+		}
+		options.tls = &tls.Config{ServerName: host, InsecureSkipVerify: conn.config.InsecureSkipVerify()}
+	}
+	return dial(options)
+}
+
+// Close writes the pending rows and disconnects
+func (conn *Connection) Close() {
+	close(conn.rows)
+	conn.wg.Wait()
+}
+
+// Counters returns the counter of failed writes as metric
+func (conn *Connection) Counters() []runtime.Counter {
+	return []runtime.Counter{
+		{Name: "postgres_write_errors", Help: "Failed writes of batches to PostgreSQL", Value: atomic.LoadUint64(&conn.writeErrors)},
+	}
+}
+
+// PruneNodes deletes the rows of the time series older than the given duration
+func (conn *Connection) PruneNodes(deleteAfter time.Duration) {
+	select {
+	case conn.prune <- deleteAfter:
+	default:
+		// a pruning is pending already
+	}
+}
+
+// writes the rows in batches and runs the pruning, it reconnects after a lost connection
+func (conn *Connection) addWorker() {
+	defer conn.wg.Done()
+
+	var batch []row
+	timer := time.NewTimer(batchTimeout)
+	defer timer.Stop()
+
+	for {
+		closed := false
+		select {
+		case r, ok := <-conn.rows:
+			if !ok {
+				closed = true
+				break
+			}
+			if len(batch) == 0 {
+				timer.Reset(batchTimeout)
+			}
+			batch = append(batch, r)
+			if len(batch) < batchMaxSize {
+				continue
+			}
+		case <-timer.C:
+			if len(batch) == 0 {
+				timer.Reset(batchTimeout)
+				continue
+			}
+		case deleteAfter := <-conn.prune:
+			conn.run(pruneStatements(deleteAfter), "unable to prune data")
+			continue
+		}
+
+		if len(batch) > 0 {
+			if !conn.run(insertStatements(batch), "unable to save rows") {
+				atomic.AddUint64(&conn.writeErrors, 1)
+			}
+			batch = nil
+		}
+		if closed {
+			if conn.client != nil {
+				conn.client.close()
+			}
+			return
+		}
+	}
+}
+
+// run executes the statements in a single transaction and returns whether it succeeded,
+// the connection is established again after an error of the connection
+func (conn *Connection) run(statements string, msg string) bool {
+	if conn.client == nil {
+		c, err := conn.dial()
+		if err != nil {
+			log.WithField("database", "postgres").Errorf("%s, unable to reconnect: %s", msg, err)
+			return false
+		}
+		conn.client = c
+	}
+	_, err := conn.client.query(statements, queryTimeout)
+	if err == nil {
+		return true
+	}
+	log.WithField("database", "postgres").Errorf("%s: %s", msg, err)
+	if _, ok := err.(*Error); !ok {
+		conn.client.close()
+		conn.client = nil
+	}
+	return false
+}
+
+// insertStatements returns the statements to insert the rows, grouped by their table.
+// Of rows with the same key, the last one is kept.
+func insertStatements(batch []row) string {
+	byTable := make(map[string][]string)
+	keys := make(map[string]int)
+	for _, r := range batch {
+		if r.key != "" {
+			if i, ok := keys[r.table+"\x00"+r.key]; ok {
+				byTable[r.table][i] = r.values
+				continue
+			}
+			keys[r.table+"\x00"+r.key] = len(byTable[r.table])
+		}
+		byTable[r.table] = append(byTable[r.table], r.values)
+	}
+
+	names := make([]string, 0, len(byTable))
+	for table := range byTable {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, table := range names {
+		columns := tables[table]
+		fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES (%s)", table, columns, strings.Join(byTable[table], "), ("))
+		if conflict, ok := upserts[table]; ok {
+			var updates []string
+			for _, column := range strings.Split(columns, ", ") {
+				if column != conflict {
+					updates = append(updates, column+" = EXCLUDED."+column)
+				}
+			}
+			fmt.Fprintf(&b, " ON CONFLICT (%s) DO UPDATE SET %s", conflict, strings.Join(updates, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+// pruneStatements returns the statements to delete the rows of the time series older than the given duration
+func pruneStatements(deleteAfter time.Duration) string {
+	var statements []string
+	for _, table := range hypertables {
+		statements = append(statements, fmt.Sprintf("DELETE FROM %s WHERE time < now() - interval '%d seconds'", table, deleteAfter/time.Second))
+	}
+	return strings.Join(statements, ";\n")
+}
+
+// quote returns a string literal, requires standard_conforming_strings (checked on connect)
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, "\x00", ""), "'", "''") + "'"
+}
+
+// quoteOptional returns a string literal or NULL for an empty string
+func quoteOptional(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return quote(s)
+}
+
+// float returns a number literal or NULL for a value, which is not finite
+func float(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "NULL"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// timestamp returns a literal of the time
+func timestamp(t time.Time) string {
+	return quote(t.UTC().Format("2006-01-02 15:04:05.999999Z07:00"))
+}
+
+// values joins the literals of a row
+func values(literals ...string) string {
+	return strings.Join(literals, ", ")
+}
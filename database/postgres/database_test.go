@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// testServer simulates a PostgreSQL server with md5 authentication (user "yanic", password "secret")
+// on a single connection, which records the queries.
+// The schema version is answered by the given version.
+func testServer(t *testing.T, version string) (string, chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	queries := make(chan string, 100)
+	go func() {
+		defer close(queries)
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		send := func(typ byte, body []byte) {
+			msg := []byte{typ, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(msg[1:], uint32(len(body)+4))
+			conn.Write(append(msg, body...))
+		}
+		receive := func(withType bool) (byte, []byte) {
+			var typ byte
+			if withType {
+				typ, _ = r.ReadByte()
+			}
+			header := make([]byte, 4)
+			if _, err := io.ReadFull(r, header); err != nil {
+				return 0, nil
+			}
+			body := make([]byte, binary.BigEndian.Uint32(header)-4)
+			io.ReadFull(r, body)
+			return typ, body
+		}
+
+		// startup and md5 authentication
+		receive(false)
+		send('R', []byte{0, 0, 0, 5, 1, 2, 3, 4})
+		_, password := receive(true)
+		inner := md5.Sum([]byte("secretyanic"))
+		outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), 1, 2, 3, 4))
+		if string(password) != "md5"+hex.EncodeToString(outer[:])+"\x00" {
+			send('E', []byte("SFATAL\x00C28P01\x00Mpassword authentication failed\x00\x00"))
+			return
+		}
+		send('R', []byte{0, 0, 0, 0})
+		send('S', []byte("standard_conforming_strings\x00on\x00"))
+		send('Z', []byte{'I'})
+
+		for {
+			typ, body := receive(true)
+			if typ != 'Q' {
+				return
+			}
+			query := strings.TrimSuffix(string(body), "\x00")
+			queries <- query
+			if strings.HasPrefix(query, "SELECT COALESCE") {
+				row := []byte{0, 1, 0, 0, 0, byte(len(version))}
+				send('D', append(row, version...))
+			}
+			send('C', []byte("OK\x00"))
+			send('Z', []byte{'I'})
+		}
+	}()
+	return ln.Addr().String(), queries
+}
+
+func TestConnect(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := Connect(map[string]interface{}{})
+	assert.Nil(conn)
+	assert.Error(err)
+
+	address, _ := testServer(t, "0")
+	conn, err = Connect(map[string]interface{}{"address": address, "database": "yanic", "username": "yanic", "password": "wrong"})
+	assert.Nil(conn)
+	assert.EqualError(err, "FATAL: password authentication failed (SQLSTATE 28P01)")
+
+	// newer schema
+	address, _ = testServer(t, "1000")
+	conn, err = Connect(map[string]interface{}{"address": address, "database": "yanic", "username": "yanic", "password": "secret"})
+	assert.Nil(conn)
+	assert.Error(err)
+	assert.Contains(err.Error(), "newer")
+}
+
+func TestWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	address, queries := testServer(t, "0")
+	c, err := Connect(map[string]interface{}{"address": address, "database": "yanic", "username": "yanic", "password": "secret", "timescale": true})
+	assert.NoError(err)
+
+	assert.Contains(<-queries, "CREATE TABLE IF NOT EXISTS yanic_schema")
+	<-queries
+	migration := <-queries
+	assert.Contains(migration, "CREATE TABLE nodes")
+	assert.Contains(migration, "INSERT INTO yanic_schema (version) VALUES (1);")
+	migration = <-queries
+	assert.Contains(migration, "ALTER TABLE globals ADD COLUMN clients_owe24 integer")
+	assert.Contains(migration, "INSERT INTO yanic_schema (version) VALUES (2);")
+	migration = <-queries
+	assert.Contains(migration, "ADD COLUMN traffic_rx_rate double precision")
+	assert.Contains(migration, "INSERT INTO yanic_schema (version) VALUES (3);")
+	assert.Contains(<-queries, "ALTER TABLE globals ADD COLUMN reboots integer;")
+	assert.Contains(<-queries, "ALTER TABLE node_statistics ADD COLUMN latency double precision;")
+	assert.Contains(<-queries, "SELECT create_hypertable('node_statistics', 'time'")
+
+	conn := c.(*Connection)
+	conn.InsertNode(&runtime.Node{
+		Nodeinfo:   &data.Nodeinfo{NodeID: "a", Hostname: "it's a node"},
+		Statistics: &data.Statistics{NodeID: "a", Clients: data.Clients{Total: 23}},
+	})
+	conn.InsertGlobals(&runtime.GlobalStats{Nodes: 2}, time.Unix(1500000000, 0), runtime.GLOBAL_SITE, runtime.GLOBAL_DOMAIN)
+	conn.PruneNodes(time.Hour)
+	assert.Equal("DELETE FROM node_statistics WHERE time < now() - interval '3600 seconds';\nDELETE FROM links WHERE time < now() - interval '3600 seconds';\nDELETE FROM globals WHERE time < now() - interval '3600 seconds'", <-queries)
+	conn.Close()
+
+	insert := <-queries
+	assert.Contains(insert, "INSERT INTO globals (time, site, domain, nodes, gateways, clients, clients_wifi24, clients_wifi5, clients_owe24, clients_owe5, reboots) VALUES ('2017-07-14 02:40:00Z', ")
+	assert.Contains(insert, "INSERT INTO node_statistics")
+	assert.Contains(insert, "'it''s a node'")
+	assert.Contains(insert, "ON CONFLICT (nodeid) DO UPDATE SET hostname = EXCLUDED.hostname")
+	assert.EqualValues(0, conn.Counters()[0].Value)
+}
+
+func TestInsertStatements(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("INSERT INTO links (time, source_id, source_addr, target_id, target_addr, protocol, tq) VALUES (1), (2);\n"+
+		"INSERT INTO nodes (nodeid, hostname, site, domain, model, firmware, autoupdater, latitude, longitude, nodeinfo, lastseen) VALUES (4), (3)"+
+		" ON CONFLICT (nodeid) DO UPDATE SET hostname = EXCLUDED.hostname, site = EXCLUDED.site, domain = EXCLUDED.domain, model = EXCLUDED.model,"+
+		" firmware = EXCLUDED.firmware, autoupdater = EXCLUDED.autoupdater, latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude,"+
+		" nodeinfo = EXCLUDED.nodeinfo, lastseen = EXCLUDED.lastseen;\n",
+		insertStatements([]row{
+			{table: "nodes", key: "a", values: "1"},
+			{table: "links", values: "1"},
+			{table: "nodes", key: "b", values: "3"},
+			{table: "links", values: "2"},
+			{table: "nodes", key: "a", values: "4"},
+		}))
+
+	assert.Equal("'it''s'", quote("it's\x00"))
+	assert.Equal("NULL", quoteOptional(""))
+	assert.Equal("NULL", float(math.NaN()))
+	assert.Equal("0.5", float(0.5))
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// InsertNode updates the nodeinfo of the node in the table nodes and stores its statistics
+func (conn *Connection) InsertNode(node *runtime.Node) {
+	lastseen := node.Lastseen.GetTime()
+
+	if nodeinfo := node.Nodeinfo; nodeinfo != nil && nodeinfo.NodeID != "" {
+		model, firmware, autoupdater := nodeinfo.Hardware.Model, "", ""
+		if fw := nodeinfo.Software.Firmware; fw != nil {
+			firmware = fw.Release
+		}
+		if au := nodeinfo.Software.Autoupdater; au != nil && au.Enabled {
+			autoupdater = au.Branch
+		}
+		latitude, longitude := "NULL", "NULL"
+		if location := nodeinfo.Location; location != nil {
+			latitude, longitude = float(location.Latitude), float(location.Longitude)
+		}
+		info := "NULL"
+		if raw, err := json.Marshal(nodeinfo); err == nil {
+			info = quote(string(raw))
+		}
+		conn.rows <- row{table: "nodes", key: nodeinfo.NodeID, values: values(
+			quote(nodeinfo.NodeID),
+			quoteOptional(nodeinfo.Hostname),
+			quoteOptional(nodeinfo.System.SiteCode),
+			quoteOptional(nodeinfo.System.DomainCode),
+			quoteOptional(model),
+			quoteOptional(firmware),
+			quoteOptional(autoupdater),
+			latitude,
+			longitude,
+			info,
+			timestamp(lastseen),
+		)}
+	}
+
+	stats := node.Statistics
+	if stats == nil || stats.NodeID == "" {
+		return
+	}
+	memoryUsage := "NULL"
+	if usage, ok := stats.Memory.Usage(); ok {
+		memoryUsage = float(usage)
+	}
+	rx, tx, rxRate, txRate := "NULL", "NULL", "NULL", "NULL"
+	if stats.Traffic.Rx != nil {
+		rx = float(stats.Traffic.Rx.Bytes)
+		if rate := stats.Traffic.Rx.Rate; rate != nil {
+			rxRate = float(rate.Bytes)
+		}
+	}
+	if stats.Traffic.Tx != nil {
+		tx = float(stats.Traffic.Tx.Bytes)
+		if rate := stats.Traffic.Tx.Rate; rate != nil {
+			txRate = float(rate.Bytes)
+		}
+	}
+	latency := "NULL"
+	if node.Latency > 0 {
+		latency = float(node.Latency)
+	}
+	conn.rows <- row{table: "node_statistics", values: values(
+		timestamp(lastseen),
+		quote(stats.NodeID),
+		strconv.FormatUint(uint64(stats.Clients.Total), 10),
+		strconv.FormatUint(uint64(stats.Clients.Wifi24), 10),
+		strconv.FormatUint(uint64(stats.Clients.Wifi5), 10),
+		strconv.FormatUint(uint64(stats.Clients.Owe24), 10),
+		strconv.FormatUint(uint64(stats.Clients.Owe5), 10),
+		float(stats.LoadAverage),
+		float(stats.Uptime),
+		memoryUsage,
+		float(stats.RootFsUsage),
+		rx,
+		tx,
+		rxRate,
+		txRate,
+		latency,
+	)}
+}
+
+// InsertLink stores the quality of a link
+func (conn *Connection) InsertLink(link *runtime.Link, t time.Time) {
+	conn.rows <- row{table: "links", values: values(
+		timestamp(t),
+		quote(link.SourceID),
+		quoteOptional(link.SourceAddress),
+		quote(link.TargetID),
+		quoteOptional(link.TargetAddress),
+		quoteOptional(link.Protocol),
+		float(float64(link.TQ)),
+	)}
+}
+
+// InsertGlobals stores the global statistics of a site and domain
+func (conn *Connection) InsertGlobals(stats *runtime.GlobalStats, t time.Time, site string, domain string) {
+	conn.rows <- row{table: "globals", values: values(
+		timestamp(t),
+		quote(site),
+		quote(domain),
+		strconv.FormatUint(uint64(stats.Nodes), 10),
+		strconv.FormatUint(uint64(stats.Gateways), 10),
+		strconv.FormatUint(uint64(stats.Clients), 10),
+		strconv.FormatUint(uint64(stats.ClientsWifi24), 10),
+		strconv.FormatUint(uint64(stats.ClientsWifi5), 10),
+		strconv.FormatUint(uint64(stats.ClientsOwe24), 10),
+		strconv.FormatUint(uint64(stats.ClientsOwe5), 10),
+		strconv.FormatUint(uint64(stats.Reboots), 10),
+	)}
+}
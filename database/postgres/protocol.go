@@ -0,0 +1,389 @@
+package postgres
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// protocol version 3.0 and the code of a SSLRequest
+const (
+	protocolVersion = 196608
+	sslRequestCode  = 80877103
+)
+
+// client is a minimal client of the frontend/backend protocol of PostgreSQL, which runs simple queries
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Error is an error reported by the server
+type Error struct {
+	Severity string
+	Code     string
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (SQLSTATE %s)", e.Severity, e.Message, e.Code)
+}
+
+// connectOptions of a connection
+type connectOptions struct {
+	address  string
+	user     string
+	password string
+	database string
+	tls      *tls.Config // nil without TLS
+	timeout  time.Duration
+}
+
+// dial connects and authenticates to the server
+func dial(options connectOptions) (*client, error) {
+	conn, err := net.DialTimeout("tcp", options.address, options.timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(options.timeout))
+
+	if options.tls != nil {
+		request := make([]byte, 8)
+		binary.BigEndian.PutUint32(request, 8)
+		binary.BigEndian.PutUint32(request[4:], sslRequestCode)
+		answer := make([]byte, 1)
+		if _, err = conn.Write(request); err == nil {
+			_, err = io.ReadFull(conn, answer)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if answer[0] != 'S' {
+			conn.Close()
+			return nil, errors.New("server does not support TLS")
+		}
+		tlsConn := tls.Client(conn, options.tls)
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	c := &client{conn: conn, r: bufio.NewReader(conn)}
+	if err = c.startup(options); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// send writes a message of the given type (0 for the startup message without type)
+func (c *client) send(typ byte, body []byte) error {
+	msg := make([]byte, 0, len(body)+5)
+	if typ != 0 {
+		msg = append(msg, typ)
+	}
+	msg = append(msg, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(msg[len(msg)-4:], uint32(len(body)+4))
+	msg = append(msg, body...)
+	_, err := c.conn.Write(msg)
+	return err
+}
+
+// receive reads a message of the server
+func (c *client) receive() (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return 0, nil, err
+	}
+	length := int(binary.BigEndian.Uint32(header[1:])) - 4
+	if length < 0 {
+		return 0, nil, errors.New("invalid message length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return header[0], body, nil
+}
+
+// parseError returns the error of an ErrorResponse
+func parseError(body []byte) *Error {
+	e := &Error{}
+	for len(body) > 1 {
+		field := body[0]
+		end := strings.IndexByte(string(body[1:]), 0)
+		if end < 0 {
+			break
+		}
+		value := string(body[1 : 1+end])
+		body = body[2+end:]
+		switch field {
+		case 'S':
+			e.Severity = value
+		case 'C':
+			e.Code = value
+		case 'M':
+			e.Message = value
+		}
+	}
+	return e
+}
+
+// cstring returns the string with a terminating zero byte
+func cstring(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+// startup sends the startup message, authenticates and waits until the server is ready for queries
+func (c *client) startup(options connectOptions) error {
+	body := make([]byte, 4)
+	binary.BigEndian.PutUint32(body, protocolVersion)
+	for _, param := range [][2]string{
+		{"user", options.user},
+		{"database", options.database},
+		{"application_name", "yanic"},
+		{"client_encoding", "UTF8"},
+	} {
+		body = append(body, cstring(param[0])...)
+		body = append(body, cstring(param[1])...)
+	}
+	body = append(body, 0)
+	if err := c.send(0, body); err != nil {
+		return err
+	}
+
+	var scram *scramClient
+	for {
+		typ, body, err := c.receive()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case 'E':
+			return parseError(body)
+		case 'R':
+			if len(body) < 4 {
+				return errors.New("invalid authentication request")
+			}
+			if scram, err = c.authenticate(options, binary.BigEndian.Uint32(body), body[4:], scram); err != nil {
+				return err
+			}
+		case 'S':
+			// parameters of the server, simple queries need standard strings for the quoting of the values
+			parts := strings.Split(string(body), "\x00")
+			if len(parts) >= 2 && parts[0] == "standard_conforming_strings" && parts[1] != "on" {
+				return errors.New("standard_conforming_strings has to be on")
+			}
+		case 'Z':
+			return nil
+		}
+	}
+}
+
+// authenticate answers an authentication request of the server
+func (c *client) authenticate(options connectOptions, method uint32, data []byte, scram *scramClient) (*scramClient, error) {
+	switch method {
+	case 0: // ok
+		return scram, nil
+	case 3: // cleartext password
+		return scram, c.send('p', cstring(options.password))
+	case 5: // md5 password with salt
+		if len(data) < 4 {
+			return nil, errors.New("invalid md5 authentication request")
+		}
+		inner := md5.Sum([]byte(options.password + options.user))
+		outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), data[:4]...))
+		return scram, c.send('p', cstring("md5"+hex.EncodeToString(outer[:])))
+	case 10: // SASL
+		if !strings.Contains(string(data), "SCRAM-SHA-256\x00") {
+			return nil, errors.New("server supports no SCRAM-SHA-256 authentication")
+		}
+		nonce := make([]byte, 18)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		scram = newScramClient(options.password, base64.StdEncoding.EncodeToString(nonce))
+		first := scram.clientFirst()
+		body := cstring("SCRAM-SHA-256")
+		body = append(body, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(body[len(body)-4:], uint32(len(first)))
+		return scram, c.send('p', append(body, first...))
+	case 11: // SASL continue
+		if scram == nil {
+			return nil, errors.New("unexpected SASL continue")
+		}
+		final, err := scram.clientFinal(string(data))
+		if err != nil {
+			return nil, err
+		}
+		return scram, c.send('p', []byte(final))
+	case 12: // SASL final
+		if scram == nil || !scram.verify(string(data)) {
+			return nil, errors.New("invalid signature of the server")
+		}
+		return scram, nil
+	}
+	return nil, fmt.Errorf("unsupported authentication method %d", method)
+}
+
+// query runs a simple query (could be several statements) and returns the values of the rows, NULL as nil
+func (c *client) query(sql string, timeout time.Duration) ([][]*string, error) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := c.send('Q', cstring(sql)); err != nil {
+		return nil, err
+	}
+	var rows [][]*string
+	var result error
+	for {
+		typ, body, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case 'E':
+			result = parseError(body)
+		case 'D':
+			if len(body) < 2 {
+				return nil, errors.New("invalid data row")
+			}
+			count := int(binary.BigEndian.Uint16(body))
+			body = body[2:]
+			row := make([]*string, count)
+			for i := 0; i < count && len(body) >= 4; i++ {
+				length := int32(binary.BigEndian.Uint32(body))
+				body = body[4:]
+				if length < 0 {
+					continue
+				}
+				if int(length) > len(body) {
+					return nil, errors.New("invalid data row")
+				}
+				value := string(body[:length])
+				row[i] = &value
+				body = body[length:]
+			}
+			rows = append(rows, row)
+		case 'Z':
+			return rows, result
+		}
+	}
+}
+
+// close terminates the connection
+func (c *client) close() {
+	c.send('X', nil)
+	c.conn.Close()
+}
+
+// scramClient authenticates by SCRAM-SHA-256 (RFC 7677) without channel binding
+type scramClient struct {
+	password    string
+	nonce       string
+	firstBare   string
+	serverKey   []byte
+	authMessage string
+}
+
+func newScramClient(password, nonce string) *scramClient {
+	return &scramClient{password: password, nonce: nonce}
+}
+
+// clientFirst returns the first message, the user name is taken from the startup message
+func (s *scramClient) clientFirst() string {
+	s.firstBare = "n=,r=" + s.nonce
+	return "n,," + s.firstBare
+}
+
+// clientFinal returns the proof of the password for the first message of the server
+func (s *scramClient) clientFinal(serverFirst string) (string, error) {
+	var nonce, salt string
+	var iterations int
+	for _, attr := range strings.Split(serverFirst, ",") {
+		if len(attr) < 2 || attr[1] != '=' {
+			continue
+		}
+		switch attr[0] {
+		case 'r':
+			nonce = attr[2:]
+		case 's':
+			salt = attr[2:]
+		case 'i':
+			fmt.Sscanf(attr[2:], "%d", &iterations)
+		}
+	}
+	if !strings.HasPrefix(nonce, s.nonce) || iterations <= 0 {
+		return "", errors.New("invalid SCRAM message of the server")
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return "", errors.New("invalid SCRAM salt of the server")
+	}
+
+	salted := pbkdf2SHA256([]byte(s.password), saltBytes, iterations)
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	s.serverKey = hmacSHA256(salted, "Server Key")
+
+	finalBare := "c=biws,r=" + nonce
+	s.authMessage = s.firstBare + "," + serverFirst + "," + finalBare
+	signature := hmacSHA256(storedKey[:], s.authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+	return finalBare + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// verify checks the signature of the final message of the server
+func (s *scramClient) verify(serverFinal string) bool {
+	if !strings.HasPrefix(serverFinal, "v=") || s.serverKey == nil {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(serverFinal, "v="))
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(signature, hmacSHA256(s.serverKey, s.authMessage))
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// pbkdf2SHA256 derives a key of the size of a SHA-256 hash (RFC 8018), that is a single block
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	result := append([]byte{}, u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScram(t *testing.T) {
+	assert := assert.New(t)
+
+	// example of RFC 7677
+	s := newScramClient("pencil", "rOprNGfwEbeRWgbNEkqO")
+	assert.Equal("n,,n=,r=rOprNGfwEbeRWgbNEkqO", s.clientFirst())
+	s.firstBare = "n=user,r=rOprNGfwEbeRWgbNEkqO"
+
+	final, err := s.clientFinal("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	assert.NoError(err)
+	assert.Equal("c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=", final)
+	assert.True(s.verify("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="))
+	assert.False(s.verify("v=AAAATRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="))
+
+	// nonce of the server has to extend the nonce of the client
+	_, err = s.clientFinal("r=other,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	assert.Error(err)
+}
+
+func TestParseError(t *testing.T) {
+	assert := assert.New(t)
+
+	err := parseError([]byte("SERROR\x00C42P01\x00Mrelation \"nodes\" does not exist\x00\x00"))
+	assert.Equal(&Error{Severity: "ERROR", Code: "42P01", Message: `relation "nodes" does not exist`}, err)
+	assert.EqualError(err, `ERROR: relation "nodes" does not exist (SQLSTATE 42P01)`)
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// migrations of the schema, the index is the version after the migration.
+// Released migrations must never change, a change of the schema is a new migration.
+var migrations = []string{
+	1: `
+CREATE TABLE nodes (
+	nodeid    text PRIMARY KEY,
+	hostname  text,
+	site      text,
+	domain    text,
+	model     text,
+	firmware  text,
+	autoupdater text,
+	latitude  double precision,
+	longitude double precision,
+	nodeinfo  jsonb,
+	lastseen  timestamptz NOT NULL
+);
+CREATE TABLE node_statistics (
+	time           timestamptz NOT NULL,
+	nodeid         text NOT NULL,
+	clients        integer,
+	clients_wifi24 integer,
+	clients_wifi5  integer,
+	load           double precision,
+	uptime         double precision,
+	memory_usage   double precision,
+	rootfs_usage   double precision,
+	traffic_rx     double precision,
+	traffic_tx     double precision
+);
+CREATE INDEX node_statistics_nodeid_time ON node_statistics (nodeid, time DESC);
+CREATE TABLE links (
+	time        timestamptz NOT NULL,
+	source_id   text NOT NULL,
+	source_addr text,
+	target_id   text NOT NULL,
+	target_addr text,
+	protocol    text,
+	tq          double precision
+);
+CREATE INDEX links_source_target_time ON links (source_id, target_id, time DESC);
+CREATE TABLE globals (
+	time           timestamptz NOT NULL,
+	site           text NOT NULL,
+	domain         text NOT NULL,
+	nodes          integer,
+	gateways       integer,
+	clients        integer,
+	clients_wifi24 integer,
+	clients_wifi5  integer
+);
+CREATE INDEX globals_site_domain_time ON globals (site, domain, time DESC);`,
+	2: `
+ALTER TABLE node_statistics ADD COLUMN clients_owe24 integer, ADD COLUMN clients_owe5 integer;
+ALTER TABLE globals ADD COLUMN clients_owe24 integer, ADD COLUMN clients_owe5 integer;`,
+	3: `
+ALTER TABLE node_statistics ADD COLUMN traffic_rx_rate double precision, ADD COLUMN traffic_tx_rate double precision;`,
+	4: `
+ALTER TABLE globals ADD COLUMN reboots integer;`,
+	5: `
+ALTER TABLE node_statistics ADD COLUMN latency double precision;`,
+}
+
+// hypertables are the tables of time series, which are converted to hypertables of TimescaleDB
+var hypertables = []string{"node_statistics", "links", "globals"}
+
+// migrate creates or updates the schema to the latest version
+func migrate(c *client, timescale bool) error {
+	if _, err := c.query("CREATE TABLE IF NOT EXISTS yanic_schema (version integer NOT NULL)", queryTimeout); err != nil {
+		return fmt.Errorf("unable to create the table of the schema version: %s", err)
+	}
+	rows, err := c.query("SELECT COALESCE(MAX(version), 0) FROM yanic_schema", queryTimeout)
+	if err != nil {
+		return fmt.Errorf("unable to read the schema version: %s", err)
+	}
+	version := 0
+	if len(rows) == 1 && len(rows[0]) == 1 && rows[0][0] != nil {
+		version, _ = strconv.Atoi(*rows[0][0])
+	}
+	if version > len(migrations)-1 {
+		return fmt.Errorf("schema version %d is newer than this yanic (%d)", version, len(migrations)-1)
+	}
+
+	// a simple query with several statements runs in a single transaction
+	for next := version + 1; next < len(migrations); next++ {
+		if _, err = c.query(fmt.Sprintf("%s\nINSERT INTO yanic_schema (version) VALUES (%d);", migrations[next], next), queryTimeout); err != nil {
+			return fmt.Errorf("unable to migrate the schema to version %d: %s", next, err)
+		}
+	}
+
+	if timescale {
+		statements := []string{"CREATE EXTENSION IF NOT EXISTS timescaledb"}
+		for _, table := range hypertables {
+			statements = append(statements, fmt.Sprintf("SELECT create_hypertable(%s, 'time', if_not_exists => TRUE, migrate_data => TRUE)", quote(table)))
+		}
+		if _, err = c.query(strings.Join(statements, ";\n"), queryTimeout); err != nil {
+			return fmt.Errorf("unable to create the hypertables: %s", err)
+		}
+	}
+	return nil
+}
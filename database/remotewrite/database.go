@@ -0,0 +1,215 @@
+package remotewrite
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/database"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	batchMaxSize = 1000             // series per request
+	batchTimeout = 5 * time.Second  // longest delay of a series
+	writeTimeout = 30 * time.Second // timeout of a request
+)
+
+type Connection struct {
+	// accessed atomically, keep 64-bit aligned at the beginning of the struct
+	writeErrors uint64
+
+	database.Connection
+	config Config
+	client *http.Client
+	series chan []series
+	wg     sync.WaitGroup
+}
+
+type Config map[string]interface{}
+
+func (c Config) Address() string {
+	if d, ok := c["address"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Username() string {
+	if d, ok := c["username"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) Password() string {
+	if d, ok := c["password"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) BearerToken() string {
+	if d, ok := c["bearer_token"]; ok {
+		return d.(string)
+	}
+	return ""
+}
+func (c Config) InsecureSkipVerify() bool {
+	if d, ok := c["insecure_skip_verify"]; ok {
+		return d.(bool)
+	}
+	return false
+}
+func (c Config) Labels() map[string]interface{} {
+	if c["labels"] != nil {
+		return c["labels"].(map[string]interface{})
+	}
+	return nil
+}
+
+func init() {
+	database.RegisterAdapter("remote_write", Connect)
+}
+
+func Connect(configuration map[string]interface{}) (database.Connection, error) {
+	var config Config
+	config = configuration
+
+	if config.Address() == "" {
+		return nil, errors.New("no address given")
+	}
+	for name, value := range config.Labels() {
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("label %s is not a string", name)
+		}
+	}
+
+	conn := &Connection{
+		config: config,
+		client: &http.Client{
+			Timeout: writeTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify()},
+			},
+		},
+		series: make(chan []series, batchMaxSize),
+	}
+
+	conn.wg.Add(1)
+	go conn.addWorker()
+
+	return conn, nil
+}
+
+// PruneNodes is not supported, old series are removed by the retention of the receiver
+func (conn *Connection) PruneNodes(deleteAfter time.Duration) {
+}
+
+// Close sends the pending series
+func (conn *Connection) Close() {
+	close(conn.series)
+	conn.wg.Wait()
+}
+
+// Counters returns the counter of failed writes as metric
+func (conn *Connection) Counters() []runtime.Counter {
+	return []runtime.Counter{
+		{Name: "remote_write_errors", Help: "Failed requests of Prometheus remote write", Value: atomic.LoadUint64(&conn.writeErrors)},
+	}
+}
+
+// add queues the series with the configured labels
+func (conn *Connection) add(list []series) {
+	if labels := conn.config.Labels(); len(labels) > 0 {
+		for i := range list {
+			for name, value := range labels {
+				if !hasLabel(list[i].labels, name) {
+					list[i].labels = append(list[i].labels, label{name: name, value: value.(string)})
+				}
+			}
+			sortLabels(list[i].labels)
+		}
+	}
+	conn.series <- list
+}
+
+// sends the series in batches
+func (conn *Connection) addWorker() {
+	defer conn.wg.Done()
+
+	var batch []series
+	timer := time.NewTimer(batchTimeout)
+	defer timer.Stop()
+
+	for {
+		closed := false
+		select {
+		case list, ok := <-conn.series:
+			if !ok {
+				closed = true
+				break
+			}
+			if len(batch) == 0 {
+				timer.Reset(batchTimeout)
+			}
+			batch = append(batch, list...)
+			if len(batch) < batchMaxSize {
+				continue
+			}
+		case <-timer.C:
+			if len(batch) == 0 {
+				timer.Reset(batchTimeout)
+				continue
+			}
+		}
+
+		if len(batch) > 0 {
+			if err := conn.write(batch); err != nil {
+				atomic.AddUint64(&conn.writeErrors, 1)
+				log.WithFields(map[string]interface{}{
+					"database": "remote_write",
+					"count":    len(batch),
+				}).Errorf("unable to send series: %s", err)
+			}
+			batch = nil
+		}
+		if closed {
+			return
+		}
+	}
+}
+
+// write sends the series by a single request
+func (conn *Connection) write(list []series) error {
+	req, err := http.NewRequest(http.MethodPost, conn.config.Address(), bytes.NewReader(encodeSnappy(encodeWriteRequest(list))))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "yanic")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if token := conn.config.BearerToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if username := conn.config.Username(); username != "" {
+		req.SetBasicAuth(username, conn.config.Password())
+	}
+
+	res, err := conn.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("%s: %s", res.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
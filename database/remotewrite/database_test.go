@@ -0,0 +1,85 @@
+package remotewrite
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/lib/jsontime"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestConnect(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := Connect(map[string]interface{}{})
+	assert.Nil(conn)
+	assert.Error(err)
+
+	conn, err = Connect(map[string]interface{}{
+		"address": "http://localhost/api/v1/write",
+		"labels":  map[string]interface{}{"instance": 1},
+	})
+	assert.Nil(conn)
+	assert.Error(err)
+}
+
+func TestWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	var bodies [][]byte
+	var headers []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, decodeSnappy(t, body))
+		headers = append(headers, r.Header)
+		if len(bodies) > 1 {
+			http.Error(w, "out of order sample", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := Connect(map[string]interface{}{
+		"address":      srv.URL,
+		"bearer_token": "secret",
+		"labels":       map[string]interface{}{"instance": "yanic", "site": "overwritten"},
+	})
+	assert.NoError(err)
+	conn := c.(*Connection)
+
+	now := time.Unix(1500000000, 0)
+	conn.InsertNode(&runtime.Node{
+		Lastseen: jsontime.Now(),
+		Nodeinfo: &data.Nodeinfo{NodeID: "a", Hostname: "node a", System: data.System{SiteCode: "ffhb"}},
+		Statistics: &data.Statistics{
+			NodeID:  "a",
+			Clients: data.Clients{Total: 23},
+			Memory:  data.Memory{Total: 100, Available: 25},
+		},
+	})
+	conn.InsertGlobals(&runtime.GlobalStats{Nodes: 2, Firmwares: runtime.CounterMap{"v2020": 2}}, now, "ffhb", runtime.GLOBAL_DOMAIN)
+	// without statistics
+	conn.InsertNode(&runtime.Node{Nodeinfo: &data.Nodeinfo{NodeID: "b"}})
+	conn.Close()
+
+	assert.Len(bodies, 1)
+	assert.Equal("snappy", headers[0].Get("Content-Encoding"))
+	assert.Equal("application/x-protobuf", headers[0].Get("Content-Type"))
+	assert.Equal("Bearer secret", headers[0].Get("Authorization"))
+
+	body := string(bodies[0])
+	for _, s := range []string{"yanic_node_clients", "yanic_node_memory_usage", "node a", "instance", "yanic_firmware_nodes", "v2020"} {
+		assert.Contains(body, s)
+	}
+	// the labels of the series are kept
+	assert.NotContains(body, "overwritten")
+	assert.EqualValues(0, conn.Counters()[0].Value)
+
+	// failed request
+	assert.Error(conn.write([]series{newSeries("up", nil, 1, 0)}))
+}
@@ -0,0 +1,141 @@
+package remotewrite
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// label is a label of a time series
+type label struct {
+	name  string
+	value string
+}
+
+// sample is a value of a time series at a timestamp
+type sample struct {
+	value     float64
+	timestamp int64 // milliseconds since the epoch
+}
+
+// series is a time series of the remote write protocol
+type series struct {
+	labels  []label
+	samples []sample
+}
+
+// newSeries returns a series of a single sample, the labels are sorted by their name as required by the protocol
+func newSeries(name string, labels []label, value float64, timestamp int64) series {
+	all := append([]label{{name: "__name__", value: name}}, labels...)
+	sortLabels(all)
+	return series{labels: all, samples: []sample{{value: value, timestamp: timestamp}}}
+}
+
+func sortLabels(labels []label) {
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+}
+
+func hasLabel(labels []label, name string) bool {
+	for _, l := range labels {
+		if l.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// protobuf wire types
+const (
+	wireVarint = 0
+	wire64Bit  = 1
+	wireBytes  = 2
+)
+
+// protoBuffer encodes the messages of the protobuf wire format
+type protoBuffer []byte
+
+func (b *protoBuffer) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	*b = append(*b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func (b *protoBuffer) tag(field int, wire int) {
+	b.varint(uint64(field<<3 | wire))
+}
+
+func (b *protoBuffer) bytes(field int, data []byte) {
+	b.tag(field, wireBytes)
+	b.varint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+func (b *protoBuffer) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *protoBuffer) double(field int, v float64) {
+	b.tag(field, wire64Bit)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	*b = append(*b, buf[:]...)
+}
+
+func (b *protoBuffer) int64(field int, v int64) {
+	b.tag(field, wireVarint)
+	b.varint(uint64(v))
+}
+
+// encodeWriteRequest returns the protobuf encoded WriteRequest of prometheus/prompb:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(list []series) []byte {
+	var request protoBuffer
+	for _, s := range list {
+		var ts protoBuffer
+		for _, l := range s.labels {
+			var lb protoBuffer
+			lb.string(1, l.name)
+			lb.string(2, l.value)
+			ts.bytes(1, lb)
+		}
+		for _, smp := range s.samples {
+			var sb protoBuffer
+			sb.double(1, smp.value)
+			sb.int64(2, smp.timestamp)
+			ts.bytes(2, sb)
+		}
+		request.bytes(1, ts)
+	}
+	return request
+}
+
+// snappyMaxLiteral is the longest literal of a single element, the length has to fit into 4 bytes
+const snappyMaxLiteral = 1 << 16
+
+// encodeSnappy returns the data in the snappy block format (as required by remote write).
+// The data is not compressed, it is stored as literals, which every decoder accepts.
+func encodeSnappy(data []byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	result := append([]byte{}, buf[:binary.PutUvarint(buf[:], uint64(len(data)))]...)
+	for len(data) > 0 {
+		n := len(data)
+		if n > snappyMaxLiteral {
+			n = snappyMaxLiteral
+		}
+		// tag of a literal: the length-1 in the upper 6 bits, or in the following 1-2 bytes (tag 60 or 61)
+		switch l := n - 1; {
+		case l < 60:
+			result = append(result, byte(l<<2))
+		case l < 1<<8:
+			result = append(result, 60<<2, byte(l))
+		default:
+			result = append(result, 61<<2, byte(l), byte(l>>8))
+		}
+		result = append(result, data[:n]...)
+		data = data[n:]
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package remotewrite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeSnappy decodes a snappy block of literals
+func decodeSnappy(t *testing.T, data []byte) []byte {
+	length, n := binary.Uvarint(data)
+	data = data[n:]
+	var result []byte
+	for len(data) > 0 {
+		tag := data[0]
+		assert.Equal(t, byte(0), tag&3, "literal")
+		l := int(tag >> 2)
+		data = data[1:]
+		switch l {
+		case 60:
+			l = int(data[0])
+			data = data[1:]
+		case 61:
+			l = int(data[0]) | int(data[1])<<8
+			data = data[2:]
+		}
+		result = append(result, data[:l+1]...)
+		data = data[l+1:]
+	}
+	assert.EqualValues(t, length, len(result))
+	return result
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	s := newSeries("up", []label{{name: "job", value: "a"}}, 1, 2)
+	assert.Equal([]label{{"__name__", "up"}, {"job", "a"}}, s.labels)
+
+	assert.Equal([]byte{
+		0x0a, 0x27, // timeseries
+		0x0a, 0x0e, 0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_', 0x12, 0x02, 'u', 'p', // label __name__
+		0x0a, 0x08, 0x0a, 0x03, 'j', 'o', 'b', 0x12, 0x01, 'a', // label job
+		0x12, 0x0b, 0x09, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0x10, 0x02, // sample 1.0 at 2
+	}, encodeWriteRequest([]series{s}))
+}
+
+func TestEncodeSnappy(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, size := range []int{0, 1, 60, 61, 256, 257, snappyMaxLiteral, 3*snappyMaxLiteral + 7} {
+		data := bytes.Repeat([]byte{'x'}, size)
+		assert.Equal(data, append([]byte{}, decodeSnappy(t, encodeSnappy(data))...), size)
+	}
+}
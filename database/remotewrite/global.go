@@ -0,0 +1,56 @@
+package remotewrite
+
+import (
+	"time"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// InsertGlobals stores the global statistics of a site and domain, with the names of the prometheus output
+func (conn *Connection) InsertGlobals(stats *runtime.GlobalStats, t time.Time, site string, domain string) {
+	labels := []label{
+		{name: "site", value: site},
+		{name: "domain", value: domain},
+	}
+	timestamp := t.UnixNano() / int64(time.Millisecond)
+
+	list := []series{
+		newSeries("yanic_nodes", labels, float64(stats.Nodes), timestamp),
+		newSeries("yanic_gateways", labels, float64(stats.Gateways), timestamp),
+		newSeries("yanic_reboots", labels, float64(stats.Reboots), timestamp),
+		newSeries("yanic_nodes_uplink_only", labels, float64(stats.NodesUplinkOnly), timestamp),
+		newSeries("yanic_clients", labels, float64(stats.Clients), timestamp),
+		newSeries("yanic_clients_wifi", labels, float64(stats.ClientsWifi), timestamp),
+		newSeries("yanic_clients_wifi24", labels, float64(stats.ClientsWifi24), timestamp),
+		newSeries("yanic_clients_wifi5", labels, float64(stats.ClientsWifi5), timestamp),
+		newSeries("yanic_clients_owe", labels, float64(stats.ClientsOwe), timestamp),
+		newSeries("yanic_clients_owe24", labels, float64(stats.ClientsOwe24), timestamp),
+		newSeries("yanic_clients_owe5", labels, float64(stats.ClientsOwe5), timestamp),
+		newSeries("yanic_clients_unique", labels, float64(stats.ClientsUnique), timestamp),
+	}
+	for gateway, gw := range stats.PerGateway {
+		gatewayLabels := append([]label{{name: "gateway", value: gateway}, {name: "hostname", value: gw.Hostname}}, labels...)
+		list = append(list,
+			newSeries("yanic_gateway_nodes", gatewayLabels, float64(gw.Nodes), timestamp),
+			newSeries("yanic_gateway_clients", gatewayLabels, float64(gw.Clients), timestamp),
+		)
+	}
+	for _, counter := range []struct {
+		name   string
+		label  string
+		values runtime.CounterMap
+	}{
+		{"yanic_firmware_nodes", "firmware", stats.Firmwares},
+		{"yanic_model_nodes", "model", stats.Models},
+		{"yanic_autoupdater_nodes", "branch", stats.Autoupdater},
+		{"yanic_autoupdater_disabled_nodes", "branch", stats.AutoupdaterDisabled},
+		{"yanic_channel24_nodes", "channel", stats.Channels24},
+		{"yanic_channel5_nodes", "channel", stats.Channels5},
+		{"yanic_latency_nodes", "bucket", stats.Latency},
+	} {
+		for key, count := range counter.values {
+			list = append(list, newSeries(counter.name, append([]label{{name: counter.label, value: key}}, labels...), float64(count), timestamp))
+		}
+	}
+	conn.add(list)
+}
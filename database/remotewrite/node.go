@@ -0,0 +1,105 @@
+package remotewrite
+
+import (
+	"time"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// nodeMetric is a metric of the statistics of a node
+type nodeMetric struct {
+	name  string
+	value func(*runtime.Node) (float64, bool)
+}
+
+var nodeMetrics = []nodeMetric{
+	{"yanic_node_clients", func(node *runtime.Node) (float64, bool) {
+		return float64(node.Statistics.Clients.Total), true
+	}},
+	{"yanic_node_clients_wifi", func(node *runtime.Node) (float64, bool) {
+		return float64(node.Statistics.Clients.Wifi), true
+	}},
+	{"yanic_node_clients_wifi24", func(node *runtime.Node) (float64, bool) {
+		return float64(node.Statistics.Clients.Wifi24), true
+	}},
+	{"yanic_node_clients_wifi5", func(node *runtime.Node) (float64, bool) {
+		return float64(node.Statistics.Clients.Wifi5), true
+	}},
+	{"yanic_node_clients_owe24", func(node *runtime.Node) (float64, bool) {
+		return float64(node.Statistics.Clients.Owe24), true
+	}},
+	{"yanic_node_clients_owe5", func(node *runtime.Node) (float64, bool) {
+		return float64(node.Statistics.Clients.Owe5), true
+	}},
+	{"yanic_node_clients_unique", func(node *runtime.Node) (float64, bool) {
+		if unique := node.Statistics.Clients.Unique; unique != nil {
+			return float64(*unique), true
+		}
+		return 0, false
+	}},
+	{"yanic_node_load", func(node *runtime.Node) (float64, bool) {
+		return node.Statistics.LoadAverage, true
+	}},
+	{"yanic_node_uptime_seconds", func(node *runtime.Node) (float64, bool) {
+		return node.Statistics.Uptime, true
+	}},
+	{"yanic_node_rootfs_usage", func(node *runtime.Node) (float64, bool) {
+		return node.Statistics.RootFsUsage, true
+	}},
+	{"yanic_node_memory_usage", func(node *runtime.Node) (float64, bool) {
+		return node.Statistics.Memory.Usage()
+	}},
+	{"yanic_node_latency_seconds", func(node *runtime.Node) (float64, bool) {
+		return node.Latency, node.Latency > 0
+	}},
+	{"yanic_node_traffic_rx_bytes_total", func(node *runtime.Node) (float64, bool) {
+		if rx := node.Statistics.Traffic.Rx; rx != nil {
+			return rx.Bytes, true
+		}
+		return 0, false
+	}},
+	{"yanic_node_traffic_tx_bytes_total", func(node *runtime.Node) (float64, bool) {
+		if tx := node.Statistics.Traffic.Tx; tx != nil {
+			return tx.Bytes, true
+		}
+		return 0, false
+	}},
+}
+
+// InsertNode stores the statistics of a node as series labeled by the node
+func (conn *Connection) InsertNode(node *runtime.Node) {
+	if node.Statistics == nil || node.Nodeinfo == nil || node.Statistics.NodeID == "" {
+		return
+	}
+	nodeinfo := node.Nodeinfo
+	labels := []label{
+		{name: "nodeid", value: node.Statistics.NodeID},
+		{name: "hostname", value: nodeinfo.Hostname},
+		{name: "site", value: nodeinfo.System.SiteCode},
+		{name: "domain", value: nodeinfo.System.DomainCode},
+	}
+	timestamp := node.Lastseen.GetTime().UnixNano() / int64(time.Millisecond)
+
+	var list []series
+	for _, m := range nodeMetrics {
+		if value, ok := m.value(node); ok {
+			list = append(list, newSeries(m.name, labels, value, timestamp))
+		}
+	}
+	conn.add(list)
+}
+
+// InsertLink stores the quality of a link
+func (conn *Connection) InsertLink(link *runtime.Link, t time.Time) {
+	labels := []label{
+		{name: "source_id", value: link.SourceID},
+		{name: "source_addr", value: link.SourceAddress},
+		{name: "target_id", value: link.TargetID},
+		{name: "target_addr", value: link.TargetAddress},
+		{name: "protocol", value: link.Protocol},
+	}
+	if link.Type != "" {
+		labels = append(labels, label{name: "type", value: link.Type})
+	}
+	conn.add([]series{newSeries("yanic_link_tq", labels, float64(link.TQ), t.UnixNano()/int64(time.Millisecond))})
+}
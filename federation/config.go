@@ -0,0 +1,24 @@
+package federation
+
+import "github.com/FreifunkBremen/yanic/lib/duration"
+
+// Config of the nodes pulled from other instances
+type Config struct {
+	Enable   bool              `toml:"enable"`
+	Interval duration.Duration `toml:"interval"` // of the pulls (default intervalDefault)
+	Remotes  []RemoteConfig    `toml:"remote"`
+}
+
+// formats of the remotes
+const (
+	FormatYanic      = "yanic"      // the API (/api/nodes) or the raw output of another instance
+	FormatMeshviewer = "meshviewer" // nodes.json of version 1 or 2
+	FormatFFRGB      = "meshviewer-ffrgb"
+)
+
+// RemoteConfig is another instance or map, of which the nodes are pulled
+type RemoteConfig struct {
+	URL    string `toml:"url"`
+	Format string `toml:"format"` // default FormatYanic
+	Origin string `toml:"origin"` // tag of the merged nodes, the origin of another instance is kept by default
+}
@@ -0,0 +1,166 @@
+package federation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/output/meshviewer"
+	meshviewerFFRGB "github.com/FreifunkBremen/yanic/output/meshviewer-ffrgb"
+	"github.com/FreifunkBremen/yanic/output/raw"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	intervalDefault = time.Minute
+	fetchTimeout    = 30 * time.Second
+)
+
+// Federation pulls the nodes of other instances and merges them into the local nodes
+type Federation struct {
+	remotes  []RemoteConfig
+	interval time.Duration
+	nodes    *runtime.Nodes
+	client   *http.Client
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Start pulls the nodes of the remotes now and then periodically
+func Start(config Config, nodes *runtime.Nodes) (*Federation, error) {
+	for _, remote := range config.Remotes {
+		if remote.URL == "" {
+			return nil, errors.New("remote without url")
+		}
+		switch remote.Format {
+		case "", FormatYanic, FormatMeshviewer, FormatFFRGB:
+		default:
+			return nil, fmt.Errorf("remote %s: unknown format %q", remote.URL, remote.Format)
+		}
+	}
+	f := &Federation{
+		remotes:  config.Remotes,
+		interval: config.Interval.Duration,
+		nodes:    nodes,
+		client:   &http.Client{Timeout: fetchTimeout},
+		stop:     make(chan struct{}),
+	}
+	if f.interval <= 0 {
+		f.interval = intervalDefault
+	}
+	f.wg.Add(1)
+	go f.worker()
+	return f, nil
+}
+
+// Close stops the pulling
+func (f *Federation) Close() {
+	close(f.stop)
+	f.wg.Wait()
+}
+
+func (f *Federation) worker() {
+	defer f.wg.Done()
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		f.pull()
+		select {
+		case <-ticker.C:
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// pull merges the nodes of all remotes
+func (f *Federation) pull() {
+	for _, remote := range f.remotes {
+		list, err := f.fetch(remote)
+		if err != nil {
+			log.WithField("url", remote.URL).Errorf("federation: %s", err)
+			continue
+		}
+		merged := 0
+		for nodeID, node := range list {
+			if remote.Origin != "" {
+				node.Origin = remote.Origin
+			}
+			if f.nodes.Merge(nodeID, node) {
+				merged++
+			}
+		}
+		log.WithField("url", remote.URL).Debugf("federation: merged %d of %d nodes", merged, len(list))
+	}
+}
+
+// fetch returns the nodes of a remote by their node ID
+func (f *Federation) fetch(remote RemoteConfig) (map[string]*runtime.Node, error) {
+	res, err := f.client.Get(remote.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote responded %s", res.Status)
+	}
+	switch remote.Format {
+	case FormatMeshviewer:
+		return meshviewer.ReadCurrentNodes(res.Body)
+	case FormatFFRGB:
+		return meshviewerFFRGB.ReadNodes(res.Body)
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return decodeNodes(body)
+}
+
+// decodeNodes decodes the nodes of the API (an object of the nodes by their node ID)
+// or of the raw output (a list of nodes with their neighbours)
+func decodeNodes(body []byte) (map[string]*runtime.Node, error) {
+	var probe struct {
+		Version string          `json:"version"`
+		Nodes   json.RawMessage `json:"nodes"`
+	}
+	if err := json.Unmarshal(body, &probe); err == nil && probe.Version != "" && len(probe.Nodes) > 0 && probe.Nodes[0] == '[' {
+		var nodelist raw.NodeList
+		if err := json.Unmarshal(body, &nodelist); err != nil {
+			return nil, err
+		}
+		list := make(map[string]*runtime.Node, len(nodelist.List))
+		for _, node := range nodelist.List {
+			if node == nil || node.Nodeinfo == nil || node.Nodeinfo.NodeID == "" {
+				continue
+			}
+			list[node.Nodeinfo.NodeID] = &runtime.Node{
+				Firstseen:    node.Firstseen,
+				Lastseen:     node.Lastseen,
+				Online:       node.Online,
+				Statistics:   node.Statistics,
+				Nodeinfo:     node.Nodeinfo,
+				Neighbours:   node.Neighbours,
+				CustomFields: node.CustomFields,
+				Origin:       node.Origin,
+			}
+		}
+		return list, nil
+	}
+
+	var list map[string]*runtime.Node
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	for nodeID, node := range list {
+		if node == nil {
+			delete(list, nodeID)
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,100 @@
+package federation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/lib/jsontime"
+	meshviewerFFRGB "github.com/FreifunkBremen/yanic/output/meshviewer-ffrgb"
+	"github.com/FreifunkBremen/yanic/output/raw"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestDecodeNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	// api
+	list, err := decodeNodes([]byte(`{"000000000001":{"online":true,"nodeinfo":{"node_id":"000000000001","hostname":"node1"}},"000000000002":null}`))
+	assert.NoError(err)
+	assert.Len(list, 1)
+	assert.Equal("node1", list["000000000001"].Nodeinfo.Hostname)
+
+	// raw output
+	body, _ := json.Marshal(&raw.NodeList{Version: "1.0.0", List: []*raw.RawNode{
+		{Online: true, Nodeinfo: &data.Nodeinfo{NodeID: "000000000001"}, Neighbours: &data.Neighbours{NodeID: "000000000001"}},
+		{Online: true},
+	}})
+	list, err = decodeNodes(body)
+	assert.NoError(err)
+	assert.Len(list, 1)
+	assert.Equal("000000000001", list["000000000001"].Neighbours.NodeID)
+
+	_, err = decodeNodes([]byte(`[]`))
+	assert.Error(err)
+}
+
+func TestFederation(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Start(Config{Remotes: []RemoteConfig{{}}}, nil)
+	assert.Error(err)
+	_, err = Start(Config{Remotes: []RemoteConfig{{URL: "http://localhost", Format: "hopglass"}}}, nil)
+	assert.Error(err)
+
+	remote := map[string]*runtime.Node{
+		"000000000001": {
+			Lastseen: jsontime.Now(),
+			Online:   true,
+			Nodeinfo: &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"},
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(remote)
+	}))
+	defer ts.Close()
+	failing := httptest.NewServer(http.NotFoundHandler())
+	defer failing.Close()
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	f, err := Start(Config{Remotes: []RemoteConfig{{URL: failing.URL}, {URL: ts.URL}}}, nodes)
+	assert.NoError(err)
+	assert.Eventually(func() bool {
+		nodes.RLock()
+		defer nodes.RUnlock()
+		return nodes.List["000000000001"] != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	f.Close()
+
+	assert.Equal("node1", nodes.List["000000000001"].Nodeinfo.Hostname)
+	assert.Empty(nodes.List["000000000001"].Origin)
+}
+
+func TestFederationMeshviewer(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&meshviewerFFRGB.Meshviewer{Nodes: []*meshviewerFFRGB.Node{
+			{NodeID: "000000000001", Hostname: "neighbour", IsOnline: true, Lastseen: jsontime.Now()},
+		}})
+	}))
+	defer ts.Close()
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	f, err := Start(Config{Remotes: []RemoteConfig{{URL: ts.URL, Format: FormatFFRGB, Origin: "ffxx"}}}, nodes)
+	assert.NoError(err)
+	assert.Eventually(func() bool {
+		nodes.RLock()
+		defer nodes.RUnlock()
+		return nodes.List["000000000001"] != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	f.Close()
+
+	assert.Equal("neighbour", nodes.List["000000000001"].Nodeinfo.Hostname)
+	assert.Equal("ffxx", nodes.List["000000000001"].Origin)
+}
@@ -26,6 +26,9 @@ func (d *Duration) UnmarshalText(data []byte) error {
 	}
 
 	unit := data[len(data)-1]
+	if unit >= '0' && unit <= '9' {
+		return fmt.Errorf("missing unit in duration \"%s\" (valid units are s, m, h, d, w and y)", data)
+	}
 	value, err := strconv.Atoi(string(data[:len(data)-1]))
 	if err != nil {
 		return errors.Wrapf(err, "unable to parse duration \"%s\"", data)
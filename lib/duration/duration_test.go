@@ -19,6 +19,7 @@ func TestDuration(t *testing.T) {
 		{"3", "invalid duration: \"3\"", 0},
 		{"am", "unable to parse duration \"am\": strconv.Atoi: parsing \"a\": invalid syntax", 0},
 		{"1x", "invalid duration unit \"x\"", 0},
+		{"60", "missing unit in duration \"60\" (valid units are s, m, h, d, w and y)", 0},
 		{"1s", "", time.Second},
 		{"73s", "", time.Second * 73},
 		{"1m", "", time.Minute},
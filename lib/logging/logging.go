@@ -0,0 +1,142 @@
+// Package logging configures the format and the levels per module of the log output
+package logging
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/bdlm/log"
+	"github.com/bdlm/std/logger"
+)
+
+// modulePrefix is the import path of yanic, the module of a log entry is the package below it
+const modulePrefix = "github.com/FreifunkBremen/yanic/"
+
+// formats of the log output
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var levelNames = map[string]logger.Level{
+	"fatal": log.FatalLevel,
+	"panic": log.PanicLevel,
+	"error": log.ErrorLevel,
+	"warn":  log.WarnLevel,
+	"info":  log.InfoLevel,
+	"debug": log.DebugLevel,
+	"trace": logger.Trace,
+}
+
+// ParseLevel parses a level by its name (e.g. "debug") or its number (e.g. 50)
+func ParseLevel(s string) (logger.Level, error) {
+	if level, ok := levelNames[strings.ToLower(s)]; ok {
+		return level, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return logger.Level(n), nil
+}
+
+// ModuleFormatter drops the entries above the level of their module, before they are formatted.
+// The logger itself has to log up to the highest level of all modules.
+type ModuleFormatter struct {
+	log.Formatter
+	Level   logger.Level            // of the modules without an own level
+	Modules map[string]logger.Level // by the package path below yanic, e.g. "respond" or "database/influxdb"
+}
+
+// Format implements log.Formatter, a dropped entry is formatted to nothing
+func (f *ModuleFormatter) Format(entry *log.Entry) ([]byte, error) {
+	if entry.Level > f.level(callerModule()) {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// level returns the level of a module, a module inherits the level of its parent (e.g. "database" for "database/influxdb")
+func (f *ModuleFormatter) level(module string) logger.Level {
+	for module != "" {
+		if level, ok := f.Modules[module]; ok {
+			return level
+		}
+		i := strings.LastIndex(module, "/")
+		if i < 0 {
+			break
+		}
+		module = module[:i]
+	}
+	return f.Level
+}
+
+// callerModule returns the module of the first caller outside of the logger
+func callerModule() string {
+	pc := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.Function, "github.com/bdlm/") && !strings.HasPrefix(frame.Function, modulePrefix+"lib/logging.") {
+			return functionModule(frame.Function)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// functionModule returns the package path below yanic of a function name,
+// e.g. "database/influxdb" of "github.com/FreifunkBremen/yanic/database/influxdb.(*Connection).addWorker"
+func functionModule(function string) string {
+	if !strings.HasPrefix(function, modulePrefix) {
+		return ""
+	}
+	module := strings.TrimPrefix(function, modulePrefix)
+	slash := strings.LastIndex(module, "/")
+	if dot := strings.Index(module[slash+1:], "."); dot >= 0 {
+		module = module[:slash+1+dot]
+	}
+	return module
+}
+
+// Setup configures the standard logger with the format, the level and the levels by module (e.g. "respond": "debug")
+func Setup(format string, level logger.Level, modules map[string]string, disableTimestamp bool) error {
+	var formatter log.Formatter
+	switch format {
+	case FormatText, "":
+		formatter = &log.TextFormatter{DisableTimestamp: disableTimestamp}
+	case FormatJSON:
+		formatter = &log.JSONFormatter{DisableTimestamp: disableTimestamp}
+	default:
+		return fmt.Errorf("invalid log format %q, expected %q or %q", format, FormatText, FormatJSON)
+	}
+
+	if len(modules) == 0 {
+		log.SetFormatter(formatter)
+		log.SetLevel(level)
+		return nil
+	}
+
+	moduleFormatter := &ModuleFormatter{
+		Formatter: formatter,
+		Level:     level,
+		Modules:   make(map[string]logger.Level, len(modules)),
+	}
+	max := level
+	for module, s := range modules {
+		moduleLevel, err := ParseLevel(s)
+		if err != nil {
+			return fmt.Errorf("module %s: %s", module, err)
+		}
+		moduleFormatter.Modules[strings.Trim(module, "/")] = moduleLevel
+		if moduleLevel > max {
+			max = moduleLevel
+		}
+	}
+	log.SetFormatter(moduleFormatter)
+	log.SetLevel(max)
+	return nil
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/bdlm/log"
+	"github.com/bdlm/std/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	level, err := ParseLevel("debug")
+	assert.NoError(err)
+	assert.Equal(log.DebugLevel, level)
+
+	level, err = ParseLevel("WARN")
+	assert.NoError(err)
+	assert.Equal(log.WarnLevel, level)
+
+	level, err = ParseLevel("40")
+	assert.NoError(err)
+	assert.Equal(log.InfoLevel, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(err)
+}
+
+func TestFunctionModule(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("database/influxdb", functionModule("github.com/FreifunkBremen/yanic/database/influxdb.(*Connection).addWorker"))
+	assert.Equal("respond", functionModule("github.com/FreifunkBremen/yanic/respond.(*Collector).parser.func1"))
+	assert.Equal("cmd", functionModule("github.com/FreifunkBremen/yanic/cmd.glob..func1"))
+	assert.Equal("", functionModule("main.main"))
+	assert.Equal("", functionModule("net/http.(*conn).serve"))
+}
+
+func TestModuleFormatter(t *testing.T) {
+	assert := assert.New(t)
+
+	f := &ModuleFormatter{
+		Formatter: &log.JSONFormatter{},
+		Level:     log.WarnLevel,
+		Modules: map[string]logger.Level{
+			"database":          log.DebugLevel,
+			"database/influxdb": log.ErrorLevel,
+		},
+	}
+	assert.Equal(log.WarnLevel, f.level("respond"))
+	assert.Equal(log.WarnLevel, f.level(""))
+	assert.Equal(log.DebugLevel, f.level("database"))
+	assert.Equal(log.DebugLevel, f.level("database/graphite"))
+	assert.Equal(log.ErrorLevel, f.level("database/influxdb"))
+
+	// the entries of the tests have no module
+	entry := log.NewEntry(log.New())
+	entry.Message = "message"
+	entry.Level = log.InfoLevel
+	out, err := f.Format(entry)
+	assert.NoError(err)
+	assert.Empty(out)
+
+	entry.Level = log.WarnLevel
+	out, err = f.Format(entry)
+	assert.NoError(err)
+	assert.Contains(string(out), "message")
+}
+
+func TestSetup(t *testing.T) {
+	assert := assert.New(t)
+	defer log.SetLevel(log.GetLevel())
+
+	assert.Error(Setup("xml", log.InfoLevel, nil, false))
+	assert.Error(Setup(FormatJSON, log.InfoLevel, map[string]string{"respond": "verbose"}, false))
+
+	assert.NoError(Setup(FormatJSON, log.InfoLevel, map[string]string{"respond": "debug", "database/": "error"}, false))
+	// the logger logs up to the most verbose module
+	assert.Equal(log.DebugLevel, log.GetLevel())
+	f, ok := log.StandardLogger().Formatter.(*ModuleFormatter)
+	if assert.True(ok) {
+		assert.Equal(log.ErrorLevel, f.Modules["database"])
+		assert.IsType(&log.JSONFormatter{}, f.Formatter)
+	}
+
+	assert.NoError(Setup(FormatText, log.InfoLevel, nil, false))
+	assert.Equal(log.InfoLevel, log.GetLevel())
+	assert.IsType(&log.TextFormatter{}, log.StandardLogger().Formatter)
+}
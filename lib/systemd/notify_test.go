@@ -0,0 +1,71 @@
+package systemd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotify(t *testing.T) {
+	assert := assert.New(t)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	// not supervised
+	os.Unsetenv("NOTIFY_SOCKET")
+	sent, err := Notify(StateReady)
+	assert.NoError(err)
+	assert.False(sent)
+
+	dir, err := ioutil.TempDir("", "yanic-systemd")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unsupported: %s", err)
+	}
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", path)
+	sent, err = Notify(StateReady)
+	assert.NoError(err)
+	assert.True(sent)
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	assert.NoError(err)
+	assert.Equal(StateReady, string(buf[:n]))
+
+	os.Setenv("NOTIFY_SOCKET", filepath.Join(dir, "missing"))
+	_, err = Notify(StateReady)
+	assert.Error(err)
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	assert := assert.New(t)
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	os.Unsetenv("WATCHDOG_USEC")
+	assert.Zero(WatchdogInterval())
+
+	os.Setenv("WATCHDOG_USEC", "30000000")
+	assert.Equal(30*time.Second, WatchdogInterval())
+
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	assert.Equal(30*time.Second, WatchdogInterval())
+
+	os.Setenv("WATCHDOG_PID", "1")
+	assert.Zero(WatchdogInterval())
+
+	os.Setenv("WATCHDOG_PID", "")
+	os.Setenv("WATCHDOG_USEC", "invalid")
+	assert.Zero(WatchdogInterval())
+}
@@ -0,0 +1,56 @@
+package notify
+
+import "fmt"
+
+// formats of the payload of a webhook
+const (
+	FormatJSON   = "json"
+	FormatSlack  = "slack"
+	FormatMatrix = "matrix"
+)
+
+// Config of the notifications
+type Config struct {
+	Enable   bool            `toml:"enable"`
+	Webhooks []WebhookConfig `toml:"webhook"`
+	Rules    []RuleConfig    `toml:"rule"`
+}
+
+// WebhookConfig is an endpoint, which is called on events
+type WebhookConfig struct {
+	URL    string   `toml:"url"`
+	Format string   `toml:"format"` // payload: json (default), slack or matrix
+	Token  string   `toml:"token"`  // sent as bearer token, e.g. the access token of matrix
+	Events []string `toml:"events"` // types of the events, which are sent (default all)
+	Nodes  []string `toml:"nodes"`  // IDs of the nodes, of which the events are sent (default all)
+}
+
+func (c *WebhookConfig) validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("webhook without url")
+	}
+	switch c.Format {
+	case "", FormatJSON, FormatSlack, FormatMatrix:
+	default:
+		return fmt.Errorf("webhook %s: unknown format %q", c.URL, c.Format)
+	}
+	return nil
+}
+
+// accepts returns whether the event should be sent to the webhook
+func (c *WebhookConfig) accepts(event *Event) bool {
+	return contains(c.Events, event.Type) && contains(c.Nodes, event.NodeID)
+}
+
+// contains returns true if the list is empty or contains the value
+func contains(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// types of the events
+const (
+	EventOnline  = "online"
+	EventOffline = "offline"
+	EventReboot  = "reboot"
+)
+
+// Event is sent to the webhooks
+type Event struct {
+	Type     string    `json:"event"`
+	NodeID   string    `json:"node_id"`
+	Hostname string    `json:"hostname,omitempty"`
+	Site     string    `json:"site,omitempty"`
+	Domain   string    `json:"domain,omitempty"`
+	Time     time.Time `json:"time"`
+	Lastseen time.Time `json:"lastseen"`
+	Message  string    `json:"message"`
+}
+
+// NewNodeEvent creates an event of a node with the message
+func NewNodeEvent(eventType, nodeID string, node *runtime.Node, t time.Time, message string) *Event {
+	event := &Event{
+		Type:     eventType,
+		NodeID:   nodeID,
+		Time:     t,
+		Lastseen: node.Lastseen.GetTime(),
+		Message:  message,
+	}
+	if nodeinfo := node.Nodeinfo; nodeinfo != nil {
+		event.Hostname = nodeinfo.Hostname
+		event.Site = nodeinfo.System.SiteCode
+		event.Domain = nodeinfo.System.DomainCode
+	}
+	return event
+}
+
+// newStateEvent creates the event of a state change
+func newStateEvent(change runtime.StateChange) *Event {
+	if change.Online {
+		return NewNodeEvent(EventOnline, change.NodeID, &change.Node, change.Time, "is online again")
+	}
+	return NewNodeEvent(EventOffline, change.NodeID, &change.Node, change.Time, "is offline")
+}
+
+// newRebootEvent creates the event of a reboot
+func newRebootEvent(reboot runtime.Reboot) *Event {
+	return NewNodeEvent(EventReboot, reboot.NodeID, &reboot.Node, reboot.Time, "rebooted")
+}
+
+// name returns the hostname and the node ID of the node
+func (event *Event) name() string {
+	if event.Hostname == "" {
+		return event.NodeID
+	}
+	return fmt.Sprintf("%s (%s)", event.Hostname, event.NodeID)
+}
+
+// Text returns the event as a message for a chat
+func (event *Event) Text() string {
+	text := fmt.Sprintf("Node %s %s", event.name(), event.Message)
+	if event.Site != "" {
+		text += fmt.Sprintf(" [%s", event.Site)
+		if event.Domain != "" {
+			text += "/" + event.Domain
+		}
+		text += "]"
+	}
+	return text
+}
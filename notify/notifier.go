@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	queueSize   = 100              // events waiting to be sent
+	sendTimeout = 10 * time.Second // of a single request to a webhook
+)
+
+// Notifier sends the events to the configured webhooks
+type Notifier struct {
+	webhooks []WebhookConfig
+	rules    rules
+	client   *http.Client
+	queue    chan *Event
+	wg       sync.WaitGroup
+	closed   bool
+	sub      *runtime.Subscription // of the rules
+	mu       sync.Mutex            // protects closed, sub and the sending to the queue
+	txnID    uint64                // increasing transaction id of the matrix requests
+}
+
+// New creates a notifier and starts its worker
+func New(config Config) (*Notifier, error) {
+	for i := range config.Webhooks {
+		if err := config.Webhooks[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range config.Rules {
+		if err := config.Rules[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	n := &Notifier{
+		webhooks: config.Webhooks,
+		rules:    rules{rules: config.Rules, states: make(map[string]*ruleState)},
+		client:   &http.Client{Timeout: sendTimeout},
+		queue:    make(chan *Event, queueSize),
+	}
+	n.wg.Add(1)
+	go n.worker()
+	return n, nil
+}
+
+// Watch sends an event on every change of a node from online to offline or back and on every reboot,
+// and checks the updates of the nodes against the rules
+func (n *Notifier) Watch(nodes *runtime.Nodes) {
+	nodes.OnStateChange(func(change runtime.StateChange) {
+		n.Notify(newStateEvent(change))
+	})
+	nodes.OnReboot(func(reboot runtime.Reboot) {
+		n.Notify(newRebootEvent(reboot))
+	})
+	if len(n.rules.rules) > 0 {
+		n.subscribe(nodes)
+	}
+}
+
+// Notify queues the event, it is dropped if the queue is full
+func (n *Notifier) Notify(event *Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- event:
+	default:
+		log.WithField("node_id", event.NodeID).Warnf("notify: queue is full, %s event dropped", event.Type)
+	}
+}
+
+// Close sends the queued events and stops the worker
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+		if n.sub != nil {
+			n.sub.Close()
+		}
+	}
+	n.mu.Unlock()
+	n.wg.Wait()
+}
+
+func (n *Notifier) worker() {
+	defer n.wg.Done()
+	for event := range n.queue {
+		for i := range n.webhooks {
+			webhook := &n.webhooks[i]
+			if !webhook.accepts(event) {
+				continue
+			}
+			if err := n.send(webhook, event); err != nil {
+				log.WithField("url", webhook.URL).Errorf("notify: %s", err)
+			}
+		}
+	}
+}
+
+// send calls the webhook with the event in its format
+func (n *Notifier) send(webhook *WebhookConfig, event *Event) error {
+	method, url := http.MethodPost, webhook.URL
+	var payload interface{}
+	switch webhook.Format {
+	case FormatSlack:
+		payload = map[string]string{"text": event.Text()}
+	case FormatMatrix:
+		// the url is .../_matrix/client/r0/rooms/<room>/send/m.room.message, a transaction id is appended
+		method = http.MethodPut
+		txnID := strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(atomic.AddUint64(&n.txnID, 1), 10)
+		url += "/" + txnID
+		payload = map[string]string{"msgtype": "m.text", "body": event.Text()}
+	default:
+		payload = event
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if webhook.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+webhook.Token)
+	}
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", res.Status)
+	}
+	return nil
+}
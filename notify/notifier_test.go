@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+type request struct {
+	method string
+	path   string
+	auth   string
+	body   map[string]interface{}
+}
+
+// recorder is a webhook, which records the requests
+type recorder struct {
+	requests []request
+	sync.Mutex
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	rec.Lock()
+	rec.requests = append(rec.requests, request{method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization"), body: body})
+	rec.Unlock()
+}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := New(Config{Webhooks: []WebhookConfig{{}}})
+	assert.Error(err)
+	_, err = New(Config{Webhooks: []WebhookConfig{{URL: "http://localhost", Format: "irc"}}})
+	assert.Error(err)
+
+	n, err := New(Config{Webhooks: []WebhookConfig{{URL: "http://localhost", Format: FormatSlack}}})
+	assert.NoError(err)
+	n.Close()
+	n.Close()
+	// ignored after close
+	n.Notify(&Event{Type: EventOffline})
+}
+
+func TestFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	rec := &recorder{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+
+	n, err := New(Config{Webhooks: []WebhookConfig{
+		{URL: ts.URL + "/json"},
+		{URL: ts.URL + "/slack", Format: FormatSlack, Events: []string{EventOffline}},
+		{URL: ts.URL + "/matrix", Format: FormatMatrix, Token: "secret", Nodes: []string{"000000000001"}},
+	}})
+	assert.NoError(err)
+
+	event := &Event{Type: EventOffline, NodeID: "000000000001", Hostname: "node1", Site: "ffhb", Domain: "city", Message: "is offline"}
+	assert.Equal("Node node1 (000000000001) is offline [ffhb/city]", event.Text())
+	n.Notify(event)
+	n.Notify(&Event{Type: EventOnline, NodeID: "000000000002", Message: "is online again"})
+	n.Close()
+
+	assert.Len(rec.requests, 4)
+	byPath := make(map[string][]request)
+	for _, req := range rec.requests {
+		path := req.path
+		if strings.HasPrefix(path, "/matrix/") {
+			path = "/matrix"
+		}
+		byPath[path] = append(byPath[path], req)
+	}
+
+	assert.Len(byPath["/json"], 2)
+	assert.Equal(http.MethodPost, byPath["/json"][0].method)
+	assert.Equal("offline", byPath["/json"][0].body["event"])
+	assert.Equal("000000000001", byPath["/json"][0].body["node_id"])
+	assert.Equal("online", byPath["/json"][1].body["event"])
+
+	assert.Len(byPath["/slack"], 1)
+	assert.Equal("Node node1 (000000000001) is offline [ffhb/city]", byPath["/slack"][0].body["text"])
+
+	assert.Len(byPath["/matrix"], 1)
+	assert.Equal(http.MethodPut, byPath["/matrix"][0].method)
+	assert.Equal("Bearer secret", byPath["/matrix"][0].auth)
+	assert.Equal("m.text", byPath["/matrix"][0].body["msgtype"])
+	assert.Equal("Node node1 (000000000001) is offline [ffhb/city]", byPath["/matrix"][0].body["body"])
+}
+
+func TestWatch(t *testing.T) {
+	assert := assert.New(t)
+
+	rec := &recorder{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+
+	n, err := New(Config{Webhooks: []WebhookConfig{{URL: ts.URL}}})
+	assert.NoError(err)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	n.Watch(nodes)
+
+	node := nodes.Update("000000000001", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"},
+	})
+	node.Online = false
+	nodes.Update("000000000001", &data.ResponseData{
+		Nodeinfo:   &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"},
+		Statistics: &data.Statistics{Uptime: 3600},
+	})
+	nodes.Update("000000000001", &data.ResponseData{
+		Nodeinfo:   &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"},
+		Statistics: &data.Statistics{Uptime: 10},
+	})
+	n.Close()
+
+	assert.Len(rec.requests, 2)
+	assert.Equal("online", rec.requests[0].body["event"])
+	assert.Equal("node1", rec.requests[0].body["hostname"])
+	assert.NotEmpty(rec.requests[0].body["time"])
+	_, err = time.Parse(time.RFC3339, rec.requests[0].body["time"].(string))
+	assert.NoError(err)
+
+	assert.Equal("reboot", rec.requests[1].body["event"])
+	assert.Equal("rebooted", rec.requests[1].body["message"])
+}
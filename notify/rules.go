@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/lib/duration"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// types of the events of the rules
+const (
+	EventAlert    = "alert"
+	EventResolved = "resolved"
+)
+
+// metrics of the rules
+const (
+	MetricLoad        = "load"
+	MetricRootfsUsage = "rootfs_usage" // in percent
+	MetricClients     = "clients"
+)
+
+// ruleBuffer is the maximum of nodes with pending updates of the rules
+const ruleBuffer = 100000
+
+// RuleConfig is a threshold of a statistic of the nodes
+type RuleConfig struct {
+	Name      string            `toml:"name"`
+	Metric    string            `toml:"metric"`
+	Operator  string            `toml:"operator"` // ">" (default) or "<"
+	Threshold float64           `toml:"threshold"`
+	Cooldown  duration.Duration `toml:"cooldown"` // minimum period between two alerts of a node
+	Nodes     []string          `toml:"nodes"`    // IDs of the nodes, which are checked (default all)
+}
+
+func (c *RuleConfig) validate() error {
+	switch c.Metric {
+	case MetricLoad, MetricRootfsUsage, MetricClients:
+	default:
+		return fmt.Errorf("rule %s: unknown metric %q", c.Name, c.Metric)
+	}
+	switch c.Operator {
+	case "", ">", "<":
+	default:
+		return fmt.Errorf("rule %s: unknown operator %q", c.Name, c.Operator)
+	}
+	if c.Name == "" {
+		c.Name = fmt.Sprintf("%s %s %g", c.Metric, c.operator(), c.Threshold)
+	}
+	return nil
+}
+
+func (c *RuleConfig) operator() string {
+	if c.Operator == "" {
+		return ">"
+	}
+	return c.Operator
+}
+
+// value returns the metric of the node, false if the node has no statistics of it
+func (c *RuleConfig) value(node *runtime.Node) (float64, bool) {
+	stats := node.Statistics
+	if stats == nil {
+		return 0, false
+	}
+	switch c.Metric {
+	case MetricLoad:
+		return stats.LoadAverage, true
+	case MetricRootfsUsage:
+		return stats.RootFsUsage * 100, true
+	case MetricClients:
+		return float64(stats.Clients.Total), true
+	}
+	return 0, false
+}
+
+// exceeded returns whether the value violates the threshold
+func (c *RuleConfig) exceeded(value float64) bool {
+	if c.operator() == "<" {
+		return value < c.Threshold
+	}
+	return value > c.Threshold
+}
+
+// ruleState is the state of a rule of a single node
+type ruleState struct {
+	active    bool      // an alert was sent and not resolved yet
+	lastAlert time.Time // of the cooldown
+}
+
+// rules checks the updates of the nodes against the rules
+type rules struct {
+	rules  []RuleConfig
+	states map[string]*ruleState // by rule index and node ID
+	sync.Mutex
+}
+
+// check returns the events of the rules for the update of a node,
+// an alert is sent once when the threshold is violated and again at most after the cooldown
+func (r *rules) check(nodeID string, node *runtime.Node, now time.Time) []*Event {
+	r.Lock()
+	defer r.Unlock()
+
+	var events []*Event
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !contains(rule.Nodes, nodeID) {
+			continue
+		}
+		value, ok := rule.value(node)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%d/%s", i, nodeID)
+		state := r.states[key]
+		if state == nil {
+			state = &ruleState{}
+			r.states[key] = state
+		}
+
+		if rule.exceeded(value) {
+			if state.active || now.Sub(state.lastAlert) < rule.Cooldown.Duration {
+				continue
+			}
+			state.active = true
+			state.lastAlert = now
+			events = append(events, NewNodeEvent(EventAlert, nodeID, node, now,
+				fmt.Sprintf("%s: %s is %g", rule.Name, rule.Metric, value)))
+		} else if state.active {
+			state.active = false
+			events = append(events, NewNodeEvent(EventResolved, nodeID, node, now,
+				fmt.Sprintf("%s resolved: %s is %g", rule.Name, rule.Metric, value)))
+		}
+	}
+	return events
+}
+
+// subscribe starts to check the updates of the nodes against the rules
+func (n *Notifier) subscribe(nodes *runtime.Nodes) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	n.sub = nodes.Subscribe(runtime.SubscriptionConfig{Buffer: ruleBuffer})
+	n.wg.Add(1)
+	go n.watchRules(nodes, n.sub)
+}
+
+// watchRules checks the updates of the nodes until the subscription is closed,
+// it subscribes again if the subscription was dropped
+func (n *Notifier) watchRules(nodes *runtime.Nodes, sub *runtime.Subscription) {
+	defer n.wg.Done()
+	for update := range sub.C {
+		for _, event := range n.rules.check(update.NodeID, update.Node, time.Now()) {
+			n.Notify(event)
+		}
+	}
+	if sub.Dropped() {
+		log.Warn("notify: rules do not keep up with the updates of the nodes")
+		n.subscribe(nodes)
+	}
+}
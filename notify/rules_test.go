@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/lib/duration"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestRuleValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Error((&RuleConfig{Metric: "uptime"}).validate())
+	assert.Error((&RuleConfig{Metric: MetricLoad, Operator: ">="}).validate())
+
+	rule := &RuleConfig{Metric: MetricRootfsUsage, Threshold: 90}
+	assert.NoError(rule.validate())
+	assert.Equal("rootfs_usage > 90", rule.Name)
+
+	_, err := New(Config{Rules: []RuleConfig{{Metric: "uptime"}}})
+	assert.Error(err)
+}
+
+func TestRules(t *testing.T) {
+	assert := assert.New(t)
+
+	r := &rules{states: make(map[string]*ruleState), rules: []RuleConfig{
+		{Name: "high load", Metric: MetricLoad, Threshold: 5, Cooldown: duration.Duration{Duration: time.Hour}},
+		{Metric: MetricRootfsUsage, Threshold: 90, Nodes: []string{"000000000002"}},
+		{Metric: MetricClients, Operator: "<", Threshold: 1},
+	}}
+	for i := range r.rules {
+		assert.NoError(r.rules[i].validate())
+	}
+
+	node := func(load, rootfs float64, clients uint32) *runtime.Node {
+		return &runtime.Node{Statistics: &data.Statistics{
+			LoadAverage: load,
+			RootFsUsage: rootfs,
+			Clients:     data.Clients{Total: clients},
+		}}
+	}
+	now := time.Now()
+
+	// no statistics
+	assert.Empty(r.check("000000000001", &runtime.Node{}, now))
+
+	events := r.check("000000000001", node(6, 0.95, 3), now)
+	assert.Len(events, 1)
+	assert.Equal(EventAlert, events[0].Type)
+	assert.Equal("high load: load is 6", events[0].Message)
+
+	// deduplicated
+	assert.Empty(r.check("000000000001", node(7, 0.95, 3), now.Add(time.Minute)))
+
+	events = r.check("000000000001", node(1, 0.95, 3), now.Add(2*time.Minute))
+	assert.Len(events, 1)
+	assert.Equal(EventResolved, events[0].Type)
+
+	// within the cooldown
+	assert.Empty(r.check("000000000001", node(6, 0.95, 3), now.Add(3*time.Minute)))
+	events = r.check("000000000001", node(6, 0.95, 3), now.Add(61*time.Minute))
+	assert.Len(events, 1)
+	assert.Equal(EventAlert, events[0].Type)
+
+	// rootfs only of the second node and clients below
+	events = r.check("000000000002", node(1, 0.95, 0), now)
+	assert.Len(events, 2)
+	assert.Equal("rootfs_usage > 90: rootfs_usage is 95", events[0].Message)
+	assert.Equal("clients < 1: clients is 0", events[1].Message)
+}
+
+func TestWatchRules(t *testing.T) {
+	assert := assert.New(t)
+
+	rec := &recorder{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+
+	n, err := New(Config{
+		Webhooks: []WebhookConfig{{URL: ts.URL}},
+		Rules:    []RuleConfig{{Metric: MetricClients, Threshold: 150}},
+	})
+	assert.NoError(err)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	n.Watch(nodes)
+	nodes.Update("000000000001", &data.ResponseData{
+		Nodeinfo:   &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"},
+		Statistics: &data.Statistics{Clients: data.Clients{Total: 151}},
+	})
+
+	assert.Eventually(func() bool {
+		rec.Lock()
+		defer rec.Unlock()
+		return len(rec.requests) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	n.Close()
+
+	assert.Equal(EventAlert, rec.requests[0].body["event"])
+	assert.Equal("node1", rec.requests[0].body["hostname"])
+}
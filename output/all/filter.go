@@ -1,11 +1,16 @@
 package all
 
 import (
+	_ "github.com/FreifunkBremen/yanic/output/filter/anonymize"
 	_ "github.com/FreifunkBremen/yanic/output/filter/blocklist"
+	_ "github.com/FreifunkBremen/yanic/output/filter/domain"
 	_ "github.com/FreifunkBremen/yanic/output/filter/domainappendsite"
 	_ "github.com/FreifunkBremen/yanic/output/filter/domainassite"
 	_ "github.com/FreifunkBremen/yanic/output/filter/haslocation"
+	_ "github.com/FreifunkBremen/yanic/output/filter/hostname"
 	_ "github.com/FreifunkBremen/yanic/output/filter/inarea"
+	_ "github.com/FreifunkBremen/yanic/output/filter/nocustomnodeinfo"
 	_ "github.com/FreifunkBremen/yanic/output/filter/noowner"
+	_ "github.com/FreifunkBremen/yanic/output/filter/online"
 	_ "github.com/FreifunkBremen/yanic/output/filter/site"
 )
@@ -11,34 +11,78 @@ import (
 var quit chan struct{}
 var wg = sync.WaitGroup{}
 var outputA output.Output
+var outputNodes *runtime.Nodes
 
 func Start(nodes *runtime.Nodes, config runtime.NodesConfig) (err error) {
 	outputA, err = Register(config.Output)
 	if err != nil {
 		return
 	}
-	quit = make(chan struct{})
-	wg.Add(1)
-	go saveWorker(nodes, config.SaveInterval.Duration)
+	outputNodes = nodes
+	startWorker(config.SaveInterval.Duration)
 	return
 }
 
+// Reload replaces the outputs and the save interval of the started outputs,
+// the previous outputs are kept on an invalid config
+func Reload(config runtime.NodesConfig) error {
+	o, err := Register(config.Output)
+	if err != nil {
+		return err
+	}
+	stopWorker()
+	outputA = o
+	startWorker(config.SaveInterval.Duration)
+	return nil
+}
+
 func Close() {
+	stopWorker()
+	// final save on shutdown
+	if o, ok := outputA.(*Output); ok {
+		o.saveAll(outputNodes)
+	} else {
+		outputA.Save(outputNodes)
+	}
+	quit = nil
+}
+
+// startWorker schedules every output by its own interval (or the save interval),
+// so a slow output does not delay the others
+func startWorker(saveInterval time.Duration) {
+	quit = make(chan struct{})
+	o, ok := outputA.(*Output)
+	if !ok {
+		wg.Add(1)
+		go saveWorker(outputA.Save, saveInterval, quit)
+		return
+	}
+	for _, t := range o.targets {
+		interval := t.interval
+		if interval <= 0 {
+			interval = saveInterval
+		}
+		t := t
+		wg.Add(1)
+		go saveWorker(func(nodes *runtime.Nodes) { t.save(nodes, time.Now()) }, interval, quit)
+	}
+}
+
+func stopWorker() {
 	close(quit)
 	wg.Wait()
-	quit = nil
 }
 
 // save periodically to output
-func saveWorker(nodes *runtime.Nodes, saveInterval time.Duration) {
-	ticker := time.NewTicker(saveInterval)
+func saveWorker(save func(*runtime.Nodes), interval time.Duration, quit chan struct{}) {
+	defer wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			outputA.Save(nodes)
+			save(outputNodes)
 		case <-quit:
-			ticker.Stop()
-			wg.Done()
 			return
 		}
 	}
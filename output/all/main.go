@@ -5,6 +5,7 @@ import (
 	_ "github.com/FreifunkBremen/yanic/output/meshviewer"
 	_ "github.com/FreifunkBremen/yanic/output/meshviewer-ffrgb"
 	_ "github.com/FreifunkBremen/yanic/output/nodelist"
+	_ "github.com/FreifunkBremen/yanic/output/prometheus"
 	_ "github.com/FreifunkBremen/yanic/output/raw"
 	_ "github.com/FreifunkBremen/yanic/output/raw-jsonl"
 )
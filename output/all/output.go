@@ -1,25 +1,52 @@
 package all
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
 
 	"github.com/bdlm/log"
 
+	"github.com/FreifunkBremen/yanic/lib/duration"
 	"github.com/FreifunkBremen/yanic/output"
 	"github.com/FreifunkBremen/yanic/output/filter"
+	"github.com/FreifunkBremen/yanic/output/upload"
 	"github.com/FreifunkBremen/yanic/runtime"
 )
 
+const (
+	// intervalSlack is the tolerance of the interval of an output to the ticks of its scheduler
+	intervalSlack = time.Second
+
+	// execTimeoutDefault is the maximum runtime of the hook of an output
+	execTimeoutDefault = time.Minute
+)
+
 type Output struct {
 	output.Output
-	list         map[int]output.Output
-	outputFilter map[int]filter.Set
+	targets []*target
+}
+
+// target is a configured output with its filters and its own interval
+type target struct {
+	name     string // type of the output
+	output   output.Output
+	filter   filter.Set
+	interval time.Duration // own interval of the output, saved with the save interval if not set
+	lastSave time.Time
+	upload   upload.Uploader // copies the written files after every successful save (optional)
+	exec     []string        // command with its arguments, run after every successful save (optional)
+	timeout  time.Duration   // of the command
+	sync.Mutex
 }
 
 func Register(configuration map[string]interface{}) (output.Output, error) {
-	list := make(map[int]output.Output)
-	outputFilter := make(map[int]filter.Set)
-	i := 1
+	var targets []*target
 	allOutputs := configuration
 	for outputType, outputRegister := range output.Adapters {
 		configForOutput := allOutputs[outputType]
@@ -39,33 +66,161 @@ func Register(configuration map[string]interface{}) (output.Output, error) {
 			if c, ok := config["enable"].(bool); ok && !c {
 				continue
 			}
-			output, err := outputRegister(config)
+			o, err := outputRegister(config)
 			if err != nil {
 				return nil, err
 			}
-			if output == nil {
+			if o == nil {
 				continue
 			}
+			t := &target{name: outputType, output: o}
 			var errs []error
-			var filterSet filter.Set
 			if c := config["filter"]; c != nil {
 				if filterConf, ok := c.(map[string]interface{}); ok {
-					filterSet, errs = filter.New(filterConf)
+					t.filter, errs = filter.New(filterConf)
 				}
 				if len(errs) > 0 {
 					return nil, fmt.Errorf("filter configuration errors: %v", errs)
 				}
-				outputFilter[i] = filterSet
 			}
-			list[i] = output
-			i++
+			if c := config["interval"]; c != nil {
+				value, ok := c.(string)
+				if !ok {
+					return nil, fmt.Errorf("the interval of output type '%s' has the wrong format", outputType)
+				}
+				var interval duration.Duration
+				if err := interval.UnmarshalText([]byte(value)); err != nil {
+					return nil, fmt.Errorf("the interval of output type '%s' is invalid: %s", outputType, err)
+				}
+				t.interval = interval.Duration
+			}
+			if c := config["upload"]; c != nil {
+				uploadConf, ok := c.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("the upload of output type '%s' has the wrong format", outputType)
+				}
+				if _, ok := o.(output.FileOutput); !ok {
+					return nil, fmt.Errorf("the output type '%s' writes no files to upload", outputType)
+				}
+				if t.upload, err = upload.New(uploadConf); err != nil {
+					return nil, fmt.Errorf("the upload of output type '%s' is invalid: %s", outputType, err)
+				}
+			}
+			if err := t.readExec(config); err != nil {
+				return nil, fmt.Errorf("output type '%s': %s", outputType, err)
+			}
+			targets = append(targets, t)
 		}
 	}
-	return &Output{list: list, outputFilter: outputFilter}, nil
+	return &Output{targets: targets}, nil
 }
 
 func (o *Output) Save(nodes *runtime.Nodes) {
-	for i, item := range o.list {
-		item.Save(o.outputFilter[i].Apply(nodes))
+	o.save(nodes, time.Now())
+}
+
+// saveAll saves every output, regardless of its own interval
+func (o *Output) saveAll(nodes *runtime.Nodes) {
+	for _, t := range o.targets {
+		t.save(nodes, time.Now())
+	}
+}
+
+// save saves every output, whose own interval is elapsed
+func (o *Output) save(nodes *runtime.Nodes, now time.Time) {
+	for _, t := range o.targets {
+		if t.due(now) {
+			t.save(nodes, now)
+		}
+	}
+}
+
+// due returns whether the own interval of the output is elapsed
+func (t *target) due(now time.Time) bool {
+	if t.interval <= 0 {
+		return true
+	}
+	t.Lock()
+	defer t.Unlock()
+	return t.lastSave.IsZero() || now.Sub(t.lastSave)+intervalSlack >= t.interval
+}
+
+// readExec reads the hook of the output
+func (t *target) readExec(config map[string]interface{}) error {
+	t.timeout = execTimeoutDefault
+	if c := config["exec_timeout"]; c != nil {
+		value, ok := c.(string)
+		if !ok {
+			return errors.New("exec_timeout has the wrong format")
+		}
+		var timeout duration.Duration
+		if err := timeout.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("exec_timeout is invalid: %s", err)
+		}
+		t.timeout = timeout.Duration
+	}
+	c := config["exec"]
+	if c == nil {
+		return nil
+	}
+	values, ok := c.([]interface{})
+	if !ok || len(values) == 0 {
+		return errors.New("exec has the wrong format, array of the command and its arguments expected")
+	}
+	for _, value := range values {
+		arg, ok := value.(string)
+		if !ok {
+			return errors.New("exec has the wrong format, array of strings expected")
+		}
+		t.exec = append(t.exec, arg)
+	}
+	return nil
+}
+
+// save applies the filters and saves the output, the files are uploaded and the hook is run after a successful save
+func (t *target) save(nodes *runtime.Nodes, now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	t.lastSave = now
+	start := time.Now()
+	if err := t.saveOutput(nodes); err != nil {
+		log.WithField("output", t.name).Errorf("unable to save: %s", err)
+		return
+	}
+	log.WithField("output", t.name).Debugf("saved in %s", time.Since(start))
+
+	if t.upload != nil {
+		if err := t.upload.Upload(t.output.(output.FileOutput).Paths()); err != nil {
+			log.WithField("output", t.name).Errorf("unable to upload: %s", err)
+		}
+	}
+	if len(t.exec) > 0 {
+		if err := t.runExec(); err != nil {
+			log.WithField("output", t.name).Errorf("exec %s failed: %s", t.exec[0], err)
+		}
+	}
+}
+
+// saveOutput saves the output, a panic of the output (e.g. on a failed write) is returned as error
+func (t *target) saveOutput(nodes *runtime.Nodes) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	t.output.Save(t.filter.Apply(nodes))
+	return nil
+}
+
+// runExec runs the hook of the output, e.g. to copy the written files to a webserver
+func (t *target) runExec() error {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, t.exec[0], t.exec[1:]...)
+	cmd.Env = append(os.Environ(), "YANIC_OUTPUT="+t.name)
+	output, err := cmd.CombinedOutput()
+	if err != nil && len(output) > 0 {
+		return fmt.Errorf("%s: %s", err, bytes.TrimSpace(output))
 	}
+	return err
 }
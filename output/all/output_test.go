@@ -2,9 +2,16 @@ package all
 
 import (
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/FreifunkBremen/yanic/lib/duration"
 	"github.com/FreifunkBremen/yanic/output"
 	"github.com/FreifunkBremen/yanic/runtime"
 	"github.com/stretchr/testify/assert"
@@ -119,3 +126,281 @@ func TestStart(t *testing.T) {
 	})
 	assert.Error(err)
 }
+
+func TestSaveInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := &runtime.Nodes{}
+
+	intervalOutput := &testOutput{}
+	output.RegisterAdapter("interval", func(config map[string]interface{}) (output.Output, error) {
+		return intervalOutput, nil
+	})
+	defer delete(output.Adapters, "interval")
+
+	allOutput, err := Register(map[string]interface{}{
+		"interval": []interface{}{
+			map[string]interface{}{
+				"interval": "5m",
+			},
+		},
+	})
+	assert.NoError(err)
+
+	now := time.Now()
+	o := allOutput.(*Output)
+	o.save(nodes, now)
+	assert.Equal(1, intervalOutput.Get())
+	o.save(nodes, now.Add(time.Minute))
+	assert.Equal(1, intervalOutput.Get())
+	// slightly early tick
+	o.save(nodes, now.Add(5*time.Minute-time.Millisecond))
+	assert.Equal(2, intervalOutput.Get())
+
+	// invalid interval
+	_, err = Register(map[string]interface{}{
+		"interval": []interface{}{
+			map[string]interface{}{
+				"interval": "5x",
+			},
+		},
+	})
+	assert.Error(err)
+	_, err = Register(map[string]interface{}{
+		"interval": []interface{}{
+			map[string]interface{}{
+				"interval": 5,
+			},
+		},
+	})
+	assert.Error(err)
+}
+
+func TestCloseSaves(t *testing.T) {
+	assert := assert.New(t)
+
+	finalOutput := &testOutput{}
+	output.RegisterAdapter("final", func(config map[string]interface{}) (output.Output, error) {
+		return finalOutput, nil
+	})
+	defer delete(output.Adapters, "final")
+
+	err := Start(&runtime.Nodes{}, runtime.NodesConfig{
+		SaveInterval: duration.Duration{Duration: time.Hour},
+		Output: map[string]interface{}{
+			"final": []interface{}{
+				map[string]interface{}{
+					"interval": "2h",
+				},
+			},
+		},
+	})
+	assert.NoError(err)
+	assert.Equal(0, finalOutput.Get())
+
+	Close()
+	assert.Equal(1, finalOutput.Get())
+}
+
+func TestReload(t *testing.T) {
+	assert := assert.New(t)
+
+	oldOutput := &testOutput{}
+	output.RegisterAdapter("old", func(config map[string]interface{}) (output.Output, error) {
+		return oldOutput, nil
+	})
+	defer delete(output.Adapters, "old")
+	newOutput := &testOutput{}
+	output.RegisterAdapter("new", func(config map[string]interface{}) (output.Output, error) {
+		return newOutput, nil
+	})
+	defer delete(output.Adapters, "new")
+
+	config := runtime.NodesConfig{
+		SaveInterval: duration.Duration{Duration: time.Hour},
+		Output: map[string]interface{}{
+			"old": []interface{}{map[string]interface{}{}},
+		},
+	}
+	err := Start(&runtime.Nodes{}, config)
+	assert.NoError(err)
+
+	// invalid config keeps the outputs
+	config.Output = map[string]interface{}{"new": true}
+	assert.Error(Reload(config))
+
+	config.Output = map[string]interface{}{
+		"new": []interface{}{map[string]interface{}{}},
+	}
+	config.SaveInterval = duration.Duration{Duration: time.Millisecond}
+	assert.NoError(Reload(config))
+	time.Sleep(time.Millisecond * 20)
+
+	Close()
+	assert.Equal(0, oldOutput.Get())
+	assert.True(newOutput.Get() > 1)
+}
+
+func TestScheduleOwnInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	fastOutput := &testOutput{}
+	output.RegisterAdapter("fast", func(config map[string]interface{}) (output.Output, error) {
+		return fastOutput, nil
+	})
+	defer delete(output.Adapters, "fast")
+	slowOutput := &testOutput{}
+	output.RegisterAdapter("slow", func(config map[string]interface{}) (output.Output, error) {
+		return slowOutput, nil
+	})
+	defer delete(output.Adapters, "slow")
+
+	// the own interval is not bound to the ticks of the save interval
+	err := Start(&runtime.Nodes{}, runtime.NodesConfig{
+		SaveInterval: duration.Duration{Duration: time.Hour},
+		Output: map[string]interface{}{
+			"fast": []interface{}{map[string]interface{}{"interval": "1s"}},
+			"slow": []interface{}{map[string]interface{}{}},
+		},
+	})
+	assert.NoError(err)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(1, fastOutput.Get())
+	assert.Equal(0, slowOutput.Get())
+
+	Close()
+	assert.Equal(2, fastOutput.Get())
+	assert.Equal(1, slowOutput.Get())
+}
+
+type panicOutput struct {
+	output.Output
+}
+
+func (panicOutput) Save(nodes *runtime.Nodes) {
+	panic("disk full")
+}
+
+func TestExec(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "yanic-exec")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	hookOutput := &testOutput{}
+	output.RegisterAdapter("hook", func(config map[string]interface{}) (output.Output, error) {
+		return hookOutput, nil
+	})
+	defer delete(output.Adapters, "hook")
+	output.RegisterAdapter("failing", func(config map[string]interface{}) (output.Output, error) {
+		return panicOutput{}, nil
+	})
+	defer delete(output.Adapters, "failing")
+
+	allOutput, err := Register(map[string]interface{}{
+		"hook": []interface{}{map[string]interface{}{
+			"exec": []interface{}{"sh", "-c", `echo "$YANIC_OUTPUT" > "$0"`, filepath.Join(dir, "hook")},
+		}},
+		"failing": []interface{}{map[string]interface{}{
+			"exec": []interface{}{"touch", filepath.Join(dir, "failing")},
+		}},
+	})
+	assert.NoError(err)
+	allOutput.Save(&runtime.Nodes{})
+	assert.Equal(1, hookOutput.Get())
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "hook"))
+	assert.NoError(err)
+	assert.Equal("hook\n", string(content))
+
+	// the hook is skipped, if the output fails
+	_, err = os.Stat(filepath.Join(dir, "failing"))
+	assert.True(os.IsNotExist(err))
+
+	// failing hook and timeout
+	allOutput, err = Register(map[string]interface{}{
+		"hook": []interface{}{map[string]interface{}{
+			"exec":         []interface{}{"sleep", "10"},
+			"exec_timeout": "1s",
+		}},
+	})
+	assert.NoError(err)
+	start := time.Now()
+	allOutput.Save(&runtime.Nodes{})
+	assert.True(time.Since(start) < 5*time.Second)
+
+	// invalid config
+	for _, config := range []map[string]interface{}{
+		{"exec": "touch"},
+		{"exec": []interface{}{}},
+		{"exec": []interface{}{"touch", 3}},
+		{"exec": []interface{}{"touch"}, "exec_timeout": "1x"},
+		{"exec": []interface{}{"touch"}, "exec_timeout": 1},
+	} {
+		_, err = Register(map[string]interface{}{"hook": []interface{}{config}})
+		assert.Error(err, config)
+	}
+}
+
+type fileOutput struct {
+	path string
+}
+
+func (o fileOutput) Save(nodes *runtime.Nodes) {
+	if err := ioutil.WriteFile(o.path, []byte("{}"), 0644); err != nil {
+		panic(err)
+	}
+}
+
+func (o fileOutput) Paths() []string {
+	return []string{o.path}
+}
+
+func TestUpload(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "yanic-upload")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	var uploaded []string
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		uploaded = append(uploaded, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	output.RegisterAdapter("file", func(config map[string]interface{}) (output.Output, error) {
+		return fileOutput{path: filepath.Join(dir, "nodes.json")}, nil
+	})
+	defer delete(output.Adapters, "file")
+	output.RegisterAdapter("nofile", func(config map[string]interface{}) (output.Output, error) {
+		return &testOutput{}, nil
+	})
+	defer delete(output.Adapters, "nofile")
+
+	allOutput, err := Register(map[string]interface{}{
+		"file": []interface{}{map[string]interface{}{
+			"upload": map[string]interface{}{"type": "webdav", "url": ts.URL + "/map"},
+		}},
+	})
+	assert.NoError(err)
+	allOutput.Save(&runtime.Nodes{})
+	assert.Equal([]string{"PUT /map/nodes.json"}, uploaded)
+
+	// invalid config
+	for outputType, config := range map[string]map[string]interface{}{
+		"file":   {"upload": "https://dav.example.org/"},
+		"nofile": {"upload": map[string]interface{}{"type": "webdav", "url": "https://dav.example.org/"}},
+	} {
+		_, err = Register(map[string]interface{}{outputType: []interface{}{config}})
+		assert.Error(err, outputType)
+	}
+	_, err = Register(map[string]interface{}{"file": []interface{}{map[string]interface{}{
+		"upload": map[string]interface{}{"type": "ftp", "url": "https://dav.example.org/"},
+	}}})
+	assert.Error(err)
+}
@@ -0,0 +1,191 @@
+package anonymize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/output/filter"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	modeRemove = "remove"
+	modeHash   = "hash"
+)
+
+type anonymize struct {
+	owner             string // "", modeRemove or modeHash
+	macs              bool   // hash the MAC addresses
+	addresses         bool   // remove the IP addresses
+	locationPrecision int    // decimals of the coordinates, -1 to keep them
+	salt              string
+}
+
+func init() {
+	filter.RegisterModifier("anonymize", build)
+}
+
+func build(config interface{}) (filter.Filter, error) {
+	values, ok := config.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid configuration, map expected")
+	}
+
+	a := anonymize{locationPrecision: -1}
+	if v, ok := values["owner"]; ok {
+		mode, ok := v.(string)
+		if !ok || (mode != modeRemove && mode != modeHash) {
+			return nil, fmt.Errorf("invalid owner, %q or %q expected", modeRemove, modeHash)
+		}
+		a.owner = mode
+	}
+	if v, ok := values["macs"]; ok {
+		if a.macs, ok = v.(bool); !ok {
+			return nil, errors.New("invalid macs, boolean expected")
+		}
+	}
+	if v, ok := values["addresses"]; ok {
+		if a.addresses, ok = v.(bool); !ok {
+			return nil, errors.New("invalid addresses, boolean expected")
+		}
+	}
+	if v, ok := values["location_precision"]; ok {
+		precision, ok := v.(int64)
+		if !ok || precision < 0 || precision > 15 {
+			return nil, errors.New("invalid location_precision, number of decimals between 0 and 15 expected")
+		}
+		a.locationPrecision = int(precision)
+	}
+	if v, ok := values["salt"]; ok {
+		if a.salt, ok = v.(string); !ok {
+			return nil, errors.New("invalid salt, string expected")
+		}
+	}
+	return &a, nil
+}
+
+// Apply returns a copy of the node with the anonymized fields, the node itself is not changed
+func (a *anonymize) Apply(node *runtime.Node) *runtime.Node {
+	copied := *node
+	node = &copied
+
+	if node.Nodeinfo != nil {
+		nodeinfo := *node.Nodeinfo
+		node.Nodeinfo = &nodeinfo
+
+		if nodeinfo.Owner != nil {
+			switch a.owner {
+			case modeRemove:
+				nodeinfo.Owner = nil
+			case modeHash:
+				nodeinfo.Owner = &data.Owner{Contact: a.hash(nodeinfo.Owner.Contact)}
+			}
+		}
+		if a.addresses {
+			nodeinfo.Network.Addresses = nil
+		}
+		if a.macs {
+			nodeinfo.Network = a.network(nodeinfo.Network)
+		}
+		if nodeinfo.Location != nil && a.locationPrecision >= 0 {
+			scale := math.Pow(10, float64(a.locationPrecision))
+			nodeinfo.Location = &data.Location{
+				Latitude:  math.Round(nodeinfo.Location.Latitude*scale) / scale,
+				Longitude: math.Round(nodeinfo.Location.Longitude*scale) / scale,
+				Altitude:  nodeinfo.Location.Altitude,
+			}
+		}
+	}
+	if a.macs && node.Neighbours != nil {
+		node.Neighbours = a.neighbours(node.Neighbours)
+	}
+	return node
+}
+
+// hash returns the salted hash of a value
+func (a *anonymize) hash(value string) string {
+	sum := sha256.Sum256([]byte(a.salt + value))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashMAC returns a locally administered MAC address derived from the hash of the MAC address,
+// so the links between the nodes are kept
+func (a *anonymize) hashMAC(mac string) string {
+	sum := sha256.Sum256([]byte(a.salt + strings.ToLower(mac)))
+	return fmt.Sprintf("02:%02x:%02x:%02x:%02x:%02x", sum[0], sum[1], sum[2], sum[3], sum[4])
+}
+
+func (a *anonymize) hashMACs(macs []string) []string {
+	if macs == nil {
+		return nil
+	}
+	result := make([]string, len(macs))
+	for i, mac := range macs {
+		result[i] = a.hashMAC(mac)
+	}
+	return result
+}
+
+// network returns a copy of the network with hashed MAC addresses
+func (a *anonymize) network(network data.Network) data.Network {
+	if network.Mac != "" {
+		network.Mac = a.hashMAC(network.Mac)
+	}
+	network.MeshInterfaces = a.hashMACs(network.MeshInterfaces)
+	if network.Mesh != nil {
+		mesh := make(map[string]*data.NetworkInterface, len(network.Mesh))
+		for name, iface := range network.Mesh {
+			if iface == nil {
+				continue
+			}
+			hashed := &data.NetworkInterface{}
+			hashed.Interfaces.Wireless = a.hashMACs(iface.Interfaces.Wireless)
+			hashed.Interfaces.Other = a.hashMACs(iface.Interfaces.Other)
+			hashed.Interfaces.Tunnel = a.hashMACs(iface.Interfaces.Tunnel)
+			mesh[name] = hashed
+		}
+		network.Mesh = mesh
+	}
+	return network
+}
+
+// neighbours returns a copy of the neighbours with hashed MAC addresses (of batman-adv, wifi and LLDP)
+func (a *anonymize) neighbours(neighbours *data.Neighbours) *data.Neighbours {
+	result := *neighbours
+	if neighbours.Batadv != nil {
+		result.Batadv = make(map[string]data.BatadvNeighbours, len(neighbours.Batadv))
+		for mac, list := range neighbours.Batadv {
+			hashed := data.BatadvNeighbours{Neighbours: make(map[string]data.BatmanLink, len(list.Neighbours))}
+			for neighbour, link := range list.Neighbours {
+				hashed.Neighbours[a.hashMAC(neighbour)] = link
+			}
+			result.Batadv[a.hashMAC(mac)] = hashed
+		}
+	}
+	if neighbours.Wifi != nil {
+		result.Wifi = make(map[string]data.WifiNeighbours, len(neighbours.Wifi))
+		for mac, list := range neighbours.Wifi {
+			hashed := data.WifiNeighbours{Neighbours: make(map[string]data.WifiLink, len(list.Neighbours))}
+			for neighbour, link := range list.Neighbours {
+				hashed.Neighbours[a.hashMAC(neighbour)] = link
+			}
+			result.Wifi[a.hashMAC(mac)] = hashed
+		}
+	}
+	if neighbours.LLDP != nil {
+		result.LLDP = make(map[string]data.LLDPNeighbours, len(neighbours.LLDP))
+		for mac, list := range neighbours.LLDP {
+			hashed := make(data.LLDPNeighbours, len(list))
+			for neighbour, link := range list {
+				hashed[a.hashMAC(neighbour)] = link
+			}
+			result.LLDP[a.hashMAC(mac)] = hashed
+		}
+	}
+	return &result
+}
@@ -0,0 +1,94 @@
+package anonymize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestBuild(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, config := range []interface{}{
+		"nope",
+		map[string]interface{}{"owner": "blub"},
+		map[string]interface{}{"macs": "yes"},
+		map[string]interface{}{"addresses": 1},
+		map[string]interface{}{"location_precision": int64(-1)},
+		map[string]interface{}{"location_precision": 1.5},
+		map[string]interface{}{"salt": 1},
+	} {
+		_, err := build(config)
+		assert.Error(err, config)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	node := &runtime.Node{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID: "000000000001",
+			Owner:  &data.Owner{Contact: "mail@example.org"},
+			Network: data.Network{
+				Mac:       "00:00:00:00:00:01",
+				Addresses: []string{"fe80::1"},
+				Mesh: map[string]*data.NetworkInterface{
+					"bat0": {},
+				},
+			},
+			Location: &data.Location{Latitude: 53.123456, Longitude: 8.654321, Altitude: 12},
+		},
+		Neighbours: &data.Neighbours{
+			Batadv: map[string]data.BatadvNeighbours{
+				"00:00:00:00:00:01": {Neighbours: map[string]data.BatmanLink{"00:00:00:00:00:02": {Tq: 200}}},
+			},
+		},
+	}
+	node.Nodeinfo.Network.Mesh["bat0"].Interfaces.Wireless = []string{"00:00:00:00:00:01"}
+
+	// keep everything
+	f, err := build(map[string]interface{}{})
+	assert.NoError(err)
+	n := f.Apply(node)
+	assert.Equal(node.Nodeinfo, n.Nodeinfo)
+	assert.Equal(node.Neighbours, n.Neighbours)
+
+	f, err = build(map[string]interface{}{
+		"owner":              "hash",
+		"macs":               true,
+		"addresses":          true,
+		"location_precision": int64(2),
+		"salt":               "secret",
+	})
+	assert.NoError(err)
+	n = f.Apply(node)
+
+	assert.Len(n.Nodeinfo.Owner.Contact, 64)
+	assert.NotContains(n.Nodeinfo.Owner.Contact, "example")
+	assert.Nil(n.Nodeinfo.Network.Addresses)
+	assert.Equal(data.Location{Latitude: 53.12, Longitude: 8.65, Altitude: 12}, *n.Nodeinfo.Location)
+
+	// the MAC is hashed the same in the nodeinfo and the neighbours
+	mac := n.Nodeinfo.Network.Mac
+	assert.Regexp("^02(:[0-9a-f]{2}){5}$", mac)
+	assert.NotEqual("00:00:00:00:00:01", mac)
+	assert.Equal([]string{mac}, n.Nodeinfo.Network.Mesh["bat0"].Interfaces.Wireless)
+	assert.Contains(n.Neighbours.Batadv, mac)
+	assert.Len(n.Neighbours.Batadv[mac].Neighbours, 1)
+	assert.NotContains(n.Neighbours.Batadv[mac].Neighbours, "00:00:00:00:00:02")
+
+	// the node itself is not changed
+	assert.Equal("mail@example.org", node.Nodeinfo.Owner.Contact)
+	assert.Equal("00:00:00:00:00:01", node.Nodeinfo.Network.Mac)
+	assert.Equal([]string{"00:00:00:00:00:01"}, node.Nodeinfo.Network.Mesh["bat0"].Interfaces.Wireless)
+	assert.Equal(53.123456, node.Nodeinfo.Location.Latitude)
+	assert.Contains(node.Neighbours.Batadv, "00:00:00:00:00:01")
+
+	// remove the owner
+	f, _ = build(map[string]interface{}{"owner": "remove"})
+	assert.Nil(f.Apply(node).Nodeinfo.Owner)
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"errors"
+
+	"github.com/FreifunkBremen/yanic/output/filter"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+type domains map[string]interface{}
+
+func init() {
+	filter.Register("domains", build)
+}
+
+func build(config interface{}) (filter.Filter, error) {
+	values, ok := config.([]interface{})
+	if !ok {
+		return nil, errors.New("invalid configuration, array (of strings) expected")
+	}
+
+	list := make(domains)
+	for _, value := range values {
+		if domain, ok := value.(string); ok {
+			list[domain] = struct{}{}
+		} else {
+			return nil, errors.New("invalid configuration, array of strings expected")
+		}
+	}
+	return &list, nil
+}
+
+func (list domains) Apply(node *runtime.Node) *runtime.Node {
+	if nodeinfo := node.Nodeinfo; nodeinfo != nil {
+		if _, ok := list[nodeinfo.System.DomainCode]; ok {
+			return node
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterDomain(t *testing.T) {
+	assert := assert.New(t)
+
+	// invalid config
+	filter, err := build("city")
+	assert.Error(err)
+
+	filter, err = build([]interface{}{3, "city"})
+	assert.Error(err)
+
+	filter, err = build([]interface{}{"city"})
+	assert.NoError(err)
+
+	// wronge node
+	n := filter.Apply(&runtime.Node{Nodeinfo: &data.Nodeinfo{System: data.System{DomainCode: "harbour"}}})
+	assert.Nil(n)
+
+	// right node
+	n = filter.Apply(&runtime.Node{Nodeinfo: &data.Nodeinfo{System: data.System{DomainCode: "city"}}})
+	assert.NotNil(n)
+
+	// node without data -> wrong node
+	n = filter.Apply(&runtime.Node{})
+	assert.Nil(n)
+}
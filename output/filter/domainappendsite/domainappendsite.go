@@ -11,7 +11,7 @@ import (
 type domainAppendSite struct{ set bool }
 
 func init() {
-	filter.Register("domain_append_site", build)
+	filter.RegisterModifier("domain_append_site", build)
 }
 
 func build(config interface{}) (filter.Filter, error) {
@@ -42,6 +42,7 @@ func (config *domainAppendSite) Apply(node *runtime.Node) *runtime.Node {
 				Hardware: nodeinfo.Hardware,
 				VPN:      nodeinfo.VPN,
 				Wireless: nodeinfo.Wireless,
+				Custom:   nodeinfo.Custom,
 			},
 			Neighbours: node.Neighbours,
 		}
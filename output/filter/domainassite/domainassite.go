@@ -11,7 +11,7 @@ import (
 type domainAsSite struct{ set bool }
 
 func init() {
-	filter.Register("domain_as_site", build)
+	filter.RegisterModifier("domain_as_site", build)
 }
 
 func build(config interface{}) (filter.Filter, error) {
@@ -42,6 +42,7 @@ func (config *domainAsSite) Apply(node *runtime.Node) *runtime.Node {
 				Hardware: nodeinfo.Hardware,
 				VPN:      nodeinfo.VPN,
 				Wireless: nodeinfo.Wireless,
+				Custom:   nodeinfo.Custom,
 			},
 			Neighbours: node.Neighbours,
 		}
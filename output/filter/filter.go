@@ -2,6 +2,7 @@ package filter
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/bdlm/log"
 	"github.com/pkg/errors"
@@ -21,7 +22,10 @@ type Filter interface {
 // Set is a list of configured filters
 type Set []Filter
 
-var filters = make(map[string]factory)
+var (
+	filters   = make(map[string]factory)
+	modifiers = make(map[string]bool) // filters, which change the nodes instead of selecting them
+)
 
 // Register registers a new filter
 func Register(name string, f factory) {
@@ -31,9 +35,28 @@ func Register(name string, f factory) {
 	filters[name] = f
 }
 
+// RegisterModifier registers a new filter, which changes the nodes (e.g. their site codes) instead of selecting them.
+// Modifiers are applied after the other filters, so these select by the unchanged nodes.
+func RegisterModifier(name string, f factory) {
+	Register(name, f)
+	modifiers[name] = true
+}
+
 // New returns and initializes a set of filters
 func New(configs map[string]interface{}) (set Set, errs []error) {
-	for name, config := range configs {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if modifiers[names[i]] != modifiers[names[j]] {
+			return !modifiers[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		config := configs[name]
 		if config == nil {
 			return
 		}
@@ -58,18 +81,21 @@ func (set Set) Apply(nodesOrigin *runtime.Nodes) *runtime.Nodes {
 	defer nodesOrigin.Unlock()
 
 	for _, nodeOrigin := range nodesOrigin.List {
-		//maybe cloning of this object is better?
-		node := nodeOrigin
-		for _, filter := range set {
-			node = filter.Apply(node)
-			if node == nil {
-				break
-			}
-		}
-
-		if node != nil {
+		if node := set.ApplyNode(nodeOrigin); node != nil {
 			nodes.AddNode(node)
 		}
 	}
 	return nodes
 }
+
+// ApplyNode applies the filter set to a single node, it returns nil if the node is filtered out
+func (set Set) ApplyNode(node *runtime.Node) *runtime.Node {
+	//maybe cloning of this object is better?
+	for _, filter := range set {
+		node = filter.Apply(node)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
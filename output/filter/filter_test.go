@@ -94,3 +94,35 @@ func TestFilter(t *testing.T) {
 	nodes = filter.Apply(nodes)
 	assert.Len(nodes.List, 1)
 }
+
+// filterRename changes the hostname of the nodes
+type filterRename struct{}
+
+func (filterRename) Apply(node *runtime.Node) *runtime.Node {
+	return &runtime.Node{Nodeinfo: &data.Nodeinfo{NodeID: node.Nodeinfo.NodeID, Hostname: "renamed"}}
+}
+
+// filterHostname keeps the nodes with the hostname
+type filterHostname struct{}
+
+func (filterHostname) Apply(node *runtime.Node) *runtime.Node {
+	if node.Nodeinfo.Hostname == "node" {
+		return node
+	}
+	return nil
+}
+
+func TestFilterModifier(t *testing.T) {
+	assert := assert.New(t)
+
+	// the modifier is sorted first by its name, but applied last
+	RegisterModifier("a_rename", func(interface{}) (Filter, error) { return filterRename{}, nil })
+	Register("b_hostname", func(interface{}) (Filter, error) { return filterHostname{}, nil })
+
+	set, errs := New(map[string]interface{}{"a_rename": true, "b_hostname": true})
+	assert.Len(errs, 0)
+	assert.Len(set, 2)
+	node := set.ApplyNode(&runtime.Node{Nodeinfo: &data.Nodeinfo{NodeID: "a", Hostname: "node"}})
+	assert.NotNil(node)
+	assert.Equal("renamed", node.Nodeinfo.Hostname)
+}
@@ -0,0 +1,90 @@
+package hostname
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/FreifunkBremen/yanic/output/filter"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+type hostname struct {
+	maxLength   int            // in characters, 0 for unlimited
+	disallowed  *regexp.Regexp // characters outside of the allowed charset, nil to allow all
+	replacement string         // of the disallowed characters
+}
+
+func init() {
+	filter.RegisterModifier("hostname", build)
+}
+
+func build(config interface{}) (filter.Filter, error) {
+	values, ok := config.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid configuration, map expected")
+	}
+
+	h := hostname{}
+	if v, ok := values["max_length"]; ok {
+		length, ok := v.(int64)
+		if !ok || length < 1 {
+			return nil, errors.New("invalid max_length, positive number expected")
+		}
+		h.maxLength = int(length)
+	}
+	if v, ok := values["charset"]; ok {
+		charset, ok := v.(string)
+		if !ok || charset == "" {
+			return nil, errors.New("invalid charset, string expected")
+		}
+		disallowed, err := regexp.Compile("[^" + charset + "]")
+		if err != nil {
+			return nil, errors.New("invalid charset, characters of a bracket expression (e.g. \"a-zA-Z0-9-\") expected")
+		}
+		h.disallowed = disallowed
+	}
+	if v, ok := values["replacement"]; ok {
+		if h.replacement, ok = v.(string); !ok {
+			return nil, errors.New("invalid replacement, string expected")
+		}
+	}
+	return &h, nil
+}
+
+// Apply returns a copy of the node with the sanitized hostname, the node itself is not changed
+func (h *hostname) Apply(node *runtime.Node) *runtime.Node {
+	if node.Nodeinfo == nil {
+		return node
+	}
+	sanitized := h.sanitize(node.Nodeinfo.Hostname)
+	if sanitized == node.Nodeinfo.Hostname {
+		return node
+	}
+	copied := *node
+	nodeinfo := *node.Nodeinfo
+	nodeinfo.Hostname = sanitized
+	copied.Nodeinfo = &nodeinfo
+	return &copied
+}
+
+// sanitize replaces newlines and other control characters by spaces, the characters outside of the charset
+// by the replacement and shortens the hostname to the max length
+func (h *hostname) sanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ")
+
+	if h.disallowed != nil {
+		name = h.disallowed.ReplaceAllLiteralString(name, h.replacement)
+	}
+	if runes := []rune(name); h.maxLength > 0 && len(runes) > h.maxLength {
+		name = string(runes[:h.maxLength])
+	}
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,65 @@
+package hostname
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestBuild(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, config := range []interface{}{
+		"nope",
+		map[string]interface{}{"max_length": int64(0)},
+		map[string]interface{}{"max_length": "10"},
+		map[string]interface{}{"charset": ""},
+		map[string]interface{}{"charset": `a\`},
+		map[string]interface{}{"charset": "z-a"},
+		map[string]interface{}{"replacement": 1},
+	} {
+		_, err := build(config)
+		assert.Error(err, config)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	node := &runtime.Node{Nodeinfo: &data.Nodeinfo{
+		NodeID:   "000000000001",
+		Hostname: "ffhb-\U0001F680-node\n\tsecond line ",
+	}}
+
+	// only the control characters
+	f, err := build(map[string]interface{}{})
+	assert.NoError(err)
+	n := f.Apply(node)
+	assert.Equal("ffhb-\U0001F680-node second line", n.Nodeinfo.Hostname)
+	// the node itself is kept
+	assert.Equal("ffhb-\U0001F680-node\n\tsecond line ", node.Nodeinfo.Hostname)
+
+	f, err = build(map[string]interface{}{
+		"max_length":  int64(12),
+		"charset":     "a-zA-Z0-9-",
+		"replacement": "_",
+	})
+	assert.NoError(err)
+	assert.Equal("ffhb-_-node_", f.Apply(node).Nodeinfo.Hostname)
+
+	// removed characters and counted by characters instead of bytes
+	f, err = build(map[string]interface{}{"max_length": int64(6), "charset": `\pL\pN -`})
+	assert.NoError(err)
+	assert.Equal("Bremen", f.Apply(&runtime.Node{Nodeinfo: &data.Nodeinfo{Hostname: "Bremen-Überseestadt"}}).Nodeinfo.Hostname)
+	assert.Equal("Überse", f.Apply(&runtime.Node{Nodeinfo: &data.Nodeinfo{Hostname: "Überseestadt"}}).Nodeinfo.Hostname)
+	assert.Equal("node", f.Apply(&runtime.Node{Nodeinfo: &data.Nodeinfo{Hostname: "no\U0001F680de"}}).Nodeinfo.Hostname)
+
+	// unchanged
+	node = &runtime.Node{Nodeinfo: &data.Nodeinfo{Hostname: "node"}}
+	assert.True(node == f.Apply(node))
+	node = &runtime.Node{}
+	assert.True(node == f.Apply(node))
+}
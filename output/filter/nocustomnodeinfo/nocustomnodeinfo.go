@@ -0,0 +1,38 @@
+package nocustomnodeinfo
+
+import (
+	"errors"
+
+	"github.com/FreifunkBremen/yanic/output/filter"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+type nocustomnodeinfo struct{}
+
+func init() {
+	filter.RegisterModifier("no_custom_nodeinfo", build)
+}
+
+func build(config interface{}) (filter.Filter, error) {
+	value, ok := config.(bool)
+	if !ok {
+		return nil, errors.New("invalid configuration, boolean expected")
+	}
+	if !value {
+		// keep the custom fields
+		return nil, nil
+	}
+	return &nocustomnodeinfo{}, nil
+}
+
+// Apply returns a copy of the node without the fields of the nodeinfo unknown to yanic, the node itself is not changed
+func (*nocustomnodeinfo) Apply(node *runtime.Node) *runtime.Node {
+	if node.Nodeinfo == nil || len(node.Nodeinfo.Custom) == 0 {
+		return node
+	}
+	copied := *node
+	nodeinfo := *node.Nodeinfo
+	nodeinfo.Custom = nil
+	copied.Nodeinfo = &nodeinfo
+	return &copied
+}
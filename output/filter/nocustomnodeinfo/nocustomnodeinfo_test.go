@@ -0,0 +1,39 @@
+package nocustomnodeinfo
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	// invalid config
+	_, err := build("nope")
+	assert.Error(err)
+
+	// keep the custom fields
+	filter, err := build(false)
+	assert.NoError(err)
+	assert.Nil(filter)
+
+	filter, err = build(true)
+	assert.NoError(err)
+	node := &runtime.Node{Nodeinfo: &data.Nodeinfo{
+		NodeID: "000000000001",
+		Custom: map[string]json.RawMessage{"community": json.RawMessage(`"ffhb"`)},
+	}}
+	n := filter.Apply(node)
+	assert.Equal("000000000001", n.Nodeinfo.NodeID)
+	assert.Nil(n.Nodeinfo.Custom)
+	// the node itself is kept
+	assert.Len(node.Nodeinfo.Custom, 1)
+
+	// without nodeinfo
+	n = filter.Apply(&runtime.Node{})
+	assert.NotNil(n)
+}
@@ -11,7 +11,7 @@ import (
 type noowner struct{ has bool }
 
 func init() {
-	filter.Register("no_owner", build)
+	filter.RegisterModifier("no_owner", build)
 }
 
 func build(config interface{}) (filter.Filter, error) {
@@ -40,6 +40,7 @@ func (no *noowner) Apply(node *runtime.Node) *runtime.Node {
 				Hardware: nodeinfo.Hardware,
 				VPN:      nodeinfo.VPN,
 				Wireless: nodeinfo.Wireless,
+				Custom:   nodeinfo.Custom,
 			},
 			Neighbours:   node.Neighbours,
 			CustomFields: node.CustomFields,
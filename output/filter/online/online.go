@@ -0,0 +1,33 @@
+package online
+
+import (
+	"errors"
+
+	"github.com/FreifunkBremen/yanic/output/filter"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+type online struct{}
+
+func init() {
+	filter.Register("online", build)
+}
+
+func build(config interface{}) (filter.Filter, error) {
+	value, ok := config.(bool)
+	if !ok {
+		return nil, errors.New("invalid configuration, bool expected")
+	}
+	if !value {
+		// all nodes
+		return nil, nil
+	}
+	return &online{}, nil
+}
+
+func (*online) Apply(node *runtime.Node) *runtime.Node {
+	if node.Online {
+		return node
+	}
+	return nil
+}
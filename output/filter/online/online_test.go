@@ -0,0 +1,30 @@
+package online
+
+import (
+	"testing"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterOnline(t *testing.T) {
+	assert := assert.New(t)
+
+	// invalid config
+	filter, err := build("true")
+	assert.Error(err)
+
+	// disabled
+	filter, err = build(false)
+	assert.NoError(err)
+	assert.Nil(filter)
+
+	filter, err = build(true)
+	assert.NoError(err)
+
+	n := filter.Apply(&runtime.Node{Online: true})
+	assert.NotNil(n)
+
+	n = filter.Apply(&runtime.Node{})
+	assert.Nil(n)
+}
@@ -1,6 +1,7 @@
 package geojson
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 
@@ -12,6 +13,7 @@ const (
 	POINT_UMAP_CLASS         = "Circle"
 	POINT_UMAP_ONLINE_COLOR  = "Green"
 	POINT_UMAP_OFFLINE_COLOR = "Red"
+	LINE_UMAP_COLOR          = "Blue"
 )
 
 func newNodePoint(n *runtime.Node) (point *geojson.Feature) {
@@ -73,7 +75,103 @@ func getUMapOptions(n *runtime.Node) map[string]string {
 	return result
 }
 
-func transform(nodes *runtime.Nodes) *geojson.FeatureCollection {
+// linkLine is a link between two nodes with the TQ of both directions,
+// the source is the node with the lower ID
+type linkLine struct {
+	source, target *runtime.Node
+	protocol       string
+	linkType       string // of all links between both nodes (see runtime.MergeLinkTypes), empty if unknown
+	sourceTQ       float32
+	targetTQ       float32
+	sourceSignal   int // of the wifi link, 0 if unknown
+	targetSignal   int
+}
+
+func newLinkLine(line *linkLine) *geojson.Feature {
+	source := line.source.Nodeinfo
+	target := line.target.Nodeinfo
+	feature := geojson.NewLineStringFeature([][]float64{
+		{source.Location.Longitude, source.Location.Latitude},
+		{target.Location.Longitude, target.Location.Latitude},
+	})
+	feature.Properties["source"] = source.NodeID
+	feature.Properties["target"] = target.NodeID
+	feature.Properties["protocol"] = line.protocol
+	if line.linkType != "" {
+		feature.Properties["type"] = line.linkType
+	}
+	feature.Properties["source_tq"] = line.sourceTQ
+	feature.Properties["target_tq"] = line.targetTQ
+	if line.sourceSignal != 0 {
+		feature.Properties["source_signal"] = line.sourceSignal
+	}
+	if line.targetSignal != 0 {
+		feature.Properties["target_signal"] = line.targetSignal
+	}
+	feature.Properties["description"] = source.Hostname + " - " + target.Hostname
+	feature.Properties["_umap_options"] = map[string]string{
+		"color": LINE_UMAP_COLOR,
+	}
+	return feature
+}
+
+// linkLines returns the links between online nodes with a location, sorted by their nodes
+func linkLines(nodes *runtime.Nodes) []*linkLine {
+	lines := make(map[[2]string]*linkLine)
+	hasLocation := func(n *runtime.Node) bool {
+		return n != nil && n.Online && n.Nodeinfo != nil && n.Nodeinfo.Location != nil
+	}
+
+	for _, n := range nodes.List {
+		if !hasLocation(n) {
+			continue
+		}
+		for _, link := range nodes.NodeLinks(n) {
+			target := nodes.List[link.TargetID]
+			if !hasLocation(target) || link.SourceID == link.TargetID {
+				continue
+			}
+			key := [2]string{link.SourceID, link.TargetID}
+			reverse := link.TargetID < link.SourceID
+			if reverse {
+				key = [2]string{link.TargetID, link.SourceID}
+			}
+			line := lines[key]
+			if line == nil {
+				line = &linkLine{
+					source:   nodes.List[key[0]],
+					target:   nodes.List[key[1]],
+					protocol: link.Protocol,
+				}
+				lines[key] = line
+			}
+			line.linkType = runtime.MergeLinkTypes(line.linkType, link.Type)
+			// keep the best link of a direction (e.g. of multiple interfaces)
+			if reverse && link.TQ > line.targetTQ {
+				line.targetTQ = link.TQ
+				line.targetSignal = link.Signal
+			} else if !reverse && link.TQ > line.sourceTQ {
+				line.sourceTQ = link.TQ
+				line.sourceSignal = link.Signal
+			}
+		}
+	}
+
+	result := make([]*linkLine, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, line)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.source.Nodeinfo.NodeID != b.source.Nodeinfo.NodeID {
+			return a.source.Nodeinfo.NodeID < b.source.Nodeinfo.NodeID
+		}
+		return a.target.Nodeinfo.NodeID < b.target.Nodeinfo.NodeID
+	})
+	return result
+}
+
+func transform(nodes *runtime.Nodes, withLinks bool) *geojson.FeatureCollection {
 	nodelist := geojson.NewFeatureCollection()
 
 	for _, n := range nodes.List {
@@ -85,5 +183,11 @@ func transform(nodes *runtime.Nodes) *geojson.FeatureCollection {
 			nodelist.Features = append(nodelist.Features, point)
 		}
 	}
+
+	if withLinks {
+		for _, line := range linkLines(nodes) {
+			nodelist.Features = append(nodelist.Features, newLinkLine(line))
+		}
+	}
 	return nodelist
 }
@@ -16,7 +16,7 @@ const (
 
 func TestTransform(t *testing.T) {
 	testNodes := createTestNodes()
-	nodes := transform(testNodes)
+	nodes := transform(testNodes, false)
 
 	assert := assert.New(t)
 	assert.Len(testNodes.List, 4)
@@ -132,3 +132,63 @@ func createTestNodes() *runtime.Nodes {
 
 	return nodes
 }
+
+func TestTransformLinks(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	newNode := func(nodeID, mac, neighbour string, tq int, location *data.Location) {
+		node := &runtime.Node{
+			Online: true,
+			Nodeinfo: &data.Nodeinfo{
+				NodeID:   nodeID,
+				Hostname: "node-" + nodeID,
+				Network:  data.Network{Mac: mac},
+				Location: location,
+			},
+			Neighbours: &data.Neighbours{
+				NodeID: nodeID,
+				Batadv: map[string]data.BatadvNeighbours{
+					mac: {
+						Neighbours: map[string]data.BatmanLink{
+							neighbour: {Tq: tq},
+						},
+					},
+				},
+			},
+		}
+		nodes.AddNode(node)
+	}
+	newNode("000000000002", "00:00:00:00:00:02", "00:00:00:00:00:01", 204, &data.Location{Latitude: 53.1, Longitude: 8.8})
+	newNode("000000000001", "00:00:00:00:00:01", "00:00:00:00:00:02", 102, &data.Location{Latitude: 53.0, Longitude: 8.7})
+	// without location
+	newNode("000000000003", "00:00:00:00:00:03", "00:00:00:00:00:01", 255, nil)
+	// over wifi
+	nodes.List["000000000002"].Neighbours.Wifi = map[string]data.WifiNeighbours{
+		"00:00:00:00:00:02": {
+			Neighbours: map[string]data.WifiLink{
+				"00:00:00:00:00:01": {Signal: -70},
+			},
+		},
+	}
+	wireless := &data.NetworkInterface{}
+	wireless.Interfaces.Wireless = []string{"00:00:00:00:00:02"}
+	nodes.List["000000000002"].Nodeinfo.Network.Mesh = map[string]*data.NetworkInterface{"bat0": wireless}
+
+	collection := transform(nodes, false)
+	assert.Len(collection.Features, 2)
+
+	collection = transform(nodes, true)
+	assert.Len(collection.Features, 3)
+	line := collection.Features[2]
+	assert.True(line.Geometry.IsLineString())
+	assert.Equal([][]float64{{8.7, 53.0}, {8.8, 53.1}}, line.Geometry.LineString)
+	assert.Equal("000000000001", line.Properties["source"])
+	assert.Equal("000000000002", line.Properties["target"])
+	assert.InDelta(0.4, line.Properties["source_tq"], 0.001)
+	assert.InDelta(0.8, line.Properties["target_tq"], 0.001)
+	assert.Equal(-70, line.Properties["target_signal"])
+	assert.NotContains(line.Properties, "source_signal")
+	assert.Equal(runtime.LINK_TYPE_WIRELESS, line.Properties["type"])
+	assert.Equal("node-000000000001 - node-000000000002", line.Properties["description"])
+}
@@ -9,7 +9,8 @@ import (
 
 type Output struct {
 	output.Output
-	path string
+	path  string
+	links bool
 }
 
 type Config map[string]interface{}
@@ -21,6 +22,13 @@ func (c Config) Path() string {
 	return ""
 }
 
+func (c Config) Links() bool {
+	if links, ok := c["links"]; ok {
+		return links.(bool)
+	}
+	return false
+}
+
 func init() {
 	output.RegisterAdapter("geojson", Register)
 }
@@ -31,7 +39,8 @@ func Register(configuration map[string]interface{}) (output.Output, error) {
 
 	if path := config.Path(); path != "" {
 		return &Output{
-			path: path,
+			path:  path,
+			links: config.Links(),
 		}, nil
 	}
 	return nil, errors.New("no path given")
@@ -42,5 +51,10 @@ func (o *Output) Save(nodes *runtime.Nodes) {
 	nodes.RLock()
 	defer nodes.RUnlock()
 
-	runtime.SaveJSON(transform(nodes), o.path)
+	runtime.SaveJSON(transform(nodes, o.links), o.path)
+}
+
+// Paths returns the written file
+func (o *Output) Paths() []string {
+	return []string{o.path}
 }
@@ -25,4 +25,12 @@ func TestOutput(t *testing.T) {
 	out.Save(&runtime.Nodes{})
 	_, err = os.Stat("/tmp/nodes.geojson")
 	assert.NoError(err)
+	assert.False(out.(*Output).links)
+
+	out, err = Register(map[string]interface{}{
+		"path":  "/tmp/nodes.geojson",
+		"links": true,
+	})
+	assert.NoError(err)
+	assert.True(out.(*Output).links)
 }
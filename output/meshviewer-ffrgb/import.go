@@ -0,0 +1,94 @@
+package meshviewerFFRGB
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// ReadNodes reads a meshviewer.json (e.g. of the map of another community) into nodes.
+// Only the fields of the meshviewer are known, the links are not read.
+func ReadNodes(r io.Reader) (map[string]*runtime.Node, error) {
+	var file Meshviewer
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*runtime.Node)
+	for _, node := range file.Nodes {
+		if node == nil || node.NodeID == "" {
+			continue
+		}
+		result[node.NodeID] = &runtime.Node{
+			Firstseen:    node.Firstseen,
+			Lastseen:     node.Lastseen,
+			Online:       node.IsOnline,
+			Nodeinfo:     node.nodeinfo(),
+			Statistics:   node.statistics(),
+			CustomFields: node.CustomFields,
+		}
+	}
+	return result, nil
+}
+
+// nodeinfo transforms the node back to a respondd nodeinfo, as far as it is known
+func (node *Node) nodeinfo() *data.Nodeinfo {
+	nodeinfo := &data.Nodeinfo{
+		NodeID:   node.NodeID,
+		Hostname: node.Hostname,
+		Network: data.Network{
+			Mac:       node.MAC,
+			Addresses: node.Addresses,
+		},
+		System:   data.System{DomainCode: node.DomainCode},
+		Hardware: data.Hardware{Nproc: node.Nproc, Model: node.Model},
+	}
+	if node.Owner != "" {
+		nodeinfo.Owner = &data.Owner{Contact: node.Owner}
+	}
+	if location := node.Location; location != nil {
+		nodeinfo.Location = &data.Location{Longitude: location.Longitude, Latitude: location.Latitude}
+	}
+	if node.Firmware.Base != "" || node.Firmware.Release != "" {
+		nodeinfo.Software.Firmware = &struct {
+			Base    string `json:"base,omitempty"`
+			Release string `json:"release,omitempty"`
+		}{
+			Base:    node.Firmware.Base,
+			Release: node.Firmware.Release,
+		}
+	}
+	nodeinfo.Software.Autoupdater = &struct {
+		Enabled bool   `json:"enabled,omitempty"`
+		Branch  string `json:"branch,omitempty"`
+	}{
+		Enabled: node.Autoupdater.Enabled,
+		Branch:  node.Autoupdater.Branch,
+	}
+	return nodeinfo
+}
+
+// statistics transforms the node back to respondd statistics, as far as they are known
+func (node *Node) statistics() *data.Statistics {
+	stats := &data.Statistics{
+		NodeID: node.NodeID,
+		Clients: data.Clients{
+			Total:  node.Clients,
+			Wifi24: node.ClientsWifi24,
+			Wifi5:  node.ClientsWifi5,
+			Wifi:   node.ClientsWifi24 + node.ClientsWifi5,
+		},
+		RootFsUsage:    node.RootFSUsage,
+		LoadAverage:    node.LoadAverage,
+		GatewayNexthop: node.GatewayNexthop,
+		GatewayIPv4:    node.GatewayIPv4,
+		GatewayIPv6:    node.GatewayIPv6,
+	}
+	if !node.Uptime.IsZero() {
+		stats.Uptime = node.Lastseen.GetTime().Sub(node.Uptime.GetTime()).Round(time.Second).Seconds()
+	}
+	return stats
+}
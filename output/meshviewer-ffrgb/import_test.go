@@ -0,0 +1,55 @@
+package meshviewerFFRGB
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/lib/jsontime"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestReadNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	node := NewNode(nodes, &runtime.Node{
+		Lastseen: jsontime.Now(),
+		Online:   true,
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "000000000001",
+			Hostname: "node1",
+			Owner:    &data.Owner{Contact: "whoami"},
+			Location: &data.Location{Latitude: 53.1, Longitude: 8.6},
+			System:   data.System{DomainCode: "city"},
+		},
+		Statistics: &data.Statistics{
+			Clients:     data.Clients{Total: 5, Wifi24: 2, Wifi5: 1},
+			LoadAverage: 0.5,
+			Uptime:      3600,
+		},
+	})
+	var buf bytes.Buffer
+	assert.NoError(json.NewEncoder(&buf).Encode(&Meshviewer{Nodes: []*Node{node, {Hostname: "without id"}}}))
+
+	list, err := ReadNodes(&buf)
+	assert.NoError(err)
+	assert.Len(list, 1)
+	result := list["000000000001"]
+	assert.True(result.Online)
+	assert.Equal("node1", result.Nodeinfo.Hostname)
+	assert.Equal("whoami", result.Nodeinfo.Owner.Contact)
+	assert.Equal(53.1, result.Nodeinfo.Location.Latitude)
+	assert.Equal("city", result.Nodeinfo.System.DomainCode)
+	assert.EqualValues(5, result.Statistics.Clients.Total)
+	assert.EqualValues(3, result.Statistics.Clients.Wifi)
+	assert.Equal(0.5, result.Statistics.LoadAverage)
+	assert.InDelta(3600, result.Statistics.Uptime, 1)
+
+	_, err = ReadNodes(strings.NewReader(`[]`))
+	assert.Error(err)
+}
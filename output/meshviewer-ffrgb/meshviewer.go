@@ -2,6 +2,7 @@ package meshviewerFFRGB
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/bdlm/log"
@@ -16,6 +17,18 @@ const (
 	LINK_TYPE_FALLBACK = "other"
 )
 
+// meshviewerLinkType returns the type of the meshviewer for the type of a link (see runtime.LINK_TYPE_VPN)
+func meshviewerLinkType(linkType string) string {
+	switch linkType {
+	case runtime.LINK_TYPE_VPN:
+		return LINK_TYPE_TUNNEL
+	case runtime.LINK_TYPE_WIRELESS:
+		return LINK_TYPE_WIRELESS
+	default:
+		return LINK_TYPE_FALLBACK
+	}
+}
+
 func transform(nodes *runtime.Nodes) *Meshviewer {
 
 	meshviewer := &Meshviewer{
@@ -25,12 +38,12 @@ func transform(nodes *runtime.Nodes) *Meshviewer {
 	}
 
 	links := make(map[string]*Link)
-	typeList := make(map[string]string)
 
 	nodes.RLock()
 	defer nodes.RUnlock()
 
-	for _, nodeOrigin := range nodes.List {
+	for _, nodeID := range runtime.SortedNodeIDs(nodes.List) {
+		nodeOrigin := nodes.List[nodeID]
 		node := NewNode(nodes, nodeOrigin)
 		meshviewer.Nodes = append(meshviewer.Nodes, node)
 
@@ -38,19 +51,6 @@ func transform(nodes *runtime.Nodes) *Meshviewer {
 			continue
 		}
 
-		if nodeinfo := nodeOrigin.Nodeinfo; nodeinfo != nil {
-			if meshes := nodeinfo.Network.Mesh; meshes != nil {
-				for _, mesh := range meshes {
-					for _, addr := range mesh.Interfaces.Wireless {
-						typeList[addr] = LINK_TYPE_WIRELESS
-					}
-					for _, addr := range mesh.Interfaces.Tunnel {
-						typeList[addr] = LINK_TYPE_TUNNEL
-					}
-				}
-			}
-		}
-
 		for _, linkOrigin := range nodes.NodeLinks(nodeOrigin) {
 			var key string
 			// keep source and target in the same order
@@ -61,33 +61,23 @@ func transform(nodes *runtime.Nodes) *Meshviewer {
 				key = fmt.Sprintf("%s-%s", linkOrigin.TargetAddress, linkOrigin.SourceAddress)
 			}
 
-			if link := links[key]; link != nil {
-				linkType, linkTypeFound := typeList[linkOrigin.SourceAddress]
-				if !linkTypeFound {
-					linkType, linkTypeFound = typeList[linkOrigin.TargetAddress]
-				}
+			linkType := meshviewerLinkType(linkOrigin.Type)
 
+			if link := links[key]; link != nil {
 				if switchSourceTarget {
 					link.TargetTQ = linkOrigin.TQ
-
-					linkType, linkTypeFound = typeList[linkOrigin.TargetAddress]
-					if !linkTypeFound {
-						linkType, linkTypeFound = typeList[linkOrigin.SourceAddress]
-					}
 				} else {
 					link.SourceTQ = linkOrigin.TQ
 				}
 
-				if linkTypeFound && linkType != link.Type {
+				if linkType != link.Type {
 					if link.Type == LINK_TYPE_FALLBACK {
 						link.Type = linkType
-					} else {
+					} else if linkType != LINK_TYPE_FALLBACK {
 						log.WithFields(map[string]interface{}{
-							"link":   fmt.Sprintf("%s-%s", linkOrigin.SourceAddress, linkOrigin.TargetAddress),
-							"prev":   link.Type,
-							"new":    linkType,
-							"source": typeList[linkOrigin.SourceAddress],
-							"target": typeList[linkOrigin.TargetAddress],
+							"link": fmt.Sprintf("%s-%s", linkOrigin.SourceAddress, linkOrigin.TargetAddress),
+							"prev": link.Type,
+							"new":  linkType,
 						}).Warn("different linktypes")
 					}
 				}
@@ -101,11 +91,7 @@ func transform(nodes *runtime.Nodes) *Meshviewer {
 				TargetAddress: linkOrigin.TargetAddress,
 				SourceTQ:      linkOrigin.TQ,
 				TargetTQ:      0,
-			}
-
-			linkType, linkTypeFound := typeList[linkOrigin.SourceAddress]
-			if !linkTypeFound {
-				linkType, linkTypeFound = typeList[linkOrigin.TargetAddress]
+				Type:          linkType,
 			}
 
 			if switchSourceTarget {
@@ -115,22 +101,20 @@ func transform(nodes *runtime.Nodes) *Meshviewer {
 				link.TargetTQ = linkOrigin.TQ
 				link.Target = linkOrigin.SourceID
 				link.TargetAddress = linkOrigin.SourceAddress
-
-				linkType, linkTypeFound = typeList[linkOrigin.TargetAddress]
-				if !linkTypeFound {
-					linkType, linkTypeFound = typeList[linkOrigin.SourceAddress]
-				}
-			}
-
-			if linkTypeFound {
-				link.Type = linkType
-			} else {
-				link.Type = LINK_TYPE_FALLBACK
 			}
 			links[key] = link
 			meshviewer.Links = append(meshviewer.Links, link)
 		}
 	}
 
+	// the links of a node are in a random order
+	sort.Slice(meshviewer.Links, func(i, j int) bool {
+		a, b := meshviewer.Links[i], meshviewer.Links[j]
+		if a.SourceAddress != b.SourceAddress {
+			return a.SourceAddress < b.SourceAddress
+		}
+		return a.TargetAddress < b.TargetAddress
+	})
+
 	return meshviewer
 }
@@ -158,6 +158,14 @@ func TestTransform(t *testing.T) {
 	links := meshviewer.Links
 	assert.Len(links, 3)
 
+	// sorted nodes and links
+	for i, nodeID := range []string{"node_a", "node_b", "node_c", "node_d"} {
+		assert.Equal(nodeID, meshviewer.Nodes[i].NodeID)
+	}
+	for i, address := range []string{"node:a:mac:lan", "node:a:mac:wifi", "node:b:mac:lan"} {
+		assert.Equal(address, links[i].SourceAddress)
+	}
+
 	for _, link := range links {
 		switch link.SourceAddress {
 		case "node:a:mac:lan":
@@ -41,3 +41,8 @@ func Register(configuration map[string]interface{}) (output.Output, error) {
 func (o *Output) Save(nodes *runtime.Nodes) {
 	runtime.SaveJSON(transform(nodes), o.path)
 }
+
+// Paths returns the written file
+func (o *Output) Paths() []string {
+	return []string{o.path}
+}
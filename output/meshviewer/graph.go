@@ -2,6 +2,7 @@ package meshviewer
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/FreifunkBremen/yanic/runtime"
@@ -31,6 +32,7 @@ type GraphLink struct {
 	VPN      bool    `json:"vpn"`
 	TQ       float32 `json:"tq"`
 	Bidirect bool    `json:"bidirect"`
+	Type     string  `json:"type,omitempty"` // e.g. runtime.LINK_TYPE_VPN, empty if unknown
 }
 
 // GraphBuilder a temporaty struct during fill the graph from the node neighbours
@@ -48,7 +50,7 @@ func BuildGraph(nodes *runtime.Nodes) *Graph {
 		links:   make(map[string]*GraphLink),
 	}
 
-	builder.readNodes(nodes.List)
+	builder.readNodes(nodes)
 
 	graph := &Graph{Version: 1}
 	graph.Batadv.Directed = false
@@ -56,11 +58,16 @@ func BuildGraph(nodes *runtime.Nodes) *Graph {
 	return graph
 }
 
-func (builder *graphBuilder) readNodes(nodes map[string]*runtime.Node) {
+func (builder *graphBuilder) readNodes(list *runtime.Nodes) {
+	nodes := list.List
 	vpnInterface := make(map[string]interface{})
 
+	// sorted, so an address claimed by more nodes is resolved deterministic
+	nodeIDs := runtime.SortedNodeIDs(nodes)
+
 	// Fill mac->id map
-	for sourceID, node := range nodes {
+	for _, sourceID := range nodeIDs {
+		node := nodes[sourceID]
 		if nodeinfo := node.Nodeinfo; nodeinfo != nil {
 
 			if nodeinfo.Network.Mac != "" {
@@ -89,15 +96,25 @@ func (builder *graphBuilder) readNodes(nodes map[string]*runtime.Node) {
 	}
 
 	// Add links
-	for sourceID, node := range nodes {
+	for _, sourceID := range nodeIDs {
+		node := nodes[sourceID]
 		if node.Online {
 			if neighbours := node.Neighbours; neighbours != nil {
+				// types of the batman-adv links by their interfaces
+				types := make(map[[2]string]string)
+				for _, link := range list.NodeLinks(node) {
+					if link.Protocol == runtime.LINK_PROTOCOL_BATADV {
+						types[[2]string{link.SourceAddress, link.TargetAddress}] = link.Type
+					}
+				}
+
 				// Batman neighbours
 				for sourceMAC, batadvNeighbours := range neighbours.Batadv {
 					for targetAddress, link := range batadvNeighbours.Neighbours {
 						if targetID, found := builder.macToID[targetAddress]; found {
+							linkType := types[[2]string{sourceMAC, targetAddress}]
 							_, vpn := vpnInterface[sourceMAC]
-							builder.addLink(targetID, sourceID, link.Tq, vpn)
+							builder.addLink(targetID, sourceID, link.Tq, vpn || linkType == runtime.LINK_TYPE_VPN, linkType)
 						}
 					}
 				}
@@ -105,7 +122,7 @@ func (builder *graphBuilder) readNodes(nodes map[string]*runtime.Node) {
 				for _, neighbours := range neighbours.LLDP {
 					for targetAddress := range neighbours {
 						if targetID, found := builder.macToID[targetAddress]; found {
-							builder.addLink(targetID, sourceID, 255, false)
+							builder.addLink(targetID, sourceID, 255, false, runtime.LINK_TYPE_WIRED)
 						}
 					}
 				}
@@ -147,19 +164,25 @@ func (builder *graphBuilder) extract() ([]*GraphNode, []*GraphLink) {
 	links := make([]*GraphLink, len(builder.links))
 	cache := newGraphNodeCache(builder.idToMac)
 
+	// sort keys to get the links and nodes in a deterministic order
+	keys := make([]string, 0, len(builder.links))
+	for key := range builder.links {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
 	// collect links
-	i := 0
-	for key, link := range builder.links {
+	for i, key := range keys {
+		link := builder.links[key]
 		pos := strings.IndexByte(key, '-')
 		link.Source = cache.getIndex(key[:pos])
 		link.Target = cache.getIndex(key[pos+1:])
 		links[i] = link
-		i++
 	}
 	return cache.Nodes, links
 }
 
-func (builder *graphBuilder) addLink(targetID string, sourceID string, linkTq int, vpn bool) {
+func (builder *graphBuilder) addLink(targetID string, sourceID string, linkTq int, vpn bool, linkType string) {
 	// Sort IDs to generate the key
 	var key string
 	if strings.Compare(sourceID, targetID) > 0 {
@@ -175,14 +198,18 @@ func (builder *graphBuilder) addLink(targetID string, sourceID string, linkTq in
 
 	if link, ok := builder.links[key]; !ok {
 		builder.links[key] = &GraphLink{
-			VPN: vpn,
-			TQ:  tq,
+			VPN:  vpn,
+			TQ:   tq,
+			Type: linkType,
 		}
 	} else {
 		// Use lowest of both link qualities
 		if tq < link.TQ {
 			link.TQ = tq
 		}
+		// a VPN link, if one of both directions is reported on a tunnel interface
+		link.VPN = link.VPN || vpn
+		link.Type = runtime.MergeLinkTypes(link.Type, linkType)
 		link.Bidirect = true
 	}
 }
@@ -28,9 +28,51 @@ func TestGenerateGraph(t *testing.T) {
 	assert.Len(graph.Batadv.Links, 3, "wrong Links count")
 	assert.Equal(4, testNodesCountWithLinks(graph.Batadv.Links), "wrong unneed nodes in graph")
 	assert.Len(graph.Batadv.Nodes, 4, "wrong Nodes count")
+
+	// identical state generates identical output
+	for i := 0; i < 10; i++ {
+		assert.Equal(graph, BuildGraph(nodes))
+	}
 	// TODO more tests required
 }
 
+func TestGraphVPN(t *testing.T) {
+	assert := assert.New(t)
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+
+	// only one side reports the link on its tunnel interface, it is added as second direction
+	for nodeID, addresses := range map[string][2]string{"a": {"a:mesh", "b:vpn"}, "b": {"b:vpn", "a:mesh"}} {
+		iface := &data.NetworkInterface{}
+		if nodeID == "b" {
+			iface.Interfaces.Tunnel = []string{addresses[0]}
+		} else {
+			iface.Interfaces.Other = []string{addresses[0]}
+		}
+		nodes.Update(nodeID, &data.ResponseData{
+			Nodeinfo: &data.Nodeinfo{
+				NodeID:  nodeID,
+				Network: data.Network{Mac: nodeID, Mesh: map[string]*data.NetworkInterface{"bat0": iface}},
+			},
+			Neighbours: &data.Neighbours{
+				NodeID: nodeID,
+				Batadv: map[string]data.BatadvNeighbours{
+					addresses[0]: {Neighbours: map[string]data.BatmanLink{addresses[1]: {Tq: 255}}},
+				},
+			},
+		})
+	}
+
+	for i := 0; i < 10; i++ {
+		graph := BuildGraph(nodes)
+		if assert.Len(graph.Batadv.Links, 1) {
+			assert.True(graph.Batadv.Links[0].VPN)
+			assert.True(graph.Batadv.Links[0].Bidirect)
+			// by the interfaces of both nodes
+			assert.Equal(runtime.LINK_TYPE_VPN, graph.Batadv.Links[0].Type)
+		}
+	}
+}
+
 func testGetNodesByFile(files ...string) *runtime.Nodes {
 
 	nodes := runtime.NewNodes(&runtime.NodesConfig{})
@@ -0,0 +1,90 @@
+package meshviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// ReadNodes reads a nodes.json of version 1 or 2 (e.g. of an older collector) into the nodes of a state file.
+// Only the nodeinfo, the first and last seen time and the statistics known by the meshviewer are kept,
+// all nodes are offline until they answer again.
+func ReadNodes(r io.Reader) (map[string]*runtime.Node, error) {
+	return readNodes(r, false)
+}
+
+// ReadCurrentNodes reads a nodes.json like ReadNodes, but keeps the online flag of the nodes,
+// e.g. to merge the current nodes of the map of another community
+func ReadCurrentNodes(r io.Reader) (map[string]*runtime.Node, error) {
+	return readNodes(r, true)
+}
+
+func readNodes(r io.Reader, online bool) (map[string]*runtime.Node, error) {
+	var file struct {
+		Version int             `json:"version"`
+		Nodes   json.RawMessage `json:"nodes"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	var list []*Node
+	switch file.Version {
+	case 1:
+		var nodes map[string]*Node
+		if err := json.Unmarshal(file.Nodes, &nodes); err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			list = append(list, node)
+		}
+	case 2:
+		if err := json.Unmarshal(file.Nodes, &list); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported version of nodes: %d", file.Version)
+	}
+
+	result := make(map[string]*runtime.Node)
+	for _, node := range list {
+		if node == nil || node.Nodeinfo == nil || node.Nodeinfo.NodeID == "" {
+			continue
+		}
+		result[node.Nodeinfo.NodeID] = &runtime.Node{
+			Firstseen:  node.Firstseen,
+			Lastseen:   node.Lastseen,
+			Online:     online && node.Flags.Online,
+			Nodeinfo:   node.Nodeinfo,
+			Statistics: node.Statistics.respondd(node.Nodeinfo.NodeID),
+		}
+	}
+	if len(result) == 0 && len(list) > 0 {
+		return nil, errors.New("no node with a nodeinfo")
+	}
+	return result, nil
+}
+
+// respondd transforms the meshviewer statistics back to respondd statistics, as far as they are known
+func (stats *Statistics) respondd(nodeID string) *data.Statistics {
+	if stats == nil {
+		return nil
+	}
+	return &data.Statistics{
+		NodeID:      nodeID,
+		Clients:     data.Clients{Total: stats.Clients},
+		RootFsUsage: stats.RootFsUsage,
+		LoadAverage: stats.LoadAverage,
+		Uptime:      stats.Uptime,
+		Idletime:    stats.Idletime,
+		GatewayIPv4: stats.GatewayIPv4,
+		GatewayIPv6: stats.GatewayIPv6,
+		Processes:   stats.Processes,
+		MeshVPN:     stats.MeshVPN,
+		Traffic:     stats.Traffic,
+	}
+}
@@ -1,6 +1,9 @@
 package meshviewer
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,6 +23,42 @@ func TestNodesV2(t *testing.T) {
 
 	assert := assert.New(t)
 	assert.Len(nodes.List, 2)
+
+	// sorted by node ID
+	assert.Equal("112233445566", nodes.List[0].Nodeinfo.NodeID)
+	assert.Equal("abcdef012345", nodes.List[1].Nodeinfo.NodeID)
+}
+
+func TestReadNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, build := range []func(*runtime.Nodes) interface{}{BuildNodesV1, BuildNodesV2} {
+		var buf bytes.Buffer
+		assert.NoError(json.NewEncoder(&buf).Encode(build(createTestNodes())))
+
+		nodes, err := ReadNodes(&buf)
+		assert.NoError(err)
+		assert.Len(nodes, 2)
+		node := nodes["abcdef012345"]
+		assert.Equal("abcdef012345", node.Nodeinfo.NodeID)
+		assert.Equal("abcdef012345", node.Statistics.NodeID)
+		assert.False(node.Online)
+	}
+
+	// keeps the online flag
+	online := createTestNodes()
+	online.List["abcdef012345"].Online = true
+	var buf bytes.Buffer
+	assert.NoError(json.NewEncoder(&buf).Encode(BuildNodesV2(online)))
+	nodes, err := ReadCurrentNodes(&buf)
+	assert.NoError(err)
+	assert.True(nodes["abcdef012345"].Online)
+	assert.False(nodes["112233445566"].Online)
+
+	_, err = ReadNodes(strings.NewReader(`{"version":3,"nodes":[]}`))
+	assert.EqualError(err, "unsupported version of nodes: 3")
+	_, err = ReadNodes(strings.NewReader(`{"version":2,"nodes":[{"firstseen":"2017-01-01T00:00:00+0000"}]}`))
+	assert.Error(err)
 }
 
 func createTestNodes() *runtime.Nodes {
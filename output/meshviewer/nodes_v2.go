@@ -20,7 +20,8 @@ func BuildNodesV2(nodes *runtime.Nodes) interface{} {
 		Timestamp: jsontime.Now(),
 	}
 
-	for _, nodeOrigin := range nodes.List {
+	for _, nodeID := range runtime.SortedNodeIDs(nodes.List) {
+		nodeOrigin := nodes.List[nodeID]
 		if nodeOrigin.Statistics == nil {
 			continue
 		}
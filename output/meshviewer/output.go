@@ -71,3 +71,15 @@ func (o *Output) Save(nodes *runtime.Nodes) {
 		runtime.SaveJSON(BuildGraph(nodes), path)
 	}
 }
+
+// Paths returns the written files of the nodes and the graph
+func (o *Output) Paths() []string {
+	var paths []string
+	if path := o.config.NodesPath(); path != "" {
+		paths = append(paths, path)
+	}
+	if path := o.config.GraphPath(); path != "" {
+		paths = append(paths, path)
+	}
+	return paths
+}
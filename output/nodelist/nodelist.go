@@ -51,10 +51,11 @@ func transform(nodes *runtime.Nodes) *NodeList {
 	nodelist := &NodeList{
 		Version:   "1.0.1",
 		Timestamp: jsontime.Now(),
+		List:      make([]*Node, 0, len(nodes.List)), // an empty list instead of null
 	}
 
-	for _, nodeOrigin := range nodes.List {
-		node := NewNode(nodeOrigin)
+	for _, nodeID := range runtime.SortedNodeIDs(nodes.List) {
+		node := NewNode(nodes.List[nodeID])
 		if node != nil {
 			nodelist.List = append(nodelist.List, node)
 		}
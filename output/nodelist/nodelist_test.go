@@ -1,6 +1,7 @@
 package nodelist
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,6 +15,19 @@ func TestTransform(t *testing.T) {
 
 	assert := assert.New(t)
 	assert.Len(nodes.List, 3)
+
+	// sorted by node ID
+	assert.Equal("0xdeadbeef0x", nodes.List[0].ID)
+	assert.Equal("112233445566", nodes.List[1].ID)
+	assert.Equal("abcdef012345", nodes.List[2].ID)
+}
+
+func TestTransformEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	out, err := json.Marshal(transform(runtime.NewNodes(&runtime.NodesConfig{})))
+	assert.NoError(err)
+	assert.Contains(string(out), `"nodes":[]`)
 }
 
 func createTestNodes() *runtime.Nodes {
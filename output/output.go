@@ -8,6 +8,13 @@ type Output interface {
 	Save(nodes *runtime.Nodes)
 }
 
+// FileOutput is an output writing files, e.g. to upload them after a save
+type FileOutput interface {
+	Output
+	// Paths returns the files written by a save
+	Paths() []string
+}
+
 // Register function with config to get a output interface
 type Register func(config map[string]interface{}) (Output, error)
 
@@ -0,0 +1,178 @@
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// globalMetric is a metric of the global statistics of a site and domain
+type globalMetric struct {
+	name  string
+	help  string
+	value func(*runtime.GlobalStats) uint32
+}
+
+var globalMetrics = []globalMetric{
+	{
+		name:  "yanic_nodes",
+		help:  "Count of online nodes",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.Nodes },
+	},
+	{
+		name:  "yanic_gateways",
+		help:  "Count of online gateways",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.Gateways },
+	},
+	{
+		name:  "yanic_reboots",
+		help:  "Count of nodes, which rebooted within the last hour",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.Reboots },
+	},
+	{
+		name:  "yanic_nodes_uplink_only",
+		help:  "Count of nodes, which have only VPN links (no mesh neighbours)",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.NodesUplinkOnly },
+	},
+	{
+		name:  "yanic_clients",
+		help:  "Count of clients",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.Clients },
+	},
+	{
+		name:  "yanic_clients_wifi",
+		help:  "Count of wifi clients",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.ClientsWifi },
+	},
+	{
+		name:  "yanic_clients_wifi24",
+		help:  "Count of wifi clients on 2.4 GHz",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.ClientsWifi24 },
+	},
+	{
+		name:  "yanic_clients_wifi5",
+		help:  "Count of wifi clients on 5 GHz",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.ClientsWifi5 },
+	},
+	{
+		name:  "yanic_clients_owe",
+		help:  "Count of wifi clients of the encrypted (OWE) networks",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.ClientsOwe },
+	},
+	{
+		name:  "yanic_clients_owe24",
+		help:  "Count of wifi clients of the encrypted (OWE) networks on 2.4 GHz",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.ClientsOwe24 },
+	},
+	{
+		name:  "yanic_clients_owe5",
+		help:  "Count of wifi clients of the encrypted (OWE) networks on 5 GHz",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.ClientsOwe5 },
+	},
+	{
+		name:  "yanic_clients_unique",
+		help:  "Count of clients without the dual-band devices counted twice (the total of nodes without deduplication)",
+		value: func(stats *runtime.GlobalStats) uint32 { return stats.ClientsUnique },
+	},
+}
+
+// globalCounterMetric is a metric of a counter map of the global statistics, labeled by its keys
+type globalCounterMetric struct {
+	name   string
+	help   string
+	label  string
+	values func(*runtime.GlobalStats) runtime.CounterMap
+}
+
+var globalCounterMetrics = []globalCounterMetric{
+	{
+		name:   "yanic_firmware_nodes",
+		help:   "Count of online nodes per firmware release",
+		label:  "firmware",
+		values: func(stats *runtime.GlobalStats) runtime.CounterMap { return stats.Firmwares },
+	},
+	{
+		name:   "yanic_model_nodes",
+		help:   "Count of online nodes per hardware model",
+		label:  "model",
+		values: func(stats *runtime.GlobalStats) runtime.CounterMap { return stats.Models },
+	},
+	{
+		name:   "yanic_autoupdater_nodes",
+		help:   "Count of online nodes per autoupdater branch (disabled for nodes without autoupdater)",
+		label:  "branch",
+		values: func(stats *runtime.GlobalStats) runtime.CounterMap { return stats.Autoupdater },
+	},
+	{
+		name:   "yanic_autoupdater_disabled_nodes",
+		help:   "Count of online nodes with disabled autoupdater per configured branch",
+		label:  "branch",
+		values: func(stats *runtime.GlobalStats) runtime.CounterMap { return stats.AutoupdaterDisabled },
+	},
+	{
+		name:   "yanic_channel24_nodes",
+		help:   "Count of online nodes per configured wifi channel on 2.4 GHz",
+		label:  "channel",
+		values: func(stats *runtime.GlobalStats) runtime.CounterMap { return stats.Channels24 },
+	},
+	{
+		name:   "yanic_channel5_nodes",
+		help:   "Count of online nodes per configured wifi channel on 5 GHz",
+		label:  "channel",
+		values: func(stats *runtime.GlobalStats) runtime.CounterMap { return stats.Channels5 },
+	},
+	{
+		name:   "yanic_latency_nodes",
+		help:   "Count of online nodes per bucket of their response latency, by its upper bound in seconds",
+		label:  "bucket",
+		values: func(stats *runtime.GlobalStats) runtime.CounterMap { return stats.Latency },
+	},
+}
+
+// writeGlobals writes the global statistics of every site and domain, sorted by their labels
+func writeGlobals(buf *bufio.Writer, stats map[string]map[string]*runtime.GlobalStats, format string) {
+	type entry struct {
+		site   string
+		domain string
+		labels string
+		stats  *runtime.GlobalStats
+	}
+	var entries []entry
+	for site, domains := range stats {
+		for domain, stat := range domains {
+			entries = append(entries, entry{
+				site:   site,
+				domain: domain,
+				labels: formatLabels([][2]string{{"site", site}, {"domain", domain}}),
+				stats:  stat,
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].labels < entries[j].labels })
+
+	for _, m := range globalMetrics {
+		sample := writeHeader(buf, m.name, m.help, false, format)
+		for _, e := range entries {
+			fmt.Fprintf(buf, "%s%s %s\n", sample, e.labels, strconv.FormatUint(uint64(m.value(e.stats)), 10))
+		}
+	}
+
+	for _, m := range globalCounterMetrics {
+		sample := writeHeader(buf, m.name, m.help, false, format)
+		for _, e := range entries {
+			values := m.values(e.stats)
+			keys := make([]string, 0, len(values))
+			for key := range values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				labels := formatLabels([][2]string{{"site", e.site}, {"domain", e.domain}, {m.label, key}})
+				fmt.Fprintf(buf, "%s%s %s\n", sample, labels, strconv.FormatUint(uint64(values[key]), 10))
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	contentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// HandlerConfig are the optional sources of the metrics of the handler
+type HandlerConfig struct {
+	SitesDomains func() map[string][]string // sites and domains of the global statistics
+	Counters     func() []runtime.Counter   // internal counters of yanic, e.g. of the collector
+	MaxNodes     int                        // maximum count of exported nodes, the least recently seen are dropped (0 for all)
+}
+
+type handler struct {
+	nodes  *runtime.Nodes
+	config HandlerConfig
+}
+
+// NewHandler returns a handler, which serves the metrics of the online nodes,
+// the global statistics of the sites and domains and the internal counters.
+// The OpenMetrics format is served, if it is accepted by the client.
+func NewHandler(nodes *runtime.Nodes, config HandlerConfig) http.Handler {
+	return &handler{
+		nodes:  nodes,
+		config: config,
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	format, contentType := FormatPrometheus, contentTypePrometheus
+	if strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+		format, contentType = FormatOpenMetrics, contentTypeOpenMetrics
+	}
+
+	var sitesDomains map[string][]string
+	if h.config.SitesDomains != nil {
+		sitesDomains = h.config.SitesDomains()
+	}
+	stats := runtime.NewGlobalStats(h.nodes, sitesDomains)
+	var counters []runtime.Counter
+	if h.config.Counters != nil {
+		counters = h.config.Counters()
+	}
+
+	var buf bytes.Buffer
+	h.nodes.RLock()
+	list, dropped := onlineNodes(h.nodes, h.config.MaxNodes)
+	err := writeMetrics(&buf, list, stats, counters, format)
+	h.nodes.RUnlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if dropped > 0 {
+		log.WithField("dropped", dropped).Warnf("more than %d online nodes, dropped the least recently seen from the metrics", h.config.MaxNodes)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
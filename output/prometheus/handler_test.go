@@ -0,0 +1,102 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	nodes.AddNode(&runtime.Node{
+		Online: true,
+		Nodeinfo: &data.Nodeinfo{
+			NodeID: "000000000001",
+			System: data.System{SiteCode: "ffhb", DomainCode: "city"},
+			Software: data.Software{
+				Autoupdater: &struct {
+					Enabled bool   `json:"enabled,omitempty"`
+					Branch  string `json:"branch,omitempty"`
+				}{
+					Enabled: true,
+					Branch:  "stable",
+				},
+			},
+		},
+		Statistics: &data.Statistics{
+			Clients: data.Clients{Total: 23, Wifi: 20, Wifi24: 12, Wifi5: 8, Owe: 4, Owe24: 1, Owe5: 3},
+		},
+	})
+	nodes.AddNode(&runtime.Node{
+		Online: true,
+		Nodeinfo: &data.Nodeinfo{
+			NodeID: "000000000002",
+			System: data.System{SiteCode: "ffhb", DomainCode: "city"},
+		},
+	})
+	handler := NewHandler(nodes, HandlerConfig{
+		SitesDomains: func() map[string][]string {
+			return map[string][]string{"ffhb": {"city"}}
+		},
+		Counters: func() []runtime.Counter {
+			return []runtime.Counter{
+				{Name: "responses_dropped_late", Help: "Late responses", Value: 3},
+				{Name: "queue_length", Help: "Queued responses", Value: 5, Gauge: true},
+			}
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(contentTypePrometheus, rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Contains(body, `yanic_node_clients{nodeid="000000000001",hostname="",site="ffhb",domain="city"} 23`)
+	assert.Contains(body, "# TYPE yanic_nodes gauge\n")
+	assert.Contains(body, `yanic_nodes{site="global",domain="global"} 2`)
+	assert.Contains(body, `yanic_clients{site="ffhb",domain="city"} 23`)
+
+	// clients by band
+	assert.Contains(body, `yanic_node_clients_wifi24{nodeid="000000000001",hostname="",site="ffhb",domain="city"} 12`)
+	assert.Contains(body, `yanic_node_clients_owe5{nodeid="000000000001",hostname="",site="ffhb",domain="city"} 3`)
+	assert.Contains(body, `yanic_clients_wifi5{site="global",domain="global"} 8`)
+	assert.Contains(body, `yanic_clients_owe24{site="global",domain="global"} 1`)
+	assert.NotContains(body, "# EOF")
+
+	// internal counters
+	assert.Contains(body, "# TYPE yanic_responses_dropped_late_total counter\nyanic_responses_dropped_late_total 3\n")
+	assert.Contains(body, "# TYPE yanic_queue_length gauge\nyanic_queue_length 5\n")
+
+	// counter maps
+	assert.Contains(body, `yanic_autoupdater_nodes{site="ffhb",domain="city",branch="disabled"} 1`)
+	assert.Contains(body, `yanic_autoupdater_nodes{site="ffhb",domain="city",branch="stable"} 1`)
+	assert.True(strings.Index(body, `branch="disabled"} 1`) < strings.Index(body, `branch="stable"} 1`))
+	assert.Contains(body, "# TYPE yanic_firmware_nodes gauge\n")
+
+	// sorted by site and domain
+	assert.True(strings.Index(body, `yanic_nodes{site="ffhb",domain="city"}`) < strings.Index(body, `yanic_nodes{site="ffhb",domain="global"}`))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0,text/plain;q=0.5")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(contentTypeOpenMetrics, rec.Header().Get("Content-Type"))
+	assert.True(strings.HasSuffix(rec.Body.String(), "# EOF\n"))
+	assert.Contains(rec.Body.String(), "# TYPE yanic_responses_dropped_late counter\nyanic_responses_dropped_late_total 3\n")
+
+	// limited count of nodes
+	handler = NewHandler(nodes, HandlerConfig{MaxNodes: 1})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body = rec.Body.String()
+	assert.Equal(1, strings.Count(body, "yanic_node_lastseen_seconds{"))
+	assert.Contains(body, `yanic_nodes{site="global",domain="global"} 2`)
+}
@@ -0,0 +1,214 @@
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// metric is a metric of a node
+type metric struct {
+	name    string
+	help    string
+	counter bool
+	value   func(*runtime.Node) (float64, bool)
+}
+
+var metrics = []metric{
+	{
+		name: "yanic_node_lastseen_seconds",
+		help: "Unix time of the last response of the node",
+		value: func(node *runtime.Node) (float64, bool) {
+			return float64(node.Lastseen.Unix()), true
+		},
+	},
+	{
+		name: "yanic_node_clients",
+		help: "Count of clients of the node",
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil {
+				return float64(stats.Clients.Total), true
+			}
+			return 0, false
+		},
+	},
+	{
+		name: "yanic_node_clients_wifi24",
+		help: "Count of wifi clients of the node on 2.4 GHz",
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil {
+				return float64(stats.Clients.Wifi24), true
+			}
+			return 0, false
+		},
+	},
+	{
+		name: "yanic_node_clients_wifi5",
+		help: "Count of wifi clients of the node on 5 GHz",
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil {
+				return float64(stats.Clients.Wifi5), true
+			}
+			return 0, false
+		},
+	},
+	{
+		name: "yanic_node_clients_owe24",
+		help: "Count of wifi clients of the encrypted (OWE) networks of the node on 2.4 GHz",
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil {
+				return float64(stats.Clients.Owe24), true
+			}
+			return 0, false
+		},
+	},
+	{
+		name: "yanic_node_clients_owe5",
+		help: "Count of wifi clients of the encrypted (OWE) networks of the node on 5 GHz",
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil {
+				return float64(stats.Clients.Owe5), true
+			}
+			return 0, false
+		},
+	},
+	{
+		name: "yanic_node_clients_unique",
+		help: "Count of clients of the node without the dual-band devices counted twice",
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil && stats.Clients.Unique != nil {
+				return float64(*stats.Clients.Unique), true
+			}
+			return 0, false
+		},
+	},
+	{
+		name: "yanic_node_load",
+		help: "Load average of the node",
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil {
+				return stats.LoadAverage, true
+			}
+			return 0, false
+		},
+	},
+	{
+		name: "yanic_node_uptime_seconds",
+		help: "Uptime of the node",
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil {
+				return stats.Uptime, true
+			}
+			return 0, false
+		},
+	},
+	{
+		name: "yanic_node_latency_seconds",
+		help: "Time between the last request and the response of the node",
+		value: func(node *runtime.Node) (float64, bool) {
+			return node.Latency, node.Latency > 0
+		},
+	},
+	{
+		name:    "yanic_node_traffic_rx_bytes",
+		help:    "Received bytes of the node",
+		counter: true,
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil && stats.Traffic.Rx != nil {
+				return stats.Traffic.Rx.Bytes, true
+			}
+			return 0, false
+		},
+	},
+	{
+		name:    "yanic_node_traffic_tx_bytes",
+		help:    "Transmitted bytes of the node",
+		counter: true,
+		value: func(node *runtime.Node) (float64, bool) {
+			if stats := node.Statistics; stats != nil && stats.Traffic.Tx != nil {
+				return stats.Traffic.Tx.Bytes, true
+			}
+			return 0, false
+		},
+	},
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels of a node, nodes without nodeinfo are not exported
+func labels(node *runtime.Node) string {
+	nodeinfo := node.Nodeinfo
+	return formatLabels([][2]string{
+		{"nodeid", nodeinfo.NodeID},
+		{"hostname", nodeinfo.Hostname},
+		{"site", nodeinfo.System.SiteCode},
+		{"domain", nodeinfo.System.DomainCode},
+	})
+}
+
+// formatLabels formats the label pairs with escaped values
+func formatLabels(pairs [][2]string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, pair := range pairs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pair[0])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pair[1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// writeHeader writes the help and type of a metric and returns the name of its samples
+func writeHeader(buf *bufio.Writer, name, help string, counter bool, format string) string {
+	// in the prometheus format the type is given for the sample name,
+	// in openmetrics for the family name (without _total)
+	family, sample, typ := name, name, "gauge"
+	if counter {
+		typ = "counter"
+		sample += "_total"
+		if format == FormatPrometheus {
+			family = sample
+		}
+	}
+	fmt.Fprintf(buf, "# HELP %s %s\n", family, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", family, typ)
+	return sample
+}
+
+// writeMetrics writes the metrics of the nodes, the global statistics (if not nil) and the counters in the given format
+func writeMetrics(w io.Writer, nodes []*runtime.Node, stats map[string]map[string]*runtime.GlobalStats, counters []runtime.Counter, format string) error {
+	buf := bufio.NewWriter(w)
+	nodeLabels := make([]string, len(nodes))
+	for i, node := range nodes {
+		nodeLabels[i] = labels(node)
+	}
+
+	for _, m := range metrics {
+		sample := writeHeader(buf, m.name, m.help, m.counter, format)
+		for i, node := range nodes {
+			if value, ok := m.value(node); ok {
+				fmt.Fprintf(buf, "%s%s %s\n", sample, nodeLabels[i], strconv.FormatFloat(value, 'g', -1, 64))
+			}
+		}
+	}
+	if stats != nil {
+		writeGlobals(buf, stats, format)
+	}
+	for _, counter := range counters {
+		sample := writeHeader(buf, "yanic_"+counter.Name, counter.Help, !counter.Gauge, format)
+		fmt.Fprintf(buf, "%s %s\n", sample, strconv.FormatUint(counter.Value, 10))
+	}
+	if format == FormatOpenMetrics {
+		buf.WriteString("# EOF\n")
+	}
+	return buf.Flush()
+}
@@ -0,0 +1,58 @@
+package prometheus
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	node := &runtime.Node{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "000000000001",
+			Hostname: "node \"one\"\\",
+			System: data.System{
+				SiteCode:   "ffhb",
+				DomainCode: "city",
+			},
+		},
+		Statistics: &data.Statistics{
+			Clients: data.Clients{Total: 23},
+			Traffic: struct {
+				Tx      *data.Traffic `json:"tx"`
+				Rx      *data.Traffic `json:"rx"`
+				Forward *data.Traffic `json:"forward"`
+				MgmtTx  *data.Traffic `json:"mgmt_tx"`
+				MgmtRx  *data.Traffic `json:"mgmt_rx"`
+			}{
+				Rx: &data.Traffic{Bytes: 1213},
+			},
+		},
+	}
+	labels := `{nodeid="000000000001",hostname="node \"one\"\\",site="ffhb",domain="city"}`
+
+	var buf bytes.Buffer
+	assert.NoError(writeMetrics(&buf, []*runtime.Node{node, {Nodeinfo: &data.Nodeinfo{NodeID: "000000000002"}}}, nil, nil, FormatPrometheus))
+	output := buf.String()
+	assert.Contains(output, "# TYPE yanic_node_clients gauge\n")
+	assert.Contains(output, "yanic_node_clients"+labels+" 23\n")
+	assert.Contains(output, "# TYPE yanic_node_traffic_rx_bytes_total counter\n")
+	assert.Contains(output, "yanic_node_traffic_rx_bytes_total"+labels+" 1213\n")
+	assert.NotContains(output, "yanic_node_traffic_tx_bytes_total{")
+	assert.NotContains(output, `yanic_node_clients{nodeid="000000000002"`)
+	assert.NotContains(output, "# EOF")
+
+	buf.Reset()
+	assert.NoError(writeMetrics(&buf, []*runtime.Node{node}, nil, nil, FormatOpenMetrics))
+	output = buf.String()
+	assert.Contains(output, "# TYPE yanic_node_traffic_rx_bytes counter\n")
+	assert.Contains(output, "yanic_node_traffic_rx_bytes_total"+labels+" 1213\n")
+	assert.True(strings.HasSuffix(output, "\n# EOF\n"))
+}
@@ -0,0 +1,126 @@
+package prometheus
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/output"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	FormatPrometheus  = "prometheus"  // Prometheus text format
+	FormatOpenMetrics = "openmetrics" // OpenMetrics text format
+)
+
+type Output struct {
+	output.Output
+	path     string
+	format   string
+	maxNodes int
+}
+
+type Config map[string]interface{}
+
+func (c Config) Path() string {
+	if path, ok := c["path"]; ok {
+		return path.(string)
+	}
+	return ""
+}
+
+func (c Config) Format() string {
+	if format, ok := c["format"]; ok {
+		return format.(string)
+	}
+	return FormatPrometheus
+}
+
+func (c Config) MaxNodes() int64 {
+	if v, ok := c["max_nodes"]; ok {
+		return v.(int64)
+	}
+	return 0
+}
+
+func init() {
+	output.RegisterAdapter("prometheus", Register)
+}
+
+func Register(configuration map[string]interface{}) (output.Output, error) {
+	var config Config
+	config = configuration
+
+	path := config.Path()
+	if path == "" {
+		return nil, errors.New("no path given")
+	}
+	format := config.Format()
+	if format != FormatPrometheus && format != FormatOpenMetrics {
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+	return &Output{
+		path:     path,
+		format:   format,
+		maxNodes: int(config.MaxNodes()),
+	}, nil
+}
+
+func (o *Output) Save(nodes *runtime.Nodes) {
+	nodes.RLock()
+	list := o.selectNodes(nodes)
+
+	err := runtime.WriteFileAtomic(o.path, func(w io.Writer) error {
+		return writeMetrics(w, list, nil, nil, o.format)
+	})
+	nodes.RUnlock()
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// selectNodes returns the online nodes, limited to maxNodes by dropping the least recently seen
+func (o *Output) selectNodes(nodes *runtime.Nodes) []*runtime.Node {
+	list, dropped := onlineNodes(nodes, o.maxNodes)
+	if dropped > 0 {
+		log.WithFields(map[string]interface{}{
+			"path":    o.path,
+			"dropped": dropped,
+		}).Warnf("more than %d online nodes, dropped the least recently seen", o.maxNodes)
+	}
+	return list
+}
+
+// onlineNodes returns the online nodes sorted by their last response,
+// limited to maxNodes (if positive) and the count of the dropped nodes.
+// The caller has to hold the read lock of the nodes.
+func onlineNodes(nodes *runtime.Nodes, maxNodes int) ([]*runtime.Node, int) {
+	list := make([]*runtime.Node, 0, len(nodes.List))
+	for _, node := range nodes.List {
+		if node.Online && node.Nodeinfo != nil {
+			list = append(list, node)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Lastseen.After(b.Lastseen) || b.Lastseen.After(a.Lastseen) {
+			return a.Lastseen.After(b.Lastseen)
+		}
+		return a.Nodeinfo.NodeID < b.Nodeinfo.NodeID
+	})
+
+	if maxNodes > 0 && len(list) > maxNodes {
+		return list[:maxNodes], len(list) - maxNodes
+	}
+	return list, 0
+}
+
+// Paths returns the written file
+func (o *Output) Paths() []string {
+	return []string{o.path}
+}
@@ -0,0 +1,85 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/lib/jsontime"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	out, err := Register(map[string]interface{}{})
+	assert.Error(err)
+	assert.Nil(out)
+
+	out, err = Register(map[string]interface{}{
+		"path":   "/tmp/yanic.prom",
+		"format": "unknown",
+	})
+	assert.Error(err)
+	assert.Nil(out)
+
+	out, err = Register(map[string]interface{}{
+		"path": "/tmp/yanic.prom",
+	})
+	os.Remove("/tmp/yanic.prom")
+	assert.NoError(err)
+	assert.NotNil(out)
+
+	out.Save(testNodes())
+	content, err := ioutil.ReadFile("/tmp/yanic.prom")
+	assert.NoError(err)
+	assert.Contains(string(content), `yanic_node_clients{nodeid="000000000001"`)
+	os.Remove("/tmp/yanic.prom")
+}
+
+func TestSelectNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := testNodes()
+
+	o := &Output{}
+	list := o.selectNodes(nodes)
+	assert.Len(list, 2)
+	assert.Equal("000000000002", list[0].Nodeinfo.NodeID)
+	assert.Equal("000000000001", list[1].Nodeinfo.NodeID)
+
+	// least recently seen is dropped
+	o.maxNodes = 1
+	list = o.selectNodes(nodes)
+	assert.Len(list, 1)
+	assert.Equal("000000000002", list[0].Nodeinfo.NodeID)
+}
+
+func testNodes() *runtime.Nodes {
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	now := jsontime.Now()
+	nodes.AddNode(&runtime.Node{
+		Online:   true,
+		Lastseen: now.Add(-time.Minute),
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "000000000001",
+			Hostname: "node \"one\"",
+		},
+		Statistics: &data.Statistics{
+			Clients: data.Clients{Total: 23},
+		},
+	})
+	nodes.AddNode(&runtime.Node{
+		Online:   true,
+		Lastseen: now,
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000002"},
+	})
+	nodes.AddNode(&runtime.Node{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000003"},
+	})
+	return nodes
+}
@@ -44,3 +44,8 @@ func (o *Output) Save(nodes *runtime.Nodes) {
 
 	runtime.SaveJSONL(transform(nodes), o.path)
 }
+
+// Paths returns the written file
+func (o *Output) Paths() []string {
+	return []string{o.path}
+}
@@ -9,7 +9,8 @@ import (
 
 type Output struct {
 	output.Output
-	path string
+	path   string
+	pretty bool
 }
 
 type Config map[string]interface{}
@@ -21,6 +22,13 @@ func (c Config) Path() string {
 	return ""
 }
 
+func (c Config) Pretty() bool {
+	if pretty, ok := c["pretty"]; ok {
+		return pretty.(bool)
+	}
+	return false
+}
+
 func init() {
 	output.RegisterAdapter("raw", Register)
 }
@@ -31,7 +39,8 @@ func Register(configuration map[string]interface{}) (output.Output, error) {
 
 	if path := config.Path(); path != "" {
 		return &Output{
-			path: path,
+			path:   path,
+			pretty: config.Pretty(),
 		}, nil
 	}
 	return nil, errors.New("no path given")
@@ -42,5 +51,14 @@ func (o *Output) Save(nodes *runtime.Nodes) {
 	nodes.RLock()
 	defer nodes.RUnlock()
 
-	runtime.SaveJSON(transform(nodes), o.path)
+	if o.pretty {
+		runtime.SaveJSONIndent(transform(nodes), o.path, "  ")
+	} else {
+		runtime.SaveJSON(transform(nodes), o.path)
+	}
+}
+
+// Paths returns the written file
+func (o *Output) Paths() []string {
+	return []string{o.path}
 }
@@ -1,6 +1,7 @@
 package raw
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -25,4 +26,16 @@ func TestOutput(t *testing.T) {
 	out.Save(&runtime.Nodes{})
 	_, err = os.Stat("/tmp/raw.json")
 	assert.NoError(err)
+
+	// pretty-printed
+	out, err = Register(map[string]interface{}{
+		"path":   "/tmp/raw.json",
+		"pretty": true,
+	})
+	assert.NoError(err)
+	out.Save(&runtime.Nodes{})
+	content, err := ioutil.ReadFile("/tmp/raw.json")
+	assert.NoError(err)
+	assert.Contains(string(content), "{\n  \"version\": \"1.0.0\",\n")
+	os.Remove("/tmp/raw.json")
 }
@@ -15,6 +15,7 @@ type RawNode struct {
 	Nodeinfo     *data.Nodeinfo         `json:"nodeinfo"`
 	Neighbours   *data.Neighbours       `json:"neighbours"`
 	CustomFields map[string]interface{} `json:"custom_fields"`
+	Origin       string                 `json:"origin,omitempty"`
 }
 
 type NodeList struct {
@@ -39,6 +40,7 @@ func transform(nodes *runtime.Nodes) *NodeList {
 				Nodeinfo:     nodeOrigin.Nodeinfo,
 				Neighbours:   nodeOrigin.Neighbours,
 				CustomFields: nodeOrigin.CustomFields,
+				Origin:       nodeOrigin.Origin,
 			}
 			nodelist.List = append(nodelist.List, node)
 		}
@@ -0,0 +1,169 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// types of the destinations
+const (
+	TypeWebDAV = "webdav"
+	TypeS3     = "s3"
+)
+
+// timeout of the upload of a single file
+const uploadTimeout = time.Minute
+
+// Uploader copies the files of an output to a remote destination
+type Uploader interface {
+	Upload(paths []string) error
+}
+
+// Config of the destination of an output
+type Config map[string]interface{}
+
+func (c Config) Type() string {
+	if value, ok := c["type"].(string); ok {
+		return value
+	}
+	return ""
+}
+
+func (c Config) URL() string {
+	if value, ok := c["url"].(string); ok {
+		return value
+	}
+	return ""
+}
+
+func (c Config) Username() string {
+	if value, ok := c["username"].(string); ok {
+		return value
+	}
+	return ""
+}
+
+func (c Config) Password() string {
+	if value, ok := c["password"].(string); ok {
+		return value
+	}
+	return ""
+}
+
+func (c Config) Region() string {
+	if value, ok := c["region"].(string); ok {
+		return value
+	}
+	return "us-east-1"
+}
+
+func (c Config) AccessKey() string {
+	if value, ok := c["access_key"].(string); ok {
+		return value
+	}
+	return ""
+}
+
+func (c Config) SecretKey() string {
+	if value, ok := c["secret_key"].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// New returns the uploader of the destination
+func New(configuration map[string]interface{}) (Uploader, error) {
+	var config Config
+	config = configuration
+
+	base, err := url.Parse(config.URL())
+	if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("invalid url %q", config.URL())
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	dest := &destination{
+		base:   base,
+		client: &http.Client{Timeout: uploadTimeout},
+	}
+
+	switch config.Type() {
+	case TypeWebDAV:
+		username, password := config.Username(), config.Password()
+		if username != "" {
+			dest.sign = func(req *http.Request, payload []byte) {
+				req.SetBasicAuth(username, password)
+			}
+		}
+	case TypeS3:
+		accessKey, secretKey := config.AccessKey(), config.SecretKey()
+		if accessKey == "" || secretKey == "" {
+			return nil, errors.New("s3 needs an access_key and a secret_key")
+		}
+		region := config.Region()
+		dest.sign = func(req *http.Request, payload []byte) {
+			signV4(req, payload, accessKey, secretKey, region, time.Now())
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", config.Type())
+	}
+	return dest, nil
+}
+
+// destination uploads the files by PUT requests to its base url
+type destination struct {
+	base   *url.URL
+	client *http.Client
+	sign   func(req *http.Request, payload []byte) // authenticates a request (optional)
+}
+
+// Upload puts every file by its name below the base url, it stops on the first error
+func (dest *destination) Upload(paths []string) error {
+	for _, p := range paths {
+		if err := dest.put(p); err != nil {
+			return fmt.Errorf("upload of %s: %s", p, err)
+		}
+	}
+	return nil
+}
+
+func (dest *destination) put(p string) error {
+	payload, err := ioutil.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	target := *dest.base
+	target.Path = path.Join(dest.base.Path, filepath.Base(p))
+
+	req, err := http.NewRequest(http.MethodPut, target.String(), strings.NewReader(string(payload)))
+	if err != nil {
+		return err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(p))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", contentType)
+	if dest.sign != nil {
+		dest.sign(req, payload)
+	}
+
+	res, err := dest.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("destination responded %s", res.Status)
+	}
+	return nil
+}
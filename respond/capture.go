@@ -0,0 +1,168 @@
+package respond
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/bdlm/log"
+)
+
+// CaptureSizeMax is the maximum count of kept datagrams (up to 8 KiB each)
+const CaptureSizeMax = 10000
+
+// CaptureRecord is a raw received datagram
+type CaptureRecord struct {
+	Time    time.Time
+	Address *net.UDPAddr
+	Raw     []byte
+}
+
+// captureBuffer keeps the most recent received datagrams in memory
+type captureBuffer struct {
+	records []CaptureRecord
+	next    int // position of the next record to overwrite, if the buffer is full
+	size    int
+	sync.Mutex
+}
+
+func newCaptureBuffer(size int) *captureBuffer {
+	return &captureBuffer{
+		records: make([]CaptureRecord, 0, size),
+		size:    size,
+	}
+}
+
+// add a record and drop the oldest one, if the buffer is full
+func (b *captureBuffer) add(record CaptureRecord) {
+	b.Lock()
+	defer b.Unlock()
+
+	if len(b.records) < b.size {
+		b.records = append(b.records, record)
+		return
+	}
+	b.records[b.next] = record
+	b.next = (b.next + 1) % b.size
+}
+
+// list returns the records oldest first
+func (b *captureBuffer) list() []CaptureRecord {
+	b.Lock()
+	defer b.Unlock()
+
+	result := make([]CaptureRecord, 0, len(b.records))
+	result = append(result, b.records[b.next:]...)
+	return append(result, b.records[:b.next]...)
+}
+
+// WriteCapture writes the records in the length-prefixed capture format.
+// Every record consists of (all integers big-endian):
+//
+//	8 bytes  receive time in nanoseconds since the unix epoch
+//	2 bytes  length of the source address
+//	n bytes  source address (e.g. "[fe80::1%eth0]:1001")
+//	4 bytes  length of the datagram
+//	n bytes  datagram (deflated json, as received)
+func WriteCapture(w io.Writer, records []CaptureRecord) error {
+	buf := bufio.NewWriter(w)
+	for _, record := range records {
+		addr := ""
+		if record.Address != nil {
+			addr = record.Address.String()
+		}
+		if err := binary.Write(buf, binary.BigEndian, record.Time.UnixNano()); err != nil {
+			return err
+		}
+		if err := binary.Write(buf, binary.BigEndian, uint16(len(addr))); err != nil {
+			return err
+		}
+		if _, err := buf.WriteString(addr); err != nil {
+			return err
+		}
+		if err := binary.Write(buf, binary.BigEndian, uint32(len(record.Raw))); err != nil {
+			return err
+		}
+		if _, err := buf.Write(record.Raw); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// ReadCapture reads records written by WriteCapture
+func ReadCapture(r io.Reader) ([]CaptureRecord, error) {
+	var records []CaptureRecord
+	buf := bufio.NewReader(r)
+	for {
+		var t int64
+		if err := binary.Read(buf, binary.BigEndian, &t); err != nil {
+			if err == io.EOF {
+				return records, nil
+			}
+			return records, err
+		}
+
+		var addrLen uint16
+		if err := binary.Read(buf, binary.BigEndian, &addrLen); err != nil {
+			return records, unexpectedEOF(err)
+		}
+		addr := make([]byte, addrLen)
+		if _, err := io.ReadFull(buf, addr); err != nil {
+			return records, unexpectedEOF(err)
+		}
+
+		var rawLen uint32
+		if err := binary.Read(buf, binary.BigEndian, &rawLen); err != nil {
+			return records, unexpectedEOF(err)
+		}
+		if rawLen > maxUDPSize {
+			return records, errors.New("invalid length of datagram in capture")
+		}
+		raw := make([]byte, rawLen)
+		if _, err := io.ReadFull(buf, raw); err != nil {
+			return records, unexpectedEOF(err)
+		}
+
+		record := CaptureRecord{
+			Time: time.Unix(0, t),
+			Raw:  raw,
+		}
+		if addrLen > 0 {
+			udpAddr, err := net.ResolveUDPAddr("udp", string(addr))
+			if err != nil {
+				return records, err
+			}
+			record.Address = udpAddr
+		}
+		records = append(records, record)
+	}
+}
+
+// Replay passes the recorded datagrams to the parsers of the collector like received ones,
+// without checking their age. Records without source address are skipped.
+func (coll *Collector) Replay(records []CaptureRecord) {
+	for _, record := range records {
+		if record.Address == nil {
+			log.WithField("time", record.Time).Warn("skipped record without address")
+			continue
+		}
+		coll.queue <- &Response{
+			Address:   record.Address,
+			Interface: record.Address.Zone,
+			Raw:       record.Raw,
+		}
+	}
+}
+
+// an incomplete record is an unexpected end of the capture
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
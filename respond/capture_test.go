@@ -0,0 +1,108 @@
+package respond
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestCaptureBuffer(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := newCaptureBuffer(2)
+	assert.Len(buffer.list(), 0)
+
+	buffer.add(CaptureRecord{Raw: []byte("a")})
+	assert.Len(buffer.list(), 1)
+
+	buffer.add(CaptureRecord{Raw: []byte("b")})
+	buffer.add(CaptureRecord{Raw: []byte("c")})
+	records := buffer.list()
+	assert.Len(records, 2)
+	assert.Equal("b", string(records[0].Raw))
+	assert.Equal("c", string(records[1].Raw))
+
+	buffer.add(CaptureRecord{Raw: []byte("d")})
+	records = buffer.list()
+	assert.Equal("c", string(records[0].Raw))
+	assert.Equal("d", string(records[1].Raw))
+}
+
+func TestCaptureFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1500000000, 123)
+	records := []CaptureRecord{
+		{
+			Time:    now,
+			Address: &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 1001},
+			Raw:     []byte("datagram"),
+		},
+		{
+			Time: now.Add(time.Second),
+			Raw:  []byte{},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(WriteCapture(&buf, records))
+
+	result, err := ReadCapture(bytes.NewReader(buf.Bytes()))
+	assert.NoError(err)
+	assert.Len(result, 2)
+	assert.True(now.Equal(result[0].Time))
+	assert.Equal("[fe80::1]:1001", result[0].Address.String())
+	assert.Equal("datagram", string(result[0].Raw))
+	assert.Nil(result[1].Address)
+	assert.Len(result[1].Raw, 0)
+
+	// truncated capture
+	result, err = ReadCapture(bytes.NewReader(buf.Bytes()[:buf.Len()-2]))
+	assert.Equal(io.ErrUnexpectedEOF, err)
+	assert.Len(result, 1)
+}
+
+func TestCollectorCapture(t *testing.T) {
+	assert := assert.New(t)
+
+	collector := &Collector{}
+	assert.False(collector.CaptureEnabled())
+	assert.Error(collector.WriteCapture(&bytes.Buffer{}))
+
+	collector.capture = newCaptureBuffer(1)
+	collector.capture.add(CaptureRecord{Raw: []byte("a")})
+	assert.True(collector.CaptureEnabled())
+
+	var buf bytes.Buffer
+	assert.NoError(collector.WriteCapture(&buf))
+	records, err := ReadCapture(&buf)
+	assert.NoError(err)
+	assert.Len(records, 1)
+}
+
+func TestReplay(t *testing.T) {
+	assert := assert.New(t)
+
+	compressed, err := ioutil.ReadFile("testdata/nodeinfo.flated")
+	assert.NoError(err)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector := NewCollector(nil, nodes, &Config{})
+	collector.Replay([]CaptureRecord{
+		{Raw: compressed}, // without address
+		{Address: &net.UDPAddr{IP: net.ParseIP("fe80::1"), Zone: "bat0"}, Raw: compressed},
+	})
+	collector.Close()
+
+	node := nodes.List["f81a67a5e9c1"]
+	if assert.NotNil(node) {
+		assert.Equal("bat0", node.Interface)
+	}
+}
@@ -1,8 +1,12 @@
 package respond
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bdlm/log"
@@ -13,90 +17,374 @@ import (
 	"github.com/FreifunkBremen/yanic/runtime"
 )
 
+// Counters of the collector
+type Counters struct {
+	DroppedLate      uint64 // responses received later than MaxResponseAge after the last request
+	DroppedProcessor uint64 // responses dropped by a processor
+	BusyRounds       uint64 // rounds started while the responses of the previous round were still processed
+	Excluded         uint64 // responses of excluded nodes
+	Truncated        uint64 // datagrams filling the whole read buffer, which are probably truncated
+	DecodeErrors     uint64 // responses which could not be decoded or without a valid node ID
+	Spoofed          uint64 // responses from an address not announced by the node (VerifySourceAddress)
+	RateLimited      uint64 // responses above the RateLimit of their source address
+	Overflows        uint64 // datagrams dropped by the kernel on a full receive buffer (only reported on linux)
+	Received         uint64 // datagrams received on all sockets
+	Stored           uint64 // responses stored into the nodes
+	QueueHighWater   uint64 // highest count of queued responses since the start
+
+	// only set in a snapshot by Counters
+	QueueLength uint64 // responses currently queued to be parsed
+	LastRound   uint64 // responses stored between the last two rounds
+}
+
 // Collector for a specificle respond messages
 type Collector struct {
-	connections []multicastConn // UDP sockets
+	// accessed atomically, keep 64-bit aligned at the beginning of the struct
+	counters          Counters
+	lastTruncatedWarn int64  // unix time in nanoseconds of the last warning of a truncated datagram
+	nextInterval      int64  // interval in nanoseconds, which replaces the interval after the next round
+	roundStored       uint64 // responses stored since the start of the current round
+	lastRoundStored   uint64 // responses stored in the previous round
+	rounds            uint64 // rounds of requests sent
+	started           int64  // unix time in nanoseconds of the start of the requests, 0 before Start
+
+	connections     []multicastConn // UDP sockets
+	connectionsLock sync.RWMutex    // guards the sockets of the connections, which are replaced on a re-bind
 
 	queue    chan *Response // received responses
 	db       database.Connection
 	nodes    *runtime.Nodes
 	interval time.Duration // Interval for multicast packets
 	stop     chan interface{}
+	workers  sync.WaitGroup // receivers and the global stats worker
+	sending  sync.WaitGroup // sender of the requests
+	trigger  chan struct{}  // a round requested by Collect
+	complete chan struct{}  // a round is complete, the next one starts
+	parsers  sync.WaitGroup
+	parsed   chan struct{} // closed after the parsers processed the whole queue
 	config   *Config
+	capture  *captureBuffer // recent received datagrams, nil if disabled
+
+	processors   []ResponseProcessor
+	warnBusy     sync.Once
+	excludeNodes atomic.Value // *nodeList
+	includeNodes atomic.Value // *nodeList, all nodes are accepted if empty
+	schedule     *requestSchedule
+	request      atomic.Value // *request of the current round
+	staticNodes  atomic.Value // []*net.IPAddr requested by unicast
+	sitesDomains atomic.Value // map[string][]string of the global statistics
+	resolver     *resolver    // nil if disabled
+	rateLimiter  *rateLimiter // nil if disabled
+	queries      queries      // pending queries of single addresses
 }
 
 type multicastConn struct {
 	Conn             *net.UDPConn
 	SendRequest      bool
 	MulticastAddress net.IP
+	RequestPort      int // destination port of the requests, 0 for the port of the config
+	status           *interfaceStatus
 }
 
-// NewCollector creates a Collector struct
+// NewCollector creates a Collector struct and panics on an invalid config
 func NewCollector(db database.Connection, nodes *runtime.Nodes, config *Config) *Collector {
+	coll, err := newCollector(db, nodes, config)
+	if err != nil {
+		log.Panic(err)
+	}
+	return coll
+}
 
+// NewCollectorFromConfig creates a Collector with a copy of the given config, e.g. for embedding it
+// in another daemon. Unlike NewCollector it returns an error for an invalid config or a socket,
+// which could not be opened.
+func NewCollectorFromConfig(db database.Connection, nodes *runtime.Nodes, config Config) (*Collector, error) {
+	return newCollector(db, nodes, &config)
+}
+
+func newCollector(db database.Connection, nodes *runtime.Nodes, config *Config) (*Collector, error) {
 	coll := &Collector{
-		db:     db,
-		nodes:  nodes,
-		queue:  make(chan *Response, 400),
-		stop:   make(chan interface{}),
-		config: config,
+		db:       db,
+		nodes:    nodes,
+		queue:    make(chan *Response, config.queueSize()),
+		stop:     make(chan interface{}),
+		parsed:   make(chan struct{}),
+		trigger:  make(chan struct{}, 1),
+		complete: make(chan struct{}, 1),
+		config:   config,
+		schedule: newRequestSchedule(),
+	}
+
+	coll.SetExcludeNodes(config.ExcludeNodes)
+	coll.SetIncludeNodes(config.IncludeNodes)
+	coll.SetSitesDomains(config.SitesDomains())
+
+	staticNodes, err := config.StaticNodes()
+	if err != nil {
+		return nil, err
+	}
+	coll.SetStaticNodes(staticNodes)
+	if err := coll.SetRequestIntervals(config.RequestIntervalsByCategory()); err != nil {
+		return nil, err
 	}
 
+	if coll.processors, err = newProcessors(config.Processors); err != nil {
+		return nil, err
+	}
+
+	switch config.Resolver {
+	case "":
+	case RESOLVER_DNS:
+		coll.SetResolver(DNSResolver)
+	default:
+		return nil, fmt.Errorf("unknown resolver: %s", config.Resolver)
+	}
+
+	if config.CaptureSize > 0 {
+		coll.capture = newCaptureBuffer(config.CaptureSize)
+	}
+	coll.rateLimiter = newRateLimiter(config.RateLimit, config.RateLimitBurst)
+
 	for _, iface := range config.Interfaces {
-		coll.listenUDP(iface)
+		if err := coll.listenInterface(iface); err != nil {
+			// stop the resolver and the receivers of the opened sockets
+			close(coll.stop)
+			for _, conn := range coll.connections {
+				conn.Conn.Close()
+			}
+			coll.workers.Wait()
+			return nil, err
+		}
 	}
 
-	go coll.parser()
+	for i := 0; i < config.parserWorkers(); i++ {
+		coll.parsers.Add(1)
+		go coll.parser()
+	}
+	go func() {
+		coll.parsers.Wait()
+		close(coll.parsed)
+	}()
 
 	if coll.db != nil {
+		coll.workers.Add(1)
 		go coll.globalStatsWorker()
 	}
 
-	return coll
+	return coll, nil
 }
 
-func (coll *Collector) listenUDP(iface InterfaceConfig) {
-
-	var addr net.IP
+// backoff of the retries to open a socket on startup (see BindTimeout)
+const (
+	bindRetryMin = time.Second
+	bindRetryMax = 30 * time.Second
+)
 
-	var err error
-	if iface.IPAddress != "" {
-		addr = net.ParseIP(iface.IPAddress)
-	} else {
-		addr, err = getUnicastAddr(iface.InterfaceName)
-		if err != nil {
-			log.WithField("iface", iface.InterfaceName).Panic(err)
+// listenInterface opens the socket of an interface, on an error it is retried with a backoff up to the
+// BindTimeout, e.g. as the interface is not up yet or has no address at boot
+func (coll *Collector) listenInterface(iface InterfaceConfig) error {
+	deadline := time.Now().Add(coll.config.BindTimeout.Duration)
+	wait := bindRetryMin
+	for {
+		err := coll.listenUDP(iface)
+		if err == nil || time.Now().Add(wait).After(deadline) {
+			return err
+		}
+		log.WithField("iface", iface.InterfaceName).Warnf("unable to listen, retrying in %s: %s", wait, err)
+		time.Sleep(wait)
+		if wait *= 2; wait > bindRetryMax {
+			wait = bindRetryMax
 		}
 	}
+}
+
+func (coll *Collector) listenUDP(iface InterfaceConfig) error {
+	conn, zone, multicastIP, err := coll.openSocket(iface)
+	if err != nil {
+		return err
+	}
+
+	status := &interfaceStatus{status: InterfaceStatus{
+		Interface:        zone,
+		LocalAddress:     conn.LocalAddr().String(),
+		MulticastAddress: multicastIP.String(),
+		SendRequest:      !iface.SendNoRequest,
+		Bound:            time.Now(),
+	}}
+	log.WithFields(map[string]interface{}{
+		"iface":     zone,
+		"local":     status.status.LocalAddress,
+		"multicast": status.status.MulticastAddress,
+		"request":   status.status.SendRequest,
+	}).Info("listening for respondd")
 
+	coll.connectionsLock.Lock()
+	index := len(coll.connections)
+	coll.connections = append(coll.connections, multicastConn{
+		Conn:             conn,
+		SendRequest:      !iface.SendNoRequest,
+		MulticastAddress: multicastIP,
+		RequestPort:      iface.RequestPort,
+		status:           status,
+	})
+	coll.connectionsLock.Unlock()
+
+	// Start receiver
+	coll.workers.Add(1)
+	go coll.interfaceReceiver(index, iface)
+	return nil
+}
+
+// openSocket opens the socket of an interface, it returns the socket with the zone and the multicast address
+func (coll *Collector) openSocket(iface InterfaceConfig) (*net.UDPConn, string, net.IP, error) {
 	multicastAddress := MulticastAddressDefault
 	if iface.MulticastAddress != "" {
 		multicastAddress = iface.MulticastAddress
 	}
 
+	zone, multicastIP, err := resolveZones(iface.InterfaceName, multicastAddress)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("interface %s: %s", iface.InterfaceName, err)
+	}
+
+	ipv4 := multicastIP.To4() != nil
+	network := "udp6"
+	if ipv4 {
+		network = "udp4"
+	}
+
+	var addr net.IP
+	if iface.IPAddress != "" {
+		addr = net.ParseIP(iface.IPAddress)
+		if addr == nil || (addr.To4() != nil) != ipv4 {
+			return nil, "", nil, fmt.Errorf("interface %s: ip address %q does not match the family of the multicast address", zone, iface.IPAddress)
+		}
+	} else {
+		addr, err = getUnicastAddr(zone, ipv4)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("interface %s: %s", zone, err)
+		}
+	}
+
 	// Open socket
-	conn, err := net.ListenUDP("udp", &net.UDPAddr{
+	conn, err := net.ListenUDP(network, &net.UDPAddr{
 		IP:   addr,
 		Port: iface.Port,
-		Zone: iface.InterfaceName,
+		Zone: zone,
 	})
 	if err != nil {
-		log.Panic(err)
+		return nil, "", nil, err
+	}
+	if coll.config.ReceiveBuffer > 0 {
+		if err = conn.SetReadBuffer(coll.config.ReceiveBuffer); err != nil {
+			log.WithField("iface", zone).Warnf("unable to set the receive buffer: %s", err)
+		}
+	}
+	if err = enableOverflowCounter(conn); err != nil {
+		log.WithField("iface", zone).Debugf("datagrams dropped on a full receive buffer are not counted: %s", err)
+	}
+	return conn, zone, multicastIP, nil
+}
+
+// sockets returns a copy of the connections, as their sockets are replaced on a re-bind
+func (coll *Collector) sockets() []multicastConn {
+	coll.connectionsLock.RLock()
+	defer coll.connectionsLock.RUnlock()
+	return append([]multicastConn(nil), coll.connections...)
+}
+
+// interfaceReceiver reads the responses of the socket of an interface. If the socket fails (e.g. the interface
+// is restarted), the socket is opened again as soon as the interface is back.
+func (coll *Collector) interfaceReceiver(index int, iface InterfaceConfig) {
+	defer coll.workers.Done()
+	for {
+		conn := coll.sockets()[index]
+		if !coll.receive(conn.Conn, conn.status, !iface.SendNoRequest) {
+			return
+		}
+		conn.Conn.Close()
+		conn.status.failed(time.Now())
+		if !coll.rebind(index, iface) {
+			return
+		}
 	}
-	conn.SetReadBuffer(MaxDataGramSize)
+}
 
-	coll.connections = append(coll.connections, multicastConn{
-		Conn:             conn,
-		SendRequest:      !iface.SendNoRequest,
-		MulticastAddress: net.ParseIP(multicastAddress),
-	})
+// rebind opens the socket of the interface again, retrying with a backoff.
+// It returns false if the collector is closed meanwhile.
+func (coll *Collector) rebind(index int, iface InterfaceConfig) bool {
+	logger := log.WithField("iface", iface.InterfaceName)
+	wait := bindRetryMin
+	for {
+		if !coll.sleep(wait) {
+			return false
+		}
+		conn, _, _, err := coll.openSocket(iface)
+		if err != nil {
+			logger.Debugf("unable to re-bind, retrying in %s: %s", wait, err)
+			if wait *= 2; wait > bindRetryMax {
+				wait = bindRetryMax
+			}
+			continue
+		}
 
-	// Start receiver
-	go coll.receiver(conn)
+		coll.connectionsLock.Lock()
+		select {
+		case <-coll.stop:
+			// closed meanwhile, the old socket was already closed by Close
+			coll.connectionsLock.Unlock()
+			conn.Close()
+			return false
+		default:
+		}
+		coll.connections[index].Conn = conn
+		status := coll.connections[index].status
+		coll.connectionsLock.Unlock()
+
+		down := status.rebound(conn.LocalAddr().String(), time.Now())
+		logger.WithFields(map[string]interface{}{
+			"local": conn.LocalAddr().String(),
+			"down":  down.String(),
+		}).Info("socket re-bound, listening for respondd again")
+		return true
+	}
 }
 
-// Returns a unicast address of given interface (linklocal or global unicast address)
-func getUnicastAddr(ifname string) (net.IP, error) {
+// checkSocket closes the socket of a connection, if its address is not on its interface anymore
+// (e.g. the interface was restarted). The receiver of the socket opens it again.
+func (coll *Collector) checkSocket(conn *multicastConn) {
+	local, ok := conn.Conn.LocalAddr().(*net.UDPAddr)
+	if !ok || local.IP.IsUnspecified() || !socketStale(conn.status.status.Interface, local.IP) {
+		return
+	}
+	log.WithFields(map[string]interface{}{
+		"iface": conn.status.status.Interface,
+		"local": local.String(),
+	}).Warn("address of the socket is gone, re-binding")
+	conn.Conn.Close()
+}
+
+// socketStale returns true if the interface is missing or does not have the address anymore
+func socketStale(ifname string, ip net.IP) bool {
+	iface, err := net.InterfaceByName(ifname)
+	if err != nil {
+		return true
+	}
+	addresses, err := iface.Addrs()
+	if err != nil {
+		return true
+	}
+	for _, addr := range addresses {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a unicast address of given interface (linklocal or global unicast address),
+// for IPv4 the first unicast address
+func getUnicastAddr(ifname string, ipv4 bool) (net.IP, error) {
 	iface, err := net.InterfaceByName(ifname)
 	if err != nil {
 		return nil, err
@@ -110,7 +398,13 @@ func getUnicastAddr(ifname string) (net.IP, error) {
 
 	for _, addr := range addresses {
 		ipnet, ok := addr.(*net.IPNet)
-		if !ok {
+		if !ok || (ipnet.IP.To4() != nil) != ipv4 {
+			continue
+		}
+		if ipv4 {
+			if ipnet.IP.IsGlobalUnicast() {
+				return ipnet.IP, nil
+			}
 			continue
 		}
 		if (ip == nil && ipnet.IP.IsGlobalUnicast()) || ipnet.IP.IsLinkLocalUnicast() {
@@ -132,46 +426,161 @@ func (coll *Collector) Start(interval time.Duration) {
 		log.Panic("invalid collector interval")
 	}
 	coll.interval = interval
+	atomic.StoreInt64(&coll.nextInterval, int64(interval))
+	atomic.StoreInt64(&coll.started, time.Now().UnixNano())
 
+	coll.sending.Add(1)
 	go func() {
+		defer coll.sending.Done()
 		coll.sendOnce() // immediately
 		coll.sender()   // periodically
 	}()
 }
 
-// Close Collector
+// Collect triggers a round of requests outside of the interval, e.g. after a maintenance.
+// Further triggers before the round started are ignored.
+func (coll *Collector) Collect() error {
+	if coll.trigger == nil || atomic.LoadInt64(&coll.nextInterval) == 0 {
+		return errors.New("collector is not started")
+	}
+	select {
+	case coll.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// SetInterval changes the interval of the requests of a started collector,
+// it is applied after the next round
+func (coll *Collector) SetInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("invalid collector interval")
+	}
+	atomic.StoreInt64(&coll.nextInterval, int64(interval))
+	return nil
+}
+
+// Close Collector, the already received responses are processed before it returns
 func (coll *Collector) Close() {
 	close(coll.stop)
+	// the sender writes to the sockets
+	coll.sending.Wait()
+	coll.connectionsLock.Lock()
 	for _, conn := range coll.connections {
 		conn.Conn.Close()
 	}
+	coll.connectionsLock.Unlock()
+	coll.workers.Wait()
+
+	// drain the queue
 	close(coll.queue)
+	<-coll.parsed
+}
+
+// SetRequestIntervals sets the requested categories with the interval of their requests,
+// a zero interval requests the category in every round.
+// Without any intervals all categories are requested in every round.
+func (coll *Collector) SetRequestIntervals(intervals map[string]time.Duration) error {
+	return coll.schedule.set(intervals)
+}
+
+// currentRequest returns the request of the current round (all categories before the first round)
+func (coll *Collector) currentRequest() *request {
+	if req, ok := coll.request.Load().(*request); ok {
+		return req
+	}
+	return newRequest(RequestCategoriesDefault)
 }
 
 func (coll *Collector) sendOnce() {
 	now := jsontime.Now()
-	coll.sendMulticast()
+	req := coll.schedule.next(now.GetTime(), coll.interval/2)
+	if req == nil {
+		log.Debug("no category to request in this round")
+		return
+	}
+	if coll.config.RequestSplay.Duration > 0 {
+		req.split = true
+	}
+	coll.request.Store(req)
+	atomic.StoreUint64(&coll.lastRoundStored, atomic.SwapUint64(&coll.roundStored, 0))
+	atomic.AddUint64(&coll.rounds, 1)
+	coll.sendMulticast(req)
+	coll.sendStatic(req)
 
 	// Wait for the multicast responses to be processed and send unicasts
-	time.Sleep(coll.interval / 2)
-	coll.sendUnicasts(now)
+	if !coll.sleep(coll.interval / 2) {
+		return
+	}
+	coll.sendUnicasts(now, req)
 }
 
-func (coll *Collector) sendMulticast() {
-	log.Info("sending multicasts")
-	for _, conn := range coll.connections {
+// sleep waits for the given duration, it returns false if the collector is closed meanwhile
+func (coll *Collector) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-coll.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (coll *Collector) sendMulticast(req *request) {
+	log.WithField("request", string(req.payload)).Info("sending multicasts")
+	if req.split {
+		coll.sendMulticastSplayed(req, coll.config.RequestSplay.Duration)
+		return
+	}
+	for _, conn := range coll.sockets() {
+		if conn.SendRequest {
+			coll.multicastSent(&conn, coll.sendPacket(&conn, conn.MulticastAddress, req))
+		}
+	}
+}
+
+// multicastSent records the result of a multicast, on an error the socket is checked for a re-bind
+func (coll *Collector) multicastSent(conn *multicastConn, err error) {
+	conn.status.multicastSent(err)
+	if err != nil {
+		coll.checkSocket(conn)
+	}
+}
+
+// sendMulticastSplayed sends a multicast per category and interface, spread evenly over the splay period,
+// so the nodes do not answer all at once
+func (coll *Collector) sendMulticastSplayed(req *request, splay time.Duration) {
+	var conns []multicastConn
+	for _, conn := range coll.sockets() {
 		if conn.SendRequest {
-			coll.sendPacket(conn.Conn, conn.MulticastAddress)
+			conns = append(conns, conn)
+		}
+	}
+	parts := req.parts()
+	count := len(parts) * len(conns)
+	if count == 0 {
+		return
+	}
+	gap := splay / time.Duration(count)
+
+	sent := 0
+	for _, part := range parts {
+		for _, conn := range conns {
+			coll.multicastSent(&conn, coll.sendPacket(&conn, conn.MulticastAddress, part))
+			if sent++; sent < count && !coll.sleep(gap) {
+				return
+			}
 		}
 	}
 }
 
 // Send unicast packets to nodes that did not answer the multicast
-func (coll *Collector) sendUnicasts(seenBefore jsontime.Time) {
+func (coll *Collector) sendUnicasts(seenBefore jsontime.Time, req *request) {
 	seenAfter := seenBefore.Add(-time.Minute * 10)
 
 	// Select online nodes that has not been seen recently
-	nodes := coll.nodes.Select(func(n *runtime.Node) bool {
+	nodes := coll.nodes.Filter(func(n *runtime.Node) bool {
 		return n.Lastseen.After(seenAfter) && n.Lastseen.Before(seenBefore) && n.Address != nil
 	})
 
@@ -179,18 +588,17 @@ func (coll *Collector) sendUnicasts(seenBefore jsontime.Time) {
 	count := 0
 	for _, node := range nodes {
 		send := 0
-		for _, conn := range coll.connections {
-			if node.Address.Zone != "" && conn.Conn.LocalAddr().(*net.UDPAddr).Zone != node.Address.Zone && conn.SendRequest {
-				continue
-			}
-			coll.sendPacket(conn.Conn, node.Address.IP)
+		for _, conn := range coll.connectionsFor(node) {
+			coll.sendPacket(&conn, node.Address.IP, req)
 			send++
 		}
 		if send == 0 {
 			log.WithField("iface", node.Address.Zone).Error("unable to find connection")
 		} else {
-			time.Sleep(10 * time.Millisecond)
 			count += send
+			if !coll.sleep(10 * time.Millisecond) {
+				break
+			}
 		}
 	}
 	log.WithFields(map[string]interface{}{
@@ -199,22 +607,51 @@ func (coll *Collector) sendUnicasts(seenBefore jsontime.Time) {
 	}).Info("sending unicast pkg")
 }
 
+// connectionsFor returns the connections to reach the node by unicast
+func (coll *Collector) connectionsFor(node *runtime.Node) (result []multicastConn) {
+	ipv4 := node.Address.IP.To4() != nil
+	for _, conn := range coll.sockets() {
+		if (conn.MulticastAddress.To4() != nil) != ipv4 {
+			continue
+		}
+		if node.Address.Zone != "" && conn.status.status.Interface != node.Address.Zone && conn.SendRequest {
+			continue
+		}
+		// without zone (e.g. global address) use the interface of the last response
+		if node.Address.Zone == "" && node.Interface != "" && conn.status.status.Interface != node.Interface {
+			continue
+		}
+		result = append(result, conn)
+	}
+	return
+}
+
 // SendPacket sends a UDP request to the given unicast or multicast address on the first UDP socket
 func (coll *Collector) SendPacket(destination net.IP) {
-	coll.sendPacket(coll.connections[0].Conn, destination)
+	coll.sendPacket(&coll.sockets()[0], destination, coll.currentRequest())
 }
 
 // sendPacket sends a UDP request to the given unicast or multicast address on the given UDP socket
-func (coll *Collector) sendPacket(conn *net.UDPConn, destination net.IP) {
+func (coll *Collector) sendPacket(conn *multicastConn, destination net.IP, req *request) error {
+	port := conn.RequestPort
+	if port <= 0 {
+		port = coll.config.requestPort()
+	}
 	addr := net.UDPAddr{
 		IP:   destination,
-		Port: PortDefault,
-		Zone: conn.LocalAddr().(*net.UDPAddr).Zone,
+		Port: port,
+		Zone: conn.Conn.LocalAddr().(*net.UDPAddr).Zone,
+	}
+
+	if conn.status != nil {
+		conn.status.requestSent(time.Now())
 	}
 
-	if _, err := conn.WriteToUDP([]byte("GET nodeinfo statistics neighbours"), &addr); err != nil {
+	_, err := conn.Conn.WriteToUDP(req.payload, &addr)
+	if err != nil {
 		log.WithField("address", addr.String()).Errorf("WriteToUDP failed: %s", err)
 	}
+	return err
 }
 
 // send packets continuously
@@ -226,23 +663,86 @@ func (coll *Collector) sender() {
 			ticker.Stop()
 			return
 		case <-ticker.C:
-			// send the multicast packet to request per-node statistics
-			coll.sendOnce()
+			// also if the next round is skipped or requests no category
+			coll.roundComplete()
+			if coll.nextRound() {
+				// send the multicast packet to request per-node statistics
+				coll.sendOnce()
+			}
+			ticker = coll.applyInterval(ticker)
+		case <-coll.trigger:
+			log.Info("collecting on demand")
+			if coll.nextRound() {
+				coll.sendOnce()
+			}
 		}
 	}
 }
 
+// roundComplete signals the end of a periodic round to the global statistics,
+// the rounds on demand (see Collect) are no intervals of their own
+func (coll *Collector) roundComplete() {
+	select {
+	case coll.complete <- struct{}{}:
+	default:
+	}
+}
+
+// applyInterval replaces the ticker, if the interval was changed
+func (coll *Collector) applyInterval(ticker *time.Ticker) *time.Ticker {
+	interval := time.Duration(atomic.LoadInt64(&coll.nextInterval))
+	if interval == coll.interval {
+		return ticker
+	}
+	log.WithField("interval", interval.String()).Info("changed collect interval")
+	coll.interval = interval
+	ticker.Stop()
+	return time.NewTicker(interval)
+}
+
+// busy returns whether the queue is still filled with responses of the previous round
+func (coll *Collector) busy() bool {
+	return len(coll.queue) > cap(coll.queue)/2
+}
+
+// nextRound returns whether the next round should be sent, it is skipped on a busy queue if configured
+func (coll *Collector) nextRound() bool {
+	if !coll.busy() {
+		return true
+	}
+	atomic.AddUint64(&coll.counters.BusyRounds, 1)
+	logger := log.WithField("queue", len(coll.queue))
+
+	if coll.config.SkipBusyRounds {
+		logger.Warn("skipping round, responses of the previous round are still processed (collect_interval too short?)")
+		return false
+	}
+	coll.warnBusy.Do(func() {
+		logger.Warn("responses of the previous round are still processed, collect_interval seems to be too short")
+	})
+	return true
+}
+
 func (coll *Collector) parser() {
+	defer coll.parsers.Done()
 	for obj := range coll.queue {
+		coll.queries.answer(obj, coll.config.CustomFields)
 		if data, err := obj.parse(coll.config.CustomFields); err != nil {
-			log.WithField("address", obj.Address.String()).Errorf("unable to decode response %s", err)
+			atomic.AddUint64(&coll.counters.DecodeErrors, 1)
+			// counted as decode errors, a garbled datagram should not flood the log
+			log.WithField("address", obj.Address.String()).Debugf("unable to decode response %s", err)
+		} else if data = process(coll.processors, data); data == nil {
+			atomic.AddUint64(&coll.counters.DroppedProcessor, 1)
+			log.WithField("address", obj.Address.String()).Debug("response dropped by processor")
 		} else {
-			coll.saveResponse(obj.Address, data)
+			coll.saveResponse(obj.Address, obj.Interface, obj.Latency, data)
 		}
 	}
 }
 
-func (coll *Collector) saveResponse(addr *net.UDPAddr, res *data.ResponseData) {
+// saveResponse stores a response, received from addr on the interface iface of the collector
+// with the latency since the request (0 if unknown)
+func (coll *Collector) saveResponse(addr *net.UDPAddr, iface string, latency time.Duration, res *data.ResponseData) {
 	// Search for NodeID
 	var nodeID string
 	if val := res.Nodeinfo; val != nil {
@@ -255,10 +755,17 @@ func (coll *Collector) saveResponse(addr *net.UDPAddr, res *data.ResponseData) {
 
 	// Check length of nodeID
 	if len(nodeID) != 12 {
+		atomic.AddUint64(&coll.counters.DecodeErrors, 1)
 		log.WithFields(map[string]interface{}{
 			"node_id": nodeID,
 			"address": addr.String(),
-		}).Warn("invalid NodeID")
+		}).Debug("invalid NodeID")
+		return
+	}
+
+	if coll.isExcluded(nodeID) {
+		atomic.AddUint64(&coll.counters.Excluded, 1)
+		log.WithField("node_id", nodeID).Debug("response of excluded node dropped")
 		return
 	}
 
@@ -273,18 +780,49 @@ func (coll *Collector) saveResponse(addr *net.UDPAddr, res *data.ResponseData) {
 		res.Nodeinfo = nil
 	}
 
-	// Process the data and update IP address
-	node := coll.nodes.Update(nodeID, res)
-	node.Address = addr
+	if coll.config != nil && coll.config.VerifySourceAddress && !coll.isAnnounced(nodeID, addr.IP, res.Nodeinfo) {
+		atomic.AddUint64(&coll.counters.Spoofed, 1)
+		log.WithFields(map[string]interface{}{
+			"node_id": nodeID,
+			"address": addr.String(),
+		}).Warn("response from an address not announced by the node dropped")
+		return
+	}
+
+	// the sections of the response itself, before the unrequested sections are kept
+	hasStatistics := res.Statistics != nil
+	hasNeighbours := res.Neighbours != nil
+
+	// Process the data and update IP address,
+	// within the update of the node, as responses of the node could be processed in parallel
+	var changed bool
+	node := coll.nodes.UpdateFunc(nodeID, res, func(node *runtime.Node) {
+		coll.keepUnrequested(node, res)
+		changed = node.Address == nil || !node.Address.IP.Equal(addr.IP)
+		node.Address = addr
+		node.Interface = iface
+		if latency > 0 {
+			node.Latency = latency.Seconds()
+		}
+	})
+	atomic.AddUint64(&coll.counters.Stored, 1)
+	atomic.AddUint64(&coll.roundStored, 1)
+
+	if coll.resolver != nil && changed {
+		coll.resolver.lookup(nodeID, addr.IP)
+	}
 
-	// Store statistics in database
+	// Store statistics in database, only if they are part of the response
+	// (not kept of the previous round, e.g. of a split request)
 	if db := coll.db; db != nil {
-		db.InsertNode(node)
+		if hasStatistics {
+			db.InsertNode(&node)
+		}
 
 		// Store link data
-		if neighbours := node.Neighbours; neighbours != nil {
+		if hasNeighbours {
 			coll.nodes.RLock()
-			for _, link := range coll.nodes.NodeLinks(node) {
+			for _, link := range coll.nodes.NodeLinks(&node) {
 				db.InsertLink(&link, node.Lastseen.GetTime())
 			}
 			coll.nodes.RUnlock()
@@ -292,53 +830,350 @@ func (coll *Collector) saveResponse(addr *net.UDPAddr, res *data.ResponseData) {
 	}
 }
 
-func (coll *Collector) receiver(conn *net.UDPConn) {
-	buf := make([]byte, MaxDataGramSize)
+// isAnnounced returns whether the address is announced by the node, in the nodeinfo of the response
+// or the known nodeinfo. Without a nodeinfo or addresses the address could not be verified and is accepted.
+func (coll *Collector) isAnnounced(nodeID string, ip net.IP, nodeinfo *data.Nodeinfo) bool {
+	var addresses []string
+	if nodeinfo != nil {
+		addresses = nodeinfo.Network.Addresses
+	} else {
+		coll.nodes.RLock()
+		if node, ok := coll.nodes.List[nodeID]; ok && node.Nodeinfo != nil {
+			addresses = node.Nodeinfo.Network.Addresses
+		}
+		coll.nodes.RUnlock()
+	}
+	if len(addresses) == 0 {
+		return true
+	}
+	for _, address := range addresses {
+		if ip.Equal(net.ParseIP(address)) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepUnrequested keeps the sections of the known node, which are not requested in the current round
+// (called under the lock of the nodes)
+func (coll *Collector) keepUnrequested(node *runtime.Node, res *data.ResponseData) {
+	req := coll.currentRequest()
+	if req.all && !req.split {
+		return
+	}
+
+	// a response of a split request contains only one of the requested categories
+	if res.Nodeinfo == nil && (req.split || !req.categories[CategoryNodeinfo]) {
+		res.Nodeinfo = node.Nodeinfo
+	}
+	if res.Statistics == nil && (req.split || !req.categories[CategoryStatistics]) {
+		res.Statistics = node.Statistics
+	}
+	if res.Neighbours == nil && (req.split || !req.categories[CategoryNeighbours]) {
+		res.Neighbours = node.Neighbours
+	}
+	for name, value := range node.CustomFields {
+		if _, ok := res.CustomFields[name]; !ok {
+			if res.CustomFields == nil {
+				res.CustomFields = make(map[string]interface{})
+			}
+			res.CustomFields[name] = value
+		}
+	}
+}
+
+// truncatedWarnInterval is the minimum period between the warnings of truncated datagrams
+const truncatedWarnInterval = time.Minute
+
+// truncated counts a probably truncated datagram, which is dropped
+func (coll *Collector) truncated(src *net.UDPAddr, received time.Time) {
+	atomic.AddUint64(&coll.counters.Truncated, 1)
+
+	last := atomic.LoadInt64(&coll.lastTruncatedWarn)
+	if received.UnixNano()-last < int64(truncatedWarnInterval) ||
+		!atomic.CompareAndSwapInt64(&coll.lastTruncatedWarn, last, received.UnixNano()) {
+		return
+	}
+	log.WithFields(map[string]interface{}{
+		"address": src.String(),
+		"size":    coll.config.maxDatagramSize(),
+		"count":   atomic.LoadUint64(&coll.counters.Truncated),
+	}).Warn("datagram possibly truncated, raise the maximum datagram size")
+}
+
+// SitesDomains returns the configured sites with their domains,
+// with discover_sites including the sites and domains of the online nodes
+func (coll *Collector) SitesDomains() map[string][]string {
+	sitesDomains, ok := coll.sitesDomains.Load().(map[string][]string)
+	if !ok {
+		sitesDomains = coll.config.SitesDomains()
+	}
+	if !coll.config.DiscoverSites || coll.nodes == nil {
+		return sitesDomains
+	}
+
+	result := coll.nodes.SitesDomains()
+	for site, domains := range sitesDomains {
+		result[site] = mergeDomains(result[site], domains)
+	}
+	return result
+}
+
+// mergeDomains returns the domains of both lists without duplicates
+func mergeDomains(a, b []string) []string {
+	result := append([]string{}, a...)
+	for _, domain := range b {
+		found := false
+		for _, d := range a {
+			if d == domain {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, domain)
+		}
+	}
+	return result
+}
+
+// SetSitesDomains replaces the sites with their domains of the global statistics
+func (coll *Collector) SetSitesDomains(sitesDomains map[string][]string) {
+	coll.sitesDomains.Store(sitesDomains)
+}
+
+// CaptureEnabled returns whether the recent received datagrams are kept
+func (coll *Collector) CaptureEnabled() bool {
+	return coll.capture != nil
+}
+
+// WriteCapture writes the recent received datagrams in the capture format (see WriteCapture)
+func (coll *Collector) WriteCapture(w io.Writer) error {
+	if coll.capture == nil {
+		return errors.New("capture is disabled")
+	}
+	return WriteCapture(w, coll.capture.list())
+}
+
+// Counters returns a snapshot of the counters of the collector
+func (coll *Collector) Counters() Counters {
+	return Counters{
+		DroppedLate:      atomic.LoadUint64(&coll.counters.DroppedLate),
+		DroppedProcessor: atomic.LoadUint64(&coll.counters.DroppedProcessor),
+		BusyRounds:       atomic.LoadUint64(&coll.counters.BusyRounds),
+		Excluded:         atomic.LoadUint64(&coll.counters.Excluded),
+		Truncated:        atomic.LoadUint64(&coll.counters.Truncated),
+		DecodeErrors:     atomic.LoadUint64(&coll.counters.DecodeErrors),
+		Spoofed:          atomic.LoadUint64(&coll.counters.Spoofed),
+		RateLimited:      atomic.LoadUint64(&coll.counters.RateLimited),
+		Overflows:        atomic.LoadUint64(&coll.counters.Overflows),
+		Received:         atomic.LoadUint64(&coll.counters.Received),
+		Stored:           atomic.LoadUint64(&coll.counters.Stored),
+		QueueHighWater:   atomic.LoadUint64(&coll.counters.QueueHighWater),
+		QueueLength:      uint64(len(coll.queue)),
+		LastRound:        atomic.LoadUint64(&coll.lastRoundStored),
+	}
+}
+
+// InternalCounters returns the counters of the collector and of its database as metrics
+func (coll *Collector) InternalCounters() []runtime.Counter {
+	counters := coll.Counters()
+	result := []runtime.Counter{
+		{Name: "responses_dropped_late", Help: "Responses received later than max_response_age after the last request", Value: counters.DroppedLate},
+		{Name: "responses_dropped_processor", Help: "Responses dropped by a processor", Value: counters.DroppedProcessor},
+		{Name: "responses_excluded", Help: "Responses of excluded nodes", Value: counters.Excluded},
+		{Name: "responses_decode_errors", Help: "Responses which could not be decoded or without a valid node ID", Value: counters.DecodeErrors},
+		{Name: "responses_dropped_spoofed", Help: "Responses from an address not announced by the node", Value: counters.Spoofed},
+		{Name: "responses_dropped_rate_limit", Help: "Responses above the rate limit of their source address", Value: counters.RateLimited},
+		{Name: "datagrams_truncated", Help: "Datagrams filling the whole read buffer, which are probably truncated", Value: counters.Truncated},
+		{Name: "datagrams_dropped_overflow", Help: "Datagrams dropped by the kernel on a full receive buffer of a socket", Value: counters.Overflows},
+		{Name: "busy_rounds", Help: "Rounds started while the responses of the previous round were still processed", Value: counters.BusyRounds},
+		{Name: "datagrams_received", Help: "Datagrams received on all sockets", Value: counters.Received},
+		{Name: "responses_stored", Help: "Responses stored into the nodes", Value: counters.Stored},
+		{Name: "responses_last_round", Help: "Responses stored between the last two rounds of requests", Value: counters.LastRound, Gauge: true},
+		{Name: "queue_length", Help: "Responses queued to be parsed", Value: counters.QueueLength, Gauge: true},
+		{Name: "queue_high_water", Help: "Highest count of queued responses since the start", Value: counters.QueueHighWater, Gauge: true},
+	}
+	if counted, ok := coll.db.(database.Counted); ok {
+		result = append(result, counted.Counters()...)
+	}
+	return result
+}
+
+// isLate returns true if a response received at the given time on the socket of the status
+// arrived later than MaxResponseAge after the last request on this socket
+// (a unicast request renews the age of all responses on its socket, it could not be assigned to a request)
+func (coll *Collector) isLate(status *interfaceStatus, received time.Time) bool {
+	maxAge := coll.config.MaxResponseAge.Duration
+	if maxAge <= 0 {
+		return false
+	}
+	lastRequest := status.lastRequestTime()
+	if lastRequest.IsZero() {
+		return false
+	}
+	return received.Sub(lastRequest) > maxAge
+}
+
+// responseLatency returns the time since the last request on the socket, 0 if no request was sent
+// (a unicast request renews the request time of all responses on the socket, like for isLate)
+func responseLatency(status *interfaceStatus, received time.Time) time.Duration {
+	lastRequest := status.lastRequestTime()
+	if lastRequest.IsZero() || received.Before(lastRequest) {
+		return 0
+	}
+	return received.Sub(lastRequest)
+}
+
+// receive reads the responses of the socket until it fails, it returns false if the collector is closed.
+// The age of a response is only checked if requests are sent on this socket.
+func (coll *Collector) receive(conn *net.UDPConn, status *interfaceStatus, checkAge bool) bool {
+	buf := make([]byte, coll.config.maxDatagramSize())
+	oob := make([]byte, 64)
+	var overflows uint32 // last count of the kernel
 	for {
-		n, src, err := conn.ReadFromUDP(buf)
+		n, oobn, _, src, err := conn.ReadMsgUDP(buf, oob)
 
 		if err != nil {
+			select {
+			case <-coll.stop:
+				// closed by Close
+				return false
+			default:
+			}
 			if conn != nil {
 				log.WithFields(map[string]interface{}{
 					"local":  conn.LocalAddr(),
 					"remote": conn.RemoteAddr(),
-				}).Errorf("ReadFromUDP failed: %s", err)
+				}).Errorf("ReadMsgUDP failed: %s", err)
 			} else {
-				log.Errorf("ReadFromUDP failed: %s", err)
+				log.Errorf("ReadMsgUDP failed: %s", err)
 			}
-			return
+			return true
+		}
+
+		received := time.Now()
+		status.received(received)
+		atomic.AddUint64(&coll.counters.Received, 1)
+
+		if count, ok := parseOverflowCounter(oob[:oobn]); ok && count != overflows {
+			atomic.AddUint64(&coll.counters.Overflows, uint64(count-overflows))
+			log.WithFields(map[string]interface{}{
+				"iface":   status.status.Interface,
+				"dropped": count - overflows,
+			}).Warn("datagrams dropped on a full receive buffer, raise receive_buffer")
+			overflows = count
+		}
+
+		if coll.rateLimiter != nil && !coll.rateLimiter.allow(src.IP, received) {
+			atomic.AddUint64(&coll.counters.RateLimited, 1)
+			log.WithField("address", src.String()).Debug("dropped response above the rate limit")
+			continue
+		}
+
+		if checkAge && coll.isLate(status, received) {
+			atomic.AddUint64(&coll.counters.DroppedLate, 1)
+			log.WithField("address", src.String()).Debug("dropped late response")
+			continue
 		}
 
 		raw := make([]byte, n)
 		copy(raw, buf)
 
+		if coll.capture != nil {
+			coll.capture.add(CaptureRecord{
+				Time:    received,
+				Address: src,
+				Raw:     raw,
+			})
+		}
+
+		if n == len(buf) {
+			coll.truncated(src, received)
+			continue
+		}
+
+		// the zone of a link local source is the interface, the packet arrived on
+		iface := status.status.Interface
+		if src.Zone != "" {
+			iface = src.Zone
+		}
+		var latency time.Duration
+		if checkAge {
+			latency = responseLatency(status, received)
+		}
+		storeMax(&coll.counters.QueueHighWater, uint64(len(coll.queue)+1))
 		coll.queue <- &Response{
-			Address: src,
-			Raw:     raw,
+			Address:   src,
+			Interface: iface,
+			Latency:   latency,
+			Raw:       raw,
+		}
+	}
+}
+
+// storeMax stores the value atomically, if it is above the current one
+func storeMax(addr *uint64, value uint64) {
+	for {
+		current := atomic.LoadUint64(addr)
+		if value <= current || atomic.CompareAndSwapUint64(addr, current, value) {
+			return
 		}
 	}
 }
 
+// globalStatsWorker saves the global statistics every GlobalStatsInterval once the first round is complete,
+// without an interval after every complete round (at the start of the next round)
 func (coll *Collector) globalStatsWorker() {
-	ticker := time.NewTicker(time.Minute)
+	defer coll.workers.Done()
+	// only one of both is set, a nil channel never receives
+	var tick <-chan time.Time
+	complete := coll.complete
+	if interval := coll.config.GlobalStatsInterval.Duration; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+		complete = nil
+	}
+	last := time.Now()
 	for {
 		select {
 		case <-coll.stop:
-			ticker.Stop()
 			return
-		case <-ticker.C:
-			coll.saveGlobalStats()
+		case now := <-tick:
+			if coll.firstRoundComplete(now) {
+				last = coll.saveGlobalStats(last)
+			} else {
+				log.Debug("global statistics skipped, the first round of requests is not complete")
+			}
+		case <-complete:
+			last = coll.saveGlobalStats(last)
 		}
 	}
 }
 
-// saves global statistics
-func (coll *Collector) saveGlobalStats() {
-	stats := runtime.NewGlobalStats(coll.nodes, coll.config.SitesDomains())
+// firstRoundComplete returns whether a whole collect interval passed since the start of the requests.
+// Before the global statistics would count only the nodes, which answered so far.
+func (coll *Collector) firstRoundComplete(now time.Time) bool {
+	started := atomic.LoadInt64(&coll.started)
+	if started == 0 {
+		return false
+	}
+	return now.Sub(time.Unix(0, started)) >= time.Duration(atomic.LoadInt64(&coll.nextInterval))
+}
+
+// saves global statistics with the reboots since the previous save and returns the time of this save
+func (coll *Collector) saveGlobalStats(since time.Time) time.Time {
+	now := time.Now()
+	stats := runtime.NewGlobalStatsSince(coll.nodes, coll.SitesDomains(), since)
 
 	for site, domains := range stats {
 		for domain, stat := range domains {
 			coll.db.InsertGlobals(stat, time.Now(), site, domain)
 		}
 	}
+	if inserter, ok := coll.db.(database.InternalInserter); ok {
+		inserter.InsertInternal(coll.InternalCounters(), time.Now())
+	}
+	return now
 }
@@ -1,10 +1,15 @@
 package respond
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/database"
 	"github.com/FreifunkBremen/yanic/runtime"
 	"github.com/stretchr/testify/assert"
 )
@@ -30,6 +35,390 @@ func TestCollector(t *testing.T) {
 	collector.Close()
 }
 
+func TestCloseStopsSender(t *testing.T) {
+	assert := assert.New(t)
+
+	collector := NewCollector(nil, runtime.NewNodes(&runtime.NodesConfig{}), &Config{})
+	// the first round waits half of the interval before the unicasts
+	collector.Start(time.Hour)
+
+	closed := make(chan struct{})
+	go func() {
+		collector.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		assert.Fail("Close does not stop the sender")
+	}
+	assert.False(collector.sleep(time.Hour))
+}
+
+func TestNewCollectorFromConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	config := Config{
+		Interfaces: []InterfaceConfig{{
+			InterfaceName:    "lo",
+			IPAddress:        "127.0.0.1",
+			MulticastAddress: "224.0.0.1",
+		}},
+	}
+	collector, err := NewCollectorFromConfig(nil, nodes, config)
+	assert.NoError(err)
+	assert.Len(collector.connections, 1)
+
+	// the config is copied
+	config.MaxResponseAge.Duration = time.Minute
+	assert.Zero(collector.config.MaxResponseAge.Duration)
+	collector.Close()
+
+	// the opened socket is closed again on an error
+	config.Interfaces = append(config.Interfaces, InterfaceConfig{InterfaceName: "nonexisting0"})
+	collector, err = NewCollectorFromConfig(nil, nodes, config)
+	assert.Error(err)
+	assert.Nil(collector)
+
+	// retried up to the bind timeout
+	config.BindTimeout.Duration = bindRetryMin + bindRetryMin/2
+	start := time.Now()
+	_, err = NewCollectorFromConfig(nil, nodes, config)
+	assert.Error(err)
+	assert.True(time.Since(start) >= bindRetryMin)
+
+	_, err = NewCollectorFromConfig(nil, nodes, Config{Resolver: "unknown"})
+	assert.EqualError(err, "unknown resolver: unknown")
+	assert.Panics(func() {
+		NewCollector(nil, nodes, &Config{Resolver: "unknown"})
+	})
+}
+
+func TestRebind(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector, err := NewCollectorFromConfig(nil, nodes, Config{
+		Interfaces: []InterfaceConfig{{
+			InterfaceName:    "lo",
+			IPAddress:        "127.0.0.1",
+			MulticastAddress: "224.0.0.1",
+		}},
+	})
+	assert.NoError(err)
+	defer collector.Close()
+	old := collector.sockets()[0].Conn
+
+	// the failed socket is opened again
+	old.Close()
+	for i := 0; i < 300 && collector.InterfaceStatus()[0].Rebinds == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	status := collector.InterfaceStatus()[0]
+	assert.EqualValues(1, status.Rebinds)
+	assert.Nil(status.Down)
+	conn := collector.sockets()[0].Conn
+	assert.True(old != conn)
+	assert.Equal(conn.LocalAddr().String(), status.LocalAddress)
+
+	// and receives again
+	sender, err := net.DialUDP("udp", nil, conn.LocalAddr().(*net.UDPAddr))
+	assert.NoError(err)
+	defer sender.Close()
+	_, err = sender.Write([]byte("response"))
+	assert.NoError(err)
+	for i := 0; i < 100 && collector.Counters().Received == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.EqualValues(1, collector.Counters().Received)
+}
+
+func TestSocketStale(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(socketStale("lo", net.IPv4(127, 0, 0, 1)))
+	assert.True(socketStale("lo", net.ParseIP("192.0.2.1")))
+	assert.True(socketStale("nonexisting0", net.IPv4(127, 0, 0, 1)))
+}
+
+func TestSetInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	collector := &Collector{interval: time.Minute, nextInterval: int64(time.Minute)}
+	ticker := time.NewTicker(time.Minute)
+	assert.Equal(ticker, collector.applyInterval(ticker))
+
+	assert.Error(collector.SetInterval(0))
+	assert.NoError(collector.SetInterval(time.Hour))
+	assert.Equal(time.Minute, collector.interval)
+
+	changed := collector.applyInterval(ticker)
+	assert.NotEqual(ticker, changed)
+	assert.Equal(time.Hour, collector.interval)
+	changed.Stop()
+}
+
+func TestSendMulticastSplayed(t *testing.T) {
+	assert := assert.New(t)
+
+	listener, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(err)
+	defer listener.Close()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(err)
+	defer conn.Close()
+
+	collector := &Collector{
+		config: &Config{RequestPort: listener.LocalAddr().(*net.UDPAddr).Port},
+		connections: []multicastConn{{
+			Conn:             conn,
+			SendRequest:      true,
+			MulticastAddress: net.IPv4(127, 0, 0, 1),
+			status:           &interfaceStatus{},
+		}},
+	}
+
+	start := time.Now()
+	collector.sendMulticastSplayed(newRequest(RequestCategoriesDefault), 40*time.Millisecond)
+	assert.True(time.Since(start) >= 20*time.Millisecond, "spread over the splay")
+
+	buf := make([]byte, 100)
+	for _, expected := range []string{"GET nodeinfo", "GET statistics", "GET neighbours"} {
+		listener.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := listener.ReadFromUDP(buf)
+		assert.NoError(err)
+		assert.Equal(expected, string(buf[:n]))
+	}
+}
+
+func TestCollect(t *testing.T) {
+	assert := assert.New(t)
+
+	listener, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(err)
+	defer listener.Close()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(err)
+	defer conn.Close()
+
+	collector := &Collector{
+		config: &Config{RequestPort: listener.LocalAddr().(*net.UDPAddr).Port},
+		connections: []multicastConn{{
+			Conn:             conn,
+			SendRequest:      true,
+			MulticastAddress: net.IPv4(127, 0, 0, 1),
+			status:           &interfaceStatus{},
+		}},
+		nodes:    runtime.NewNodes(&runtime.NodesConfig{}),
+		stop:     make(chan interface{}),
+		trigger:  make(chan struct{}, 1),
+		schedule: newRequestSchedule(),
+	}
+	assert.Error(collector.Collect())
+
+	// started without the first round
+	collector.interval = time.Hour
+	collector.nextInterval = int64(time.Hour)
+	collector.sending.Add(1)
+	go func() {
+		defer collector.sending.Done()
+		collector.sender()
+	}()
+
+	assert.NoError(collector.Collect())
+	buf := make([]byte, 100)
+	listener.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := listener.ReadFromUDP(buf)
+	assert.NoError(err)
+	assert.Equal("GET nodeinfo statistics neighbours", string(buf[:n]))
+
+	close(collector.stop)
+	collector.sending.Wait()
+}
+
+func TestSetSitesDomains(t *testing.T) {
+	assert := assert.New(t)
+
+	config := &Config{
+		Sites: map[string]SiteConfig{
+			SITE_TEST: {Domains: []string{DOMAIN_TEST}},
+		},
+	}
+	collector := &Collector{config: config}
+	assert.Equal(map[string][]string{SITE_TEST: {DOMAIN_TEST}}, collector.SitesDomains())
+
+	collector.SetSitesDomains(map[string][]string{"other": nil})
+	assert.Equal(map[string][]string{"other": nil}, collector.SitesDomains())
+
+	// discover the sites of the nodes
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	for _, code := range [][2]string{{SITE_TEST, DOMAIN_TEST}, {SITE_TEST, "village"}, {"other", "town"}} {
+		nodes.AddNode(&runtime.Node{
+			Online: true,
+			Nodeinfo: &data.Nodeinfo{
+				NodeID: code[0] + code[1],
+				System: data.System{SiteCode: code[0], DomainCode: code[1]},
+			},
+		})
+	}
+	collector.nodes = nodes
+	config.DiscoverSites = true
+	collector.SetSitesDomains(map[string][]string{SITE_TEST: {"suburb", DOMAIN_TEST}})
+	assert.Equal(map[string][]string{
+		SITE_TEST: {DOMAIN_TEST, "village", "suburb"},
+		"other":   {"town"},
+	}, collector.SitesDomains())
+}
+
+func TestCloseDrainsQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	compressed, err := ioutil.ReadFile("testdata/nodeinfo.flated")
+	assert.NoError(err)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector := NewCollector(nil, nodes, &Config{ParserWorkers: 4})
+	for i := 0; i < 10; i++ {
+		collector.queue <- &Response{
+			Address: &net.UDPAddr{IP: net.ParseIP("fe80::1")},
+			Raw:     compressed,
+		}
+	}
+	collector.Close()
+
+	assert.NotNil(nodes.List["f81a67a5e9c1"])
+}
+
+func TestParserWorkersSameNode(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{HistoryDepth: 5})
+	collector := NewCollector(nil, nodes, &Config{ParserWorkers: 4, QueueSize: 100})
+	// the responses of one round are merged, if not all categories are requested
+	collector.request.Store(newRequest([]string{CategoryStatistics}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			for _, node := range nodes.Online() {
+				assert.NotNil(node.Address)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		collector.queue <- &Response{
+			Address:   &net.UDPAddr{IP: net.ParseIP(fmt.Sprintf("fe80::%d", i%4+1))},
+			Interface: fmt.Sprintf("bat%d", i%4),
+			Raw:       []byte(fmt.Sprintf(`{"statistics":{"node_id":"000000000001","clients":{"total":%d}}}`, i)),
+		}
+	}
+	collector.Close()
+	<-done
+
+	node := nodes.List["000000000001"]
+	assert.NotNil(node)
+	assert.NotNil(node.Address)
+	assert.NotEmpty(node.Interface)
+	assert.Len(node.History, 5)
+	for i := 1; i < len(node.History); i++ {
+		assert.False(node.History[i].Time.Before(node.History[i-1].Time))
+	}
+}
+
+func TestInternalCounters(t *testing.T) {
+	assert := assert.New(t)
+
+	collector := &Collector{config: &Config{}, db: &countingDB{nodes: 2}, queue: make(chan *Response, 2)}
+	collector.counters.Excluded = 3
+	collector.queue <- &Response{}
+	counters := collector.InternalCounters()
+	assert.Contains(counters, runtime.Counter{Name: "responses_excluded", Help: "Responses of excluded nodes", Value: 3})
+	assert.Contains(counters, runtime.Counter{Name: "queue_length", Help: "Responses queued to be parsed", Value: 1, Gauge: true})
+	// the counters of the database
+	assert.Equal(runtime.Counter{Name: "inserted_nodes", Value: 2}, counters[len(counters)-1])
+}
+
+func TestIsLate(t *testing.T) {
+	assert := assert.New(t)
+
+	config := &Config{}
+	collector := &Collector{config: config}
+	status := &interfaceStatus{}
+	now := time.Now()
+
+	// disabled
+	assert.False(collector.isLate(status, now))
+
+	config.MaxResponseAge.Duration = time.Second
+	// no request sent yet
+	assert.False(collector.isLate(status, now))
+
+	status.requestSent(now.Add(-time.Millisecond * 500))
+	assert.False(collector.isLate(status, now))
+
+	status.requestSent(now.Add(-time.Second * 2))
+	assert.True(collector.isLate(status, now))
+
+	// the requests on another socket do not count
+	other := &interfaceStatus{}
+	other.requestSent(now)
+	assert.True(collector.isLate(status, now))
+}
+
+func TestReceiverDropsLate(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(err)
+	sender, err := net.DialUDP("udp", nil, conn.LocalAddr().(*net.UDPAddr))
+	assert.NoError(err)
+	defer sender.Close()
+
+	config := &Config{}
+	config.MaxResponseAge.Duration = time.Second
+	collector := &Collector{
+		config: config,
+		queue:  make(chan *Response, 1),
+		stop:   make(chan interface{}),
+	}
+	status := &interfaceStatus{}
+	status.requestSent(time.Now().Add(-time.Minute))
+	collector.connections = []multicastConn{{Conn: conn, SendRequest: true, status: status}}
+
+	// the age is checked, as requests are sent on the interface
+	collector.workers.Add(1)
+	go collector.interfaceReceiver(0, InterfaceConfig{})
+
+	// the late response is dropped and counted
+	_, err = sender.Write([]byte("late"))
+	assert.NoError(err)
+	for i := 0; i < 100 && collector.Counters().DroppedLate == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.EqualValues(1, collector.Counters().DroppedLate)
+	assert.Len(collector.queue, 0)
+
+	// a response after a new request is queued
+	status.requestSent(time.Now())
+	_, err = sender.Write([]byte("fresh"))
+	assert.NoError(err)
+	select {
+	case res := <-collector.queue:
+		assert.Equal([]byte("fresh"), res.Raw)
+	case <-time.After(time.Second):
+		assert.Fail("response not queued")
+	}
+	assert.EqualValues(1, collector.Counters().DroppedLate)
+	assert.EqualValues(2, collector.Counters().Received)
+	assert.EqualValues(1, collector.Counters().QueueHighWater)
+
+	close(collector.stop)
+	conn.Close()
+	collector.workers.Wait()
+}
+
 func TestParse(t *testing.T) {
 	assert := assert.New(t)
 
@@ -103,3 +492,239 @@ func TestParseCustomFieldNotExistant(t *testing.T) {
 	assert.Equal("Trillian", data.Nodeinfo.Hostname)
 	assert.False(ok)
 }
+
+func TestNextRound(t *testing.T) {
+	assert := assert.New(t)
+
+	collector := &Collector{
+		config: &Config{},
+		queue:  make(chan *Response, 4),
+	}
+	assert.True(collector.nextRound())
+
+	for i := 0; i < 3; i++ {
+		collector.queue <- &Response{}
+	}
+	// only warn
+	assert.True(collector.nextRound())
+	assert.True(collector.nextRound())
+	assert.EqualValues(2, collector.Counters().BusyRounds)
+
+	// skip
+	collector.config.SkipBusyRounds = true
+	assert.False(collector.nextRound())
+	assert.EqualValues(3, collector.Counters().BusyRounds)
+
+	<-collector.queue
+	<-collector.queue
+	assert.True(collector.nextRound())
+	assert.EqualValues(3, collector.Counters().BusyRounds)
+}
+
+func TestTruncated(t *testing.T) {
+	assert := assert.New(t)
+
+	collector := &Collector{config: &Config{}}
+	src := &net.UDPAddr{IP: net.ParseIP("fe80::1")}
+	now := time.Now()
+
+	collector.truncated(src, now)
+	assert.EqualValues(now.UnixNano(), collector.lastTruncatedWarn)
+
+	// throttled
+	collector.truncated(src, now.Add(time.Second))
+	assert.EqualValues(now.UnixNano(), collector.lastTruncatedWarn)
+
+	collector.truncated(src, now.Add(truncatedWarnInterval))
+	assert.EqualValues(now.Add(truncatedWarnInterval).UnixNano(), collector.lastTruncatedWarn)
+	assert.EqualValues(3, collector.Counters().Truncated)
+}
+
+// testConnection returns a connection without socket on the given interface
+func testConnection(iface string, multicast net.IP, sendRequest bool) multicastConn {
+	return multicastConn{
+		SendRequest:      sendRequest,
+		MulticastAddress: multicast,
+		status:           &interfaceStatus{status: InterfaceStatus{Interface: iface}},
+	}
+}
+
+func TestConnectionsFor(t *testing.T) {
+	assert := assert.New(t)
+
+	ipv6 := net.ParseIP(MulticastAddressDefault)
+	collector := &Collector{
+		connections: []multicastConn{
+			testConnection("bat0", ipv6, true),
+			testConnection("bat1", ipv6, true),
+			testConnection("mesh-vpn", ipv6, true),
+			testConnection("bat0", net.IPv4bcast, true),
+		},
+	}
+	interfaces := func(node *runtime.Node) (result []string) {
+		for _, conn := range collector.connectionsFor(node) {
+			result = append(result, conn.status.status.Interface)
+		}
+		return
+	}
+
+	// link local address with zone
+	assert.Equal([]string{"bat1"}, interfaces(&runtime.Node{
+		Address: &net.UDPAddr{IP: net.ParseIP("fe80::1"), Zone: "bat1"},
+	}))
+	// global address on the interface of the last response
+	assert.Equal([]string{"mesh-vpn"}, interfaces(&runtime.Node{
+		Address:   &net.UDPAddr{IP: net.ParseIP("2001:db8::1")},
+		Interface: "mesh-vpn",
+	}))
+	// unknown interface
+	assert.Len(interfaces(&runtime.Node{
+		Address: &net.UDPAddr{IP: net.ParseIP("2001:db8::1")},
+	}), 3)
+	// only connections of the same address family
+	assert.Equal([]string{"bat0"}, interfaces(&runtime.Node{
+		Address: &net.UDPAddr{IP: net.ParseIP("10.0.0.1")},
+	}))
+}
+
+func TestSaveResponseInterface(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector := &Collector{nodes: nodes, config: &Config{}}
+	collector.saveResponse(&net.UDPAddr{IP: net.ParseIP("2001:db8::1")}, "bat1", 0, &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000001"},
+	})
+	assert.Equal("bat1", nodes.List["000000000001"].Interface)
+}
+
+func TestSaveResponseLatency(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector := &Collector{nodes: nodes, config: &Config{}}
+	addr := &net.UDPAddr{IP: net.ParseIP("fe80::1")}
+	collector.saveResponse(addr, "", 250*time.Millisecond, &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000001"},
+	})
+	assert.Equal(0.25, nodes.List["000000000001"].Latency)
+
+	// an unknown latency keeps the last one
+	collector.saveResponse(addr, "", 0, &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000001"},
+	})
+	assert.Equal(0.25, nodes.List["000000000001"].Latency)
+
+	status := &interfaceStatus{}
+	now := time.Now()
+	assert.Equal(time.Duration(0), responseLatency(status, now))
+	status.requestSent(now.Add(-time.Second))
+	assert.Equal(time.Second, responseLatency(status, now))
+	assert.Equal(time.Duration(0), responseLatency(status, now.Add(-2*time.Second)))
+}
+
+func TestSendPacketRequestPort(t *testing.T) {
+	assert := assert.New(t)
+
+	listen := func() *net.UDPConn {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Skip(err)
+		}
+		return conn
+	}
+	node := listen()
+	defer node.Close()
+	local := listen()
+	defer local.Close()
+
+	port := node.LocalAddr().(*net.UDPAddr).Port
+	collector := &Collector{config: &Config{RequestPort: 1}}
+	conn := multicastConn{Conn: local, RequestPort: port}
+	assert.NoError(collector.sendPacket(&conn, net.IPv4(127, 0, 0, 1), newRequest(RequestCategoriesDefault)))
+
+	node.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 100)
+	n, _, err := node.ReadFromUDP(buf)
+	assert.NoError(err)
+	assert.Equal(newRequest(RequestCategoriesDefault).payload, buf[:n])
+}
+
+// globalsDB passes the sites of the inserted global statistics
+type globalsDB struct {
+	database.Connection
+	globals chan string
+}
+
+func (db *globalsDB) InsertGlobals(stats *runtime.GlobalStats, t time.Time, site string, domain string) {
+	select {
+	case db.globals <- site:
+	default:
+	}
+}
+
+func TestGlobalStatsWorker(t *testing.T) {
+	assert := assert.New(t)
+
+	newCollector := func(config *Config) (*Collector, *globalsDB) {
+		db := &globalsDB{globals: make(chan string, 10)}
+		collector := &Collector{
+			db:       db,
+			nodes:    runtime.NewNodes(&runtime.NodesConfig{}),
+			config:   config,
+			stop:     make(chan interface{}),
+			complete: make(chan struct{}, 1),
+		}
+		collector.workers.Add(1)
+		go collector.globalStatsWorker()
+		return collector, db
+	}
+	received := func(db *globalsDB) bool {
+		select {
+		case site := <-db.globals:
+			return site == runtime.GLOBAL_SITE
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}
+
+	// after every complete round
+	collector, db := newCollector(&Config{})
+	assert.False(received(db))
+	collector.complete <- struct{}{}
+	assert.True(received(db))
+	close(collector.stop)
+	collector.workers.Wait()
+
+	// by the interval, regardless of the rounds
+	config := &Config{}
+	config.GlobalStatsInterval.Duration = 10 * time.Millisecond
+	collector, db = newCollector(config)
+	// not before the first round is complete
+	assert.False(received(db))
+	atomic.StoreInt64(&collector.nextInterval, int64(time.Minute))
+	atomic.StoreInt64(&collector.started, time.Now().Add(-time.Second).UnixNano())
+	assert.False(received(db))
+	atomic.StoreInt64(&collector.started, time.Now().Add(-time.Minute).UnixNano())
+	assert.True(received(db))
+	close(collector.stop)
+	collector.workers.Wait()
+
+	// a round on demand is not the end of a round
+	db = &globalsDB{globals: make(chan string, 10)}
+	collector, err := NewCollectorFromConfig(db, runtime.NewNodes(&runtime.NodesConfig{}), Config{})
+	assert.NoError(err)
+	// started after the first round
+	collector.interval = time.Hour
+	collector.nextInterval = int64(time.Hour)
+	collector.rounds = 1
+	collector.sending.Add(1)
+	go func() {
+		defer collector.sending.Done()
+		collector.sender()
+	}()
+	assert.NoError(collector.Collect())
+	assert.False(received(db))
+	assert.EqualValues(2, atomic.LoadUint64(&collector.rounds))
+	collector.Close()
+}
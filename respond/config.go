@@ -1,14 +1,86 @@
 package respond
 
-import "github.com/FreifunkBremen/yanic/lib/duration"
+import (
+	"time"
 
+	"github.com/FreifunkBremen/yanic/lib/duration"
+)
+
+// Config of a Collector, all knobs of the collector are set here (e.g. for embedding, see NewCollector)
 type Config struct {
-	Enable          bool                  `toml:"enable"`
-	Synchronize     duration.Duration     `toml:"synchronize"`
-	Interfaces      []InterfaceConfig     `toml:"interfaces"`
-	Sites           map[string]SiteConfig `toml:"sites"`
-	CollectInterval duration.Duration     `toml:"collect_interval"`
-	CustomFields    []CustomFieldConfig   `toml:"custom_field"`
+	Enable              bool                   `toml:"enable"`
+	Synchronize         duration.Duration      `toml:"synchronize"`
+	Interfaces          []InterfaceConfig      `toml:"interfaces"`
+	BindTimeout         duration.Duration      `toml:"bind_timeout"` // retry to open the sockets of the interfaces on startup up to this duration
+	Sites               map[string]SiteConfig  `toml:"sites"`
+	DiscoverSites       bool                   `toml:"discover_sites"` // add the sites and domains of the online nodes to the configured sites
+	CollectInterval     duration.Duration      `toml:"collect_interval"`
+	SkipBusyRounds      bool                   `toml:"skip_busy_rounds"`
+	RequestSplay        duration.Duration      `toml:"request_splay"` // spread the multicast requests of a round over this period
+	MaxResponseAge      duration.Duration      `toml:"max_response_age"`
+	GlobalStatsInterval duration.Duration      `toml:"global_stats_interval"` // save the global statistics this often (default after every round)
+	RequestIntervals    RequestIntervalsConfig `toml:"request_intervals"`     // requested categories with their interval (default all categories every round)
+	CaptureSize         int                    `toml:"capture_size"`
+	CustomFields        []CustomFieldConfig    `toml:"custom_field"`
+	Processors          []string               `toml:"processors"`
+	ExcludeNodes        []string               `toml:"exclude_nodes"`
+	IncludeNodes        []string               `toml:"include_nodes"`
+	VerifySourceAddress bool                   `toml:"verify_source_address"`
+	RateLimit           int                    `toml:"rate_limit"`        // maximum responses per second of a source address (default unlimited)
+	RateLimitBurst      int                    `toml:"rate_limit_burst"`  // responses of a source address above the rate at once (default RateLimit)
+	StaticNodeAddresses []string               `toml:"static_nodes"`      // addresses of nodes requested by unicast in every round
+	StaticNodesFile     string                 `toml:"static_nodes_file"` // file with further addresses of static nodes, one per line
+	Resolver            string                 `toml:"resolver"`
+	ResolverRate        int                    `toml:"resolver_rate"`
+	RequestPort         int                    `toml:"request_port"`      // destination port of the requests (default PortDefault)
+	MaxDatagramSize     int                    `toml:"max_datagram_size"` // size of the read buffer (default MaxDataGramSize)
+	ReceiveBuffer       int                    `toml:"receive_buffer"`    // size of the receive buffer of the kernel of each socket (default of the kernel)
+	QueueSize           int                    `toml:"queue_size"`        // count of received responses waiting to be parsed (default QueueSizeDefault)
+	ParserWorkers       int                    `toml:"parser_workers"`    // count of goroutines parsing the received responses (default 1)
+	Provider            ProviderConfig         `toml:"provider"`          // answer respondd requests with the collected nodes
+}
+
+func (c *Config) requestPort() int {
+	if c.RequestPort > 0 {
+		return c.RequestPort
+	}
+	return PortDefault
+}
+
+func (c *Config) maxDatagramSize() int {
+	if c.MaxDatagramSize > 0 {
+		return c.MaxDatagramSize
+	}
+	return MaxDataGramSize
+}
+
+func (c *Config) queueSize() int {
+	if c.QueueSize > 0 {
+		return c.QueueSize
+	}
+	return QueueSizeDefault
+}
+
+func (c *Config) parserWorkers() int {
+	if c.ParserWorkers > 0 {
+		return c.ParserWorkers
+	}
+	return 1
+}
+
+// RequestIntervalsByCategory returns the configured request intervals by their category
+func (c *Config) RequestIntervalsByCategory() map[string]time.Duration {
+	result := make(map[string]time.Duration)
+	for category, interval := range map[string]*duration.Duration{
+		CategoryNodeinfo:   c.RequestIntervals.Nodeinfo,
+		CategoryStatistics: c.RequestIntervals.Statistics,
+		CategoryNeighbours: c.RequestIntervals.Neighbours,
+	} {
+		if interval != nil {
+			result[category] = interval.Duration
+		}
+	}
+	return result
 }
 
 func (c *Config) SitesDomains() (result map[string][]string) {
@@ -23,12 +95,21 @@ type SiteConfig struct {
 	Domains []string `toml:"domains"`
 }
 
+// RequestIntervalsConfig are the intervals of the requested categories,
+// if any is set the categories without interval are not requested
+type RequestIntervalsConfig struct {
+	Nodeinfo   *duration.Duration `toml:"nodeinfo"`
+	Statistics *duration.Duration `toml:"statistics"`
+	Neighbours *duration.Duration `toml:"neighbours"`
+}
+
 type InterfaceConfig struct {
 	InterfaceName    string `toml:"ifname"`
 	IPAddress        string `toml:"ip_address"`
 	SendNoRequest    bool   `toml:"send_no_request"`
 	MulticastAddress string `toml:"multicast_address"`
 	Port             int    `toml:"port"`
+	RequestPort      int    `toml:"request_port"` // destination port of the requests on this interface (default Config.RequestPort)
 }
 
 type CustomFieldConfig struct {
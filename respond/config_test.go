@@ -22,3 +22,28 @@ func TestSitesDomainsConfigTransform(t *testing.T) {
 	assert.Len(domains, 1)
 	assert.Equal("city", domains[0])
 }
+
+func TestConfigDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &Config{}
+	assert.Equal(PortDefault, c.requestPort())
+	assert.Equal(MaxDataGramSize, c.maxDatagramSize())
+	assert.Equal(QueueSizeDefault, c.queueSize())
+	assert.Equal(1, c.parserWorkers())
+
+	c = &Config{
+		RequestPort:     10001,
+		MaxDatagramSize: 16384,
+		QueueSize:       1000,
+		ParserWorkers:   4,
+	}
+	assert.Equal(10001, c.requestPort())
+	assert.Equal(16384, c.maxDatagramSize())
+	assert.Equal(1000, c.queueSize())
+	assert.Equal(4, c.parserWorkers())
+
+	collector := NewCollector(nil, nil, c)
+	defer collector.Close()
+	assert.Equal(1000, cap(collector.queue))
+}
@@ -0,0 +1,67 @@
+package respond
+
+import "strings"
+
+// nodeList matches node IDs by the full ID or by a prefix (e.g. of the MAC address of a vendor)
+type nodeList struct {
+	ids      map[string]struct{}
+	prefixes []string
+}
+
+// newNodeList creates a list from node IDs, entries ending with "*" are prefixes.
+// The colons of MAC addresses (e.g. "c4:6e:1f:*") are ignored.
+func newNodeList(entries []string) *nodeList {
+	list := &nodeList{ids: make(map[string]struct{}, len(entries))}
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.Replace(entry, ":", "", -1))
+		if strings.HasSuffix(entry, "*") {
+			list.prefixes = append(list.prefixes, strings.TrimSuffix(entry, "*"))
+		} else {
+			list.ids[entry] = struct{}{}
+		}
+	}
+	return list
+}
+
+// empty returns whether the list has no entry, a nil list is empty
+func (list *nodeList) empty() bool {
+	return list == nil || (len(list.ids) == 0 && len(list.prefixes) == 0)
+}
+
+// contains returns whether the node ID matches an entry of the list
+func (list *nodeList) contains(nodeID string) bool {
+	if list == nil {
+		return false
+	}
+	nodeID = strings.ToLower(nodeID)
+	if _, ok := list.ids[nodeID]; ok {
+		return true
+	}
+	for _, prefix := range list.prefixes {
+		if strings.HasPrefix(nodeID, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetExcludeNodes replaces the list of node IDs (or prefixes ending with "*"), whose responses are dropped
+func (coll *Collector) SetExcludeNodes(nodeIDs []string) {
+	coll.excludeNodes.Store(newNodeList(nodeIDs))
+}
+
+// SetIncludeNodes replaces the list of node IDs (or prefixes ending with "*"), whose responses are accepted exclusively.
+// Without entries the responses of all nodes are accepted.
+func (coll *Collector) SetIncludeNodes(nodeIDs []string) {
+	coll.includeNodes.Store(newNodeList(nodeIDs))
+}
+
+// isExcluded returns whether the responses of the node should be dropped
+func (coll *Collector) isExcluded(nodeID string) bool {
+	excluded, _ := coll.excludeNodes.Load().(*nodeList)
+	if excluded.contains(nodeID) {
+		return true
+	}
+	included, _ := coll.includeNodes.Load().(*nodeList)
+	return !included.empty() && !included.contains(nodeID)
+}
@@ -0,0 +1,89 @@
+package respond
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestExcludeNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector := &Collector{nodes: nodes}
+	assert.False(collector.isExcluded("000000000001"))
+
+	collector.SetExcludeNodes([]string{"000000000001"})
+	assert.True(collector.isExcluded("000000000001"))
+	assert.False(collector.isExcluded("000000000002"))
+
+	addr := &net.UDPAddr{IP: net.ParseIP("fe80::1")}
+	for _, nodeID := range []string{"000000000001", "000000000002"} {
+		collector.saveResponse(addr, "", 0, &data.ResponseData{
+			Nodeinfo: &data.Nodeinfo{NodeID: nodeID},
+		})
+	}
+	assert.Len(nodes.List, 1)
+	assert.NotNil(nodes.List["000000000002"])
+	assert.EqualValues(1, collector.Counters().Excluded)
+
+	// reload
+	collector.SetExcludeNodes(nil)
+	assert.False(collector.isExcluded("000000000001"))
+
+	// prefixes, with or without colons
+	collector.SetExcludeNodes([]string{"c4:6E:1f:*", "f8d111*"})
+	assert.True(collector.isExcluded("c46e1fe2b7f4"))
+	assert.True(collector.isExcluded("f8d111000001"))
+	assert.False(collector.isExcluded("000000000001"))
+}
+
+func TestVerifySourceAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector := &Collector{nodes: nodes, config: &Config{VerifySourceAddress: true}}
+
+	nodeinfo := &data.Nodeinfo{NodeID: "000000000001", Network: data.Network{Addresses: []string{"fe80::1", "2001:db8::1"}}}
+	collector.saveResponse(&net.UDPAddr{IP: net.ParseIP("fe80::2")}, "", 0, &data.ResponseData{Nodeinfo: nodeinfo})
+	assert.Len(nodes.List, 0)
+	assert.EqualValues(1, collector.Counters().Spoofed)
+
+	collector.saveResponse(&net.UDPAddr{IP: net.ParseIP("2001:db8::1")}, "", 0, &data.ResponseData{Nodeinfo: nodeinfo})
+	assert.Len(nodes.List, 1)
+
+	// verified by the known nodeinfo
+	statistics := &data.Statistics{NodeID: "000000000001", Clients: data.Clients{Total: 23}}
+	collector.saveResponse(&net.UDPAddr{IP: net.ParseIP("fe80::3")}, "", 0, &data.ResponseData{Statistics: statistics})
+	assert.Nil(nodes.List["000000000001"].Statistics)
+	assert.EqualValues(2, collector.Counters().Spoofed)
+	collector.saveResponse(&net.UDPAddr{IP: net.ParseIP("fe80::1")}, "", 0, &data.ResponseData{Statistics: statistics})
+	assert.NotNil(nodes.List["000000000001"].Statistics)
+
+	// not verifiable without addresses
+	collector.saveResponse(&net.UDPAddr{IP: net.ParseIP("fe80::4")}, "", 0, &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000002"},
+	})
+	assert.Len(nodes.List, 2)
+}
+
+func TestIncludeNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	collector := &Collector{}
+	collector.SetIncludeNodes(nil)
+	assert.False(collector.isExcluded("000000000001"))
+
+	collector.SetIncludeNodes([]string{"000000000001", "c46e1f*"})
+	assert.False(collector.isExcluded("000000000001"))
+	assert.False(collector.isExcluded("c46e1fe2b7f4"))
+	assert.True(collector.isExcluded("000000000002"))
+
+	// excluded wins
+	collector.SetExcludeNodes([]string{"c46e1fe2b7f4"})
+	assert.True(collector.isExcluded("c46e1fe2b7f4"))
+}
@@ -0,0 +1,27 @@
+package respond
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/FreifunkBremen/yanic/database"
+)
+
+// Health returns an error, if the collector does not work: a socket is down, no response was stored
+// between the last two rounds or the last write of the database failed
+func (coll *Collector) Health() error {
+	for _, status := range coll.InterfaceStatus() {
+		if status.Down != nil {
+			return fmt.Errorf("socket of %s is down since %s", status.Interface, status.Down.Format("2006-01-02 15:04:05"))
+		}
+	}
+	// the responses of a round are known after the next round is sent
+	if atomic.LoadUint64(&coll.rounds) > 1 && atomic.LoadUint64(&coll.lastRoundStored) == 0 {
+		return errors.New("no response stored in the last round")
+	}
+	if checker, ok := coll.db.(database.HealthChecker); ok {
+		return checker.Health()
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package respond
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// healthDB is a database with a failed last write
+type healthDB struct {
+	countingDB
+	err error
+}
+
+func (db *healthDB) Health() error {
+	return db.err
+}
+
+func TestHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	status := &interfaceStatus{status: InterfaceStatus{Interface: "br-ffhb"}}
+	db := &healthDB{}
+	collector := &Collector{
+		connections: []multicastConn{{status: status}},
+		db:          db,
+	}
+	assert.NoError(collector.Health())
+
+	// the first round is not complete yet
+	collector.rounds = 1
+	assert.NoError(collector.Health())
+
+	collector.rounds = 2
+	assert.EqualError(collector.Health(), "no response stored in the last round")
+	collector.lastRoundStored = 3
+	assert.NoError(collector.Health())
+
+	db.err = errors.New("timeout")
+	assert.EqualError(collector.Health(), "timeout")
+	db.err = nil
+
+	status.failed(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	assert.EqualError(collector.Health(), "socket of br-ffhb is down since 2020-01-02 03:04:05")
+	status.rebound("[fe80::1%br-ffhb]:1001", time.Now())
+	assert.NoError(collector.Health())
+}
@@ -0,0 +1,56 @@
+package respond
+
+import (
+	"fmt"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/data"
+)
+
+// ResponseProcessor transforms a parsed response before it is saved.
+// It may modify the given response or return another one; returning nil drops the response.
+type ResponseProcessor func(*data.ResponseData) *data.ResponseData
+
+var processors = make(map[string]ResponseProcessor)
+
+// RegisterProcessor registers a processor, which could be enabled by its name in the config
+func RegisterProcessor(name string, p ResponseProcessor) {
+	if _, ok := processors[name]; ok {
+		log.WithField("processor", name).Panic("processor already registered")
+	}
+	processors[name] = p
+}
+
+// newProcessors returns the registered processors in the configured order
+func newProcessors(names []string) ([]ResponseProcessor, error) {
+	var list []ResponseProcessor
+	for _, name := range names {
+		p, ok := processors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown processor: %s", name)
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// process runs the processors in order, it stops at the first processor which drops the response
+func process(list []ResponseProcessor, res *data.ResponseData) *data.ResponseData {
+	for _, p := range list {
+		if res = p(res); res == nil {
+			return nil
+		}
+	}
+	return res
+}
+
+func init() {
+	// drop the contact information of the owners
+	RegisterProcessor("drop_owner", func(res *data.ResponseData) *data.ResponseData {
+		if res.Nodeinfo != nil {
+			res.Nodeinfo.Owner = nil
+		}
+		return res
+	})
+}
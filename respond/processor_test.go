@@ -0,0 +1,47 @@
+package respond
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+)
+
+func TestProcessors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := newProcessors([]string{"nonexisting"})
+	assert.Error(err)
+
+	list, err := newProcessors(nil)
+	assert.NoError(err)
+	res := &data.ResponseData{}
+	assert.Equal(res, process(list, res))
+
+	list, err = newProcessors([]string{"drop_owner"})
+	assert.NoError(err)
+	res = process(list, &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{Owner: &data.Owner{Contact: "nobody"}},
+	})
+	assert.Nil(res.Nodeinfo.Owner)
+
+	// order and drop
+	var called []string
+	list = []ResponseProcessor{
+		func(res *data.ResponseData) *data.ResponseData {
+			called = append(called, "first")
+			return nil
+		},
+		func(res *data.ResponseData) *data.ResponseData {
+			called = append(called, "second")
+			return res
+		},
+	}
+	assert.Nil(process(list, &data.ResponseData{}))
+	assert.Equal([]string{"first"}, called)
+
+	assert.Panics(func() {
+		RegisterProcessor("drop_owner", nil)
+	}, "already registered")
+}
@@ -0,0 +1,213 @@
+package respond
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// ProviderConfig of the answering of respondd requests with the collected nodes
+type ProviderConfig struct {
+	Enable           bool     `toml:"enable"`
+	Bind             string   `toml:"bind"`              // address of the unicast requests (default [::]:PortDefault)
+	Interfaces       []string `toml:"interfaces"`        // join the multicast group on these interfaces
+	MulticastAddress string   `toml:"multicast_address"` // default MulticastAddressDefault
+}
+
+// Provider answers respondd requests with the data of the online nodes, like every node would answer by itself,
+// so a collector upstream could request a single address instead of all nodes
+type Provider struct {
+	nodes *runtime.Nodes
+	conns []*net.UDPConn
+	wg    sync.WaitGroup
+}
+
+// NewProvider opens the sockets of the provider and starts to answer the requests
+func NewProvider(config ProviderConfig, nodes *runtime.Nodes) (*Provider, error) {
+	bind := config.Bind
+	if bind == "" {
+		bind = fmt.Sprintf("[::]:%d", PortDefault)
+	}
+	addr, err := net.ResolveUDPAddr("udp", bind)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bind address %q: %s", bind, err)
+	}
+
+	p := &Provider{nodes: nodes}
+	if len(config.Interfaces) == 0 {
+		conn, err := net.ListenUDP("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		p.conns = append(p.conns, conn)
+	}
+
+	multicastAddress := MulticastAddressDefault
+	if config.MulticastAddress != "" {
+		multicastAddress = config.MulticastAddress
+	}
+	for _, ifname := range config.Interfaces {
+		conn, err := listenMulticast(ifname, multicastAddress, addr.Port)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("interface %s: %s", ifname, err)
+		}
+		p.conns = append(p.conns, conn)
+	}
+
+	for _, conn := range p.conns {
+		p.wg.Add(1)
+		go p.serve(conn)
+	}
+	return p, nil
+}
+
+// listenMulticast opens a socket, which receives the unicast requests and the multicast requests of an interface
+func listenMulticast(ifname, multicastAddress string, port int) (*net.UDPConn, error) {
+	zone, multicastIP, err := resolveZones(ifname, multicastAddress)
+	if err != nil {
+		return nil, err
+	}
+	iface, err := net.InterfaceByName(zone)
+	if err != nil {
+		return nil, err
+	}
+	network := "udp6"
+	if multicastIP.To4() != nil {
+		network = "udp4"
+	}
+	return net.ListenMulticastUDP(network, iface, &net.UDPAddr{IP: multicastIP, Port: port})
+}
+
+// isLocalAddress returns whether the address belongs to an interface of this host,
+// the requests of the own addresses are ignored, e.g. of the own collector
+var isLocalAddress = func(ip net.IP) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops to answer the requests
+func (p *Provider) Close() {
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+	p.wg.Wait()
+}
+
+func (p *Provider) serve(conn *net.UDPConn) {
+	defer p.wg.Done()
+	buf := make([]byte, 1500)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// closed
+			return
+		}
+		if isLocalAddress(src.IP) {
+			continue
+		}
+		categories, compressed, ok := parseProviderRequest(string(buf[:n]))
+		if !ok {
+			log.WithField("address", src.String()).Debugf("provider: invalid request %q", buf[:n])
+			continue
+		}
+		for _, payload := range p.responses(categories, compressed) {
+			if _, err := conn.WriteToUDP(payload, src); err != nil {
+				log.WithField("address", src.String()).Debugf("provider: unable to answer: %s", err)
+				break
+			}
+		}
+	}
+}
+
+// parseProviderRequest returns the requested categories:
+// "GET <category>..." is answered deflated with all categories in one object (like by gluon),
+// a single category is answered with the uncompressed object of the category
+func parseProviderRequest(request string) ([]string, bool, bool) {
+	compressed := strings.HasPrefix(request, "GET ")
+	fields := strings.Fields(strings.TrimPrefix(request, "GET "))
+	if len(fields) == 0 || (!compressed && len(fields) > 1) {
+		return nil, false, false
+	}
+	for _, category := range fields {
+		if !isCategory(category) {
+			return nil, false, false
+		}
+	}
+	return fields, compressed, true
+}
+
+// responses returns the payload of every online node, which has data of the requested categories
+func (p *Provider) responses(categories []string, compressed bool) [][]byte {
+	var result [][]byte
+	p.nodes.RLock()
+	defer p.nodes.RUnlock()
+	for id, node := range p.nodes.List {
+		if !node.Online {
+			continue
+		}
+		payload, err := providerPayload(node, categories, compressed)
+		if err != nil {
+			log.WithField("node_id", id).Debugf("provider: unable to encode: %s", err)
+			continue
+		}
+		if payload != nil {
+			result = append(result, payload)
+		}
+	}
+	return result
+}
+
+// providerPayload encodes the categories of a node, nil if the node has no data of them
+func providerPayload(node *runtime.Node, categories []string, compressed bool) ([]byte, error) {
+	object := make(map[string]interface{}, len(categories))
+	for _, category := range categories {
+		switch {
+		case category == CategoryNodeinfo && node.Nodeinfo != nil:
+			object[category] = node.Nodeinfo
+		case category == CategoryStatistics && node.Statistics != nil:
+			object[category] = node.Statistics
+		case category == CategoryNeighbours && node.Neighbours != nil:
+			object[category] = node.Neighbours
+		}
+	}
+	if len(object) == 0 {
+		return nil, nil
+	}
+
+	if !compressed {
+		return json.Marshal(object[categories[0]])
+	}
+
+	buf := new(bytes.Buffer)
+	flater, err := flate.NewWriter(buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.NewEncoder(flater).Encode(object); err != nil {
+		return nil, err
+	}
+	if err = flater.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() > maxUDPSize {
+		return nil, fmt.Errorf("response of %d bytes is too large for a datagram", buf.Len())
+	}
+	return buf.Bytes(), nil
+}
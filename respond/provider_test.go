@@ -0,0 +1,90 @@
+package respond
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestParseProviderRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	categories, compressed, ok := parseProviderRequest("GET nodeinfo statistics")
+	assert.True(ok)
+	assert.True(compressed)
+	assert.Equal([]string{"nodeinfo", "statistics"}, categories)
+
+	categories, compressed, ok = parseProviderRequest("neighbours")
+	assert.True(ok)
+	assert.False(compressed)
+	assert.Equal([]string{"neighbours"}, categories)
+
+	for _, request := range []string{"", "GET ", "GET blub", "nodeinfo statistics"} {
+		_, _, ok = parseProviderRequest(request)
+		assert.False(ok, request)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	nodes.Update("000000000001", &data.ResponseData{
+		Nodeinfo:   &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"},
+		Statistics: &data.Statistics{NodeID: "000000000001", Clients: data.Clients{Total: 23}},
+	})
+	offline := nodes.Update("000000000002", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000002", Hostname: "node2"},
+	})
+	offline.Online = false
+
+	_, err := NewProvider(ProviderConfig{Bind: "invalid"}, nodes)
+	assert.Error(err)
+
+	isLocal := isLocalAddress
+	defer func() { isLocalAddress = isLocal }()
+	isLocalAddress = func(net.IP) bool { return false }
+
+	p, err := NewProvider(ProviderConfig{Bind: "127.0.0.1:0"}, nodes)
+	assert.NoError(err)
+	defer p.Close()
+
+	conn, err := net.DialUDP("udp", nil, p.conns[0].LocalAddr().(*net.UDPAddr))
+	assert.NoError(err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, maxUDPSize)
+
+	// deflated, like a node
+	_, err = conn.Write([]byte("GET nodeinfo statistics"))
+	assert.NoError(err)
+	n, err := conn.Read(buf)
+	assert.NoError(err)
+	res, err := (&Response{Raw: buf[:n]}).parse(nil)
+	assert.NoError(err)
+	assert.Equal("node1", res.Nodeinfo.Hostname)
+	assert.EqualValues(23, res.Statistics.Clients.Total)
+	assert.Nil(res.Neighbours)
+
+	// uncompressed single category
+	_, err = conn.Write([]byte("nodeinfo"))
+	assert.NoError(err)
+	n, err = conn.Read(buf)
+	assert.NoError(err)
+	var nodeinfo data.Nodeinfo
+	assert.NoError(json.Unmarshal(buf[:n], &nodeinfo))
+	assert.Equal("node1", nodeinfo.Hostname)
+
+	// no node has neighbours
+	_, err = conn.Write([]byte("GET neighbours"))
+	assert.NoError(err)
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, err = conn.Read(buf)
+	assert.Error(err)
+}
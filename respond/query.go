@@ -0,0 +1,123 @@
+package respond
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// queryResult is the parsed response to a query, or the error of the parsing
+type queryResult struct {
+	data *data.ResponseData
+	err  error
+}
+
+// queries are the addresses with a pending Query
+type queries struct {
+	waiting map[string][]chan queryResult // by ip address without zone
+	sync.Mutex
+}
+
+// add registers a query to the given address
+func (q *queries) add(ip net.IP) chan queryResult {
+	q.Lock()
+	defer q.Unlock()
+	if q.waiting == nil {
+		q.waiting = make(map[string][]chan queryResult)
+	}
+	result := make(chan queryResult, 1)
+	q.waiting[ip.String()] = append(q.waiting[ip.String()], result)
+	return result
+}
+
+// remove drops a query to the given address
+func (q *queries) remove(ip net.IP, result chan queryResult) {
+	q.Lock()
+	defer q.Unlock()
+	list := q.waiting[ip.String()]
+	for i, c := range list {
+		if c == result {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(q.waiting, ip.String())
+	} else {
+		q.waiting[ip.String()] = list
+	}
+}
+
+// answer passes a received response to the queries of its address,
+// the response is parsed separately, so the collector could process it as usual
+func (q *queries) answer(res *Response, customFields []CustomFieldConfig) {
+	q.Lock()
+	defer q.Unlock()
+	list := q.waiting[res.Address.IP.String()]
+	if len(list) == 0 {
+		return
+	}
+	var result queryResult
+	result.data, result.err = res.parse(customFields)
+	for _, c := range list {
+		select {
+		case c <- result:
+		default:
+			// answered already
+		}
+	}
+}
+
+// ParseAddress parses the address of a node with an optional zone, e.g. "fe80::1%bat0"
+func ParseAddress(s string) (*net.UDPAddr, error) {
+	var zone string
+	if i := strings.LastIndex(s, "%"); i >= 0 {
+		s, zone = s[:i], s[i+1:]
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	return &net.UDPAddr{IP: ip, Zone: zone}, nil
+}
+
+// Query sends a request of all categories to a single address and returns the parsed response, e.g. to check
+// why a node is missing. The request is sent on the connections, which would be used for the unicasts to a node
+// at this address. The response is not changed by the processors and is processed by the collector as usual.
+func (coll *Collector) Query(addr *net.UDPAddr, timeout time.Duration) (*data.ResponseData, error) {
+	conns := coll.connectionsFor(&runtime.Node{Address: addr})
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("no interface to reach %s", addr.String())
+	}
+
+	result := coll.queries.add(addr.IP)
+	defer coll.queries.remove(addr.IP, result)
+
+	req := newRequest(RequestCategoriesDefault)
+	sent := false
+	for _, conn := range conns {
+		if coll.sendPacket(&conn, addr.IP, req) == nil {
+			sent = true
+		}
+	}
+	if !sent {
+		return nil, fmt.Errorf("unable to send the request to %s", addr.String())
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case res := <-result:
+		return res.data, res.err
+	case <-timer.C:
+		return nil, fmt.Errorf("no response of %s within %s", addr.String(), timeout)
+	case <-coll.stop:
+		return nil, errors.New("collector is closed")
+	}
+}
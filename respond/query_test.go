@@ -0,0 +1,75 @@
+package respond
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestParseAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	addr, err := ParseAddress("fe80::1%bat0")
+	assert.NoError(err)
+	assert.Equal(&net.UDPAddr{IP: net.ParseIP("fe80::1"), Zone: "bat0"}, addr)
+
+	addr, err = ParseAddress("10.0.0.1")
+	assert.NoError(err)
+	assert.Equal(&net.UDPAddr{IP: net.ParseIP("10.0.0.1")}, addr)
+
+	_, err = ParseAddress("node1%bat0")
+	assert.Error(err)
+}
+
+func TestQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	// a node, which answers every request
+	node, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(err)
+	defer node.Close()
+	go func() {
+		buf := make([]byte, 100)
+		for {
+			_, addr, err := node.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			res, _ := NewRespone(&data.ResponseData{
+				Nodeinfo: &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"},
+			}, nil)
+			node.WriteToUDP(res.Raw, addr)
+		}
+	}()
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector, err := NewCollectorFromConfig(nil, nodes, Config{
+		RequestPort: node.LocalAddr().(*net.UDPAddr).Port,
+		Interfaces: []InterfaceConfig{{
+			InterfaceName:    "lo",
+			IPAddress:        "127.0.0.1",
+			MulticastAddress: "127.0.0.1",
+		}},
+	})
+	assert.NoError(err)
+	defer collector.Close()
+
+	res, err := collector.Query(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}, time.Second)
+	assert.NoError(err)
+	assert.Equal("node1", res.Nodeinfo.Hostname)
+	assert.Empty(collector.queries.waiting)
+
+	// no interface for IPv6
+	_, err = collector.Query(&net.UDPAddr{IP: net.ParseIP("fe80::1")}, time.Second)
+	assert.Error(err)
+
+	// no response
+	_, err = collector.Query(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 2)}, 50*time.Millisecond)
+	assert.Error(err)
+	assert.Empty(collector.queries.waiting)
+}
@@ -0,0 +1,80 @@
+package respond
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// rateLimiterMaxSources is the count of tracked source addresses, above the idle ones are removed
+const rateLimiterMaxSources = 10000
+
+// rateLimiter limits the responses per source address by a token bucket of each address
+type rateLimiter struct {
+	rate    float64 // tokens per second
+	burst   float64
+	buckets map[string]*tokenBucket
+	sync.Mutex
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter of rate responses per second with the given burst, nil if the rate is not set
+func newRateLimiter(rate, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = rate
+	}
+	return &rateLimiter{
+		rate:    float64(rate),
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow returns whether a response of the address received at the given time is within the limit
+func (l *rateLimiter) allow(ip net.IP, now time.Time) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	key := ip.String()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= rateLimiterMaxSources {
+			l.purge(now)
+		}
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = l.refill(bucket, now)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// refill returns the tokens of the bucket at the given time
+func (l *rateLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	tokens := bucket.tokens + now.Sub(bucket.last).Seconds()*l.rate
+	if tokens > l.burst {
+		return l.burst
+	}
+	return tokens
+}
+
+// purge removes the buckets, which are full again (the lock has to be held)
+func (l *rateLimiter) purge(now time.Time) {
+	for key, bucket := range l.buckets {
+		if l.refill(bucket, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package respond
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newRateLimiter(0, 10))
+
+	l := newRateLimiter(2, 3)
+	now := time.Now()
+	ip := net.ParseIP("fe80::1")
+
+	// burst
+	for i := 0; i < 3; i++ {
+		assert.True(l.allow(ip, now), i)
+	}
+	assert.False(l.allow(ip, now))
+	// other addresses have their own bucket
+	assert.True(l.allow(net.ParseIP("fe80::2"), now))
+
+	// refilled by the rate
+	assert.False(l.allow(ip, now.Add(200*time.Millisecond)))
+	assert.True(l.allow(ip, now.Add(700*time.Millisecond)))
+	assert.False(l.allow(ip, now.Add(700*time.Millisecond)))
+
+	// burst defaults to the rate
+	assert.Equal(float64(5), newRateLimiter(5, 0).burst)
+}
+
+func TestRateLimiterPurge(t *testing.T) {
+	assert := assert.New(t)
+
+	l := newRateLimiter(1, 1)
+	now := time.Now()
+	for i := 0; i < rateLimiterMaxSources; i++ {
+		l.allow(net.ParseIP(fmt.Sprintf("10.0.%d.%d", i/256, i%256)), now)
+	}
+	assert.Len(l.buckets, rateLimiterMaxSources)
+
+	// the idle buckets are full again and removed
+	assert.True(l.allow(net.ParseIP("fe80::1"), now.Add(time.Minute)))
+	assert.Len(l.buckets, 1)
+}
@@ -0,0 +1,117 @@
+package respond
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CategoryNodeinfo   = "nodeinfo"
+	CategoryStatistics = "statistics"
+	CategoryNeighbours = "neighbours"
+)
+
+// RequestCategoriesDefault are the categories requested in every round, if no request intervals are configured
+var RequestCategoriesDefault = []string{CategoryNodeinfo, CategoryStatistics, CategoryNeighbours}
+
+// request of a round
+type request struct {
+	payload    []byte
+	categories map[string]bool
+	all        bool // all categories are requested
+	split      bool // every category is requested by its own packet, so a response contains only one of them
+}
+
+func newRequest(categories []string) *request {
+	req := &request{
+		payload:    []byte("GET " + strings.Join(categories, " ")),
+		categories: make(map[string]bool, len(categories)),
+	}
+	for _, category := range categories {
+		req.categories[category] = true
+	}
+	req.all = len(req.categories) == len(RequestCategoriesDefault)
+	return req
+}
+
+// parts returns a request for every category of the request
+func (req *request) parts() []*request {
+	var result []*request
+	// keep the order of the default categories
+	for _, category := range RequestCategoriesDefault {
+		if req.categories[category] {
+			result = append(result, newRequest([]string{category}))
+		}
+	}
+	return result
+}
+
+// requestSchedule decides which categories are requested in a round
+type requestSchedule struct {
+	sync.Mutex
+	intervals map[string]time.Duration // categories with their request interval, zero for every round
+	last      map[string]time.Time     // time of the last request of a category
+}
+
+func newRequestSchedule() *requestSchedule {
+	return &requestSchedule{
+		last: make(map[string]time.Time),
+	}
+}
+
+// set replaces the request intervals
+func (s *requestSchedule) set(intervals map[string]time.Duration) error {
+	for category, interval := range intervals {
+		if !isCategory(category) {
+			return fmt.Errorf("unknown request category: %s", category)
+		}
+		if interval < 0 {
+			return fmt.Errorf("invalid request interval of %s: %s", category, interval)
+		}
+	}
+
+	s.Lock()
+	s.intervals = intervals
+	s.Unlock()
+	return nil
+}
+
+func isCategory(category string) bool {
+	for _, c := range RequestCategoriesDefault {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the request of the round started at now,
+// a category is due if its interval is elapsed within the given slack
+func (s *requestSchedule) next(now time.Time, slack time.Duration) *request {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.intervals) == 0 {
+		return newRequest(RequestCategoriesDefault)
+	}
+
+	var categories []string
+	// keep the order of the default categories
+	for _, category := range RequestCategoriesDefault {
+		interval, ok := s.intervals[category]
+		if !ok {
+			continue
+		}
+		if last, ok := s.last[category]; ok && now.Sub(last)+slack < interval {
+			continue
+		}
+		s.last[category] = now
+		categories = append(categories, category)
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+	return newRequest(categories)
+}
@@ -0,0 +1,149 @@
+package respond
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/database"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestRequestSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	schedule := newRequestSchedule()
+	now := time.Now()
+
+	// without intervals everything is requested
+	req := schedule.next(now, 0)
+	assert.Equal("GET nodeinfo statistics neighbours", string(req.payload))
+	assert.True(req.all)
+
+	assert.Error(schedule.set(map[string]time.Duration{"wifi": time.Minute}))
+	assert.Error(schedule.set(map[string]time.Duration{CategoryNodeinfo: -time.Minute}))
+	assert.NoError(schedule.set(map[string]time.Duration{
+		CategoryNodeinfo:   time.Hour,
+		CategoryStatistics: 0,
+	}))
+
+	req = schedule.next(now, 30*time.Second)
+	assert.Equal("GET nodeinfo statistics", string(req.payload))
+	assert.False(req.all)
+
+	req = schedule.next(now.Add(time.Minute), 30*time.Second)
+	assert.Equal("GET statistics", string(req.payload))
+	assert.False(req.categories[CategoryNodeinfo])
+
+	// the slack allows a slightly early round
+	req = schedule.next(now.Add(time.Hour-time.Second), 30*time.Second)
+	assert.Equal("GET nodeinfo statistics", string(req.payload))
+
+	// nothing is due
+	assert.NoError(schedule.set(map[string]time.Duration{CategoryNodeinfo: time.Hour}))
+	assert.Nil(schedule.next(now.Add(time.Hour), 30*time.Second))
+}
+
+func TestRequestParts(t *testing.T) {
+	assert := assert.New(t)
+
+	parts := newRequest([]string{CategoryNeighbours, CategoryNodeinfo}).parts()
+	assert.Len(parts, 2)
+	assert.Equal("GET nodeinfo", string(parts[0].payload))
+	assert.Equal("GET neighbours", string(parts[1].payload))
+}
+
+func TestKeepUnrequested(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector := &Collector{nodes: nodes, config: &Config{}}
+	addr := &net.UDPAddr{IP: net.ParseIP("2001:db8::1")}
+
+	collector.saveResponse(addr, "", 0, &data.ResponseData{
+		Nodeinfo:   &data.Nodeinfo{NodeID: "000000000001", Hostname: "node"},
+		Statistics: &data.Statistics{NodeID: "000000000001"},
+		Neighbours: &data.Neighbours{NodeID: "000000000001"},
+	})
+
+	// only statistics are requested, the nodeinfo is kept
+	collector.request.Store(newRequest([]string{CategoryStatistics}))
+	collector.saveResponse(addr, "", 0, &data.ResponseData{
+		Statistics: &data.Statistics{NodeID: "000000000001", Clients: data.Clients{Total: 3}},
+	})
+	node := nodes.List["000000000001"]
+	assert.Equal("node", node.Nodeinfo.Hostname)
+	assert.EqualValues(3, node.Statistics.Clients.Total)
+	assert.NotNil(node.Neighbours)
+
+	// the missing nodeinfo of a round requesting it is not kept
+	collector.request.Store(newRequest(RequestCategoriesDefault))
+	collector.saveResponse(addr, "", 0, &data.ResponseData{
+		Statistics: &data.Statistics{NodeID: "000000000001"},
+	})
+	assert.Nil(node.Nodeinfo)
+	assert.Nil(node.Neighbours)
+
+	// a response of a split request contains only one category
+	req := newRequest(RequestCategoriesDefault)
+	req.split = true
+	collector.request.Store(req)
+	collector.saveResponse(addr, "", 0, &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000001", Hostname: "node"},
+	})
+	assert.NotNil(node.Nodeinfo)
+	assert.NotNil(node.Statistics)
+}
+
+// countingDB counts the inserted nodes and links
+type countingDB struct {
+	database.Connection
+	nodes int
+	links int
+}
+
+func (db *countingDB) InsertNode(node *runtime.Node) { db.nodes++ }
+
+func (db *countingDB) InsertLink(link *runtime.Link, t time.Time) { db.links++ }
+
+func (db *countingDB) Counters() []runtime.Counter {
+	return []runtime.Counter{{Name: "inserted_nodes", Value: uint64(db.nodes)}}
+}
+
+func TestKeepUnrequestedInsertOnce(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{HistoryDepth: 10})
+	db := &countingDB{}
+	collector := &Collector{db: db, nodes: nodes, config: &Config{}}
+	addr := &net.UDPAddr{IP: net.ParseIP("2001:db8::1")}
+
+	nodes.AddNode(&runtime.Node{Nodeinfo: &data.Nodeinfo{
+		NodeID:  "000000000002",
+		Network: data.Network{Mac: "00:00:00:00:00:02"},
+	}})
+	neighbours := &data.Neighbours{
+		NodeID: "000000000001",
+		Batadv: map[string]data.BatadvNeighbours{
+			"00:00:00:00:00:01": {Neighbours: map[string]data.BatmanLink{"00:00:00:00:00:02": {Tq: 200}}},
+		},
+	}
+
+	// a split round: the response of every category is merged with the known sections
+	req := newRequest(RequestCategoriesDefault)
+	req.split = true
+	collector.request.Store(req)
+	for round := 0; round < 2; round++ {
+		collector.saveResponse(addr, "", 0, &data.ResponseData{Nodeinfo: &data.Nodeinfo{NodeID: "000000000001"}})
+		collector.saveResponse(addr, "", 0, &data.ResponseData{Statistics: &data.Statistics{NodeID: "000000000001"}})
+		collector.saveResponse(addr, "", 0, &data.ResponseData{Neighbours: neighbours})
+	}
+
+	// only the responses with the section itself are inserted and added to the history
+	assert.Equal(2, db.nodes)
+	assert.Equal(2, db.links)
+	assert.Len(nodes.List["000000000001"].History, 2)
+}
@@ -0,0 +1,181 @@
+package respond
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+const (
+	resolverCacheTTL    = time.Hour
+	resolverFailedTTL   = 5 * time.Minute // failed lookups are retried sooner
+	resolverCacheSize   = 10000           // maximum of cached addresses
+	resolverQueueSize   = 100
+	resolverRateDefault = 10 // lookups per second
+	RESOLVER_DNS        = "dns"
+)
+
+// Resolver maps the address of a node to a name of the infrastructure (e.g. by DNS or DHCP leases)
+type Resolver func(addr net.IP) (name string, ok bool)
+
+// DNSResolver resolves an address by a reverse DNS lookup
+func DNSResolver(addr net.IP) (string, bool) {
+	names, err := net.LookupAddr(addr.String())
+	if err != nil || len(names) == 0 {
+		return "", false
+	}
+	return strings.TrimSuffix(names[0], "."), true
+}
+
+type resolverEntry struct {
+	name    string
+	ok      bool
+	expires time.Time
+}
+
+type resolveRequest struct {
+	nodeID string
+	addr   net.IP
+}
+
+// resolver caches the results of a Resolver and limits the rate of the lookups
+type resolver struct {
+	resolve Resolver
+	nodes   *runtime.Nodes
+	rate    int
+	cache   map[string]resolverEntry
+	queue   chan resolveRequest
+	stop    chan struct{} // closed to stop the worker, if the resolver is replaced
+	sync.Mutex
+}
+
+func newResolver(resolve Resolver, nodes *runtime.Nodes, rate int) *resolver {
+	if rate <= 0 {
+		rate = resolverRateDefault
+	}
+	return &resolver{
+		resolve: resolve,
+		nodes:   nodes,
+		rate:    rate,
+		cache:   make(map[string]resolverEntry),
+		queue:   make(chan resolveRequest, resolverQueueSize),
+		stop:    make(chan struct{}),
+	}
+}
+
+// cached returns the cached result of an address
+func (r *resolver) cached(addr net.IP, now time.Time) (resolverEntry, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	entry, ok := r.cache[addr.String()]
+	if ok && now.After(entry.expires) {
+		delete(r.cache, addr.String())
+		return entry, false
+	}
+	return entry, ok
+}
+
+// lookup resolves the address of a node, from the cache or later by the worker
+func (r *resolver) lookup(nodeID string, addr net.IP) {
+	if entry, ok := r.cached(addr, time.Now()); ok {
+		r.setName(nodeID, entry)
+		return
+	}
+	select {
+	case r.queue <- resolveRequest{nodeID: nodeID, addr: addr}:
+	default:
+		log.WithField("address", addr.String()).Debug("resolver queue full, lookup skipped")
+	}
+}
+
+// lookupNow runs the Resolver and caches the result
+func (r *resolver) lookupNow(req resolveRequest) {
+	entry, ok := r.cached(req.addr, time.Now())
+	if !ok {
+		entry.name, entry.ok = r.resolve(req.addr)
+		now := time.Now()
+		if entry.ok {
+			entry.expires = now.Add(resolverCacheTTL)
+		} else {
+			entry.expires = now.Add(resolverFailedTTL)
+		}
+
+		r.Lock()
+		if len(r.cache) >= resolverCacheSize {
+			r.purge(now)
+		}
+		r.cache[req.addr.String()] = entry
+		r.Unlock()
+	}
+	r.setName(req.nodeID, entry)
+}
+
+// purge removes the expired entries of the full cache, and if it is still full an arbitrary one.
+// The lock has to be held.
+func (r *resolver) purge(now time.Time) {
+	for addr, entry := range r.cache {
+		if now.After(entry.expires) {
+			delete(r.cache, addr)
+		}
+	}
+	for addr := range r.cache {
+		if len(r.cache) < resolverCacheSize {
+			break
+		}
+		delete(r.cache, addr)
+	}
+}
+
+// setName stores the resolved name on the node
+func (r *resolver) setName(nodeID string, entry resolverEntry) {
+	r.nodes.Lock()
+	defer r.nodes.Unlock()
+
+	if node, ok := r.nodes.List[nodeID]; ok {
+		if entry.ok {
+			node.ResolvedName = entry.name
+		} else {
+			node.ResolvedName = ""
+		}
+	}
+}
+
+// worker runs the lookups with the limited rate
+func (r *resolver) worker(stop chan interface{}) {
+	ticker := time.NewTicker(time.Second / time.Duration(r.rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-r.stop:
+			return
+		case req := <-r.queue:
+			r.lookupNow(req)
+		}
+		select {
+		case <-stop:
+			return
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SetResolver sets a Resolver for the addresses of the nodes, which is called when a node is seen
+// the first time or its address changes. It has to be set before the collector is started,
+// a previous Resolver is replaced and its worker stopped.
+func (coll *Collector) SetResolver(resolve Resolver) {
+	if coll.resolver != nil {
+		close(coll.resolver.stop)
+	}
+	coll.resolver = newResolver(resolve, coll.nodes, coll.config.ResolverRate)
+	go coll.resolver.worker(coll.stop)
+}
@@ -0,0 +1,112 @@
+package respond
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestResolver(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	nodes.AddNode(&runtime.Node{Nodeinfo: &data.Nodeinfo{NodeID: "000000000001"}})
+	nodes.AddNode(&runtime.Node{Nodeinfo: &data.Nodeinfo{NodeID: "000000000002"}})
+
+	calls := 0
+	r := newResolver(func(addr net.IP) (string, bool) {
+		calls++
+		if addr.Equal(net.ParseIP("fe80::1")) {
+			return "node1.example.org", true
+		}
+		return "", false
+	}, nodes, 0)
+
+	r.lookupNow(resolveRequest{nodeID: "000000000001", addr: net.ParseIP("fe80::1")})
+	assert.Equal("node1.example.org", nodes.List["000000000001"].ResolvedName)
+	assert.Equal(1, calls)
+
+	// cached
+	r.lookup("000000000002", net.ParseIP("fe80::1"))
+	assert.Equal("node1.example.org", nodes.List["000000000002"].ResolvedName)
+	assert.Equal(1, calls)
+
+	// failed lookups are cached shorter
+	r.lookupNow(resolveRequest{nodeID: "000000000002", addr: net.ParseIP("fe80::2")})
+	assert.Equal("", nodes.List["000000000002"].ResolvedName)
+	r.lookupNow(resolveRequest{nodeID: "000000000002", addr: net.ParseIP("fe80::2")})
+	assert.Equal(2, calls)
+	_, ok := r.cached(net.ParseIP("fe80::2"), time.Now().Add(resolverFailedTTL+time.Second))
+	assert.False(ok)
+
+	// expired
+	_, ok = r.cached(net.ParseIP("fe80::1"), time.Now().Add(resolverCacheTTL+time.Second))
+	assert.False(ok)
+
+	// not cached lookups are queued for the worker
+	r.lookup("000000000001", net.ParseIP("fe80::3"))
+	assert.Len(r.queue, 1)
+}
+
+func TestResolverCacheSize(t *testing.T) {
+	assert := assert.New(t)
+
+	r := newResolver(func(addr net.IP) (string, bool) {
+		return "", false
+	}, runtime.NewNodes(&runtime.NodesConfig{}), 0)
+
+	now := time.Now()
+	for i := 0; i < resolverCacheSize; i++ {
+		r.cache[fmt.Sprintf("expired%d", i%2)] = resolverEntry{expires: now.Add(-time.Second)}
+		r.cache[fmt.Sprintf("fresh%d", i)] = resolverEntry{expires: now.Add(time.Hour)}
+	}
+	assert.Len(r.cache, resolverCacheSize+2)
+
+	r.lookupNow(resolveRequest{addr: net.ParseIP("fe80::1")})
+	assert.Len(r.cache, resolverCacheSize)
+	assert.NotContains(r.cache, "expired0")
+	assert.NotContains(r.cache, "expired1")
+	assert.Contains(r.cache, "fe80::1")
+}
+
+func TestCollectorResolver(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	collector := NewCollector(nil, nodes, &Config{})
+	defer collector.Close()
+
+	resolved := make(chan net.IP, 2)
+	collector.SetResolver(func(addr net.IP) (string, bool) {
+		resolved <- addr
+		return "node1.example.org", true
+	})
+
+	res := &data.ResponseData{Nodeinfo: &data.Nodeinfo{NodeID: "000000000001"}}
+	collector.saveResponse(&net.UDPAddr{IP: net.ParseIP("fe80::1")}, "", 0, res)
+	assert.True(net.ParseIP("fe80::1").Equal(<-resolved))
+
+	// same address is not resolved again
+	collector.saveResponse(&net.UDPAddr{IP: net.ParseIP("fe80::1")}, "", 0, res)
+	collector.saveResponse(&net.UDPAddr{IP: net.ParseIP("fe80::2")}, "", 0, res)
+	assert.True(net.ParseIP("fe80::2").Equal(<-resolved))
+
+	// a new resolver stops the worker of the previous
+	previous := collector.resolver
+	collector.SetResolver(DNSResolver)
+	select {
+	case <-previous.stop:
+	default:
+		assert.Fail("worker of the previous resolver is not stopped")
+	}
+
+	assert.Panics(func() {
+		NewCollector(nil, nodes, &Config{Resolver: "unknown"})
+	})
+}
@@ -3,10 +3,12 @@ package respond
 import (
 	"bytes"
 	"compress/flate"
+	"compress/gzip"
 	"encoding/json"
 	"io"
 	"io/ioutil"
 	"net"
+	"time"
 
 	"github.com/bdlm/log"
 	"github.com/tidwall/gjson"
@@ -21,14 +23,22 @@ const (
 	// default udp port used by announced
 	PortDefault = 1001
 
-	// maximum receivable size
+	// default maximum receivable size
 	MaxDataGramSize = 8192
+
+	// default size of the queue of received responses
+	QueueSizeDefault = 400
+
+	// maximum size of an udp datagram
+	maxUDPSize = 65535
 )
 
 // Response of the respond request
 type Response struct {
-	Address *net.UDPAddr
-	Raw     []byte
+	Address   *net.UDPAddr
+	Interface string        // interface of the collector, on which the response was received
+	Latency   time.Duration // since the last request on the socket, 0 if unknown
+	Raw       []byte
 }
 
 func NewRespone(res *data.ResponseData, addr *net.UDPAddr) (*Response, error) {
@@ -51,15 +61,40 @@ func NewRespone(res *data.ResponseData, addr *net.UDPAddr) (*Response, error) {
 	}, err
 }
 
-func (res *Response) parse(customFields []CustomFieldConfig) (*data.ResponseData, error) {
-	// Deflate
-	deflater := flate.NewReader(bytes.NewReader(res.Raw))
-	defer deflater.Close()
+// gzipMagic are the first bytes of gzip compressed data
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns the JSON of a response, which is deflated (like by gluon), gzipped or uncompressed
+func decompress(raw []byte) ([]byte, error) {
+	// uncompressed, a deflated response could start with '{' as well
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed) {
+		return trimmed, nil
+	}
 
-	jsonData, err := ioutil.ReadAll(deflater)
+	var reader io.ReadCloser
+	if bytes.HasPrefix(raw, gzipMagic) {
+		gzipReader, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		reader = gzipReader
+	} else {
+		reader = flate.NewReader(bytes.NewReader(raw))
+	}
+	defer reader.Close()
+
+	jsonData, err := ioutil.ReadAll(reader)
 	if err != nil && err != io.ErrUnexpectedEOF {
 		return nil, err
 	}
+	return jsonData, nil
+}
+
+func (res *Response) parse(customFields []CustomFieldConfig) (*data.ResponseData, error) {
+	jsonData, err := decompress(res.Raw)
+	if err != nil {
+		return nil, err
+	}
 
 	// Unmarshal
 	rdata := &data.ResponseData{}
@@ -1,6 +1,9 @@
 package respond
 
 import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
 	"net"
 	"testing"
 
@@ -18,3 +21,32 @@ func TestNewRespone(t *testing.T) {
 	assert.Equal("[fe80::2]:8080", data.Address.String())
 	assert.Equal([]uint8{0xca, 0x2b, 0xcd, 0xc9, 0xe1, 0x2, 0x0, 0x0, 0x0, 0xff, 0xff}, data.Raw)
 }
+
+func TestDecompress(t *testing.T) {
+	assert := assert.New(t)
+
+	compressed, err := ioutil.ReadFile("testdata/nodeinfo.flated")
+	assert.NoError(err)
+	jsonData, err := decompress(compressed)
+	assert.NoError(err)
+
+	// uncompressed
+	res := &Response{Raw: append([]byte(" "), jsonData...)}
+	data, err := res.parse(nil)
+	assert.NoError(err)
+	assert.Equal("f81a67a5e9c1", data.Nodeinfo.NodeID)
+
+	// gzip
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	gzipWriter.Write(jsonData)
+	gzipWriter.Close()
+	res = &Response{Raw: buf.Bytes()}
+	data, err = res.parse(nil)
+	assert.NoError(err)
+	assert.Equal("f81a67a5e9c1", data.Nodeinfo.NodeID)
+
+	// broken gzip
+	_, err = decompress(buf.Bytes()[:5])
+	assert.Error(err)
+}
@@ -0,0 +1,38 @@
+package respond
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+)
+
+// enableOverflowCounter lets the kernel report the datagrams dropped on a full receive buffer (SO_RXQ_OVFL)
+func enableOverflowCounter(conn *net.UDPConn) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = rc.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RXQ_OVFL, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// parseOverflowCounter returns the count of datagrams dropped by the kernel since the socket was opened,
+// from the control messages of a received datagram
+func parseOverflowCounter(oob []byte) (uint32, bool) {
+	messages, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, false
+	}
+	for _, msg := range messages {
+		if msg.Header.Level == syscall.SOL_SOCKET && msg.Header.Type == syscall.SO_RXQ_OVFL && len(msg.Data) >= 4 {
+			return binary.LittleEndian.Uint32(msg.Data), true
+		}
+	}
+	return 0, false
+}
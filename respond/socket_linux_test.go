@@ -0,0 +1,49 @@
+package respond
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverflowCounter(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+	assert.NoError(enableOverflowCounter(conn))
+	assert.NoError(conn.SetReadBuffer(1024))
+
+	sender, err := net.DialUDP("udp4", nil, conn.LocalAddr().(*net.UDPAddr))
+	assert.NoError(err)
+	defer sender.Close()
+	payload := make([]byte, 1000)
+	for i := 0; i < 100; i++ {
+		sender.Write(payload)
+	}
+
+	// the count is reported by the datagrams queued after the drops
+	buf := make([]byte, 2000)
+	oob := make([]byte, 64)
+	for {
+		conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+		if _, _, _, _, err = conn.ReadMsgUDP(buf, oob); err != nil {
+			break
+		}
+	}
+	sender.Write(payload)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, oobn, _, _, err := conn.ReadMsgUDP(buf, oob)
+	assert.NoError(err)
+	count, ok := parseOverflowCounter(oob[:oobn])
+	assert.True(ok)
+	assert.NotZero(count)
+
+	_, ok = parseOverflowCounter(nil)
+	assert.False(ok)
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package respond
+
+import (
+	"errors"
+	"net"
+)
+
+// enableOverflowCounter is only supported on linux
+func enableOverflowCounter(conn *net.UDPConn) error {
+	return errors.New("not supported on this platform")
+}
+
+// parseOverflowCounter is only supported on linux
+func parseOverflowCounter(oob []byte) (uint32, bool) {
+	return 0, false
+}
@@ -0,0 +1,101 @@
+package respond
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/bdlm/log"
+	"github.com/pkg/errors"
+)
+
+// StaticNodes returns the resolved addresses of the static nodes of the config and its file
+func (c *Config) StaticNodes() ([]*net.IPAddr, error) {
+	addresses := c.StaticNodeAddresses
+	if c.StaticNodesFile != "" {
+		fromFile, err := readStaticNodesFile(c.StaticNodesFile)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses[:len(addresses):len(addresses)], fromFile...)
+	}
+
+	result := make([]*net.IPAddr, 0, len(addresses))
+	for _, address := range addresses {
+		addr, err := net.ResolveIPAddr("ip", address)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid static node %q", address)
+		}
+		if addr.Zone, err = canonicalZone(addr.Zone); err != nil {
+			return nil, errors.Wrapf(err, "invalid static node %q", address)
+		}
+		result = append(result, addr)
+	}
+	return result, nil
+}
+
+// readStaticNodesFile reads a file with an address per line, empty lines and comments (#) are skipped
+func readStaticNodesFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var addresses []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			addresses = append(addresses, line)
+		}
+	}
+	return addresses, scanner.Err()
+}
+
+// SetStaticNodes replaces the addresses of the nodes, which are requested by unicast in every round
+func (coll *Collector) SetStaticNodes(addresses []*net.IPAddr) {
+	coll.staticNodes.Store(addresses)
+}
+
+// sendStatic sends the request to every static node
+func (coll *Collector) sendStatic(req *request) {
+	addresses, _ := coll.staticNodes.Load().([]*net.IPAddr)
+	if len(addresses) == 0 {
+		return
+	}
+
+	count := 0
+	for _, addr := range addresses {
+		conn := coll.staticConnection(addr)
+		if conn == nil {
+			log.WithField("address", addr.String()).Error("unable to find connection for static node")
+			continue
+		}
+		coll.sendPacket(conn, addr.IP, req)
+		count++
+	}
+	log.WithFields(map[string]interface{}{
+		"pkg_count":   count,
+		"nodes_count": len(addresses),
+	}).Info("sending unicast pkg to static nodes")
+}
+
+// staticConnection returns the first requesting connection of the address family (and zone) of the address
+func (coll *Collector) staticConnection(addr *net.IPAddr) *multicastConn {
+	ipv4 := addr.IP.To4() != nil
+	for _, conn := range coll.sockets() {
+		if !conn.SendRequest || (conn.MulticastAddress.To4() != nil) != ipv4 {
+			continue
+		}
+		if addr.Zone != "" && conn.status.status.Interface != addr.Zone {
+			continue
+		}
+		return &conn
+	}
+	return nil
+}
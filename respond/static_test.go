@@ -0,0 +1,74 @@
+package respond
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	file, err := ioutil.TempFile("", "static-nodes")
+	assert.NoError(err)
+	defer os.Remove(file.Name())
+	file.WriteString("# routed nodes\n2001:db8::2\n\n  10.0.0.2 # legacy\n")
+	file.Close()
+
+	config := &Config{
+		StaticNodeAddresses: []string{"2001:db8::1"},
+		StaticNodesFile:     file.Name(),
+	}
+	addresses, err := config.StaticNodes()
+	assert.NoError(err)
+	assert.Len(addresses, 3)
+	assert.Equal("2001:db8::1", addresses[0].String())
+	assert.Equal("2001:db8::2", addresses[1].String())
+	assert.Equal("10.0.0.2", addresses[2].String())
+
+	// link local address with zone
+	if ifaces, err := net.Interfaces(); err == nil && len(ifaces) > 0 {
+		config = &Config{StaticNodeAddresses: []string{"fe80::1%" + ifaces[0].Name}}
+		addresses, err = config.StaticNodes()
+		assert.NoError(err)
+		assert.Equal(ifaces[0].Name, addresses[0].Zone)
+	}
+
+	// invalid
+	_, err = (&Config{StaticNodesFile: file.Name() + ".nonexisting"}).StaticNodes()
+	assert.Error(err)
+	_, err = (&Config{StaticNodeAddresses: []string{"fe80::1%nonexisting-iface0"}}).StaticNodes()
+	assert.Error(err)
+}
+
+func TestStaticConnection(t *testing.T) {
+	assert := assert.New(t)
+
+	ipv6 := net.ParseIP(MulticastAddressDefault)
+	collector := &Collector{
+		connections: []multicastConn{
+			testConnection("bat0", ipv6, false),
+			testConnection("bat0", ipv6, true),
+			testConnection("bat1", ipv6, true),
+			testConnection("bat1", net.IPv4bcast, true),
+		},
+	}
+
+	conn := collector.staticConnection(&net.IPAddr{IP: net.ParseIP("2001:db8::1")})
+	if assert.NotNil(conn) {
+		assert.Equal("bat0", conn.status.status.Interface)
+		assert.True(conn.SendRequest)
+	}
+	conn = collector.staticConnection(&net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "bat1"})
+	if assert.NotNil(conn) {
+		assert.Equal("bat1", conn.status.status.Interface)
+	}
+	conn = collector.staticConnection(&net.IPAddr{IP: net.ParseIP("10.0.0.1")})
+	if assert.NotNil(conn) {
+		assert.True(net.IPv4bcast.Equal(conn.MulticastAddress))
+	}
+	assert.Nil(collector.staticConnection(&net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "bat2"}))
+}
@@ -0,0 +1,107 @@
+package respond
+
+import (
+	"sync"
+	"time"
+)
+
+// InterfaceStatus is the state of a socket of the collector.
+// Yanic does not join the multicast group, it sends the requests to the group
+// and receives the answers as unicast - so a socket works, if it is bound and
+// the requests to the group could be sent.
+type InterfaceStatus struct {
+	Interface          string     `json:"interface"`
+	LocalAddress       string     `json:"local_address"`
+	MulticastAddress   string     `json:"multicast_address"`
+	SendRequest        bool       `json:"send_request"`
+	Bound              time.Time  `json:"bound"`
+	LastMulticast      *time.Time `json:"last_multicast,omitempty"` // last successfully sent request to the group
+	LastMulticastError string     `json:"last_multicast_error,omitempty"`
+	LastResponse       *time.Time `json:"last_response,omitempty"`
+	Responses          uint64     `json:"responses"`
+	Down               *time.Time `json:"down,omitempty"` // the socket failed, until it is re-bound
+	Rebinds            uint64     `json:"rebinds"`
+}
+
+// interfaceStatus is the status of a socket, updated by sender and receiver
+type interfaceStatus struct {
+	status      InterfaceStatus
+	lastRequest time.Time // last sent request (multicast or unicast) on the socket
+	sync.Mutex
+}
+
+func (s *interfaceStatus) requestSent(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastRequest = t
+}
+
+func (s *interfaceStatus) lastRequestTime() time.Time {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.lastRequest
+}
+
+func (s *interfaceStatus) multicastSent(err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err != nil {
+		s.status.LastMulticastError = err.Error()
+		return
+	}
+	now := time.Now()
+	s.status.LastMulticast = &now
+	s.status.LastMulticastError = ""
+}
+
+func (s *interfaceStatus) received(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.status.LastResponse = &t
+	s.status.Responses++
+}
+
+// failed records the failure of the socket, until it is re-bound
+func (s *interfaceStatus) failed(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.status.Down = &t
+}
+
+// rebound records the new socket and returns how long the socket was down
+func (s *interfaceStatus) rebound(localAddress string, t time.Time) time.Duration {
+	s.Lock()
+	defer s.Unlock()
+
+	var down time.Duration
+	if s.status.Down != nil {
+		down = t.Sub(*s.status.Down)
+	}
+	s.status.Down = nil
+	s.status.LocalAddress = localAddress
+	s.status.Bound = t
+	s.status.Rebinds++
+	return down
+}
+
+func (s *interfaceStatus) get() InterfaceStatus {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.status
+}
+
+// InterfaceStatus returns the status of all sockets of the collector
+func (coll *Collector) InterfaceStatus() []InterfaceStatus {
+	connections := coll.sockets()
+	result := make([]InterfaceStatus, 0, len(connections))
+	for _, conn := range connections {
+		result = append(result, conn.status.get())
+	}
+	return result
+}
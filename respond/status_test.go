@@ -0,0 +1,37 @@
+package respond
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterfaceStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	status := &interfaceStatus{status: InterfaceStatus{Interface: "br-ffhb"}}
+	collector := &Collector{
+		connections: []multicastConn{{status: status}},
+	}
+
+	list := collector.InterfaceStatus()
+	assert.Len(list, 1)
+	assert.Equal("br-ffhb", list[0].Interface)
+	assert.Nil(list[0].LastMulticast)
+	assert.Nil(list[0].LastResponse)
+
+	status.multicastSent(errors.New("network is unreachable"))
+	list = collector.InterfaceStatus()
+	assert.Nil(list[0].LastMulticast)
+	assert.Equal("network is unreachable", list[0].LastMulticastError)
+
+	status.multicastSent(nil)
+	status.received(time.Now())
+	list = collector.InterfaceStatus()
+	assert.NotNil(list[0].LastMulticast)
+	assert.Equal("", list[0].LastMulticastError)
+	assert.NotNil(list[0].LastResponse)
+	assert.EqualValues(1, list[0].Responses)
+}
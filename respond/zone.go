@@ -0,0 +1,64 @@
+package respond
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// canonicalZone resolves an interface name or index to the interface name
+func canonicalZone(zone string) (string, error) {
+	if zone == "" {
+		return "", nil
+	}
+	if index, err := strconv.Atoi(zone); err == nil {
+		iface, err := net.InterfaceByIndex(index)
+		if err != nil {
+			return "", fmt.Errorf("invalid interface index %q: %s", zone, err)
+		}
+		return iface.Name, nil
+	}
+	iface, err := net.InterfaceByName(zone)
+	if err != nil {
+		return "", fmt.Errorf("invalid interface %q: %s", zone, err)
+	}
+	return iface.Name, nil
+}
+
+// resolveZones returns the canonical interface name and the multicast address of an interface config.
+// The interface and the multicast address (e.g. "ff05::2:1001%br-ffhb") could contain a zone,
+// given as interface name or index; if both contain one, they have to match.
+// An IPv4 address could be a multicast or a broadcast address.
+func resolveZones(ifname, multicastAddress string) (string, net.IP, error) {
+	if i := strings.LastIndex(ifname, "%"); i >= 0 {
+		ifname = ifname[i+1:]
+	}
+	zone, err := canonicalZone(ifname)
+	if err != nil {
+		return "", nil, err
+	}
+
+	address := multicastAddress
+	if i := strings.LastIndex(multicastAddress, "%"); i >= 0 {
+		address = multicastAddress[:i]
+		addressZone, err := canonicalZone(multicastAddress[i+1:])
+		if err != nil {
+			return "", nil, fmt.Errorf("multicast address %q: %s", multicastAddress, err)
+		}
+		if zone == "" {
+			zone = addressZone
+		} else if zone != addressZone {
+			return "", nil, fmt.Errorf("zone of multicast address %q does not match interface %q", multicastAddress, zone)
+		}
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil || (!ip.IsMulticast() && ip.To4() == nil) {
+		return "", nil, fmt.Errorf("invalid multicast address %q", multicastAddress)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return zone, ip, nil
+}
@@ -0,0 +1,78 @@
+package respond
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveZones(t *testing.T) {
+	assert := assert.New(t)
+
+	ifaces, err := net.Interfaces()
+	if err != nil || len(ifaces) == 0 {
+		t.Skip("no network interfaces available")
+	}
+	name := ifaces[0].Name
+	index := strconv.Itoa(ifaces[0].Index)
+	multicast := net.ParseIP(MulticastAddressDefault)
+
+	// by name
+	zone, ip, err := resolveZones(name, MulticastAddressDefault)
+	assert.NoError(err)
+	assert.Equal(name, zone)
+	assert.True(multicast.Equal(ip))
+
+	// by index
+	zone, _, err = resolveZones(index, MulticastAddressDefault)
+	assert.NoError(err)
+	assert.Equal(name, zone)
+
+	// interface name with zone
+	zone, _, err = resolveZones("%"+name, MulticastAddressDefault)
+	assert.NoError(err)
+	assert.Equal(name, zone)
+
+	// zone in the multicast address only
+	zone, ip, err = resolveZones("", MulticastAddressDefault+"%"+index)
+	assert.NoError(err)
+	assert.Equal(name, zone)
+	assert.True(multicast.Equal(ip))
+
+	// same zone given by name and index
+	zone, _, err = resolveZones(name, MulticastAddressDefault+"%"+index)
+	assert.NoError(err)
+	assert.Equal(name, zone)
+
+	// without any zone
+	zone, _, err = resolveZones("", MulticastAddressDefault)
+	assert.NoError(err)
+	assert.Equal("", zone)
+
+	// mismatch
+	if len(ifaces) > 1 {
+		_, _, err = resolveZones(name, MulticastAddressDefault+"%"+ifaces[1].Name)
+		assert.Error(err)
+		assert.Contains(err.Error(), "does not match")
+	}
+
+	// IPv4 multicast and broadcast
+	_, ip, err = resolveZones(name, "224.0.0.251")
+	assert.NoError(err)
+	assert.Len(ip, net.IPv4len)
+	_, ip, err = resolveZones(name, "255.255.255.255")
+	assert.NoError(err)
+	assert.True(net.IPv4bcast.Equal(ip))
+
+	// invalid
+	_, _, err = resolveZones("nonexisting-iface0", MulticastAddressDefault)
+	assert.Error(err)
+	_, _, err = resolveZones(name, MulticastAddressDefault+"%nonexisting-iface0")
+	assert.Error(err)
+	_, _, err = resolveZones(name, "fe80::1")
+	assert.Error(err)
+	_, _, err = resolveZones(name, "no-ip")
+	assert.Error(err)
+}
@@ -0,0 +1,188 @@
+package runtime
+
+import (
+	"net"
+	"sort"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/lib/jsontime"
+)
+
+const (
+	ADDRESS_CONFLICT_FIRST_SEEN = "first_seen" // an address keeps the node which claimed it first (default)
+	ADDRESS_CONFLICT_LAST_SEEN  = "last_seen"  // an address moves to the node which claimed it last
+)
+
+// AddressConflict is an address claimed by more than one node
+type AddressConflict struct {
+	Address string   `json:"address"`
+	NodeID  string   `json:"node_id"` // the node the address is resolved to
+	Claims  []string `json:"claims"`  // all nodes claiming the address (sorted)
+}
+
+// NodeIDCandidate is a device claiming the node ID of a node, identified by its primary MAC address
+type NodeIDCandidate struct {
+	Mac      string        `json:"mac"`
+	Address  string        `json:"address,omitempty"`
+	Hostname string        `json:"hostname,omitempty"`
+	Lastseen jsontime.Time `json:"lastseen"`
+}
+
+// newNodeIDCandidate returns the candidate of a nodeinfo, nil without a MAC address
+func newNodeIDCandidate(nodeinfo *data.Nodeinfo, addr *net.UDPAddr, seen jsontime.Time) *NodeIDCandidate {
+	if nodeinfo == nil || nodeinfo.Network.Mac == "" {
+		return nil
+	}
+	candidate := &NodeIDCandidate{
+		Mac:      nodeinfo.Network.Mac,
+		Hostname: nodeinfo.Hostname,
+		Lastseen: seen,
+	}
+	if addr != nil {
+		candidate.Address = addr.IP.String()
+	}
+	return candidate
+}
+
+// updateNodeIDConflict records the devices claiming the node ID, which were seen after since.
+// It returns whether a new device claims the node ID.
+func (node *Node) updateNodeIDConflict(previous, current *NodeIDCandidate, since jsontime.Time) bool {
+	if current == nil {
+		return false
+	}
+	candidates := node.Conflict
+	if len(candidates) == 0 {
+		if previous == nil || previous.Mac == current.Mac {
+			return false
+		}
+		candidates = []NodeIDCandidate{*previous}
+	}
+
+	result := make([]NodeIDCandidate, 0, len(candidates)+1)
+	known := false
+	for _, candidate := range candidates {
+		if candidate.Mac == current.Mac {
+			known = true
+		} else if !candidate.Lastseen.Before(since) {
+			result = append(result, candidate)
+		}
+	}
+	result = append(result, *current)
+	if len(result) < 2 {
+		// the other devices are gone
+		node.Conflict = nil
+		return false
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Mac < result[j].Mac
+	})
+	node.Conflict = result
+	return !known
+}
+
+// lastSeenWins returns whether conflicting addresses move to the latest claiming node
+func (nodes *Nodes) lastSeenWins() bool {
+	return nodes.config != nil && nodes.config.AddressConflict == ADDRESS_CONFLICT_LAST_SEEN
+}
+
+// claimAddress maps the address to the node, or records a conflict if it is claimed by another node
+func (nodes *Nodes) claimAddress(addr, nodeID string, warning bool) {
+	oldNodeID := nodes.ifaceToNodeID[addr]
+	if oldNodeID == "" || oldNodeID == nodeID {
+		nodes.ifaceToNodeID[addr] = nodeID
+		return
+	}
+
+	if nodes.addressClaims == nil {
+		nodes.addressClaims = make(map[string]map[string]struct{})
+	}
+	claims, ok := nodes.addressClaims[addr]
+	if !ok {
+		claims = map[string]struct{}{oldNodeID: {}}
+		nodes.addressClaims[addr] = claims
+	}
+	_, known := claims[nodeID]
+	claims[nodeID] = struct{}{}
+
+	if nodes.lastSeenWins() {
+		nodes.ifaceToNodeID[addr] = nodeID
+	}
+	if !known && warning {
+		log.WithFields(map[string]interface{}{
+			"address": addr,
+			"node_id": nodeID,
+			"owner":   nodes.ifaceToNodeID[addr],
+		}).Warn("address is claimed by more than one node, check for a misconfigured or cloned node")
+	}
+}
+
+// releaseAddresses removes the addresses of a pruned node, a conflicting address moves to another claiming node
+func (nodes *Nodes) releaseAddresses(nodeID string) {
+	for addr, claims := range nodes.addressClaims {
+		delete(claims, nodeID)
+		if nodes.ifaceToNodeID[addr] == nodeID {
+			nodes.ifaceToNodeID[addr] = firstClaim(claims)
+		}
+		if len(claims) < 2 {
+			delete(nodes.addressClaims, addr)
+		}
+	}
+	for addr, owner := range nodes.ifaceToNodeID {
+		if owner == nodeID {
+			delete(nodes.ifaceToNodeID, addr)
+		}
+	}
+	delete(nodes.nodeAddresses, nodeID)
+}
+
+// releaseAddress removes an address, which a node does not report anymore, a conflicting address moves to another claiming node
+func (nodes *Nodes) releaseAddress(addr, nodeID string) {
+	claims := nodes.addressClaims[addr]
+	delete(claims, nodeID)
+	if nodes.ifaceToNodeID[addr] == nodeID {
+		if owner := firstClaim(claims); owner != "" {
+			nodes.ifaceToNodeID[addr] = owner
+		} else {
+			delete(nodes.ifaceToNodeID, addr)
+		}
+	}
+	if len(claims) < 2 {
+		delete(nodes.addressClaims, addr)
+	}
+}
+
+// firstClaim returns the smallest node id for a deterministic choice
+func firstClaim(claims map[string]struct{}) string {
+	first := ""
+	for nodeID := range claims {
+		if first == "" || nodeID < first {
+			first = nodeID
+		}
+	}
+	return first
+}
+
+// AddressConflicts returns all addresses claimed by more than one node (sorted by address)
+func (nodes *Nodes) AddressConflicts() []AddressConflict {
+	nodes.RLock()
+	defer nodes.RUnlock()
+
+	result := make([]AddressConflict, 0, len(nodes.addressClaims))
+	for addr, claims := range nodes.addressClaims {
+		conflict := AddressConflict{
+			Address: addr,
+			NodeID:  nodes.ifaceToNodeID[addr],
+		}
+		for nodeID := range claims {
+			conflict.Claims = append(conflict.Claims, nodeID)
+		}
+		sort.Strings(conflict.Claims)
+		result = append(result, conflict)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+	return result
+}
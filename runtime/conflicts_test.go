@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+)
+
+func testNodeinfo(nodeID, mac string) *data.ResponseData {
+	return &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:  nodeID,
+			Network: data.Network{Mac: mac},
+		},
+	}
+}
+
+func TestAddressConflicts(t *testing.T) {
+	assert := assert.New(t)
+
+	config := &NodesConfig{}
+	config.OfflineAfter.Duration = time.Minute * 10
+	nodes := NewNodes(config)
+
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:01"))
+	nodes.Update("000000000002", testNodeinfo("000000000002", "de:ad:be:ef:00:01"))
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:01"))
+	nodes.Update("000000000002", testNodeinfo("000000000002", "de:ad:be:ef:00:01"))
+
+	// first seen wins
+	assert.Equal("000000000001", nodes.GetNodeIDbyAddress("de:ad:be:ef:00:01"))
+	assert.Equal([]AddressConflict{{
+		Address: "de:ad:be:ef:00:01",
+		NodeID:  "000000000001",
+		Claims:  []string{"000000000001", "000000000002"},
+	}}, nodes.AddressConflicts())
+
+	// release on prune
+	node := nodes.List["000000000001"]
+	node.Lastseen = node.Lastseen.Add(-8 * time.Hour * 24)
+	nodes.expire()
+	assert.Equal("000000000002", nodes.GetNodeIDbyAddress("de:ad:be:ef:00:01"))
+	assert.Len(nodes.AddressConflicts(), 0)
+
+	node = nodes.List["000000000002"]
+	node.Lastseen = node.Lastseen.Add(-8 * time.Hour * 24)
+	nodes.expire()
+	assert.Equal("", nodes.GetNodeIDbyAddress("de:ad:be:ef:00:01"))
+}
+
+func TestAddressConflictsLastSeen(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := NewNodes(&NodesConfig{AddressConflict: ADDRESS_CONFLICT_LAST_SEEN})
+
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:01"))
+	nodes.Update("000000000002", testNodeinfo("000000000002", "de:ad:be:ef:00:01"))
+	assert.Equal("000000000002", nodes.GetNodeIDbyAddress("de:ad:be:ef:00:01"))
+
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:01"))
+	assert.Equal("000000000001", nodes.GetNodeIDbyAddress("de:ad:be:ef:00:01"))
+	assert.Len(nodes.AddressConflicts(), 1)
+}
+
+func TestNodeIDConflict(t *testing.T) {
+	assert := assert.New(t)
+
+	config := &NodesConfig{}
+	config.OfflineAfter.Duration = time.Minute * 10
+	nodes := NewNodes(config)
+
+	// the same device
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:01"))
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:01"))
+	assert.Nil(nodes.List["000000000001"].Conflict)
+
+	// a cloned configuration
+	nodes.UpdateFunc("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:02"), func(node *Node) {
+		node.Address = &net.UDPAddr{IP: net.ParseIP("fe80::2")}
+	})
+	conflict := nodes.List["000000000001"].Conflict
+	assert.Len(conflict, 2)
+	assert.Equal("de:ad:be:ef:00:01", conflict[0].Mac)
+	assert.Equal("de:ad:be:ef:00:02", conflict[1].Mac)
+	assert.Equal("fe80::2", conflict[1].Address)
+
+	// both are kept, while they answer
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:01"))
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:03"))
+	assert.Len(nodes.List["000000000001"].Conflict, 3)
+	// a nodeinfo of the known node is not a new claim
+	node := nodes.List["000000000001"]
+	nodes.Update("000000000001", &data.ResponseData{Nodeinfo: node.Nodeinfo})
+	assert.Len(nodes.List["000000000001"].Conflict, 3)
+
+	// the conflict ends, when the other devices are gone
+	for i := range node.Conflict {
+		node.Conflict[i].Lastseen = node.Conflict[i].Lastseen.Add(-time.Minute * 11)
+	}
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:03"))
+	assert.Nil(nodes.List["000000000001"].Conflict)
+
+	// a device replaced after the offline period
+	node.Lastseen = node.Lastseen.Add(-time.Minute * 11)
+	nodes.Update("000000000001", testNodeinfo("000000000001", "de:ad:be:ef:00:04"))
+	assert.Nil(nodes.List["000000000001"].Conflict)
+}
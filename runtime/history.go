@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/lib/jsontime"
+)
+
+// HistorySample is a compact sample of the statistics of a node
+type HistorySample struct {
+	Time        jsontime.Time `json:"time"`
+	Clients     uint32        `json:"clients"`
+	Unique      *uint32       `json:"clients_unique,omitempty"` // if the clients are deduplicated
+	LoadAverage float64       `json:"loadavg"`
+	RootFsUsage float64       `json:"rootfs_usage"`
+	Uptime      float64       `json:"uptime"`
+	TrafficRx   float64       `json:"traffic_rx_bytes"`
+	TrafficTx   float64       `json:"traffic_tx_bytes"`
+	SignalAvg   *float64      `json:"clients_signal_avg,omitempty"`
+	SignalMin   *float64      `json:"clients_signal_min,omitempty"`
+}
+
+// NewHistorySample creates a sample of the given statistics
+func NewHistorySample(t jsontime.Time, stats *data.Statistics) HistorySample {
+	sample := HistorySample{
+		Time:        t,
+		Clients:     stats.Clients.Total,
+		LoadAverage: stats.LoadAverage,
+		RootFsUsage: stats.RootFsUsage,
+		Uptime:      stats.Uptime,
+	}
+	if traffic := stats.Traffic.Rx; traffic != nil {
+		sample.TrafficRx = traffic.Bytes
+	}
+	if traffic := stats.Traffic.Tx; traffic != nil {
+		sample.TrafficTx = traffic.Bytes
+	}
+	if unique := stats.Clients.Unique; unique != nil {
+		count := *unique
+		sample.Unique = &count
+	}
+	if signal := stats.Clients.Signal; signal != nil {
+		avg, min := signal.Avg, signal.Min
+		sample.SignalAvg = &avg
+		sample.SignalMin = &min
+	}
+	return sample
+}
+
+// adds a sample to the history of the node and drops the oldest samples above the given depth
+func (node *Node) addHistory(sample HistorySample, depth int) {
+	node.History = append(node.History, sample)
+	if over := len(node.History) - depth; over > 0 {
+		// copy to release the memory of the dropped samples
+		node.History = append([]HistorySample(nil), node.History[over:]...)
+	}
+}
+
+// History returns a copy of the buffered statistics samples of a node (oldest first)
+func (nodes *Nodes) History(nodeID string) ([]HistorySample, bool) {
+	nodes.RLock()
+	defer nodes.RUnlock()
+
+	node, ok := nodes.List[nodeID]
+	if !ok {
+		return nil, false
+	}
+	return append([]HistorySample{}, node.History...), true
+}
@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/lib/duration"
+	"github.com/FreifunkBremen/yanic/lib/jsontime"
+)
+
+func TestHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	config := &NodesConfig{
+		HistoryDepth: 2,
+		OfflineAfter: duration.Duration{Duration: time.Minute},
+	}
+	nodes := NewNodes(config)
+
+	_, ok := nodes.History("abcdef012345")
+	assert.False(ok)
+
+	for _, clients := range []uint32{1, 2, 3} {
+		nodes.Update("abcdef012345", &data.ResponseData{
+			Statistics: &data.Statistics{
+				Clients: data.Clients{Total: clients},
+				Traffic: struct {
+					Tx      *data.Traffic `json:"tx"`
+					Rx      *data.Traffic `json:"rx"`
+					Forward *data.Traffic `json:"forward"`
+					MgmtTx  *data.Traffic `json:"mgmt_tx"`
+					MgmtRx  *data.Traffic `json:"mgmt_rx"`
+				}{
+					Rx: &data.Traffic{Bytes: 42},
+				},
+			},
+		})
+	}
+	// response without statistics
+	nodes.Update("abcdef012345", &data.ResponseData{})
+
+	history, ok := nodes.History("abcdef012345")
+	assert.True(ok)
+	assert.Len(history, 2)
+	assert.EqualValues(2, history[0].Clients)
+	assert.EqualValues(3, history[1].Clients)
+	assert.EqualValues(42, history[1].TrafficRx)
+	assert.EqualValues(0, history[1].TrafficTx)
+	assert.Nil(history[1].SignalAvg)
+
+	// history is dropped for offline nodes
+	node := nodes.List["abcdef012345"]
+	node.Lastseen = node.Lastseen.Add(-config.OfflineAfter.Duration - 1)
+	nodes.expire()
+	assert.False(node.Online)
+	history, ok = nodes.History("abcdef012345")
+	assert.True(ok)
+	assert.Len(history, 0)
+
+	// disabled
+	nodes = NewNodes(&NodesConfig{})
+	nodes.Update("abcdef012345", &data.ResponseData{
+		Statistics: &data.Statistics{},
+	})
+	history, _ = nodes.History("abcdef012345")
+	assert.Len(history, 0)
+}
+
+func TestHistorySampleSignal(t *testing.T) {
+	assert := assert.New(t)
+
+	stats := &data.Statistics{
+		Clients: data.Clients{
+			Signal: &data.ClientSignal{Avg: -65, Min: -85},
+		},
+	}
+	sample := NewHistorySample(jsontime.Now(), stats)
+	assert.EqualValues(-65, *sample.SignalAvg)
+	assert.EqualValues(-85, *sample.SignalMin)
+}
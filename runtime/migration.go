@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// StateVersion is the version of the state file written by this version of yanic
+const StateVersion = 1
+
+// stateFile is the content of the state file
+type stateFile struct {
+	Version int              `json:"version"`
+	List    map[string]*Node `json:"nodes"`
+}
+
+// stateMigrations convert a state file of the version by its index to the next version.
+// They work on the generic JSON document, as the old structure could not be decoded anymore.
+var stateMigrations = []func(state map[string]interface{}){
+	// 0: without a version, the online state of the nodes was kept in their flags
+	func(state map[string]interface{}) {
+		list, _ := state["nodes"].(map[string]interface{})
+		for _, value := range list {
+			node, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if flags, ok := node["flags"].(map[string]interface{}); ok {
+				if _, ok := node["online"]; !ok {
+					node["online"] = flags["online"] == true
+				}
+				delete(node, "flags")
+			}
+		}
+	},
+}
+
+// migrateState converts the state file to the current version and returns the version it was written with
+func migrateState(content []byte) ([]byte, int, error) {
+	var state map[string]interface{}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, 0, err
+	}
+	version := 0
+	if value, ok := state["version"]; ok {
+		number, ok := value.(float64)
+		if !ok || number < 0 || number != float64(int(number)) {
+			return nil, 0, fmt.Errorf("invalid version of the state file: %v", value)
+		}
+		version = int(number)
+	}
+	if version >= StateVersion {
+		// current, or of a newer version of yanic: loaded as far as known
+		return content, version, nil
+	}
+
+	for v := version; v < StateVersion; v++ {
+		stateMigrations[v](state)
+	}
+	state["version"] = StateVersion
+
+	content, err := json.Marshal(state)
+	return content, version, err
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateState(t *testing.T) {
+	assert := assert.New(t)
+
+	content, err := ioutil.ReadFile("testdata/nodes-v0.json")
+	assert.NoError(err)
+	content, version, err := migrateState(content)
+	assert.NoError(err)
+	assert.Equal(0, version)
+
+	var state map[string]interface{}
+	assert.NoError(json.Unmarshal(content, &state))
+	assert.EqualValues(StateVersion, state["version"])
+	node := state["nodes"].(map[string]interface{})["f4f26dd7a30a"].(map[string]interface{})
+	assert.Equal(true, node["online"])
+	assert.NotContains(node, "flags")
+
+	// the current version is kept as it is
+	current := []byte(`{"version":1,"nodes":{}}`)
+	content, version, err = migrateState(current)
+	assert.NoError(err)
+	assert.Equal(1, version)
+	assert.Equal(current, content)
+
+	// a newer version is loaded as far as known
+	_, version, err = migrateState([]byte(`{"version":42,"nodes":{}}`))
+	assert.NoError(err)
+	assert.Equal(42, version)
+
+	for _, invalid := range []string{`{"version":"1"}`, `{"version":-1}`, `{"version":1.5}`, `[]`} {
+		_, _, err = migrateState([]byte(invalid))
+		assert.Error(err, invalid)
+	}
+}
+
+func TestLoadMigratedState(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := NewNodes(&NodesConfig{StatePath: "testdata/nodes-v0.json"})
+	assert.Len(nodes.List, 2)
+	assert.True(nodes.List["f4f26dd7a30a"].Online)
+	assert.False(nodes.List["f4f26dd7a30b"].Online)
+	assert.Equal("node1", nodes.List["f4f26dd7a30a"].Nodeinfo.Hostname)
+
+	// saved with the current version
+	tmpfile, _ := ioutil.TempFile("/tmp", "nodes")
+	tmpfile.Close()
+	defer os.Remove(tmpfile.Name())
+	nodes.config = &NodesConfig{StatePath: tmpfile.Name()}
+	nodes.save()
+
+	content, err := ioutil.ReadFile(tmpfile.Name())
+	assert.NoError(err)
+	var state stateFile
+	assert.NoError(json.Unmarshal(content, &state))
+	assert.Equal(StateVersion, state.Version)
+	assert.Len(state.List, 2)
+}
@@ -10,6 +10,7 @@ import (
 // Node struct
 type Node struct {
 	Address      *net.UDPAddr           `json:"-"` // the last known address
+	Interface    string                 `json:"-"` // interface of the collector, on which the last response was received
 	Firstseen    jsontime.Time          `json:"firstseen"`
 	Lastseen     jsontime.Time          `json:"lastseen"`
 	Online       bool                   `json:"online"`
@@ -17,17 +18,40 @@ type Node struct {
 	Nodeinfo     *data.Nodeinfo         `json:"nodeinfo"`
 	Neighbours   *data.Neighbours       `json:"-"`
 	CustomFields map[string]interface{} `json:"custom_fields"`
+	History      []HistorySample        `json:"-"`                         // recent statistics, only kept for online nodes
+	ResolvedName string                 `json:"resolved_name,omitempty"`   // name of the address, given by an external resolver
+	Origin       string                 `json:"origin,omitempty"`          // source of a merged node (e.g. another map), empty if collected by this instance
+	LastReboot   *jsontime.Time         `json:"last_reboot,omitempty"`     // time of the last reboot, detected by a decreased uptime
+	Conflict     []NodeIDCandidate      `json:"nodeid_conflict,omitempty"` // devices claiming the node ID (e.g. cloned), empty without a conflict
+	Latency      float64                `json:"latency,omitempty"`         // seconds between the last request and the response, 0 if unknown
+
+	rebootDetected jsontime.Time // time of the response, which showed the last reboot
 }
 
+const (
+	LINK_PROTOCOL_BATADV = "batadv"
+	LINK_PROTOCOL_BABEL  = "babel"
+)
+
+// types of the links by the mesh interfaces of their addresses, see Link.Type
+const (
+	LINK_TYPE_VPN      = "vpn" // over a tunnel interface, e.g. the mesh-vpn uplink of a node
+	LINK_TYPE_WIRELESS = "wireless"
+	LINK_TYPE_WIRED    = "wired" // over another mesh interface, e.g. mesh on lan or wan
+)
+
 // Link represents a link between two nodes
 type Link struct {
-	SourceID       string
-	SourceHostname string
-	SourceAddress  string
-	TargetID       string
-	TargetAddress  string
-	TargetHostname string
-	TQ             float32
+	Protocol       string  `json:"protocol"` // routing protocol of the link, e.g. LINK_PROTOCOL_BATADV
+	SourceID       string  `json:"source_id"`
+	SourceHostname string  `json:"source_hostname,omitempty"`
+	SourceAddress  string  `json:"source_addr"`
+	TargetID       string  `json:"target_id"`
+	TargetAddress  string  `json:"target_addr"`
+	TargetHostname string  `json:"target_hostname,omitempty"`
+	TQ             float32 `json:"tq"`
+	Signal         int     `json:"signal,omitempty"` // signal in dBm of a batman-adv link over wifi (by the wifi neighbours of the interface), 0 if unknown
+	Type           string  `json:"type,omitempty"`   // e.g. LINK_TYPE_VPN, empty if the interfaces of both addresses are unknown
 }
 
 // IsGateway returns whether the node is a gateway
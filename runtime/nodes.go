@@ -2,7 +2,13 @@ package runtime
 
 import (
 	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,10 +20,20 @@ import (
 
 // Nodes struct: cache DB of Node's structs
 type Nodes struct {
-	List          map[string]*Node  `json:"nodes"` // the current nodemap, indexed by node ID
-	ifaceToNodeID map[string]string // mapping from MAC address to NodeID
+	List          map[string]*Node               `json:"nodes"` // the current nodemap, indexed by node ID
+	ifaceToNodeID map[string]string              // mapping from MAC address to NodeID
+	nodeAddresses map[string][]string            // addresses read of each NodeID (see readIfaces)
+	addressClaims map[string]map[string]struct{} // NodeIDs of addresses claimed by more than one node
 	config        *NodesConfig
 	sync.RWMutex
+
+	subscriptions   map[*Subscription]struct{}
+	stateChanged    []func(StateChange) // registered by OnStateChange
+	rebooted        []func(Reboot)      // registered by OnReboot
+	subscriptionsMu sync.Mutex
+
+	stop    chan struct{}
+	stopped chan struct{}
 }
 
 // NewNodes create Nodes structs
@@ -37,9 +53,23 @@ func NewNodes(config *NodesConfig) *Nodes {
 
 // Start all services to manage Nodes
 func (nodes *Nodes) Start() {
+	nodes.stop = make(chan struct{})
+	nodes.stopped = make(chan struct{})
 	go nodes.worker()
 }
 
+// Close stops the services and saves the state a last time
+func (nodes *Nodes) Close() {
+	if nodes.stop == nil {
+		return
+	}
+	close(nodes.stop)
+	<-nodes.stopped
+	nodes.stop = nil
+
+	nodes.save()
+}
+
 func (nodes *Nodes) AddNode(node *Node) {
 	nodeinfo := node.Nodeinfo
 	if nodeinfo == nil || nodeinfo.NodeID == "" {
@@ -48,44 +78,172 @@ func (nodes *Nodes) AddNode(node *Node) {
 	nodes.Lock()
 	defer nodes.Unlock()
 	nodes.List[nodeinfo.NodeID] = node
-	nodes.readIfaces(nodeinfo, false)
+	nodes.readIfaces(nodeinfo, node.Neighbours, false)
 }
 
 // Update a Node
 func (nodes *Nodes) Update(nodeID string, res *data.ResponseData) *Node {
-	now := jsontime.Now()
+	node, _ := nodes.update(nodeID, res, jsontime.Time{}, nil)
+	return node
+}
 
+// UpdateFunc updates a node like Update, f is called under the lock with the node before the response is applied
+// (e.g. to complete the response by the known node or to set the address of the node).
+// It returns a shallow copy of the updated node without its history, which must not be modified.
+func (nodes *Nodes) UpdateFunc(nodeID string, res *data.ResponseData, f func(node *Node)) Node {
+	_, nodeCopy := nodes.update(nodeID, res, jsontime.Time{}, f)
+	return nodeCopy
+}
+
+// Merge updates a node by the node of another source (e.g. another instance of yanic), if it is online
+// and was seen after the known node. It keeps the times the node was seen by the other source, so the node
+// does not bounce between instances merging each other. It returns whether the node was updated.
+func (nodes *Nodes) Merge(nodeID string, remote *Node) bool {
+	if !remote.Online || remote.Nodeinfo == nil {
+		return false
+	}
+	nodes.RLock()
+	known := nodes.List[nodeID]
+	newer := known == nil || remote.Lastseen.After(known.Lastseen)
+	nodes.RUnlock()
+	if !newer {
+		return false
+	}
+
+	res := &data.ResponseData{
+		Nodeinfo:     remote.Nodeinfo,
+		Statistics:   remote.Statistics,
+		Neighbours:   remote.Neighbours,
+		CustomFields: remote.CustomFields,
+	}
+	nodes.update(nodeID, res, remote.Lastseen, func(node *Node) {
+		if remote.Firstseen.Before(node.Firstseen) {
+			node.Firstseen = remote.Firstseen
+		}
+		node.Origin = remote.Origin
+	})
+	return true
+}
+
+// update applies the response to the node, seen is the time of the response (zero for now)
+func (nodes *Nodes) update(nodeID string, res *data.ResponseData, seen jsontime.Time, f func(node *Node)) (*Node, Node) {
 	nodes.Lock()
+	// under the lock, so the samples of parallel updates are in order
+	now := seen
+	if now.IsZero() {
+		now = jsontime.Now()
+	}
 	node, _ := nodes.List[nodeID]
 
 	if node == nil {
 		node = &Node{
 			Firstseen: now,
+			Online:    true, // a new node does not change its state
 		}
 		nodes.List[nodeID] = node
 	}
+	cameOnline := !node.Online
+	previous := newNodeIDCandidate(node.Nodeinfo, node.Address, node.Lastseen)
+	if f != nil {
+		f(node)
+	}
 	if res.Nodeinfo != nil {
-		nodes.readIfaces(res.Nodeinfo, true)
+		nodes.readIfaces(res.Nodeinfo, res.Neighbours, true)
 	}
-	nodes.Unlock()
-
-	// Update wireless statistics
-	if statistics := res.Statistics; statistics != nil {
+	// a nodeinfo kept of the known node is not new
+	if res.Nodeinfo != nil && res.Nodeinfo != node.Nodeinfo {
+		current := newNodeIDCandidate(res.Nodeinfo, node.Address, now)
+		if node.updateNodeIDConflict(previous, current, now.Add(-nodes.offlinePeriod())) {
+			log.WithFields(map[string]interface{}{
+				"node_id": nodeID,
+				"mac":     current.Mac,
+			}).Warn("node ID is claimed by more than one device, check for a cloned configuration")
+		}
+	}
+	// statistics kept of the known node (e.g. in a round, which does not request them) are not new
+	freshStatistics := res.Statistics != nil && res.Statistics != node.Statistics
+
+	// Update wireless statistics and traffic rates
+	if statistics := res.Statistics; freshStatistics {
+		statistics.Normalize()
+		if nodes.config != nil && nodes.config.ClientsDedup {
+			statistics.Clients.Deduplicate()
+		}
 		// Update channel utilization if previous statistics are present
 		if node.Statistics != nil && node.Statistics.Wireless != nil && statistics.Wireless != nil {
 			statistics.Wireless.SetUtilization(node.Statistics.Wireless)
 		}
+		if node.Statistics != nil {
+			statistics.SetTrafficRates(node.Statistics, now.GetTime().Sub(node.Lastseen.GetTime()).Seconds())
+		}
+	}
+	// a reboot is detected by a decreased uptime
+	rebooted := freshStatistics && node.Statistics != nil && res.Statistics.Uptime > 0 && res.Statistics.Uptime < node.Statistics.Uptime
+	if rebooted {
+		boot := now.Add(-time.Duration(res.Statistics.Uptime * float64(time.Second)))
+		node.LastReboot = &boot
+		node.rebootDetected = now
 	}
 
-	// Update fields
+	// Update fields, responses of a node could be processed in parallel
 	node.Lastseen = now
 	node.Online = true
+	if seen.IsZero() {
+		// collected by this instance
+		node.Origin = ""
+	}
 	node.Neighbours = res.Neighbours
 	node.Nodeinfo = res.Nodeinfo
 	node.Statistics = res.Statistics
 	node.CustomFields = res.CustomFields
+	if freshStatistics && nodes.config != nil && nodes.config.HistoryDepth > 0 {
+		node.addHistory(NewHistorySample(now, res.Statistics), nodes.config.HistoryDepth)
+	}
+	nodeCopy := *node
+	nodeCopy.History = nil
+	nodes.Unlock()
 
-	return node
+	nodes.notify(nodeID, nodeCopy)
+	if cameOnline {
+		nodes.notifyStateChanges([]StateChange{{NodeID: nodeID, Node: nodeCopy, Online: true, Time: now.GetTime()}})
+	}
+	if rebooted {
+		nodes.notifyReboot(Reboot{NodeID: nodeID, Node: nodeCopy, Time: nodeCopy.LastReboot.GetTime()})
+	}
+
+	return node, nodeCopy
+}
+
+// SitesDomains returns the site codes of the online nodes with their sorted domain codes
+func (nodes *Nodes) SitesDomains() map[string][]string {
+	found := make(map[string]map[string]bool)
+	nodes.RLock()
+	for _, node := range nodes.List {
+		info := node.Nodeinfo
+		if !node.Online || info == nil || info.System.SiteCode == "" {
+			continue
+		}
+		domains, ok := found[info.System.SiteCode]
+		if !ok {
+			domains = make(map[string]bool)
+			found[info.System.SiteCode] = domains
+		}
+		if info.System.DomainCode != "" {
+			domains[info.System.DomainCode] = true
+		}
+	}
+	nodes.RUnlock()
+
+	result := make(map[string][]string, len(found))
+	for site, domains := range found {
+		list := make([]string, 0, len(domains))
+		for domain := range domains {
+			list = append(list, domain)
+		}
+		sort.Strings(list)
+		result[site] = list
+	}
+	return result
 }
 
 // Select selects a list of nodes to be returned
@@ -102,11 +260,99 @@ func (nodes *Nodes) Select(f func(*Node) bool) []*Node {
 	return result
 }
 
+// SortedNodeIDs returns the IDs of the given nodes in sorted order,
+// e.g. to get a byte-stable output for identical states
+func SortedNodeIDs(list map[string]*Node) []string {
+	ids := make([]string, 0, len(list))
+	for nodeID := range list {
+		ids = append(ids, nodeID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Filter returns copies of all nodes matching the predicate.
+// The predicate is called under the read lock and must not modify the node.
+// The copies are shallow: the data of the respondd sections is shared and must not be modified either.
+func (nodes *Nodes) Filter(pred func(*Node) bool) []*Node {
+	nodes.RLock()
+	defer nodes.RUnlock()
+
+	var result []*Node
+	for _, node := range nodes.List {
+		if pred(node) {
+			nodeCopy := *node
+			nodeCopy.History = append([]HistorySample(nil), node.History...)
+			result = append(result, &nodeCopy)
+		}
+	}
+	return result
+}
+
+// Online returns copies of all online nodes
+func (nodes *Nodes) Online() []*Node {
+	return nodes.Filter(func(node *Node) bool {
+		return node.Online
+	})
+}
+
+// ByModel returns copies of all nodes with the given hardware model
+func (nodes *Nodes) ByModel(model string) []*Node {
+	return nodes.Filter(func(node *Node) bool {
+		return node.Nodeinfo != nil && node.Nodeinfo.Hardware.Model == model
+	})
+}
+
+// ByFirmware returns copies of all nodes with the given firmware release
+func (nodes *Nodes) ByFirmware(release string) []*Node {
+	return nodes.Filter(func(node *Node) bool {
+		return node.Nodeinfo != nil && node.Nodeinfo.Software.Firmware != nil && node.Nodeinfo.Software.Firmware.Release == release
+	})
+}
+
 func (nodes *Nodes) GetNodeIDbyAddress(addr string) string {
-	return nodes.ifaceToNodeID[addr]
+	return nodes.nodeIDOf(addr)
+}
+
+// nodeIDOf returns the node ID of the node with the given MAC or IP address, an empty string if it is unknown
+func (nodes *Nodes) nodeIDOf(addr string) string {
+	return nodes.ifaceToNodeID[normalizeAddress(addr)]
+}
+
+// GatewayOf returns the node ID of the gateway currently used by the node (by statistics.gateway or gateway6),
+// the address of the gateway if it is unknown or an empty string without a gateway
+func (nodes *Nodes) GatewayOf(node *Node) string {
+	stats := node.Statistics
+	if stats == nil {
+		return ""
+	}
+	for _, addr := range []string{stats.GatewayIPv4, stats.GatewayIPv6} {
+		if addr == "" {
+			continue
+		}
+		if nodeID := nodes.nodeIDOf(addr); nodeID != "" {
+			return nodeID
+		}
+		return addr
+	}
+	return ""
+}
+
+// linkProtocol returns true if links of the given protocol should be used
+func (nodes *Nodes) linkProtocol(protocol string) bool {
+	if nodes.config == nil || len(nodes.config.LinkProtocols) == 0 {
+		return true
+	}
+	for _, p := range nodes.config.LinkProtocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
 }
 
 // NodeLinks returns a list of links to known neighbours
+// (only of the protocols configured in LinkProtocols)
 func (nodes *Nodes) NodeLinks(node *Node) (result []Link) {
 	// Store link data
 	neighbours := node.Neighbours
@@ -114,18 +360,36 @@ func (nodes *Nodes) NodeLinks(node *Node) (result []Link) {
 		return
 	}
 
+	if nodes.linkProtocol(LINK_PROTOCOL_BATADV) {
+		result = append(result, nodes.batadvLinks(node)...)
+	}
+	if nodes.linkProtocol(LINK_PROTOCOL_BABEL) {
+		result = append(result, nodes.babelLinks(node)...)
+	}
+	return result
+}
+
+// batadvLinks returns the batman-adv links to known neighbours
+func (nodes *Nodes) batadvLinks(node *Node) (result []Link) {
+	neighbours := node.Neighbours
 	for sourceMAC, batadv := range neighbours.Batadv {
 		for neighbourMAC, link := range batadv.Neighbours {
-			if neighbourID := nodes.ifaceToNodeID[neighbourMAC]; neighbourID != "" {
+			if neighbourID := nodes.nodeIDOf(neighbourMAC); neighbourID != "" {
 				neighbour := nodes.List[neighbourID]
 
 				link := Link{
+					Protocol:      LINK_PROTOCOL_BATADV,
 					SourceID:      neighbours.NodeID,
 					SourceAddress: sourceMAC,
 					TargetID:      neighbourID,
 					TargetAddress: neighbourMAC,
 					TQ:            float32(link.Tq) / 255.0,
 				}
+				if wifi, ok := neighbours.Wifi[sourceMAC]; ok {
+					link.Signal = wifi.Neighbours[neighbourMAC].Signal
+				}
+
+				link.Type = linkType(node, sourceMAC, neighbour, neighbourMAC)
 
 				if neighbour.Nodeinfo != nil {
 					link.TargetHostname = neighbour.Nodeinfo.Hostname
@@ -138,15 +402,23 @@ func (nodes *Nodes) NodeLinks(node *Node) (result []Link) {
 			}
 		}
 	}
+	return result
+}
+
+// babelLinks returns the babel links to known neighbours
+func (nodes *Nodes) babelLinks(node *Node) (result []Link) {
+	neighbours := node.Neighbours
 	for _, iface := range neighbours.Babel {
 		for neighbourIP, link := range iface.Neighbours {
-			if neighbourID := nodes.ifaceToNodeID[neighbourIP]; neighbourID != "" {
+			if neighbourID := nodes.nodeIDOf(neighbourIP); neighbourID != "" {
 				result = append(result, Link{
+					Protocol:      LINK_PROTOCOL_BABEL,
 					SourceID:      neighbours.NodeID,
 					SourceAddress: iface.LinkLocalAddress,
 					TargetID:      neighbourID,
 					TargetAddress: neighbourIP,
 					TQ:            1.0 - (float32(link.Cost) / 65535.0),
+					Type:          linkType(node, iface.LinkLocalAddress, nodes.List[neighbourID], neighbourIP),
 				})
 			}
 		}
@@ -154,14 +426,96 @@ func (nodes *Nodes) NodeLinks(node *Node) (result []Link) {
 	return result
 }
 
+// linkType returns the type of a link by the mesh interfaces of its addresses on both nodes:
+// a link is a VPN link if one of its interfaces is a tunnel, otherwise wireless if one of them is wireless
+func linkType(source *Node, sourceAddress string, target *Node, targetAddress string) string {
+	return MergeLinkTypes(interfaceType(source, sourceAddress), interfaceType(target, targetAddress))
+}
+
+// MergeLinkTypes returns the type of a link by the types of both of its directions or interfaces:
+// LINK_TYPE_VPN before LINK_TYPE_WIRELESS before LINK_TYPE_WIRED, empty if both are unknown
+func MergeLinkTypes(a, b string) string {
+	for _, t := range []string{LINK_TYPE_VPN, LINK_TYPE_WIRELESS, LINK_TYPE_WIRED} {
+		if a == t || b == t {
+			return t
+		}
+	}
+	return ""
+}
+
+// interfaceType returns the link type of the mesh interface of the node with the given address, empty if it is unknown.
+// An address listed more than once is a tunnel before a wireless before another interface.
+func interfaceType(node *Node, addr string) string {
+	if node == nil || node.Nodeinfo == nil || addr == "" {
+		return ""
+	}
+	addr = normalizeAddress(addr)
+	mesh := node.Nodeinfo.Network.Mesh
+	for _, iface := range mesh {
+		if containsAddress(iface.Interfaces.Tunnel, addr) {
+			return LINK_TYPE_VPN
+		}
+	}
+	for _, iface := range mesh {
+		if containsAddress(iface.Interfaces.Wireless, addr) {
+			return LINK_TYPE_WIRELESS
+		}
+	}
+	for _, iface := range mesh {
+		if containsAddress(iface.Interfaces.Other, addr) {
+			return LINK_TYPE_WIRED
+		}
+	}
+	return ""
+}
+
+// containsAddress returns whether the list contains the normalized address
+func containsAddress(list []string, addr string) bool {
+	for _, a := range list {
+		if normalizeAddress(a) == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// UplinkOnly returns whether the node (which is no gateway) has links, but only VPN links, e.g. a node without mesh neighbours
+func (nodes *Nodes) UplinkOnly(node *Node) bool {
+	if node.IsGateway() {
+		return false
+	}
+	links := nodes.NodeLinks(node)
+	for _, link := range links {
+		if link.Type != LINK_TYPE_VPN {
+			return false
+		}
+	}
+	return len(links) > 0
+}
+
 // Periodically saves the cached DB to json file
 func (nodes *Nodes) worker() {
-	c := time.Tick(nodes.config.SaveInterval.Duration)
+	defer close(nodes.stopped)
+	ticker := time.NewTicker(nodes.config.SaveInterval.Duration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-nodes.stop:
+			return
+		case <-ticker.C:
+			nodes.expire()
+			nodes.save()
+		}
+	}
+}
 
-	for range c {
-		nodes.expire()
-		nodes.save()
+// offlinePeriod returns the period, after which a node not seen is offline
+func (nodes *Nodes) offlinePeriod() time.Duration {
+	if nodes.config == nil || nodes.config.OfflineAfter.Duration == 0 {
+		return time.Minute * 10 // our default
 	}
+	return nodes.config.OfflineAfter.Duration
 }
 
 // Expires nodes and set nodes offline
@@ -176,25 +530,45 @@ func (nodes *Nodes) expire() {
 	pruneAfter := now.Add(-prunePeriod)
 
 	// Nodes last seen within OfflineAfter are changed to 'offline'
-	offlineAfter := now.Add(-nodes.config.OfflineAfter.Duration)
+	offlineAfter := now.Add(-nodes.offlinePeriod())
 
 	// Locking foo
 	nodes.Lock()
-	defer nodes.Unlock()
+	var changes []StateChange
+	defer func() {
+		nodes.Unlock()
+		nodes.notifyStateChanges(changes)
+	}()
 
+	pruned, offline := 0, 0
 	for id, node := range nodes.List {
 		if node.Lastseen.Before(pruneAfter) {
 			// expire
 			delete(nodes.List, id)
+			nodes.releaseAddresses(id)
+			pruned++
 		} else if node.Lastseen.Before(offlineAfter) {
 			// set to offline
-			node.Online = false
+			node.History = nil
+			if node.Online {
+				offline++
+				node.Online = false
+				changes = append(changes, StateChange{NodeID: id, Node: *node, Online: false, Time: now.GetTime()})
+			}
 		}
 	}
+	if pruned > 0 || offline > 0 {
+		log.WithFields(map[string]interface{}{
+			"pruned":  pruned,
+			"offline": offline,
+		}).Info("expired nodes")
+	}
 }
 
-// adds the nodes interface addresses to the internal map
-func (nodes *Nodes) readIfaces(nodeinfo *data.Nodeinfo, warning bool) {
+// adds the nodes interface addresses to the internal map and removes the addresses, which the node does not report anymore:
+// the MAC addresses of the mesh interfaces (wireless, other and tunnel) of the nodeinfo and
+// the addresses of the interfaces, which the node reports neighbours on (e.g. interfaces missing in the nodeinfo)
+func (nodes *Nodes) readIfaces(nodeinfo *data.Nodeinfo, neighbours *data.Neighbours, warning bool) {
 	nodeID := nodeinfo.NodeID
 	network := nodeinfo.Network
 
@@ -204,36 +578,93 @@ func (nodes *Nodes) readIfaces(nodeinfo *data.Nodeinfo, warning bool) {
 	}
 
 	addresses := []string{network.Mac}
-
+	addresses = append(addresses, network.MeshInterfaces...)
 	for _, iface := range network.Mesh {
 		addresses = append(addresses, iface.Addresses()...)
 	}
+	if neighbours != nil && neighbours.NodeID == nodeID {
+		for addr := range neighbours.Batadv {
+			addresses = append(addresses, addr)
+		}
+		for _, iface := range neighbours.Babel {
+			addresses = append(addresses, iface.LinkLocalAddress)
+		}
+	}
 
+	current := make(map[string]struct{}, len(addresses))
+	list := make([]string, 0, len(addresses))
 	for _, addr := range addresses {
 		if addr == "" {
 			continue
 		}
-		if oldNodeID, _ := nodes.ifaceToNodeID[addr]; oldNodeID != nodeID {
-			if oldNodeID != "" && warning {
-				log.Warnf("override nodeID from %s to %s on MAC address %s", oldNodeID, nodeID, addr)
-			}
-			nodes.ifaceToNodeID[addr] = nodeID
+		addr = normalizeAddress(addr)
+		if _, ok := current[addr]; ok {
+			continue
 		}
+		current[addr] = struct{}{}
+		list = append(list, addr)
+		nodes.claimAddress(addr, nodeID, warning)
 	}
+
+	for _, addr := range nodes.nodeAddresses[nodeID] {
+		if _, ok := current[addr]; !ok {
+			nodes.releaseAddress(addr, nodeID)
+		}
+	}
+	if nodes.nodeAddresses == nil {
+		nodes.nodeAddresses = make(map[string][]string)
+	}
+	nodes.nodeAddresses[nodeID] = list
+}
+
+// normalizeAddress returns the canonical form of a MAC or IP address (e.g. lower case), so the addresses
+// reported by the nodes in different forms are matched
+func normalizeAddress(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil {
+		return ip.String()
+	}
+	if mac, err := net.ParseMAC(addr); err == nil {
+		return mac.String()
+	}
+	return strings.ToLower(addr)
 }
 
 func (nodes *Nodes) load() {
 	path := nodes.config.StatePath
 
-	if f, err := os.Open(path); err == nil { // transform data to legacy meshviewer
-		if err = json.NewDecoder(f).Decode(nodes); err == nil {
+	if content, err := ioutil.ReadFile(path); err == nil {
+		content, version, err := migrateState(content)
+		state := stateFile{List: nodes.List}
+		if err == nil {
+			err = json.Unmarshal(content, &state)
+		}
+		if err == nil {
+			if state.List != nil {
+				nodes.List = state.List
+			}
 			log.Infof("loaded %d nodes", len(nodes.List))
+			if version < StateVersion {
+				log.Infof("migrated the state file from version %d to %d", version, StateVersion)
+			} else if version > StateVersion {
+				log.Warnf("the state file of version %d is newer than supported version %d, unknown fields are dropped", version, StateVersion)
+			}
 
 			nodes.Lock()
+			// by first seen, to resolve conflicting addresses deterministic
+			list := make([]*Node, 0, len(nodes.List))
 			for _, node := range nodes.List {
 				if node.Nodeinfo != nil {
-					nodes.readIfaces(node.Nodeinfo, false)
+					list = append(list, node)
+				}
+			}
+			sort.SliceStable(list, func(i, j int) bool {
+				if a, b := list[i].Firstseen, list[j].Firstseen; a.Before(b) || b.Before(a) {
+					return a.Before(b)
 				}
+				return list[i].Nodeinfo.NodeID < list[j].Nodeinfo.NodeID
+			})
+			for _, node := range list {
+				nodes.readIfaces(node.Nodeinfo, node.Neighbours, false)
 			}
 			nodes.Unlock()
 
@@ -246,52 +677,82 @@ func (nodes *Nodes) load() {
 }
 
 func (nodes *Nodes) save() {
+	if nodes.config.StatePath == "" {
+		return
+	}
+
 	// Locking foo
 	nodes.RLock()
 	defer nodes.RUnlock()
 
 	// serialize nodes
-	SaveJSON(nodes, nodes.config.StatePath)
+	SaveJSON(&stateFile{Version: StateVersion, List: nodes.List}, nodes.config.StatePath)
 }
 
 // SaveJSON to path
 func SaveJSON(input interface{}, outputFile string) {
-	tmpFile := outputFile + ".tmp"
+	saveJSON(input, outputFile, "")
+}
 
-	f, err := os.OpenFile(tmpFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+// SaveJSONIndent to path, pretty-printed with the given indent
+func SaveJSONIndent(input interface{}, outputFile string, indent string) {
+	saveJSON(input, outputFile, indent)
+}
+
+func saveJSON(input interface{}, outputFile string, indent string) {
+	err := WriteFileAtomic(outputFile, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", indent)
+		return encoder.Encode(input)
+	})
 	if err != nil {
 		log.Panic(err)
 	}
+}
 
-	err = json.NewEncoder(f).Encode(input)
+// WriteFileAtomic writes a file by a temporary file in the same directory, which is synced and renamed,
+// so readers (e.g. a webserver) never see a partially written file and the old file is kept on an error
+func WriteFileAtomic(path string, write func(io.Writer) error) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, "."+name+".tmp")
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
-
-	f.Close()
-	if err := os.Rename(tmpFile, outputFile); err != nil {
-		log.Panic(err)
+	tmpFile := f.Name()
+	if err = write(f); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// like a file created by os.Create, instead of 0600 of the temporary file
+		err = os.Chmod(tmpFile, 0644)
 	}
+	if err == nil {
+		err = os.Rename(tmpFile, path)
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+	}
+	return err
 }
 
 // Save a slice of json objects as line-encoded JSON (JSONL) to a path.
 func SaveJSONL(input []interface{}, outputFile string) {
-	tmpFile := outputFile + ".tmp"
-
-	f, err := os.OpenFile(tmpFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		log.Panic(err)
-	}
-
-	for _, element := range input {
-		err = json.NewEncoder(f).Encode(element)
-		if err != nil {
-			log.Panic(err)
+	err := WriteFileAtomic(outputFile, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		for _, element := range input {
+			if err := encoder.Encode(element); err != nil {
+				return err
+			}
 		}
-	}
-
-	f.Close()
-	if err := os.Rename(tmpFile, outputFile); err != nil {
+		return nil
+	})
+	if err != nil {
 		log.Panic(err)
 	}
 }
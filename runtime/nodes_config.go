@@ -3,9 +3,13 @@ package runtime
 import "github.com/FreifunkBremen/yanic/lib/duration"
 
 type NodesConfig struct {
-	StatePath    string            `toml:"state_path"`
-	SaveInterval duration.Duration `toml:"save_interval"` // Save nodes periodically
-	OfflineAfter duration.Duration `toml:"offline_after"` // Set node to offline if not seen within this period
-	PruneAfter   duration.Duration `toml:"prune_after"`   // Remove nodes after n days of inactivity
-	Output       map[string]interface{}
+	StatePath       string            `toml:"state_path"`
+	SaveInterval    duration.Duration `toml:"save_interval"`    // Save nodes periodically
+	OfflineAfter    duration.Duration `toml:"offline_after"`    // Set node to offline if not seen within this period
+	PruneAfter      duration.Duration `toml:"prune_after"`      // Remove nodes after n days of inactivity
+	HistoryDepth    int               `toml:"history_depth"`    // Count of statistics samples to keep per online node
+	LinkProtocols   []string          `toml:"link_protocols"`   // Use only links of these protocols (empty for all)
+	AddressConflict string            `toml:"address_conflict"` // Which node keeps an address claimed by more nodes: first_seen (default) or last_seen
+	ClientsDedup    bool              `toml:"clients_dedup"`    // Derive the count of the clients without the dual-band devices counted twice
+	Output          map[string]interface{}
 }
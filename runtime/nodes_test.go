@@ -1,8 +1,11 @@
 package runtime
 
 import (
+	"errors"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -46,6 +49,15 @@ func TestExpire(t *testing.T) {
 	// one online?
 	assert.NotNil(nodes.List["online"])
 	assert.True(nodes.List["online"].Online)
+
+	// default offline period
+	config.OfflineAfter.Duration = 0
+	nodes.expire()
+	assert.True(nodes.List["online"].Online)
+	online := nodes.List["online"]
+	online.Lastseen = online.Lastseen.Add(-time.Minute * 11)
+	nodes.expire()
+	assert.False(nodes.List["online"].Online)
 }
 
 func TestLoadAndSave(t *testing.T) {
@@ -87,6 +99,60 @@ func TestLoadAndSave(t *testing.T) {
 	assert.Len(nodes.List, 2)
 }
 
+func TestWriteFileAtomic(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "yanic")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nodes.json")
+
+	assert.NoError(WriteFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write([]byte("old"))
+		return err
+	}))
+
+	// the old file is kept on an error, without a temporary file
+	assert.Error(WriteFileAtomic(path, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return errors.New("encoding failed")
+	}))
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal("old", string(content))
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(err)
+	assert.Len(files, 1)
+	assert.Equal(os.FileMode(0644), files[0].Mode().Perm())
+}
+
+func TestCloseSaves(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpfile, _ := ioutil.TempFile("/tmp", "nodes")
+	tmpfile.Close()
+	os.Remove(tmpfile.Name())
+	defer os.Remove(tmpfile.Name())
+
+	config := &NodesConfig{StatePath: tmpfile.Name()}
+	config.SaveInterval.Duration = time.Hour
+	nodes := NewNodes(config)
+	nodes.Start()
+	nodes.AddNode(&Node{Nodeinfo: &data.Nodeinfo{NodeID: "000000000001"}})
+	nodes.Close()
+	nodes.Close() // closing twice is harmless
+
+	loaded := NewNodes(config)
+	assert.Len(loaded.List, 1)
+
+	// without a state file nothing is saved
+	nodes = NewNodes(&NodesConfig{SaveInterval: config.SaveInterval})
+	nodes.Start()
+	nodes.Close()
+	_, err := os.Stat(".tmp")
+	assert.True(os.IsNotExist(err))
+}
+
 func TestUpdateNodes(t *testing.T) {
 	assert := assert.New(t)
 	nodes := &Nodes{
@@ -110,6 +176,51 @@ func TestUpdateNodes(t *testing.T) {
 	nodes.Update("abcdef012345", res)
 
 	assert.Len(nodes.List, 1)
+
+	// traffic rates by the previous statistics
+	stats := &data.Statistics{Uptime: 100}
+	stats.Traffic.Rx = &data.Traffic{Bytes: 1000}
+	nodes.Update("abcdef012345", &data.ResponseData{Statistics: stats})
+	stats = &data.Statistics{Uptime: 110}
+	stats.Traffic.Rx = &data.Traffic{Bytes: 3000}
+	node := nodes.Update("abcdef012345", &data.ResponseData{Statistics: stats})
+	assert.Equal(&data.TrafficRate{Bytes: 200}, node.Statistics.Traffic.Rx.Rate)
+}
+
+func TestMergeNodes(t *testing.T) {
+	assert := assert.New(t)
+	nodes := NewNodes(&NodesConfig{})
+
+	firstseen := jsontime.Now().Add(-time.Hour)
+	lastseen := jsontime.Now().Add(-time.Minute)
+	remote := &Node{
+		Firstseen: firstseen,
+		Lastseen:  lastseen,
+		Online:    true,
+		Nodeinfo:  &data.Nodeinfo{NodeID: "000000000001", Hostname: "remote"},
+		Origin:    "ffxx",
+	}
+	assert.True(nodes.Merge("000000000001", remote))
+	node := nodes.List["000000000001"]
+	assert.Equal("remote", node.Nodeinfo.Hostname)
+	assert.Equal("ffxx", node.Origin)
+	assert.Equal(firstseen, node.Firstseen)
+	assert.Equal(lastseen, node.Lastseen)
+	assert.True(node.Online)
+
+	// not newer
+	assert.False(nodes.Merge("000000000001", remote))
+
+	// seen locally in the meantime
+	nodes.Update("000000000001", &data.ResponseData{Nodeinfo: &data.Nodeinfo{NodeID: "000000000001", Hostname: "local"}})
+	assert.False(nodes.Merge("000000000001", &Node{Lastseen: lastseen.Add(time.Second), Online: true, Nodeinfo: remote.Nodeinfo}))
+	assert.Equal("local", nodes.List["000000000001"].Nodeinfo.Hostname)
+	assert.Empty(nodes.List["000000000001"].Origin)
+
+	// offline or incomplete
+	assert.False(nodes.Merge("000000000002", &Node{Lastseen: lastseen, Nodeinfo: remote.Nodeinfo}))
+	assert.False(nodes.Merge("000000000002", &Node{Lastseen: lastseen, Online: true}))
+	assert.Len(nodes.List, 1)
 }
 
 func TestSelectNodes(t *testing.T) {
@@ -139,6 +250,61 @@ func TestSelectNodes(t *testing.T) {
 	assert.Equal(time, selectedNodes[0].Firstseen)
 }
 
+func TestSitesDomainsNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := createTestNodes()
+	assert.Equal(map[string][]string{TEST_SITE: {TEST_DOMAIN}}, nodes.SitesDomains())
+
+	// offline nodes are skipped
+	for _, node := range nodes.List {
+		node.Online = false
+	}
+	assert.Empty(nodes.SitesDomains())
+}
+
+func TestFilterNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := NewNodes(&NodesConfig{})
+	nodes.AddNode(&Node{
+		Online: true,
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "000000000001",
+			Hardware: data.Hardware{Model: "TP-Link 841"},
+			Software: data.Software{
+				Firmware: &struct {
+					Base    string `json:"base,omitempty"`
+					Release string `json:"release,omitempty"`
+				}{
+					Release: "2016.1.6",
+				},
+			},
+		},
+	})
+	nodes.AddNode(&Node{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "000000000002",
+			Hardware: data.Hardware{Model: "TP-Link 841"},
+		},
+	})
+	nodes.AddNode(&Node{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000003"},
+	})
+
+	assert.Len(nodes.Filter(func(n *Node) bool { return true }), 3)
+	assert.Len(nodes.Filter(func(n *Node) bool { return false }), 0)
+	assert.Len(nodes.Online(), 1)
+	assert.Len(nodes.ByModel("TP-Link 841"), 2)
+	assert.Len(nodes.ByModel("Xeon Multi-Core"), 0)
+	assert.Len(nodes.ByFirmware("2016.1.6"), 1)
+
+	// results are copies
+	online := nodes.Online()
+	online[0].Online = false
+	assert.True(nodes.List["000000000001"].Online)
+}
+
 func TestAddNode(t *testing.T) {
 	assert := assert.New(t)
 	nodes := NewNodes(&NodesConfig{})
@@ -220,6 +386,15 @@ func TestLinksNodes(t *testing.T) {
 					},
 				},
 			},
+			Wifi: map[string]data.WifiNeighbours{
+				"f4:f2:6d:d7:a3:0b": {
+					Neighbours: map[string]data.WifiLink{
+						"f4:f2:6d:d7:a3:0a": {
+							Signal: -62, Noise: -95,
+						},
+					},
+				},
+			},
 		},
 	})
 
@@ -240,6 +415,9 @@ func TestLinksNodes(t *testing.T) {
 	assert.Equal("f4f26dd7a30b", link.TargetID)
 	assert.Equal("fe80::1337", link.TargetAddress)
 	assert.Equal(float32(0.6), link.TQ)
+	assert.Equal(LINK_PROTOCOL_BABEL, link.Protocol)
+	// by the other mesh interface of the target
+	assert.Equal(LINK_TYPE_WIRED, link.Type)
 
 	// batman link
 	node = nodes.List["f4f26dd7a30b"]
@@ -252,7 +430,161 @@ func TestLinksNodes(t *testing.T) {
 	assert.Equal("f4f26dd7a30a", link.TargetID)
 	assert.Equal("f4:f2:6d:d7:a3:0a", link.TargetAddress)
 	assert.Equal(float32(0.8), link.TQ)
+	assert.Equal(-62, link.Signal)
+	assert.Equal(LINK_PROTOCOL_BATADV, link.Protocol)
+	// no mesh interfaces known
+	assert.Equal("", link.Type)
+
+	// only babel links
+	nodes.config = &NodesConfig{LinkProtocols: []string{LINK_PROTOCOL_BABEL}}
+	assert.Len(nodes.NodeLinks(node), 0)
+	assert.Len(nodes.NodeLinks(nodes.List["f4f26dd7a30a"]), 1)
 
 	nodeid := nodes.GetNodeIDbyAddress("f4:f2:6d:d7:a3:0a")
 	assert.Equal("f4f26dd7a30a", nodeid)
 }
+
+func TestReadIfaces(t *testing.T) {
+	assert := assert.New(t)
+	nodes := NewNodes(&NodesConfig{})
+
+	mesh := func(wireless, tunnel string) map[string]*data.NetworkInterface {
+		iface := &data.NetworkInterface{}
+		iface.Interfaces.Wireless = []string{wireless}
+		iface.Interfaces.Tunnel = []string{tunnel}
+		return map[string]*data.NetworkInterface{"bat0": iface}
+	}
+
+	nodes.Update("f4f26dd7a300", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID: "f4f26dd7a300",
+			Network: data.Network{
+				Mac:  "f4:f2:6d:d7:a3:00",
+				Mesh: mesh("F4:F2:6D:D7:A3:01", "f4:f2:6d:d7:a3:02"),
+			},
+		},
+		Neighbours: &data.Neighbours{
+			NodeID: "f4f26dd7a300",
+			Batadv: map[string]data.BatadvNeighbours{
+				// interface missing in the nodeinfo
+				"f4:f2:6d:d7:a3:03": {},
+			},
+		},
+	})
+	for _, addr := range []string{"f4:f2:6d:d7:a3:00", "f4:f2:6d:d7:a3:01", "F4-F2-6D-D7-A3-01", "f4:f2:6d:d7:a3:02", "f4:f2:6d:d7:a3:03"} {
+		assert.Equal("f4f26dd7a300", nodes.GetNodeIDbyAddress(addr), addr)
+	}
+
+	// a changed interface and the neighbours kept of the previous response
+	node := nodes.List["f4f26dd7a300"]
+	nodes.Update("f4f26dd7a300", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID: "f4f26dd7a300",
+			Network: data.Network{
+				Mac:  "f4:f2:6d:d7:a3:00",
+				Mesh: mesh("f4:f2:6d:d7:a3:01", "f4:f2:6d:d7:a3:04"),
+			},
+		},
+		Neighbours: node.Neighbours,
+	})
+	assert.Equal("", nodes.GetNodeIDbyAddress("f4:f2:6d:d7:a3:02"))
+	assert.Equal("f4f26dd7a300", nodes.GetNodeIDbyAddress("f4:f2:6d:d7:a3:04"))
+	assert.Equal("f4f26dd7a300", nodes.GetNodeIDbyAddress("f4:f2:6d:d7:a3:03"))
+
+	// an address moves to the other claiming node, if it is not reported anymore
+	nodes.Update("f4f26dd7a310", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:  "f4f26dd7a310",
+			Network: data.Network{Mac: "f4:f2:6d:d7:a3:10", Mesh: mesh("f4:f2:6d:d7:a3:01", "")},
+		},
+	})
+	assert.Len(nodes.AddressConflicts(), 1)
+	nodes.Update("f4f26dd7a300", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:  "f4f26dd7a300",
+			Network: data.Network{Mac: "f4:f2:6d:d7:a3:00"},
+		},
+	})
+	assert.Equal("f4f26dd7a310", nodes.GetNodeIDbyAddress("f4:f2:6d:d7:a3:01"))
+	assert.Equal("", nodes.GetNodeIDbyAddress("f4:f2:6d:d7:a3:03"))
+	assert.Len(nodes.AddressConflicts(), 0)
+}
+
+func TestLinkType(t *testing.T) {
+	assert := assert.New(t)
+	nodes := NewNodes(&NodesConfig{})
+
+	mesh := func(wireless, other, tunnel string) map[string]*data.NetworkInterface {
+		iface := &data.NetworkInterface{}
+		iface.Interfaces.Wireless = []string{wireless}
+		iface.Interfaces.Other = []string{other}
+		iface.Interfaces.Tunnel = []string{tunnel}
+		return map[string]*data.NetworkInterface{"bat0": iface}
+	}
+	update := func(nodeID, wireless, other, tunnel string, vpn bool, links map[string]string) {
+		batadv := make(map[string]data.BatadvNeighbours)
+		for source, target := range links {
+			batadv[source] = data.BatadvNeighbours{Neighbours: map[string]data.BatmanLink{target: {Tq: 255}}}
+		}
+		nodes.Update(nodeID, &data.ResponseData{
+			Nodeinfo: &data.Nodeinfo{
+				NodeID:  nodeID,
+				VPN:     vpn,
+				Network: data.Network{Mac: nodeID, Mesh: mesh(wireless, other, tunnel)},
+			},
+			Neighbours: &data.Neighbours{NodeID: nodeID, Batadv: batadv},
+		})
+	}
+
+	update("gateway", "", "", "gw:vpn", true, map[string]string{"gw:vpn": "a:vpn"})
+	update("a", "a:wifi", "a:lan", "a:vpn", false, map[string]string{"a:vpn": "gw:vpn", "a:wifi": "b:wifi", "a:lan": "b:lan"})
+	update("b", "b:wifi", "b:lan", "", false, map[string]string{"b:wifi": "a:wifi", "b:lan": "a:lan"})
+	// the interface is missing in the nodeinfo
+	update("c", "c:wifi", "", "", false, map[string]string{"c:vpn": "gw:vpn"})
+
+	types := make(map[string]string)
+	for _, link := range nodes.NodeLinks(nodes.List["a"]) {
+		types[link.SourceAddress] = link.Type
+	}
+	assert.Equal(map[string]string{
+		"a:vpn":  LINK_TYPE_VPN,
+		"a:wifi": LINK_TYPE_WIRELESS,
+		"a:lan":  LINK_TYPE_WIRED,
+	}, types)
+	// by the interface of the target
+	assert.Equal(LINK_TYPE_VPN, nodes.NodeLinks(nodes.List["c"])[0].Type)
+
+	assert.False(nodes.UplinkOnly(nodes.List["gateway"]))
+	assert.False(nodes.UplinkOnly(nodes.List["a"]))
+	assert.False(nodes.UplinkOnly(nodes.List["b"]))
+	assert.True(nodes.UplinkOnly(nodes.List["c"]))
+
+	stats := NewGlobalStats(nodes, nil)[GLOBAL_SITE][GLOBAL_DOMAIN]
+	assert.EqualValues(1, stats.NodesUplinkOnly)
+}
+
+func TestInterfaceType(t *testing.T) {
+	assert := assert.New(t)
+
+	wireless := &data.NetworkInterface{}
+	wireless.Interfaces.Wireless = []string{"f4:f2:6d:d7:a3:01"}
+	wireless.Interfaces.Other = []string{"f4:f2:6d:d7:a3:02"}
+	tunnel := &data.NetworkInterface{}
+	tunnel.Interfaces.Tunnel = []string{"F4:F2:6D:D7:A3:01"}
+	tunnel.Interfaces.Wireless = []string{"f4:f2:6d:d7:a3:02"}
+	node := &Node{Nodeinfo: &data.Nodeinfo{Network: data.Network{
+		Mesh: map[string]*data.NetworkInterface{"bat0": wireless, "bat1": tunnel},
+	}}}
+
+	// an address listed more than once by a fixed order, regardless of the order of the map
+	for i := 0; i < 20; i++ {
+		assert.Equal(LINK_TYPE_VPN, interfaceType(node, "f4:f2:6d:d7:a3:01"))
+		assert.Equal(LINK_TYPE_WIRELESS, interfaceType(node, "f4:f2:6d:d7:a3:02"))
+	}
+	assert.Equal("", interfaceType(node, "f4:f2:6d:d7:a3:03"))
+	assert.Equal("", interfaceType(nil, "f4:f2:6d:d7:a3:01"))
+
+	assert.Equal(LINK_TYPE_VPN, MergeLinkTypes(LINK_TYPE_WIRED, LINK_TYPE_VPN))
+	assert.Equal(LINK_TYPE_WIRELESS, MergeLinkTypes(LINK_TYPE_WIRELESS, ""))
+	assert.Equal("", MergeLinkTypes("", ""))
+}
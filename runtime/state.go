@@ -0,0 +1,59 @@
+package runtime
+
+import "time"
+
+// StateChange is the change of a node from online to offline or back
+type StateChange struct {
+	NodeID string
+	Node   Node // copy of the node without history
+	Online bool
+	Time   time.Time
+}
+
+// OnStateChange registers a function, which is called on every change of a node from online to offline or back.
+// It is called outside the lock of the nodes and must not block.
+func (nodes *Nodes) OnStateChange(f func(StateChange)) {
+	nodes.subscriptionsMu.Lock()
+	defer nodes.subscriptionsMu.Unlock()
+	nodes.stateChanged = append(nodes.stateChanged, f)
+}
+
+// Reboot is a reboot of a node, detected by a decreased uptime
+type Reboot struct {
+	NodeID string
+	Node   Node      // copy of the node without history
+	Time   time.Time // of the reboot, by the uptime of the node
+}
+
+// OnReboot registers a function, which is called on every detected reboot of a node.
+// It is called outside the lock of the nodes and must not block.
+func (nodes *Nodes) OnReboot(f func(Reboot)) {
+	nodes.subscriptionsMu.Lock()
+	defer nodes.subscriptionsMu.Unlock()
+	nodes.rebooted = append(nodes.rebooted, f)
+}
+
+// notifyReboot passes the reboot to the registered functions
+func (nodes *Nodes) notifyReboot(reboot Reboot) {
+	nodes.subscriptionsMu.Lock()
+	funcs := nodes.rebooted
+	nodes.subscriptionsMu.Unlock()
+	for _, f := range funcs {
+		f(reboot)
+	}
+}
+
+// notifyStateChanges passes the changes to the registered functions
+func (nodes *Nodes) notifyStateChanges(changes []StateChange) {
+	if len(changes) == 0 {
+		return
+	}
+	nodes.subscriptionsMu.Lock()
+	funcs := nodes.stateChanged
+	nodes.subscriptionsMu.Unlock()
+	for _, f := range funcs {
+		for _, change := range changes {
+			f(change)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+)
+
+func TestOnStateChange(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := NewNodes(&NodesConfig{})
+	var changes []StateChange
+	nodes.OnStateChange(func(change StateChange) {
+		changes = append(changes, change)
+	})
+
+	// a new node is not a change
+	nodes.Update("000000000001", &data.ResponseData{Nodeinfo: &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"}})
+	assert.Len(changes, 0)
+
+	node := nodes.List["000000000001"]
+	node.Lastseen = node.Lastseen.Add(-time.Hour)
+	nodes.expire()
+	assert.Len(changes, 1)
+	assert.Equal("000000000001", changes[0].NodeID)
+	assert.False(changes[0].Online)
+	assert.Equal("node1", changes[0].Node.Nodeinfo.Hostname)
+
+	// still offline
+	nodes.expire()
+	assert.Len(changes, 1)
+
+	nodes.Update("000000000001", &data.ResponseData{Nodeinfo: &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"}})
+	assert.Len(changes, 2)
+	assert.True(changes[1].Online)
+
+	nodes.Update("000000000001", &data.ResponseData{})
+	assert.Len(changes, 2)
+}
+
+func TestOnReboot(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := NewNodes(&NodesConfig{})
+	var reboots []Reboot
+	nodes.OnReboot(func(reboot Reboot) {
+		reboots = append(reboots, reboot)
+	})
+	since := time.Now()
+
+	nodes.Update("000000000001", &data.ResponseData{Statistics: &data.Statistics{Uptime: 3600}})
+	nodes.Update("000000000001", &data.ResponseData{Statistics: &data.Statistics{Uptime: 3660}})
+	// without uptime
+	nodes.Update("000000000001", &data.ResponseData{Statistics: &data.Statistics{}})
+	assert.Len(reboots, 0)
+	assert.Nil(nodes.List["000000000001"].LastReboot)
+
+	nodes.Update("000000000001", &data.ResponseData{Statistics: &data.Statistics{Uptime: 3700}})
+	nodes.Update("000000000001", &data.ResponseData{Statistics: &data.Statistics{Uptime: 60}})
+	assert.Len(reboots, 1)
+	assert.Equal("000000000001", reboots[0].NodeID)
+	assert.WithinDuration(time.Now().Add(-time.Minute), reboots[0].Time, 5*time.Second)
+	assert.Equal(reboots[0].Time, nodes.List["000000000001"].LastReboot.GetTime())
+
+	// counted by the detection
+	assert.EqualValues(1, NewGlobalStatsSince(nodes, nil, since)[GLOBAL_SITE][GLOBAL_DOMAIN].Reboots)
+	assert.EqualValues(0, NewGlobalStatsSince(nodes, nil, time.Now())[GLOBAL_SITE][GLOBAL_DOMAIN].Reboots)
+	assert.EqualValues(1, NewGlobalStats(nodes, nil)[GLOBAL_SITE][GLOBAL_DOMAIN].Reboots)
+}
@@ -1,70 +1,127 @@
 package runtime
 
+import (
+	"strconv"
+	"time"
+)
+
 const (
 	DISABLED_AUTOUPDATER = "disabled"
 	GLOBAL_SITE          = "global"
 	GLOBAL_DOMAIN        = "global"
+
+	// RebootWindow is the period of the counted reboots of NewGlobalStats
+	RebootWindow = time.Hour
+
+	// LatencyAbove is the bucket of the latencies above the last of LatencyBuckets
+	LatencyAbove = "+Inf"
 )
 
+// LatencyBuckets are the upper bounds in seconds of the buckets of the response latency of the nodes
+var LatencyBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
 // CounterMap to manage multiple values
 type CounterMap map[string]uint32
 
+// Counter is an internal counter of yanic, e.g. of dropped responses
+type Counter struct {
+	Name  string // name of the metric, without the prefix yanic_ and the suffix _total
+	Help  string
+	Value uint64
+	Gauge bool // the value is a current state (e.g. a queue length), which could decrease
+}
+
 // GlobalStats struct
 type GlobalStats struct {
-	Clients       uint32
-	ClientsWifi   uint32
-	ClientsWifi24 uint32
-	ClientsWifi5  uint32
-	ClientsOwe    uint32
-	ClientsOwe24  uint32
-	ClientsOwe5   uint32
-	Gateways      uint32
-	Nodes         uint32
-
-	Firmwares   CounterMap
-	Models      CounterMap
-	Autoupdater CounterMap
+	Clients       uint32 `json:"clients"`
+	ClientsWifi   uint32 `json:"clients_wifi"`
+	ClientsWifi24 uint32 `json:"clients_wifi24"`
+	ClientsWifi5  uint32 `json:"clients_wifi5"`
+	ClientsOwe    uint32 `json:"clients_owe"`
+	ClientsOwe24  uint32 `json:"clients_owe24"`
+	ClientsOwe5   uint32 `json:"clients_owe5"`
+	ClientsUnique uint32 `json:"clients_unique"` // deduplicated clients (see clients_dedup in [nodes]), the total of nodes without
+	Gateways      uint32 `json:"gateways"`
+	Nodes         uint32 `json:"nodes"`
+	Reboots       uint32 `json:"reboots"` // count of nodes, whose reboot was detected within the interval
+
+	// count of nodes, which answered with the section
+	NodesNodeinfo   uint32 `json:"nodes_nodeinfo"`
+	NodesStatistics uint32 `json:"nodes_statistics"`
+	NodesNeighbours uint32 `json:"nodes_neighbours"`
+
+	// count of nodes, which have only VPN links (see Nodes.UplinkOnly)
+	NodesUplinkOnly uint32 `json:"nodes_uplink_only"`
+
+	// usage of the gateways by node ID of the gateway (or its address, if unknown)
+	PerGateway map[string]*GatewayStats `json:"per_gateway"`
+
+	Firmwares           CounterMap `json:"firmwares"`
+	Models              CounterMap `json:"models"`
+	Autoupdater         CounterMap `json:"autoupdater"`
+	AutoupdaterDisabled CounterMap `json:"autoupdater_disabled"` // branches of nodes with disabled autoupdater
+	Channels24          CounterMap `json:"channels24"`           // configured wifi channels on 2.4 GHz
+	Channels5           CounterMap `json:"channels5"`            // configured wifi channels on 5 GHz
+	Latency             CounterMap `json:"latency"`              // response latency by the upper bound of its bucket (see LatencyBuckets)
+
+	since time.Time // begin of the interval of the counted reboots
+}
+
+// GatewayStats is the usage of a gateway, by the nodes using it
+type GatewayStats struct {
+	Hostname string `json:"hostname,omitempty"` // of the gateway, empty if unknown
+	Nodes    uint32 `json:"nodes"`
+	Clients  uint32 `json:"clients"`
 }
 
 //NewGlobalStats returns global statistics for InfluxDB
+// with the reboots of the last RebootWindow
 func NewGlobalStats(nodes *Nodes, sitesDomains map[string][]string) (result map[string]map[string]*GlobalStats) {
+	return NewGlobalStatsSince(nodes, sitesDomains, time.Now().Add(-RebootWindow))
+}
+
+// NewGlobalStatsSince returns global statistics with the reboots detected after the given time,
+// e.g. the previous global statistics
+func NewGlobalStatsSince(nodes *Nodes, sitesDomains map[string][]string, since time.Time) (result map[string]map[string]*GlobalStats) {
 	result = make(map[string]map[string]*GlobalStats)
 
 	result[GLOBAL_SITE] = make(map[string]*GlobalStats)
-	result[GLOBAL_SITE][GLOBAL_DOMAIN] = &GlobalStats{
-		Firmwares:   make(CounterMap),
-		Models:      make(CounterMap),
-		Autoupdater: make(CounterMap),
-	}
+	result[GLOBAL_SITE][GLOBAL_DOMAIN] = newGlobalStats(since)
 
 	for site, domains := range sitesDomains {
 		result[site] = make(map[string]*GlobalStats)
-		result[site][GLOBAL_DOMAIN] = &GlobalStats{
-			Firmwares:   make(CounterMap),
-			Models:      make(CounterMap),
-			Autoupdater: make(CounterMap),
-		}
+		result[site][GLOBAL_DOMAIN] = newGlobalStats(since)
 		for _, domain := range domains {
-			result[site][domain] = &GlobalStats{
-				Firmwares:   make(CounterMap),
-				Models:      make(CounterMap),
-				Autoupdater: make(CounterMap),
-			}
+			result[site][domain] = newGlobalStats(since)
 		}
 	}
 
 	nodes.RLock()
 	for _, node := range nodes.List {
 		if node.Online {
-			result[GLOBAL_SITE][GLOBAL_DOMAIN].Add(node)
+			gateway := nodes.GatewayOf(node)
+			var hostname string
+			if gw := nodes.List[gateway]; gw != nil && gw.Nodeinfo != nil {
+				hostname = gw.Nodeinfo.Hostname
+			}
+			uplinkOnly := nodes.UplinkOnly(node)
+			add := func(s *GlobalStats) {
+				s.Add(node)
+				s.AddGateway(gateway, hostname, node)
+				if uplinkOnly {
+					s.NodesUplinkOnly++
+				}
+			}
+
+			add(result[GLOBAL_SITE][GLOBAL_DOMAIN])
 
 			if info := node.Nodeinfo; info != nil {
 				site := info.System.SiteCode
 				domain := info.System.DomainCode
 				if _, ok := result[site]; ok {
-					result[site][GLOBAL_DOMAIN].Add(node)
+					add(result[site][GLOBAL_DOMAIN])
 					if _, ok := result[site][domain]; ok {
-						result[site][domain].Add(node)
+						add(result[site][domain])
 					}
 				}
 			}
@@ -74,10 +131,33 @@ func NewGlobalStats(nodes *Nodes, sitesDomains map[string][]string) (result map[
 	return
 }
 
+func newGlobalStats(since time.Time) *GlobalStats {
+	return &GlobalStats{
+		since:               since,
+		Firmwares:           make(CounterMap),
+		Models:              make(CounterMap),
+		Autoupdater:         make(CounterMap),
+		AutoupdaterDisabled: make(CounterMap),
+		Channels24:          make(CounterMap),
+		Channels5:           make(CounterMap),
+		Latency:             make(CounterMap),
+		PerGateway:          make(map[string]*GatewayStats),
+	}
+}
+
 // Add values to GlobalStats
 // if node is online
 func (s *GlobalStats) Add(node *Node) {
 	s.Nodes++
+	if node.Nodeinfo != nil {
+		s.NodesNodeinfo++
+	}
+	if node.Statistics != nil {
+		s.NodesStatistics++
+	}
+	if node.Neighbours != nil {
+		s.NodesNeighbours++
+	}
 	if stats := node.Statistics; stats != nil {
 		s.Clients += stats.Clients.Total
 		s.ClientsWifi24 += stats.Clients.Wifi24
@@ -86,21 +166,78 @@ func (s *GlobalStats) Add(node *Node) {
 		s.ClientsOwe24 += stats.Clients.Owe24
 		s.ClientsOwe5 += stats.Clients.Owe5
 		s.ClientsOwe += stats.Clients.Owe
+		s.ClientsUnique += stats.Clients.UniqueOrTotal()
 	}
 	if node.IsGateway() {
 		s.Gateways++
 	}
+	if !s.since.IsZero() && node.rebootDetected.GetTime().After(s.since) {
+		s.Reboots++
+	}
+	if node.Latency > 0 {
+		s.Latency.Increment(latencyBucket(node.Latency))
+	}
 	if info := node.Nodeinfo; info != nil {
 		s.Models.Increment(info.Hardware.Model)
 		if info.Software.Firmware != nil {
 			s.Firmwares.Increment(info.Software.Firmware.Release)
 		}
+		if wireless := info.Wireless; wireless != nil {
+			if wireless.Channel24 > 0 {
+				s.Channels24.Increment(strconv.Itoa(int(wireless.Channel24)))
+			}
+			if wireless.Channel5 > 0 {
+				s.Channels5.Increment(strconv.Itoa(int(wireless.Channel5)))
+			}
+		}
 		if info.Software.Autoupdater != nil && info.Software.Autoupdater.Enabled {
 			s.Autoupdater.Increment(info.Software.Autoupdater.Branch)
 		} else {
 			s.Autoupdater.Increment(DISABLED_AUTOUPDATER)
+			if info.Software.Autoupdater != nil {
+				s.AutoupdaterDisabled.Increment(info.Software.Autoupdater.Branch)
+			}
+		}
+	}
+}
+
+// latencyBucket returns the bucket of the latency in seconds
+func latencyBucket(latency float64) string {
+	for _, bound := range LatencyBuckets {
+		if latency <= bound {
+			return strconv.FormatFloat(bound, 'f', -1, 64)
 		}
 	}
+	return LatencyAbove
+}
+
+// AddGateway counts the node and its clients to the gateway used by it (see Nodes.GatewayOf)
+// if the node uses a gateway
+func (s *GlobalStats) AddGateway(gateway, hostname string, node *Node) {
+	if gateway == "" {
+		return
+	}
+	if s.PerGateway == nil {
+		s.PerGateway = make(map[string]*GatewayStats)
+	}
+	gw := s.PerGateway[gateway]
+	if gw == nil {
+		gw = &GatewayStats{Hostname: hostname}
+		s.PerGateway[gateway] = gw
+	}
+	gw.Nodes++
+	if node.Statistics != nil {
+		gw.Clients += node.Statistics.Clients.Total
+	}
+}
+
+// SectionRatio returns the fraction of the nodes, which answered with a section
+// e.g. SectionRatio(s.NodesStatistics)
+func (s *GlobalStats) SectionRatio(count uint32) float64 {
+	if s.Nodes == 0 {
+		return 0
+	}
+	return float64(count) / float64(s.Nodes)
 }
 
 // Increment counter in the map by one
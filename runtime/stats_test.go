@@ -24,6 +24,13 @@ func TestGlobalStats(t *testing.T) {
 	assert.EqualValues(3, stats[GLOBAL_SITE][GLOBAL_DOMAIN].Nodes)
 	assert.EqualValues(25, stats[GLOBAL_SITE][GLOBAL_DOMAIN].Clients)
 
+	// check answered sections
+	assert.EqualValues(3, stats[GLOBAL_SITE][GLOBAL_DOMAIN].NodesNodeinfo)
+	assert.EqualValues(2, stats[GLOBAL_SITE][GLOBAL_DOMAIN].NodesStatistics)
+	assert.EqualValues(0, stats[GLOBAL_SITE][GLOBAL_DOMAIN].NodesNeighbours)
+	assert.InDelta(2.0/3.0, stats[GLOBAL_SITE][GLOBAL_DOMAIN].SectionRatio(stats[GLOBAL_SITE][GLOBAL_DOMAIN].NodesStatistics), 0.001)
+	assert.EqualValues(0, (&GlobalStats{}).SectionRatio(0))
+
 	// check models
 	assert.Len(stats[GLOBAL_SITE][GLOBAL_DOMAIN].Models, 2)
 	assert.EqualValues(2, stats[GLOBAL_SITE][GLOBAL_DOMAIN].Models["TP-Link 841"])
@@ -143,3 +150,151 @@ func createTestNodes() *Nodes {
 
 	return nodes
 }
+
+func TestGlobalStatsAutoupdaterDisabled(t *testing.T) {
+	assert := assert.New(t)
+	nodes := NewNodes(&NodesConfig{})
+
+	for nodeID, enabled := range map[string]bool{"000000000001": true, "000000000002": false, "000000000003": false} {
+		nodes.AddNode(&Node{
+			Online: true,
+			Nodeinfo: &data.Nodeinfo{
+				NodeID: nodeID,
+				Software: data.Software{
+					Autoupdater: &struct {
+						Enabled bool   `json:"enabled,omitempty"`
+						Branch  string `json:"branch,omitempty"`
+					}{
+						Enabled: enabled,
+						Branch:  "stable",
+					},
+				},
+			},
+		})
+	}
+	// without autoupdater information
+	nodes.AddNode(&Node{
+		Online:   true,
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000004"},
+	})
+
+	stats := NewGlobalStats(nodes, map[string][]string{})[GLOBAL_SITE][GLOBAL_DOMAIN]
+	assert.EqualValues(1, stats.Autoupdater["stable"])
+	assert.EqualValues(3, stats.Autoupdater[DISABLED_AUTOUPDATER])
+	assert.Len(stats.AutoupdaterDisabled, 1)
+	assert.EqualValues(2, stats.AutoupdaterDisabled["stable"])
+}
+
+func TestGlobalStatsPerGateway(t *testing.T) {
+	assert := assert.New(t)
+	nodes := NewNodes(&NodesConfig{})
+
+	nodes.AddNode(&Node{
+		Online: true,
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "00000000000a",
+			Hostname: "gw01",
+			VPN:      true,
+			Network:  data.Network{Mac: "00:00:00:00:00:0a"},
+		},
+	})
+	for nodeID, gateway := range map[string]string{
+		"000000000001": "00:00:00:00:00:0a",
+		"000000000002": "00:00:00:00:00:0a",
+		"000000000003": "00:00:00:00:00:0b",
+		"000000000004": "",
+	} {
+		nodes.AddNode(&Node{
+			Online:     true,
+			Nodeinfo:   &data.Nodeinfo{NodeID: nodeID, System: data.System{SiteCode: TEST_SITE}},
+			Statistics: &data.Statistics{GatewayIPv4: gateway, Clients: data.Clients{Total: 3}},
+		})
+	}
+
+	assert.Equal("00000000000a", nodes.GatewayOf(nodes.List["000000000001"]))
+	assert.Equal("00:00:00:00:00:0b", nodes.GatewayOf(nodes.List["000000000003"]))
+	assert.Equal("", nodes.GatewayOf(nodes.List["000000000004"]))
+	assert.Equal("", nodes.GatewayOf(nodes.List["00000000000a"]))
+
+	stats := NewGlobalStats(nodes, map[string][]string{TEST_SITE: {}})
+	for _, s := range []*GlobalStats{stats[GLOBAL_SITE][GLOBAL_DOMAIN], stats[TEST_SITE][GLOBAL_DOMAIN]} {
+		assert.Len(s.PerGateway, 2)
+		assert.Equal(&GatewayStats{Hostname: "gw01", Nodes: 2, Clients: 6}, s.PerGateway["00000000000a"])
+		assert.Equal(&GatewayStats{Nodes: 1, Clients: 3}, s.PerGateway["00:00:00:00:00:0b"])
+	}
+}
+
+func TestGlobalStatsChannels(t *testing.T) {
+	assert := assert.New(t)
+	nodes := NewNodes(&NodesConfig{})
+
+	for nodeID, wireless := range map[string]*data.Wireless{
+		"000000000001": {Channel24: 1, Channel5: 36},
+		"000000000002": {Channel24: 1, Channel5: 44},
+		"000000000003": {Channel24: 13},
+		"000000000004": nil,
+	} {
+		nodes.AddNode(&Node{
+			Online:   true,
+			Nodeinfo: &data.Nodeinfo{NodeID: nodeID, Wireless: wireless},
+		})
+	}
+
+	stats := NewGlobalStats(nodes, nil)[GLOBAL_SITE][GLOBAL_DOMAIN]
+	assert.Equal(CounterMap{"1": 2, "13": 1}, stats.Channels24)
+	assert.Equal(CounterMap{"36": 1, "44": 1}, stats.Channels5)
+}
+
+func TestGlobalStatsLatency(t *testing.T) {
+	assert := assert.New(t)
+	nodes := NewNodes(&NodesConfig{})
+
+	for nodeID, latency := range map[string]float64{
+		"000000000001": 0.005,
+		"000000000002": 0.01,
+		"000000000003": 0.3,
+		"000000000004": 7,
+		"000000000005": 0,
+	} {
+		nodes.AddNode(&Node{
+			Online:   true,
+			Latency:  latency,
+			Nodeinfo: &data.Nodeinfo{NodeID: nodeID},
+		})
+	}
+
+	stats := NewGlobalStats(nodes, nil)[GLOBAL_SITE][GLOBAL_DOMAIN]
+	assert.Equal(CounterMap{"0.01": 2, "0.5": 1, LatencyAbove: 1}, stats.Latency)
+}
+
+func TestGlobalStatsClientsDedup(t *testing.T) {
+	assert := assert.New(t)
+	nodes := NewNodes(&NodesConfig{ClientsDedup: true})
+
+	// two dual-band devices counted on both bands
+	nodes.Update("000000000001", &data.ResponseData{
+		Nodeinfo:   &data.Nodeinfo{NodeID: "000000000001"},
+		Statistics: &data.Statistics{Clients: data.Clients{Wifi: 5, Wifi24: 4, Wifi5: 3, Total: 9}},
+	})
+	// known before the deduplication
+	nodes.AddNode(&Node{
+		Online:     true,
+		Nodeinfo:   &data.Nodeinfo{NodeID: "000000000002"},
+		Statistics: &data.Statistics{Clients: data.Clients{Total: 3}},
+	})
+
+	if unique := nodes.List["000000000001"].Statistics.Clients.Unique; assert.NotNil(unique) {
+		assert.EqualValues(7, *unique)
+	}
+
+	stats := NewGlobalStats(nodes, nil)[GLOBAL_SITE][GLOBAL_DOMAIN]
+	assert.EqualValues(12, stats.Clients)
+	assert.EqualValues(10, stats.ClientsUnique)
+
+	// disabled
+	nodes = NewNodes(&NodesConfig{})
+	nodes.Update("000000000001", &data.ResponseData{
+		Statistics: &data.Statistics{Clients: data.Clients{Wifi: 5, Wifi24: 4, Wifi5: 3, Total: 9}},
+	})
+	assert.Nil(nodes.List["000000000001"].Statistics.Clients.Unique)
+}
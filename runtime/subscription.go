@@ -0,0 +1,202 @@
+package runtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/FreifunkBremen/yanic/lib/duration"
+)
+
+const subscriptionBufferDefault = 1000
+
+// SubscriptionConfig throttles the updates of a subscription
+type SubscriptionConfig struct {
+	Window duration.Duration `toml:"window"` // updates of the same node within this period are collapsed to the latest
+	Rate   int               `toml:"rate"`   // maximum updates per second (0 for unlimited)
+	Buffer int               `toml:"buffer"` // maximum pending updates, the subscription is closed above
+}
+
+// NodeUpdate is an update of a node
+type NodeUpdate struct {
+	NodeID string
+	Node   *Node // shallow copy of the node, must not be modified
+}
+
+// Subscription receives the updates of the nodes
+type Subscription struct {
+	C <-chan NodeUpdate // closed when the subscription ends
+
+	c       chan NodeUpdate
+	config  SubscriptionConfig
+	nodes   *Nodes
+	signal  chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+	pending map[string]*pendingUpdate
+	order   []string // node IDs of pending updates, in order of the first update
+	dropped bool     // closed, because the buffer was full
+	sync.Mutex
+}
+
+type pendingUpdate struct {
+	update NodeUpdate
+	due    time.Time
+}
+
+// Subscribe returns a subscription of the updates of all nodes
+func (nodes *Nodes) Subscribe(config SubscriptionConfig) *Subscription {
+	if config.Buffer <= 0 {
+		config.Buffer = subscriptionBufferDefault
+	}
+	c := make(chan NodeUpdate)
+	sub := &Subscription{
+		C:       c,
+		c:       c,
+		config:  config,
+		nodes:   nodes,
+		signal:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		pending: make(map[string]*pendingUpdate),
+	}
+
+	nodes.subscriptionsMu.Lock()
+	if nodes.subscriptions == nil {
+		nodes.subscriptions = make(map[*Subscription]struct{})
+	}
+	nodes.subscriptions[sub] = struct{}{}
+	nodes.subscriptionsMu.Unlock()
+
+	go sub.run()
+	return sub
+}
+
+// notify passes an update of a node (a copy without history) to all subscriptions
+func (nodes *Nodes) notify(nodeID string, node Node) {
+	nodes.subscriptionsMu.Lock()
+	if len(nodes.subscriptions) == 0 {
+		nodes.subscriptionsMu.Unlock()
+		return
+	}
+	subs := make([]*Subscription, 0, len(nodes.subscriptions))
+	for sub := range nodes.subscriptions {
+		subs = append(subs, sub)
+	}
+	nodes.subscriptionsMu.Unlock()
+
+	update := NodeUpdate{NodeID: nodeID, Node: &node}
+	for _, sub := range subs {
+		sub.push(update)
+	}
+}
+
+// push adds an update, an earlier pending update of the same node is replaced
+func (sub *Subscription) push(update NodeUpdate) {
+	sub.Lock()
+	defer sub.Unlock()
+
+	if sub.dropped {
+		return
+	}
+	if p, ok := sub.pending[update.NodeID]; ok {
+		p.update = update
+		return
+	}
+	if len(sub.order) >= sub.config.Buffer {
+		// the client does not keep up
+		sub.dropped = true
+		go sub.Close()
+		return
+	}
+	sub.pending[update.NodeID] = &pendingUpdate{
+		update: update,
+		due:    time.Now().Add(sub.config.Window.Duration),
+	}
+	sub.order = append(sub.order, update.NodeID)
+
+	select {
+	case sub.signal <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next due update or the period until it is due
+func (sub *Subscription) next(now time.Time) (NodeUpdate, time.Duration, bool) {
+	sub.Lock()
+	defer sub.Unlock()
+
+	if len(sub.order) == 0 {
+		return NodeUpdate{}, -1, false
+	}
+	p := sub.pending[sub.order[0]]
+	if wait := p.due.Sub(now); wait > 0 {
+		return NodeUpdate{}, wait, false
+	}
+	delete(sub.pending, sub.order[0])
+	sub.order = sub.order[1:]
+	return p.update, 0, true
+}
+
+func (sub *Subscription) run() {
+	defer close(sub.c)
+
+	var interval time.Duration
+	if sub.config.Rate > 0 {
+		interval = time.Second / time.Duration(sub.config.Rate)
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for {
+		update, wait, ok := sub.next(time.Now())
+		if !ok {
+			var due <-chan time.Time
+			if wait >= 0 {
+				timer.Reset(wait)
+				due = timer.C
+			}
+			select {
+			case <-sub.stop:
+				return
+			case <-sub.signal:
+				if due != nil && !timer.Stop() {
+					<-timer.C
+				}
+			case <-due:
+			}
+			continue
+		}
+
+		select {
+		case <-sub.stop:
+			return
+		case sub.c <- update:
+		}
+
+		if interval > 0 {
+			timer.Reset(interval)
+			select {
+			case <-sub.stop:
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// Dropped returns whether the subscription was closed, because the updates were not received fast enough
+func (sub *Subscription) Dropped() bool {
+	sub.Lock()
+	defer sub.Unlock()
+	return sub.dropped
+}
+
+// Close ends the subscription
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		sub.nodes.subscriptionsMu.Lock()
+		delete(sub.nodes.subscriptions, sub)
+		sub.nodes.subscriptionsMu.Unlock()
+		close(sub.stop)
+	})
+}
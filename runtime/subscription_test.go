@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+)
+
+func receiveUpdate(sub *Subscription, timeout time.Duration) (NodeUpdate, bool) {
+	select {
+	case update, ok := <-sub.C:
+		return update, ok
+	case <-time.After(timeout):
+		return NodeUpdate{}, false
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := NewNodes(&NodesConfig{})
+	sub := nodes.Subscribe(SubscriptionConfig{})
+
+	nodes.Update("000000000001", &data.ResponseData{
+		Statistics: &data.Statistics{Clients: data.Clients{Total: 1}},
+	})
+	update, ok := receiveUpdate(sub, time.Second)
+	assert.True(ok)
+	assert.Equal("000000000001", update.NodeID)
+	assert.EqualValues(1, update.Node.Statistics.Clients.Total)
+
+	sub.Close()
+	_, ok = receiveUpdate(sub, time.Second)
+	assert.False(ok)
+	assert.False(sub.Dropped())
+
+	// no subscriptions left
+	nodes.Update("000000000001", &data.ResponseData{})
+	assert.Len(nodes.subscriptions, 0)
+}
+
+func TestSubscriptionCoalesce(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := NewNodes(&NodesConfig{})
+	config := SubscriptionConfig{}
+	config.Window.Duration = 50 * time.Millisecond
+	sub := nodes.Subscribe(config)
+	defer sub.Close()
+
+	for _, clients := range []uint32{1, 2, 3} {
+		nodes.Update("000000000001", &data.ResponseData{
+			Statistics: &data.Statistics{Clients: data.Clients{Total: clients}},
+		})
+	}
+	nodes.Update("000000000002", &data.ResponseData{})
+
+	update, ok := receiveUpdate(sub, time.Second)
+	assert.True(ok)
+	assert.Equal("000000000001", update.NodeID)
+	assert.EqualValues(3, update.Node.Statistics.Clients.Total)
+
+	update, ok = receiveUpdate(sub, time.Second)
+	assert.True(ok)
+	assert.Equal("000000000002", update.NodeID)
+
+	_, ok = receiveUpdate(sub, 100*time.Millisecond)
+	assert.False(ok)
+}
+
+func TestSubscriptionRate(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := NewNodes(&NodesConfig{})
+	sub := nodes.Subscribe(SubscriptionConfig{Rate: 10})
+	defer sub.Close()
+
+	nodes.Update("000000000001", &data.ResponseData{})
+	nodes.Update("000000000002", &data.ResponseData{})
+
+	start := time.Now()
+	_, ok := receiveUpdate(sub, time.Second)
+	assert.True(ok)
+	_, ok = receiveUpdate(sub, time.Second)
+	assert.True(ok)
+	assert.True(time.Since(start) >= 90*time.Millisecond)
+}
+
+func TestSubscriptionBuffer(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := NewNodes(&NodesConfig{})
+	config := SubscriptionConfig{Buffer: 2}
+	config.Window.Duration = time.Hour
+	sub := nodes.Subscribe(config)
+
+	nodes.Update("000000000001", &data.ResponseData{})
+	nodes.Update("000000000002", &data.ResponseData{})
+	nodes.Update("000000000001", &data.ResponseData{}) // coalesced
+	assert.False(sub.Dropped())
+
+	nodes.Update("000000000003", &data.ResponseData{})
+	assert.True(sub.Dropped())
+	_, ok := receiveUpdate(sub, time.Second)
+	assert.False(ok)
+}
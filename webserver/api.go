@@ -0,0 +1,177 @@
+package webserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FreifunkBremen/yanic/output/filter"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// eventsKeepalive is the interval of comments sent on an idle event stream, so proxies keep the connection
+const eventsKeepalive = 30 * time.Second
+
+// apiHandler serves the live data of the nodes under /api/...
+type apiHandler struct {
+	nodes        *runtime.Nodes
+	filter       filter.Set                 // applied to the nodes before they are served
+	sitesDomains func() map[string][]string // sites and domains of the global statistics, could be nil
+	events       runtime.SubscriptionConfig
+	shutdown     chan struct{} // closed on shutdown of the webserver to end the event streams
+}
+
+// nodeEvent is an update of a node on the event stream
+type nodeEvent struct {
+	NodeID string        `json:"nodeid"`
+	Node   *runtime.Node `json:"node"`
+}
+
+func (h *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/")
+	switch {
+	case path == "nodes":
+		h.serveNodes(w)
+	case strings.HasPrefix(path, "nodes/"):
+		h.serveNode(w, r, strings.TrimPrefix(path, "nodes/"))
+	case path == "stats":
+		var sitesDomains map[string][]string
+		if h.sitesDomains != nil {
+			sitesDomains = h.sitesDomains()
+		}
+		writeJSON(w, runtime.NewGlobalStats(h.source(), sitesDomains))
+	case path == "links":
+		writeJSON(w, h.links())
+	case path == "events":
+		h.serveEvents(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// source returns the nodes of the API, filtered if a filter is configured
+func (h *apiHandler) source() *runtime.Nodes {
+	if len(h.filter) == 0 {
+		return h.nodes
+	}
+	return h.filter.Apply(h.nodes)
+}
+
+// serveNodes sends all nodes by their node ID
+func (h *apiHandler) serveNodes(w http.ResponseWriter) {
+	// encode under the lock, but do not block the nodes by a slow client
+	var buf bytes.Buffer
+	nodes := h.source()
+	nodes.RLock()
+	err := json.NewEncoder(&buf).Encode(nodes.List)
+	nodes.RUnlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+// serveNode sends a single node
+func (h *apiHandler) serveNode(w http.ResponseWriter, r *http.Request, nodeID string) {
+	var buf bytes.Buffer
+	h.nodes.RLock()
+	node, ok := h.nodes.List[nodeID]
+	var err error
+	if ok {
+		if node = h.filter.ApplyNode(node); node == nil {
+			ok = false
+		} else {
+			err = json.NewEncoder(&buf).Encode(node)
+		}
+	}
+	h.nodes.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+// serveEvents streams the updates of the nodes as server-sent events, until the client disconnects
+func (h *apiHandler) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := h.nodes.Subscribe(h.events)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventsKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.shutdown:
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case update, ok := <-sub.C:
+			if !ok {
+				// the client does not keep up
+				return
+			}
+			node := h.filter.ApplyNode(update.Node)
+			if node == nil {
+				continue
+			}
+			event, err := json.Marshal(nodeEvent{NodeID: update.NodeID, Node: node})
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: node\ndata: %s\n\n", event)
+		}
+		flusher.Flush()
+	}
+}
+
+// links returns the links of all online nodes, sorted by source and target
+func (h *apiHandler) links() []runtime.Link {
+	result := []runtime.Link{}
+	nodes := h.source()
+	nodes.RLock()
+	for _, node := range nodes.List {
+		if node.Online {
+			result = append(result, nodes.NodeLinks(node)...)
+		}
+	}
+	nodes.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.SourceAddress != b.SourceAddress {
+			return a.SourceAddress < b.SourceAddress
+		}
+		return a.TargetAddress < b.TargetAddress
+	})
+	return result
+}
@@ -0,0 +1,179 @@
+package webserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	_ "github.com/FreifunkBremen/yanic/output/filter/anonymize"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestAPI(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	nodes.Update("000000000001", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "000000000001",
+			Hostname: "node1",
+			Network:  data.Network{Mac: "00:00:00:00:00:01"},
+			System:   data.System{SiteCode: "ffhb", DomainCode: "city"},
+			Custom:   map[string]json.RawMessage{"community": json.RawMessage(`{"contact_room":"#ffhb"}`)},
+		},
+		Statistics: &data.Statistics{
+			Clients: data.Clients{Total: 23},
+		},
+		Neighbours: &data.Neighbours{
+			NodeID: "000000000001",
+			Batadv: map[string]data.BatadvNeighbours{
+				"00:00:00:00:00:01": {
+					Neighbours: map[string]data.BatmanLink{
+						"00:00:00:00:00:02": {Tq: 255},
+					},
+				},
+			},
+		},
+	})
+	nodes.Update("000000000002", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "000000000002",
+			Hostname: "node2",
+			Network:  data.Network{Mac: "00:00:00:00:00:02"},
+		},
+	})
+
+	get := func(handler http.Handler, path string, v interface{}) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code == http.StatusOK {
+			assert.Equal("application/json", rec.Header().Get("Content-Type"), path)
+			assert.NoError(json.NewDecoder(rec.Body).Decode(v), path)
+		}
+		return rec.Code
+	}
+
+	// disabled
+	handler := New(Config{Webroot: "/nonexisting"}, nodes, nil).Handler
+	assert.Equal(http.StatusNotFound, get(handler, "/api/nodes", nil))
+
+	handler = New(Config{Webroot: "/nonexisting", API: true}, nodes, nil).Handler
+
+	var list map[string]runtime.Node
+	assert.Equal(http.StatusOK, get(handler, "/api/nodes", &list))
+	assert.Len(list, 2)
+	assert.Equal("node1", list["000000000001"].Nodeinfo.Hostname)
+	assert.JSONEq(`{"contact_room":"#ffhb"}`, string(list["000000000001"].Nodeinfo.Custom["community"]))
+
+	var node runtime.Node
+	assert.Equal(http.StatusOK, get(handler, "/api/nodes/000000000002", &node))
+	assert.Equal("node2", node.Nodeinfo.Hostname)
+	assert.True(node.Online)
+
+	var stats map[string]map[string]runtime.GlobalStats
+	assert.Equal(http.StatusOK, get(handler, "/api/stats", &stats))
+	assert.EqualValues(2, stats[runtime.GLOBAL_SITE][runtime.GLOBAL_DOMAIN].Nodes)
+	assert.EqualValues(23, stats[runtime.GLOBAL_SITE][runtime.GLOBAL_DOMAIN].Clients)
+
+	var links []runtime.Link
+	assert.Equal(http.StatusOK, get(handler, "/api/links", &links))
+	assert.Equal([]runtime.Link{{
+		Protocol:       runtime.LINK_PROTOCOL_BATADV,
+		SourceID:       "000000000001",
+		SourceHostname: "node1",
+		SourceAddress:  "00:00:00:00:00:01",
+		TargetID:       "000000000002",
+		TargetAddress:  "00:00:00:00:00:02",
+		TargetHostname: "node2",
+		TQ:             1,
+	}}, links)
+
+	for _, path := range []string{"/api/nodes/112233445566", "/api/nodes/", "/api/blub"} {
+		assert.Equal(http.StatusNotFound, get(handler, path, nil), path)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/nodes", nil))
+	assert.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestAPIFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	nodes.Update("000000000001", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{
+			NodeID:   "000000000001",
+			Owner:    &data.Owner{Contact: "mail@example.org"},
+			Location: &data.Location{Latitude: 53.123456, Longitude: 8.654321},
+		},
+	})
+	handler := New(Config{Webroot: "/nonexisting", API: true, APIFilter: map[string]interface{}{
+		"anonymize": map[string]interface{}{"owner": "remove", "location_precision": int64(1)},
+	}}, nodes, nil).Handler
+
+	for _, path := range []string{"/api/nodes", "/api/nodes/000000000001"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(http.StatusOK, rec.Code, path)
+		assert.NotContains(rec.Body.String(), "example.org", path)
+		assert.NotContains(rec.Body.String(), "53.123456", path)
+		assert.Contains(rec.Body.String(), "53.1", path)
+	}
+
+	// the nodes itself are kept
+	assert.Equal("mail@example.org", nodes.List["000000000001"].Nodeinfo.Owner.Contact)
+
+	assert.Panics(func() {
+		New(Config{API: true, APIFilter: map[string]interface{}{"unknown": true}}, nodes, nil)
+	})
+}
+
+func TestAPIEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	srv := New(Config{Webroot: "/nonexisting", API: true}, nodes, nil)
+	ts := httptest.NewUnstartedServer(srv.Handler)
+	ts.Config = srv
+	ts.Start()
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/events")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.Equal("text/event-stream", res.Header.Get("Content-Type"))
+
+	nodes.Update("000000000001", &data.ResponseData{
+		Nodeinfo: &data.Nodeinfo{NodeID: "000000000001", Hostname: "node1"},
+	})
+
+	reader := bufio.NewReader(res.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(err)
+	assert.Equal("event: node\n", line)
+	line, err = reader.ReadString('\n')
+	assert.NoError(err)
+
+	var event struct {
+		NodeID string       `json:"nodeid"`
+		Node   runtime.Node `json:"node"`
+	}
+	assert.NoError(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+	assert.Equal("000000000001", event.NodeID)
+	assert.Equal("node1", event.Node.Nodeinfo.Hostname)
+
+	// the stream ends on shutdown
+	Shutdown(srv)
+	_, err = reader.ReadString('\n')
+	assert.NoError(err)
+	_, err = reader.ReadString('\n')
+	assert.Error(err)
+}
@@ -1,7 +1,19 @@
 package webserver
 
+import "github.com/FreifunkBremen/yanic/runtime"
+
 type Config struct {
-	Enable  bool   `toml:"enable"`
-	Bind    string `toml:"bind"`
-	Webroot string `toml:"webroot"`
+	Enable     bool   `toml:"enable"`
+	Bind       string `toml:"bind"`
+	Webroot    string `toml:"webroot"`
+	DebugToken string `toml:"debug_token"`
+	Metrics    bool   `toml:"metrics"`
+	MaxNodes   int    `toml:"metrics_max_nodes"`
+	API        bool   `toml:"api"`
+	Health     bool   `toml:"health"`
+
+	// filters of the nodes of the API, as the filters of the outputs
+	APIFilter map[string]interface{} `toml:"api_filter"`
+
+	Events runtime.SubscriptionConfig `toml:"events"`
 }
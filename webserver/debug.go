@@ -0,0 +1,128 @@
+package webserver
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/respond"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// debugAuth allows only requests with the debug token as bearer token
+func debugAuth(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// captureHandler streams the recent received datagrams of the collector
+type captureHandler struct {
+	collector *respond.Collector
+}
+
+func (h *captureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.collector == nil || !h.collector.CaptureEnabled() {
+		http.Error(w, "capture is disabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="capture.bin"`)
+	if err := h.collector.WriteCapture(w); err != nil {
+		log.WithField("remote", r.RemoteAddr).Warnf("unable to write capture: %s", err)
+	}
+}
+
+// collectHandler triggers a round of requests of the collector
+type collectHandler struct {
+	collector *respond.Collector
+}
+
+func (h *collectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.collector == nil {
+		http.Error(w, "respondd is disabled", http.StatusNotFound)
+		return
+	}
+	if err := h.collector.Collect(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// debugQueryTimeout is the time to wait for the response to a query
+const debugQueryTimeout = 5 * time.Second
+
+// queryHandler sends a request to a single address (e.g. ?address=fe80::1%25bat0) and returns the parsed response
+type queryHandler struct {
+	collector *respond.Collector
+}
+
+func (h *queryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.collector == nil {
+		http.Error(w, "respondd is disabled", http.StatusNotFound)
+		return
+	}
+	addr, err := respond.ParseAddress(r.URL.Query().Get("address"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := h.collector.Query(addr, debugQueryTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+		return
+	}
+	writeJSON(w, res)
+}
+
+// conflictsHandler lists the addresses claimed by more than one node
+type conflictsHandler struct {
+	nodes *runtime.Nodes
+}
+
+func (h *conflictsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, h.nodes.AddressConflicts())
+}
+
+// interfacesHandler lists the status of the sockets of the collector
+type interfacesHandler struct {
+	collector *respond.Collector
+}
+
+func (h *interfacesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.collector == nil {
+		http.Error(w, "respondd is disabled", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, h.collector.InterfaceStatus())
+}
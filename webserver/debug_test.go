@@ -0,0 +1,132 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/respond"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestDebugCapture(t *testing.T) {
+	assert := assert.New(t)
+
+	request := func(handler http.Handler, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/capture", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// without token the endpoint is disabled (and the file server answers)
+	handler := New(Config{Webroot: "/nonexisting"}, nil, nil).Handler
+	assert.Equal(http.StatusNotFound, request(handler, ""))
+
+	handler = New(Config{Webroot: "/nonexisting", DebugToken: "secret"}, nil, nil).Handler
+	assert.Equal(http.StatusUnauthorized, request(handler, ""))
+	assert.Equal(http.StatusUnauthorized, request(handler, "wrong"))
+
+	// authorized, but without collector
+	assert.Equal(http.StatusNotFound, request(handler, "secret"))
+}
+
+func TestDebugInterfaces(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, collector := range []*respond.Collector{nil, respond.NewCollector(nil, nil, &respond.Config{})} {
+		handler := New(Config{Webroot: "/nonexisting", DebugToken: "secret"}, nil, collector).Handler
+
+		req := httptest.NewRequest(http.MethodGet, "/debug/interfaces", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if collector == nil {
+			assert.Equal(http.StatusNotFound, rec.Code)
+		} else {
+			assert.Equal(http.StatusOK, rec.Code)
+			assert.Equal("[]\n", rec.Body.String())
+			collector.Close()
+		}
+	}
+}
+
+func TestDebugCollect(t *testing.T) {
+	assert := assert.New(t)
+
+	collector := respond.NewCollector(nil, nil, &respond.Config{})
+	defer collector.Close()
+
+	request := func(collector *respond.Collector, method string) int {
+		handler := New(Config{Webroot: "/nonexisting", DebugToken: "secret"}, nil, collector).Handler
+		req := httptest.NewRequest(method, "/debug/collect", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(http.StatusNotFound, request(nil, http.MethodPost))
+	assert.Equal(http.StatusMethodNotAllowed, request(collector, http.MethodGet))
+	// not started
+	assert.Equal(http.StatusServiceUnavailable, request(collector, http.MethodPost))
+
+	collector.Start(time.Hour)
+	assert.Equal(http.StatusAccepted, request(collector, http.MethodPost))
+}
+
+func TestDebugQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	collector := respond.NewCollector(nil, nil, &respond.Config{})
+	defer collector.Close()
+
+	request := func(collector *respond.Collector, path string) int {
+		handler := New(Config{Webroot: "/nonexisting", DebugToken: "secret"}, nil, collector).Handler
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(http.StatusNotFound, request(nil, "/debug/query?address=fe80::1"))
+	assert.Equal(http.StatusBadRequest, request(collector, "/debug/query?address=node1"))
+	// without interfaces
+	assert.Equal(http.StatusGatewayTimeout, request(collector, "/debug/query?address=fe80::1%25bat0"))
+}
+
+func TestDebugConflicts(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	for _, nodeID := range []string{"000000000001", "000000000002"} {
+		nodes.Update(nodeID, &data.ResponseData{
+			Nodeinfo: &data.Nodeinfo{
+				NodeID:  nodeID,
+				Network: data.Network{Mac: "de:ad:be:ef:00:01"},
+			},
+		})
+	}
+	handler := New(Config{Webroot: "/nonexisting", DebugToken: "secret"}, nodes, nil).Handler
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/conflicts", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(http.StatusOK, rec.Code)
+
+	var conflicts []runtime.AddressConflict
+	assert.NoError(json.Unmarshal(rec.Body.Bytes(), &conflicts))
+	assert.Len(conflicts, 1)
+	assert.Equal("000000000001", conflicts[0].NodeID)
+}
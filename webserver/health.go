@@ -0,0 +1,29 @@
+package webserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/FreifunkBremen/yanic/respond"
+)
+
+// healthHandler answers 200 if the collector works, otherwise 503 with the reason
+type healthHandler struct {
+	collector *respond.Collector // nil if respondd is disabled
+}
+
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	if h.collector != nil {
+		if err := h.collector.Health(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
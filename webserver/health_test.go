@@ -0,0 +1,54 @@
+package webserver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/database"
+	"github.com/FreifunkBremen/yanic/respond"
+)
+
+// failedDB is a database, which failed to write
+type failedDB struct {
+	database.Connection
+}
+
+func (db *failedDB) Health() error {
+	return errors.New("last write failed")
+}
+
+func TestHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	get := func(config Config, collector *respond.Collector) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		New(config, nil, collector).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rec
+	}
+
+	// disabled
+	assert.Equal(http.StatusNotFound, get(Config{Webroot: "/nonexisting"}, nil).Code)
+
+	config := Config{Webroot: "/nonexisting", Health: true}
+	rec := get(config, nil)
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("ok\n", rec.Body.String())
+
+	collector := respond.NewCollector(nil, nil, &respond.Config{})
+	assert.Equal(http.StatusOK, get(config, collector).Code)
+	collector.Close()
+
+	collector = respond.NewCollector(&failedDB{}, nil, &respond.Config{})
+	rec = get(config, collector)
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+	assert.Equal("last write failed\n", rec.Body.String())
+	collector.Close()
+
+	rec = httptest.NewRecorder()
+	New(config, nil, nil).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	assert.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
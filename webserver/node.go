@@ -0,0 +1,70 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// nodeHandler serves the data of a single node under /node/{nodeid}/...
+type nodeHandler struct {
+	nodes *runtime.Nodes
+}
+
+// nodeAddress is the last known address of a node
+type nodeAddress struct {
+	Address      string `json:"address"`
+	ResolvedName string `json:"resolved_name,omitempty"`
+}
+
+func (h *nodeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/node/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	nodeID := parts[0]
+
+	switch parts[1] {
+	case "history":
+		history, ok := h.nodes.History(nodeID)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, history)
+	case "address":
+		h.nodes.RLock()
+		node, ok := h.nodes.List[nodeID]
+		var result nodeAddress
+		if ok {
+			result.ResolvedName = node.ResolvedName
+			if node.Address != nil {
+				result.Address = node.Address.IP.String()
+			}
+		}
+		h.nodes.RUnlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, result)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeJSON sends the given value as json
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
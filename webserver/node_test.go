@@ -0,0 +1,67 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestNodeHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{HistoryDepth: 5})
+	nodes.Update("abcdef012345", &data.ResponseData{
+		Statistics: &data.Statistics{
+			Clients: data.Clients{Total: 23},
+		},
+	})
+	handler := New(Config{Webroot: "/tmp"}, nodes, nil).Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/node/abcdef012345/history", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("application/json", rec.Header().Get("Content-Type"))
+
+	var history []runtime.HistorySample
+	assert.NoError(json.NewDecoder(rec.Body).Decode(&history))
+	assert.Len(history, 1)
+	assert.EqualValues(23, history[0].Clients)
+
+	for _, path := range []string{"/node/112233445566/history", "/node/abcdef012345/blub", "/node/abcdef012345"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(http.StatusNotFound, rec.Code, path)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/node/abcdef012345/history", nil))
+	assert.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestNodeAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	nodes.AddNode(&runtime.Node{
+		Address:      &net.UDPAddr{IP: net.ParseIP("fe80::1")},
+		ResolvedName: "node1.example.org",
+		Nodeinfo:     &data.Nodeinfo{NodeID: "abcdef012345"},
+	})
+	handler := New(Config{Webroot: "/tmp"}, nodes, nil).Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/node/abcdef012345/address", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.JSONEq(`{"address":"fe80::1","resolved_name":"node1.example.org"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/node/112233445566/address", nil))
+	assert.Equal(http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,84 @@
+package webserver
+
+import (
+	"net/http"
+	"net/http/pprof"
+	goruntime "runtime"
+
+	"github.com/FreifunkBremen/yanic/respond"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+// NewProfiling creates the listener of the profiling endpoints (net/http/pprof) and of the internal state,
+// it should only be bound to localhost
+func NewProfiling(bind string, nodes *runtime.Nodes, collector *respond.Collector) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/state", &stateHandler{nodes: nodes, collector: collector})
+	return &http.Server{Addr: bind, Handler: mux}
+}
+
+// state is the internal state of yanic, e.g. to diagnose a growth of the memory
+type state struct {
+	Goroutines  int               `json:"goroutines"`
+	Nodes       int               `json:"nodes"`
+	NodesOnline int               `json:"nodes_online"`
+	QueueLength uint64            `json:"queue_length"`
+	Counters    map[string]uint64 `json:"counters,omitempty"`
+	Memory      memoryState       `json:"memory"`
+}
+
+// memoryState is the summary of the memory statistics of the go runtime, the details are in the heap profile
+type memoryState struct {
+	HeapAlloc   uint64 `json:"heap_alloc"`   // bytes of the allocated heap objects
+	HeapInuse   uint64 `json:"heap_inuse"`   // bytes of the used spans of the heap
+	HeapObjects uint64 `json:"heap_objects"` // count of the allocated heap objects
+	Sys         uint64 `json:"sys"`          // bytes obtained from the operating system
+	NumGC       uint32 `json:"num_gc"`
+}
+
+// stateHandler serves the internal state as json
+type stateHandler struct {
+	nodes     *runtime.Nodes
+	collector *respond.Collector // nil if respondd is disabled
+}
+
+func (h *stateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := state{Goroutines: goruntime.NumGoroutine()}
+	if h.nodes != nil {
+		h.nodes.RLock()
+		result.Nodes = len(h.nodes.List)
+		for _, node := range h.nodes.List {
+			if node.Online {
+				result.NodesOnline++
+			}
+		}
+		h.nodes.RUnlock()
+	}
+	if h.collector != nil {
+		result.QueueLength = h.collector.Counters().QueueLength
+		result.Counters = make(map[string]uint64)
+		for _, counter := range h.collector.InternalCounters() {
+			result.Counters[counter.Name] = counter.Value
+		}
+	}
+	var memory goruntime.MemStats
+	goruntime.ReadMemStats(&memory)
+	result.Memory = memoryState{
+		HeapAlloc:   memory.HeapAlloc,
+		HeapInuse:   memory.HeapInuse,
+		HeapObjects: memory.HeapObjects,
+		Sys:         memory.Sys,
+		NumGC:       memory.NumGC,
+	}
+	writeJSON(w, result)
+}
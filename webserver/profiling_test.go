@@ -0,0 +1,46 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/data"
+	"github.com/FreifunkBremen/yanic/respond"
+	"github.com/FreifunkBremen/yanic/runtime"
+)
+
+func TestProfiling(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+	nodes.Update("000000000001", &data.ResponseData{Nodeinfo: &data.Nodeinfo{NodeID: "000000000001"}})
+	collector := respond.NewCollector(nil, nodes, &respond.Config{})
+	defer collector.Close()
+	handler := NewProfiling("127.0.0.1:0", nodes, collector).Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/state", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	var result state
+	assert.NoError(json.NewDecoder(rec.Body).Decode(&result))
+	assert.Equal(1, result.Nodes)
+	assert.Equal(1, result.NodesOnline)
+	assert.True(result.Goroutines > 0)
+	assert.True(result.Memory.HeapAlloc > 0)
+	assert.Contains(result.Counters, "datagrams_received")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Contains(rec.Body.String(), "goroutine")
+
+	// without collector
+	rec = httptest.NewRecorder()
+	NewProfiling("127.0.0.1:0", nil, nil).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/state", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.NotContains(rec.Body.String(), "counters")
+}
@@ -1,17 +1,89 @@
 package webserver
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/NYTimes/gziphandler"
 	"github.com/bdlm/log"
+
+	"github.com/FreifunkBremen/yanic/output/filter"
+	"github.com/FreifunkBremen/yanic/output/prometheus"
+	"github.com/FreifunkBremen/yanic/respond"
+	"github.com/FreifunkBremen/yanic/runtime"
 )
 
 // New creates a new webserver and starts it
-func New(bindAddr, webroot string) *http.Server {
-	return &http.Server{
-		Addr:    bindAddr,
-		Handler: gziphandler.GzipHandler(http.FileServer(http.Dir(webroot))),
+func New(config Config, nodes *runtime.Nodes, collector *respond.Collector) *http.Server {
+	srv := &http.Server{
+		Addr: config.Bind,
+	}
+
+	// the event stream is served uncompressed, the gzip handler would buffer the events
+	stream := http.NewServeMux()
+	mux := http.NewServeMux()
+	stream.Handle("/", gziphandler.GzipHandler(mux))
+	mux.Handle("/", http.FileServer(http.Dir(config.Webroot)))
+	if config.Health {
+		mux.Handle("/healthz", &healthHandler{collector: collector})
+	}
+	if nodes != nil {
+		var sitesDomains func() map[string][]string
+		if collector != nil {
+			sitesDomains = collector.SitesDomains
+		}
+		mux.Handle("/node/", &nodeHandler{nodes: nodes})
+		if config.Metrics {
+			metrics := prometheus.HandlerConfig{
+				SitesDomains: sitesDomains,
+				MaxNodes:     config.MaxNodes,
+			}
+			if collector != nil {
+				metrics.Counters = collector.InternalCounters
+			}
+			mux.Handle("/metrics", prometheus.NewHandler(nodes, metrics))
+		}
+		if config.API {
+			filterSet, errs := filter.New(config.APIFilter)
+			if len(errs) > 0 {
+				log.Panicf("webserver.api_filter configuration errors: %v", errs)
+			}
+			api := &apiHandler{
+				nodes:        nodes,
+				filter:       filterSet,
+				sitesDomains: sitesDomains,
+				events:       config.Events,
+				shutdown:     make(chan struct{}),
+			}
+			srv.RegisterOnShutdown(func() { close(api.shutdown) })
+			mux.Handle("/api/", api)
+			stream.Handle("/api/events", api)
+		}
+	}
+	if config.DebugToken != "" {
+		mux.Handle("/debug/capture", debugAuth(config.DebugToken, &captureHandler{collector: collector}))
+		mux.Handle("/debug/collect", debugAuth(config.DebugToken, &collectHandler{collector: collector}))
+		mux.Handle("/debug/query", debugAuth(config.DebugToken, &queryHandler{collector: collector}))
+		mux.Handle("/debug/interfaces", debugAuth(config.DebugToken, &interfacesHandler{collector: collector}))
+		if nodes != nil {
+			mux.Handle("/debug/conflicts", debugAuth(config.DebugToken, &conflictsHandler{nodes: nodes}))
+		}
+	}
+
+	srv.Handler = stream
+	return srv
+}
+
+// shutdownTimeout is the time, running requests get to finish on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// Shutdown stops the webserver gracefully
+func Shutdown(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Errorf("webserver shutdown failed: %s", err)
 	}
 }
 
@@ -1,16 +1,21 @@
 package webserver
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/FreifunkBremen/yanic/respond"
+	"github.com/FreifunkBremen/yanic/runtime"
 )
 
 func TestWebserver(t *testing.T) {
 	assert := assert.New(t)
 
-	srv := New(":12345", "/tmp")
+	srv := New(Config{Bind: ":12345", Webroot: "/tmp"}, nil, nil)
 	assert.NotNil(srv)
 
 	go Start(srv)
@@ -21,5 +26,30 @@ func TestWebserver(t *testing.T) {
 		Start(srv)
 	}, "not allowed to listen twice")
 
-	srv.Close()
+	Shutdown(srv)
+	_, err := http.Get("http://localhost:12345/")
+	assert.Error(err)
+}
+
+func TestWebserverMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := runtime.NewNodes(&runtime.NodesConfig{})
+
+	rec := httptest.NewRecorder()
+	New(Config{Webroot: "/nonexisting"}, nodes, nil).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	New(Config{Webroot: "/nonexisting", Metrics: true}, nodes, nil).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Contains(rec.Body.String(), `yanic_nodes{site="global",domain="global"} 0`)
+
+	// with the internal counters of the collector
+	collector := respond.NewCollector(nil, nodes, &respond.Config{})
+	defer collector.Close()
+	rec = httptest.NewRecorder()
+	New(Config{Webroot: "/nonexisting", Metrics: true}, nodes, collector).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(rec.Body.String(), "yanic_responses_dropped_late_total 0\n")
+	assert.Contains(rec.Body.String(), "yanic_busy_rounds_total 0\n")
 }